@@ -0,0 +1,332 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/AndreasSko/go-jwlm/publication"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/spf13/cobra"
+)
+
+var catalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "Download and query the catalog of publications",
+	Long: `The catalog (catalog.db) contains all publications of JW Library. It is
+used to show the titles of publications, for example when merging with
+--catalog. By default, it is stored in the cache directory of your user
+(use --catalog for another location).`,
+}
+
+var catalogDownloadCmd = &cobra.Command{
+	Use:   "download",
+	Short: "Download the newest catalog, if it is not up-to-date yet",
+	Example: `go-jwlm catalog download
+go-jwlm catalog download --source https://mirror.example.com/catalogs/publications/v4
+go-jwlm catalog download --source catalog.db.gz --checksum <sha256>`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := publication.DownloadOptions{Checksum: CatalogChecksum, Retries: 2}
+		if CatalogSource != "" {
+			src, err := publication.ParseCatalogSource(CatalogSource)
+			if err != nil {
+				return err
+			}
+			opts.Source = src
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return catalogDownload(ctx, CatalogPath, opts, os.Stdout)
+	},
+	Args: cobra.NoArgs,
+}
+
+var catalogStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the version, size, and age of the catalog",
+	Long: `status shows the version, size, and age of the catalog. To tell if it is
+up-to-date, the source it has been downloaded from is asked for the newest
+version (use --source to ask another one).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var src publication.CatalogSource
+		if CatalogSource != "" {
+			var err error
+			if src, err = publication.ParseCatalogSource(CatalogSource); err != nil {
+				return err
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return catalogStatus(ctx, CatalogPath, src, os.Stdout)
+	},
+	Args: cobra.NoArgs,
+}
+
+var catalogLookupCmd = &cobra.Command{
+	Use:   "lookup",
+	Short: "Look up a publication by a document or its symbol and issue",
+	Example: `go-jwlm catalog lookup --document 1102002020 --language 0
+go-jwlm catalog lookup --symbol w --issue 20210200 --language 0 --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if CatalogLookupDocument == 0 && CatalogLookupSymbol == "" {
+			return errors.New("either --document or --symbol is needed")
+		}
+		return catalogLookup(CatalogPath, publication.Lookup{
+			DocumentID:     CatalogLookupDocument,
+			KeySymbol:      CatalogLookupSymbol,
+			IssueTagNumber: CatalogLookupIssue,
+			MepsLanguage:   CatalogLookupLanguage,
+		}, os.Stdout)
+	},
+	Args: cobra.NoArgs,
+}
+
+var catalogSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search publications by their title or symbol",
+	Example: `go-jwlm catalog search "Watchtower" --language 0
+go-jwlm catalog search nwtsty --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return catalogSearch(CatalogPath, args[0], CatalogSearchLanguage, CatalogSearchLimit, os.Stdout)
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// CatalogPath is the location of the catalog.db
+var CatalogPath string
+
+// CatalogJSON prints the results of the catalog commands as JSON
+var CatalogJSON bool
+
+// CatalogSource is the URL, directory, or catalog.db.gz the catalog is downloaded from
+var CatalogSource string
+
+// CatalogChecksum is the expected SHA-256 checksum of the downloaded catalog.db.gz
+var CatalogChecksum string
+
+// CatalogLookupDocument is the DocumentID of the publication to look up
+var CatalogLookupDocument int
+
+// CatalogLookupSymbol is the KeySymbol of the publication to look up
+var CatalogLookupSymbol string
+
+// CatalogLookupIssue is the IssueTagNumber of the publication to look up
+var CatalogLookupIssue int
+
+// CatalogLookupLanguage is the MEPS language of the publication to look up
+var CatalogLookupLanguage int
+
+// CatalogSearchLanguage only shows publications in the given MEPS language (-1 shows all)
+var CatalogSearchLanguage int
+
+// CatalogSearchLimit is the maximum number of publications shown by catalog search
+var CatalogSearchLimit int
+
+// catalogStatusInfo describes the catalog.db for catalog status
+type catalogStatusInfo struct {
+	Path     string     `json:"path"`
+	Exists   bool       `json:"exists"`
+	Version  string     `json:"version,omitempty"`
+	Size     int64      `json:"size,omitempty"`
+	Modified *time.Time `json:"modified,omitempty"`
+	// NeedsUpdate is nil if it can't be checked
+	NeedsUpdate *bool `json:"needsUpdate"`
+}
+
+// catalogDownload downloads the catalog to path while drawing a progress bar to out.
+func catalogDownload(ctx context.Context, path string, opts publication.DownloadOptions, out io.Writer) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for catalog: %w", err)
+	}
+	var modifiedBefore time.Time
+	if stat, err := os.Stat(path); err == nil {
+		modifiedBefore = stat.ModTime()
+	}
+
+	fmt.Fprintln(out, "Downloading catalog")
+	err := withProgress(out, func(prgrs chan model.Progress) error {
+		defer close(prgrs)
+		downloadPrgrs := make(chan publication.Progress)
+		done := make(chan error)
+		go func() {
+			done <- publication.DownloadCatalogFrom(ctx, downloadPrgrs, path, opts)
+		}()
+		for p := range downloadPrgrs {
+			prgrs <- model.Progress{
+				Step:      "kB",
+				Completed: int(p.BytesComplete / 1000),
+				Total:     int(p.Size / 1000),
+			}
+		}
+		return <-done
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download catalog: %w", err)
+	}
+
+	if stat, err := os.Stat(path); err == nil && stat.ModTime().Equal(modifiedBefore) {
+		fmt.Fprintf(out, "✅ Catalog at %s is already up-to-date\n", path)
+	} else {
+		fmt.Fprintf(out, "✅ Downloaded catalog %s to %s\n", publication.CatalogVersion(path), path)
+	}
+	return nil
+}
+
+// catalogStatus prints the version, size, and age of the catalog.db at path.
+// If it is up-to-date is checked by asking src or - if it is nil - the source
+// the catalog has been downloaded from. If the version of the catalog is
+// unknown, it is considered outdated after a month.
+func catalogStatus(ctx context.Context, path string, src publication.CatalogSource, out io.Writer) error {
+	info := catalogStatusInfo{Path: path}
+	var checkErr error
+	if stat, err := os.Stat(path); err == nil {
+		info.Exists = true
+		info.Version = publication.CatalogVersion(path)
+		info.Size = stat.Size()
+		modified := stat.ModTime()
+		info.Modified = &modified
+
+		needsUpdate, err := publication.CheckCatalogUpdate(ctx, path, src)
+		if errors.Is(err, publication.ErrCatalogVersionUnknown) {
+			needsUpdate, err = publication.CatalogNeedsUpdate(path), nil
+		}
+		if err == nil {
+			info.NeedsUpdate = &needsUpdate
+		}
+		checkErr = err
+	} else {
+		needsUpdate := true
+		info.NeedsUpdate = &needsUpdate
+	}
+
+	if CatalogJSON {
+		return printJSON(out, info)
+	}
+
+	if !info.Exists {
+		fmt.Fprintf(out, "No catalog found at %s. Download it using: go-jwlm catalog download\n", path)
+		return nil
+	}
+	version := info.Version
+	if version == "" {
+		version = "unknown"
+	}
+	upToDate := "yes"
+	switch {
+	case info.NeedsUpdate == nil:
+		upToDate = fmt.Sprintf("unknown (%v)", checkErr)
+	case *info.NeedsUpdate:
+		upToDate = "no, update it using: go-jwlm catalog download"
+	}
+	fmt.Fprintf(out, "Path:       %s\n", info.Path)
+	fmt.Fprintf(out, "Version:    %s\n", version)
+	fmt.Fprintf(out, "Size:       %s\n", formatBytes(info.Size))
+	fmt.Fprintf(out, "Downloaded: %s (%d days ago)\n", info.Modified.Format(time.DateTime), int(time.Since(*info.Modified).Hours()/24))
+	fmt.Fprintf(out, "Up-to-date: %s\n", upToDate)
+
+	return nil
+}
+
+// catalogLookup prints the publication described by query.
+func catalogLookup(path string, query publication.Lookup, out io.Writer) error {
+	publ, err := publication.LookupPublication(path, query)
+	if err != nil {
+		return fmt.Errorf("failed to look up publication: %w", err)
+	}
+	return printPublications(out, []publication.Publication{publ})
+}
+
+// catalogSearch prints the publications matching query.
+func catalogSearch(path string, query string, mepsLanguage int, limit int, out io.Writer) error {
+	publs, err := publication.SearchPublications(path, query, mepsLanguage, limit)
+	if err != nil {
+		return fmt.Errorf("failed to search publications: %w", err)
+	}
+	if !CatalogJSON && len(publs) == 0 {
+		fmt.Fprintln(out, "No publications found")
+		return nil
+	}
+	return printPublications(out, publs)
+}
+
+// printPublications prints publs as a table or, if --json is set, as JSON.
+func printPublications(out io.Writer, publs []publication.Publication) error {
+	if CatalogJSON {
+		return printJSON(out, publs)
+	}
+
+	t := table.NewWriter()
+	t.SetStyle(table.StyleRounded)
+	t.SetOutputMirror(out)
+	t.AppendHeader(table.Row{"ID", "Symbol", "Issue", "Language", "Year", "Title"})
+	for _, publ := range publs {
+		issue := ""
+		if publ.IssueTagNumber != 0 {
+			issue = strconv.Itoa(publ.IssueTagNumber)
+		}
+		t.AppendRow(table.Row{publ.ID, publ.KeySymbol.String, issue, publ.MepsLanguageID, publ.Year, publ.Describe()})
+	}
+	t.Render()
+
+	return nil
+}
+
+// printJSON prints v as indented JSON.
+func printJSON(out io.Writer, v interface{}) error {
+	jsn, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Fprintln(out, string(jsn))
+	return nil
+}
+
+// formatBytes formats size like "12.3 MB".
+func formatBytes(size int64) string {
+	const unit = 1000
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "kMGTPE"[exp])
+}
+
+// defaultCatalogPath returns the location of the catalog.db in the cache
+// directory of the user or in the working directory if there is none.
+func defaultCatalogPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "catalog.db"
+	}
+	return filepath.Join(dir, "go-jwlm", "catalog.db")
+}
+
+func init() {
+	rootCmd.AddCommand(catalogCmd)
+	catalogCmd.AddCommand(catalogDownloadCmd, catalogStatusCmd, catalogLookupCmd, catalogSearchCmd)
+	catalogCmd.PersistentFlags().StringVar(&CatalogPath, "catalog", defaultCatalogPath(), "Path to the catalog.db")
+	catalogCmd.PersistentFlags().BoolVar(&CatalogJSON, "json", false, "Print the results as JSON")
+	catalogDownloadCmd.Flags().StringVar(&CatalogSource, "source", "", "Download the catalog from a mirror: its URL, a local directory, or a catalog.db.gz (default is the JW CDN)")
+	catalogStatusCmd.Flags().StringVar(&CatalogSource, "source", "", "Check for updates at a mirror: its URL, a local directory, or a catalog.db.gz (default is the source it has been downloaded from)")
+	catalogDownloadCmd.Flags().StringVar(&CatalogChecksum, "checksum", "", "Expected SHA-256 checksum of the catalog.db.gz")
+	catalogLookupCmd.Flags().IntVar(&CatalogLookupDocument, "document", 0, "DocumentID of a document within the publication")
+	catalogLookupCmd.Flags().StringVar(&CatalogLookupSymbol, "symbol", "", "Symbol of the publication (like w)")
+	catalogLookupCmd.Flags().IntVar(&CatalogLookupIssue, "issue", 0, "IssueTagNumber of the publication (like 20210200)")
+	catalogLookupCmd.Flags().IntVar(&CatalogLookupLanguage, "language", 0, "MEPS language of the publication (like 0 for English)")
+	catalogSearchCmd.Flags().IntVar(&CatalogSearchLanguage, "language", -1, "Only show publications in this MEPS language")
+	catalogSearchCmd.Flags().IntVar(&CatalogSearchLimit, "limit", 20, "Maximum number of publications to show (0 shows all)")
+}
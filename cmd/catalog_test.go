@@ -0,0 +1,152 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AndreasSko/go-jwlm/publication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCatalog = filepath.Join("..", "publication", "testdata", "catalog.db")
+
+func Test_catalogDownload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "catalog.db")
+	src := &publication.FileCatalogSource{Path: testCatalog + ".gz"}
+	version, err := src.Current(context.Background())
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	err = catalogDownload(context.Background(), path, publication.DownloadOptions{Source: src}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "✅ Downloaded catalog "+version+" to "+path)
+	assert.Equal(t, publication.CatalogSize(testCatalog), publication.CatalogSize(path))
+
+	out.Reset()
+	err = catalogDownload(context.Background(), path, publication.DownloadOptions{Source: src}, &out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "✅ Catalog at "+path+" is already up-to-date")
+
+	path = filepath.Join(t.TempDir(), "catalog.db")
+	err = catalogDownload(context.Background(), path, publication.DownloadOptions{Source: src, Checksum: "abc"}, &out)
+	assert.Error(t, err)
+	assert.False(t, publication.CatalogExists(path))
+}
+
+func Test_catalogStatus(t *testing.T) {
+	defer func() { CatalogJSON = false }()
+
+	path := filepath.Join(t.TempDir(), "catalog.db")
+	var out bytes.Buffer
+	assert.NoError(t, catalogStatus(context.Background(), path, nil, &out))
+	assert.Equal(t, "No catalog found at "+path+". Download it using: go-jwlm catalog download\n", out.String())
+
+	data, err := os.ReadFile(testCatalog)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+
+	// Without a known version, only the age is checked
+	out.Reset()
+	assert.NoError(t, catalogStatus(context.Background(), path, nil, &out))
+	assert.Contains(t, out.String(), "Version:    unknown\n")
+	assert.Contains(t, out.String(), "Size:       77.8 kB\n")
+	assert.Contains(t, out.String(), "(0 days ago)\n")
+	assert.Contains(t, out.String(), "Up-to-date: yes\n")
+
+	CatalogJSON = true
+	out.Reset()
+	assert.NoError(t, catalogStatus(context.Background(), path, nil, &out))
+	info := catalogStatusInfo{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &info))
+	assert.True(t, info.Exists)
+	assert.Equal(t, int64(77824), info.Size)
+	require.NotNil(t, info.NeedsUpdate)
+	assert.False(t, *info.NeedsUpdate)
+	CatalogJSON = false
+
+	// A catalog downloaded from a mirror is compared to the mirror
+	src := &publication.FileCatalogSource{Path: testCatalog + ".gz"}
+	require.NoError(t, publication.DownloadCatalogFrom(context.Background(), nil, path, publication.DownloadOptions{Source: src}))
+	out.Reset()
+	assert.NoError(t, catalogStatus(context.Background(), path, nil, &out))
+	assert.Contains(t, out.String(), "Up-to-date: yes\n")
+
+	// Another source can be given
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"version": 1, "current": "164a1c4b-4dbd-4909-8f88-8e7a18c562f2"}`))
+	}))
+	out.Reset()
+	assert.NoError(t, catalogStatus(context.Background(), path, publication.NewHTTPCatalogSource(server.URL), &out))
+	assert.Contains(t, out.String(), "Up-to-date: no, update it using: go-jwlm catalog download\n")
+
+	// If the source can't be reached, it is unknown
+	server.Close()
+	out.Reset()
+	assert.NoError(t, catalogStatus(context.Background(), path, publication.NewHTTPCatalogSource(server.URL), &out))
+	assert.Contains(t, out.String(), "Up-to-date: unknown (")
+
+	CatalogJSON = true
+	out.Reset()
+	assert.NoError(t, catalogStatus(context.Background(), path, publication.NewHTTPCatalogSource(server.URL), &out))
+	info = catalogStatusInfo{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &info))
+	assert.Nil(t, info.NeedsUpdate)
+}
+
+func Test_catalogLookup(t *testing.T) {
+	defer func() { CatalogJSON = false }()
+
+	var out bytes.Buffer
+	assert.NoError(t, catalogLookup(testCatalog, publication.Lookup{DocumentID: 1102002020, MepsLanguage: 0}, &out))
+	assert.Contains(t, out.String(), "Draw Close to Jehovah (2014)")
+
+	CatalogJSON = true
+	out.Reset()
+	assert.NoError(t, catalogLookup(testCatalog, publication.Lookup{KeySymbol: "w", IssueTagNumber: 20210200}, &out))
+	var publs []map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &publs))
+	require.Len(t, publs, 1)
+	assert.Equal(t, float64(305097), publs[0]["id"])
+
+	assert.Error(t, catalogLookup(testCatalog, publication.Lookup{KeySymbol: "nonexistent"}, &out))
+}
+
+func Test_catalogSearch(t *testing.T) {
+	defer func() { CatalogJSON = false }()
+
+	var out bytes.Buffer
+	assert.NoError(t, catalogSearch(testCatalog, "jehov", -1, 20, &out))
+	assert.Contains(t, out.String(), "The Watchtower, February 2021")
+	assert.Contains(t, out.String(), "Draw Close to Jehovah (2014)")
+	assert.Contains(t, out.String(), "Acerquémonos a Jehová (2014)")
+
+	out.Reset()
+	assert.NoError(t, catalogSearch(testCatalog, "nonexistent", -1, 20, &out))
+	assert.Equal(t, "No publications found\n", out.String())
+
+	CatalogJSON = true
+	out.Reset()
+	assert.NoError(t, catalogSearch(testCatalog, "jehov", 1, 20, &out))
+	var publs []map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &publs))
+	require.Len(t, publs, 1)
+	assert.Equal(t, "Acerquémonos a Jehová", publs[0]["title"])
+
+	assert.Error(t, catalogSearch("nonexistent.db", "", -1, 0, &out))
+}
+
+func Test_formatBytes(t *testing.T) {
+	assert.Equal(t, "999 B", formatBytes(999))
+	assert.Equal(t, "77.8 kB", formatBytes(77824))
+	assert.Equal(t, "123.5 MB", formatBytes(123456789))
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/AndreasSko/go-jwlm/publication"
+	"github.com/MakeNowJust/heredoc"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var enrichCmd = &cobra.Command{
+	Use:   "enrich <input-backup> <output-backup>",
+	Short: "Fill missing titles of locations from the catalog",
+	Long: heredoc.Doc(`Fill missing titles and other metadata of the locations in the input
+	backup and store it as output, so JW Library shows proper titles for them.
+
+	Bible chapters get their reference (like "John 3") as title. Locations of
+	a whole publication get its title from the catalog.db given by --catalog.
+	As the catalog doesn't contain titles of single documents (like articles),
+	only their missing publication is filled in.`),
+	Example: `go-jwlm enrich original.jwlibrary enriched.jwlibrary --catalog catalog.db`,
+	Run: func(cmd *cobra.Command, args []string) {
+		inputFilename := args[0]
+		outputFilename := args[1]
+		enrich(inputFilename, outputFilename, terminal.Stdio{In: os.Stdin, Out: os.Stdout, Err: os.Stderr})
+	},
+	Args: cobra.ExactArgs(2),
+}
+
+// EnrichCatalog is the path to the catalog.db that is used to
+// look up the titles of publications
+var EnrichCatalog string
+
+func enrich(inputFilename string, outputFilename string, stdio terminal.Stdio) {
+	var catalog *publication.Catalog
+	if EnrichCatalog != "" {
+		var err error
+		catalog, err = publication.OpenCatalog(EnrichCatalog)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer catalog.Close()
+	}
+
+	fmt.Fprintln(stdio.Out, "Importing backup")
+	db := &model.Database{}
+	err := db.ImportJWLBackup(inputFilename)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Fprintln(stdio.Out, "📚 Filling missing titles")
+	enriched, err := publication.EnrichLocations(db, catalog)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(stdio.Out, "Enriched %d locations\n", enriched)
+
+	fmt.Fprintln(stdio.Out, "💾 Storing backup")
+	if err = db.ExportJWLBackup(outputFilename); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Fprintln(stdio.Out, "🎉 Done")
+}
+
+func init() {
+	rootCmd.AddCommand(enrichCmd)
+	enrichCmd.Flags().StringVar(&EnrichCatalog, "catalog", "", "Path to a catalog.db that is used to look up the titles of publications")
+}
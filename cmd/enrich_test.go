@@ -0,0 +1,70 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/AndreasSko/go-jwlm/model"
+	expect "github.com/Netflix/go-expect"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_enrich(t *testing.T) {
+	tmp := t.TempDir()
+
+	db := &model.Database{
+		Location: []*model.Location{
+			nil,
+			{
+				LocationID:    1,
+				BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+				KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+			},
+			{
+				LocationID:   2,
+				KeySymbol:    sql.NullString{String: "cl", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+				LocationType: 1,
+			},
+			{
+				LocationID:   3,
+				DocumentID:   sql.NullInt32{Int32: 1102002020, Valid: true},
+				KeySymbol:    sql.NullString{String: "cl", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+				Title:        sql.NullString{String: "Chapter 1", Valid: true},
+			},
+		},
+	}
+	inputFilename := filepath.Join(tmp, "input.jwlibrary")
+	assert.NoError(t, db.ExportJWLBackup(inputFilename))
+
+	EnrichCatalog = filepath.Join("..", "publication", "testdata", "catalog.db")
+	defer func() { EnrichCatalog = "" }()
+
+	outputFilename := filepath.Join(tmp, "enriched.jwlibrary")
+	RunCmdTest(t,
+		func(t *testing.T, c *expect.Console) {
+			_, err := c.ExpectString("Enriched 2 locations")
+			assert.NoError(t, err)
+			_, err = c.ExpectString("🎉 Done")
+			assert.NoError(t, err)
+			_, err = c.ExpectEOF()
+			assert.NoError(t, err)
+		},
+		func(t *testing.T, c *expect.Console) {
+			enrich(inputFilename, outputFilename, terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()})
+		})
+
+	output := &model.Database{}
+	assert.NoError(t, output.ImportJWLBackup(outputFilename))
+	assert.Equal(t, "John 3", output.Location[1].Title.String)
+	assert.Equal(t, "Draw Close to Jehovah", output.Location[2].Title.String)
+	assert.Equal(t, "Chapter 1", output.Location[3].Title.String)
+}
@@ -1,19 +1,26 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/AlecAivazis/survey/v2/terminal"
 	"github.com/AndreasSko/go-jwlm/merger"
 	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/AndreasSko/go-jwlm/publication"
+	"github.com/AndreasSko/go-jwlm/server"
 	"github.com/buger/goterm"
 	"github.com/jedib0t/go-pretty/table"
 	"github.com/spf13/cobra"
-
-	log "github.com/sirupsen/logrus"
 )
 
 // mergeCmd represents the merge command
@@ -25,16 +32,62 @@ exports it to the destination file. If a collision between the left and
 the right backup is detected, the user is asked to choose which side should
 be included in the merged backup. You are able to let the merger 
 automatically solve conflicts using the 'chooseLeft', 'chooseRight', and 
-'chooseNewest' resolvers (see Flags).`,
+'chooseNewest' resolvers (see Flags). With --browser, conflicts are shown
+side by side in your browser instead of the terminal.
+
+Your decisions are saved to a state file while merging (see --state). If
+the merge is interrupted, it can be continued later on using --resume
+without answering the same conflicts again. The resolvers are saved as well,
+and a decision is only reused as long as both sides of its conflict are
+unchanged. Conflicts can also be skipped for now, so they are asked again
+after the other ones.`,
 	Example: `go-jwlm merge left.jwlibrary right.jwlibrary merged.jwlibrary
-go-jwlm merge left.jwlibrary right.jwlibrary merged.jwlibrary --bookmarks chooseLeft --markings chooseRight --notes chooseNewest --inputFields chooseRight`,
+go-jwlm merge left.jwlibrary right.jwlibrary merged.jwlibrary --bookmarks chooseLeft --markings chooseRight --notes chooseNewest --inputFields chooseRight
+go-jwlm merge --resume merged.jwlibrary.state.json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		stdio := terminal.Stdio{In: os.Stdin, Out: os.Stdout, Err: os.Stderr}
+
+		// Stop the merge if it is interrupted, so no half-written backup is left behind
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		if MergeCatalog != "" && !publication.CatalogExists(MergeCatalog) {
+			return fmt.Errorf("catalog.db does not exist at %s", MergeCatalog)
+		}
+
+		if MergeResume != "" {
+			if MergeInBrowser {
+				return errors.New("--resume can't be combined with --browser")
+			}
+			state, err := loadMergeState(MergeResume)
+			if err != nil {
+				return err
+			}
+			// Resolvers given when resuming replace the stored ones
+			if state.Resolvers == nil {
+				state.Resolvers = map[string]string{}
+			}
+			for step, resolver := range mergeResolvers() {
+				state.Resolvers[step] = resolver
+			}
+			return runMerge(ctx, state, stdio)
+		}
+
 		leftFilename := args[0]
 		rightFilename := args[1]
 		mergedFilename := args[2]
-		return merge(leftFilename, rightFilename, mergedFilename, terminal.Stdio{In: os.Stdin, Out: os.Stdout, Err: os.Stderr})
+		if MergeInBrowser {
+			return mergeInBrowser(ctx, leftFilename, rightFilename, mergedFilename, stdio)
+		}
+		return merge(ctx, leftFilename, rightFilename, mergedFilename, stdio)
+	},
+	Args: func(cmd *cobra.Command, args []string) error {
+		// When resuming, the backups are taken from the state file
+		if MergeResume != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(3)(cmd, args)
 	},
-	Args: cobra.ExactArgs(3),
 }
 
 // BookmarkResolver represents a resolver that should be used for conflicting Bookmarks
@@ -53,203 +106,256 @@ var InputFieldResolver string
 // It is meant as a temporary workaround until merging of playlists is implemented.
 var SkipPlaylists bool
 
-func merge(leftFilename string, rightFilename string, mergedFilename string, stdio terminal.Stdio) error {
+// MetadataFrom indicates from which backup the name, device name, and thumbnail
+// of the merged backup should be inherited (can be 'left', 'right', or 'default')
+var MetadataFrom string
+
+// MergeInBrowser indicates if conflicts should be resolved using the browser UI
+var MergeInBrowser bool
+
+// MergeUIAddr is the address the browser UI is served on
+var MergeUIAddr string
+
+// BackupName overrides the name of the merged backup
+var BackupName string
+
+// DeviceName overrides the device name of the merged backup
+var DeviceName string
+
+// MergeStateFile is the file the decisions of a merge are saved to.
+// If it is empty, the file is stored next to the merged backup.
+var MergeStateFile string
+
+// MergeCatalog is the path to a catalog.db that is used to show the
+// publications of conflicting entries
+var MergeCatalog string
+
+// MergeResume is the state file of an interrupted merge that should be continued
+var MergeResume string
+
+// merge merges the left and right backup and exports the result to mergedFilename.
+// If ctx is canceled, the merge stops and no file is written.
+func merge(ctx context.Context, leftFilename string, rightFilename string, mergedFilename string, stdio terminal.Stdio) error {
+	return runMerge(ctx, newMergeState(MergeStateFile, leftFilename, rightFilename, mergedFilename, mergeResolvers()), stdio)
+}
+
+// runMerge merges the backups of state like merge using its resolvers.
+// Conflicts that have been decided before are solved using state, new
+// decisions are added to it.
+func runMerge(ctx context.Context, state *mergeState, stdio terminal.Stdio) error {
+	left, right, err := importLeftAndRight(ctx, state.Left, state.Right, stdio)
+	if err != nil {
+		return err
+	}
+
+	// The catalog is kept open while merging, as the publication is looked up for every conflict
+	var catalog *publication.Catalog
+	if MergeCatalog != "" {
+		if catalog, err = publication.OpenCatalog(MergeCatalog); err != nil {
+			return fmt.Errorf("failed to open catalog: %w", err)
+		}
+		defer catalog.Close()
+	}
+
+	fmt.Fprintln(stdio.Out, "⌛ Preparing Databases")
+	leftTmp, rightTmp, merged := merger.PrepareMerge(left, right)
+
+	for _, step := range merger.MergeSteps {
+		fmt.Fprintln(stdio.Out, mergeStepTitles[step.Name])
+		solutions := map[string]merger.MergeSolution{}
+		for {
+			err := step.Run(ctx, leftTmp, rightTmp, merged, solutions, state.Resolvers[step.Name])
+			if err == nil {
+				break
+			}
+			mcErr, ok := err.(merger.MergeConflictError)
+			if !ok {
+				return fmt.Errorf("failed to merge %s: %w", step.Name, err)
+			}
+			newSolutions, hErr := handleMergeConflict(mcErr.Conflicts, merged, catalog, state, stdio)
+			if hErr != nil {
+				return interruptedMerge(state, hErr)
+			}
+			addToSolutions(solutions, newSolutions)
+		}
+		fmt.Fprintln(stdio.Out, "Done.")
+	}
+
+	fmt.Fprintln(stdio.Out, "🎉 Finished merging!")
+
+	fmt.Fprintln(stdio.Out, "⌛ Preparing merged database for exporting")
+	if err := merger.PrepareDatabasesPostMerge(merged); err != nil {
+		return fmt.Errorf("failed to prepare database after merging: %w", err)
+	}
+
+	exportOptions, err := mergeExportOptions(left, right)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdio.Out, "Exporting merged database")
+	err = withProgress(stdio.Out, func(prgrs chan model.Progress) error {
+		return merged.ExportJWLBackupContext(ctx, state.Merged, exportOptions, prgrs)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export backup: %w", err)
+	}
+
+	return state.remove()
+}
+
+// mergeStepTitles are the messages shown before running each merger.MergeStep.
+var mergeStepTitles = map[string]string{
+	"locations":   "🧭 Merging Locations",
+	"bookmarks":   "📑 Merging Bookmarks",
+	"inputFields": "✍️  Merging InputFields",
+	"tags":        "🏷  Merging Tags",
+	"markings":    "🖍  Merging Markings",
+	"notes":       "📝 Merging Notes",
+	"tagMaps":     "🏷  Merging TagMaps",
+}
+
+// mergeResolvers returns the resolvers given by the flags by the name of
+// the merger.MergeStep they are used for.
+func mergeResolvers() map[string]string {
+	resolvers := map[string]string{
+		"bookmarks":   BookmarkResolver,
+		"markings":    MarkingResolver,
+		"notes":       NoteResolver,
+		"inputFields": InputFieldResolver,
+	}
+	for step, resolver := range resolvers {
+		if resolver == "" {
+			delete(resolvers, step)
+		}
+	}
+	return resolvers
+}
+
+// interruptedMerge saves state if the user interrupted the merge and
+// tells how to continue it. Other errors are returned as they are.
+func interruptedMerge(state *mergeState, err error) error {
+	if !errors.Is(err, errMergeInterrupted) {
+		return err
+	}
+	if err := state.save(); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w, continue it using: go-jwlm merge --resume %s", err, state.path)
+}
+
+// importLeftAndRight imports the left and right backup while showing their progress.
+func importLeftAndRight(ctx context.Context, leftFilename string, rightFilename string, stdio terminal.Stdio) (*model.Database, *model.Database, error) {
 	fmt.Fprintln(stdio.Out, "Importing left backup")
-	left := model.Database{
+	left := &model.Database{
 		SkipPlaylists: SkipPlaylists,
 	}
-	err := left.ImportJWLBackup(leftFilename)
+	err := withProgress(stdio.Out, func(prgrs chan model.Progress) error {
+		return left.ImportJWLBackupContext(ctx, leftFilename, prgrs)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to import left backup: %w", err)
+		return nil, nil, fmt.Errorf("failed to import left backup: %w", err)
 	}
 
 	fmt.Fprintln(stdio.Out, "Importing right backup")
-	right := model.Database{
+	right := &model.Database{
 		SkipPlaylists: SkipPlaylists,
 	}
-	err = right.ImportJWLBackup(rightFilename)
+	err = withProgress(stdio.Out, func(prgrs chan model.Progress) error {
+		return right.ImportJWLBackupContext(ctx, rightFilename, prgrs)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to import right backup: %w", err)
+		return nil, nil, fmt.Errorf("failed to import right backup: %w", err)
 	}
 
-	fmt.Fprintln(stdio.Out, "⌛ Preparing Databases")
-	merger.PrepareDatabasesPreMerge(&left, &right)
-
-	merged := model.Database{}
+	return left, right, nil
+}
 
-	fmt.Fprintln(stdio.Out, "🧭 Merging Locations")
-	mergedLocations, locationIDChanges, err := merger.MergeLocations(left.Location, right.Location)
+// mergeInBrowser merges the left and right backup like merge, but lets the
+// user resolve conflicts using the browser UI of the server package.
+// If ctx is canceled while waiting for the user, the merge is stopped.
+func mergeInBrowser(ctx context.Context, leftFilename string, rightFilename string, mergedFilename string, stdio terminal.Stdio) error {
+	left, right, err := importLeftAndRight(ctx, leftFilename, rightFilename, stdio)
 	if err != nil {
-		return fmt.Errorf("failed to merge locations: %w", err)
-	}
-	merged.Location = mergedLocations
-	merger.UpdateLRIDs(left.Bookmark, right.Bookmark, "LocationID", locationIDChanges)
-	merger.UpdateLRIDs(left.Bookmark, right.Bookmark, "PublicationLocationID", locationIDChanges)
-	merger.UpdateLRIDs(left.InputField, right.InputField, "LocationID", locationIDChanges)
-	merger.UpdateLRIDs(left.Note, right.Note, "LocationID", locationIDChanges)
-	merger.UpdateLRIDs(left.TagMap, right.TagMap, "LocationID", locationIDChanges)
-	merger.UpdateLRIDs(left.UserMark, right.UserMark, "LocationID", locationIDChanges)
-	fmt.Fprintln(stdio.Out, "Done.")
-
-	fmt.Fprintln(stdio.Out, "📑 Merging Bookmarks")
-	bookmarksConflictSolution := map[string]merger.MergeSolution{}
-	for {
-		mergedBookmarks, _, err := merger.MergeBookmarks(left.Bookmark, right.Bookmark, bookmarksConflictSolution)
-		if err == nil {
-			merged.Bookmark = mergedBookmarks
-			break
-		}
-		switch err := err.(type) {
-		case merger.MergeConflictError:
-			if BookmarkResolver != "" {
-				var resErr error
-				newSolutions, resErr := merger.AutoResolveConflicts(err.Conflicts, BookmarkResolver)
-				if resErr != nil {
-					log.Fatal(resErr)
-				}
-				addToSolutions(bookmarksConflictSolution, newSolutions)
-			} else {
-				newSolutions := handleMergeConflict(err.Conflicts, &merged, stdio)
-				addToSolutions(bookmarksConflictSolution, newSolutions)
-			}
-		default:
-			return fmt.Errorf("failed to merge bookmarks: %w", err)
-		}
+		return err
 	}
-	fmt.Fprintln(stdio.Out, "Done.")
 
-	fmt.Fprintln(stdio.Out, "✍️  Merging InputFields")
-	inputFieldConflictSolution := map[string]merger.MergeSolution{}
-	for {
-		mergedInputFields, _, err := merger.MergeInputFields(left.InputField, right.InputField, inputFieldConflictSolution)
-		if err == nil {
-			merged.InputField = mergedInputFields
-			break
-		}
-		switch err := err.(type) {
-		case merger.MergeConflictError:
-			if InputFieldResolver != "" {
-				var resErr error
-				newSolutions, resErr := merger.AutoResolveConflicts(err.Conflicts, InputFieldResolver)
-				if resErr != nil {
-					log.Fatal(resErr)
-				}
-				addToSolutions(inputFieldConflictSolution, newSolutions)
-			} else {
-				newSolutions := handleMergeConflict(err.Conflicts, &merged, stdio)
-				addToSolutions(inputFieldConflictSolution, newSolutions)
-			}
-		default:
-			return fmt.Errorf("failed to merge inputFields: %w", err)
-		}
+	exportOptions, err := mergeExportOptions(left, right)
+	if err != nil {
+		return err
 	}
-	fmt.Fprintln(stdio.Out, "Done.")
 
-	fmt.Fprintln(stdio.Out, "🏷  Merging Tags")
-	var tagsConflictSolution map[string]merger.MergeSolution
-	for {
-		mergedTags, tagIDChanges, err := merger.MergeTags(left.Tag, right.Tag, tagsConflictSolution)
-		if err == nil {
-			merged.Tag = mergedTags
-			merger.UpdateLRIDs(left.TagMap, right.TagMap, "TagID", tagIDChanges)
-			break
-		}
-		switch err := err.(type) {
-		case merger.MergeConflictError:
-			tagsConflictSolution = handleMergeConflict(err.Conflicts, nil, stdio) // TODO
-		default:
-			return fmt.Errorf("failed to merge tags: %w", err)
-		}
+	srv := server.New()
+	defer srv.Close()
+	done := make(chan error, 1)
+	srv.OnDone = func(id string, merged *model.Database) {
+		fmt.Fprintln(stdio.Out, "🎉 Finished merging!")
+		fmt.Fprintln(stdio.Out, "Exporting merged database")
+		done <- merged.ExportJWLBackupContext(ctx, mergedFilename, exportOptions, nil)
 	}
-	fmt.Fprintln(stdio.Out, "Done.")
-
-	fmt.Fprintln(stdio.Out, "🖍  Merging Markings")
-	UMBRConflictSolution := map[string]merger.MergeSolution{}
-	for {
-		mergedUserMarks, mergedBlockRanges, userMarkIDChanges, err := merger.MergeUserMarkAndBlockRange(left.UserMark, left.BlockRange, right.UserMark, right.BlockRange, UMBRConflictSolution)
-		if err == nil {
-			merged.UserMark = mergedUserMarks
-			merged.BlockRange = mergedBlockRanges
-			merger.UpdateLRIDs(left.Note, right.Note, "UserMarkID", userMarkIDChanges)
-			break
-		}
-		switch err := err.(type) {
-		case merger.MergeConflictError:
-			if MarkingResolver != "" {
-				var resErr error
-				newSolutions, resErr := merger.AutoResolveConflicts(err.Conflicts, MarkingResolver)
-				if resErr != nil {
-					log.Fatal(resErr)
-				}
-				addToSolutions(UMBRConflictSolution, newSolutions)
-			} else {
-				newSolutions := handleMergeConflict(err.Conflicts, &merged, stdio)
-				addToSolutions(UMBRConflictSolution, newSolutions)
-			}
-		default:
-			return fmt.Errorf("failed to merge markings: %w", err)
-		}
+
+	id, err := srv.CreateSession([]*model.Database{left, right}, mergeResolvers())
+	if err != nil {
+		return fmt.Errorf("failed to merge backups: %w", err)
 	}
-	fmt.Fprintln(stdio.Out, "Done.")
 
-	fmt.Fprintln(stdio.Out, "📝 Merging Notes")
-	notesConflictSolution := map[string]merger.MergeSolution{}
-	for {
-		mergedNotes, notesIDChanges, err := merger.MergeNotes(left.Note, right.Note, notesConflictSolution)
-		if err == nil {
-			merged.Note = mergedNotes
-			merger.UpdateLRIDs(left.TagMap, right.TagMap, "NoteID", notesIDChanges)
-			break
-		}
-		switch err := err.(type) {
-		case merger.MergeConflictError:
-			if NoteResolver != "" {
-				var resErr error
-				newSolutions, resErr := merger.AutoResolveConflicts(err.Conflicts, NoteResolver)
-				if resErr != nil {
-					log.Fatal(resErr)
-				}
-				addToSolutions(notesConflictSolution, newSolutions)
-			} else {
-				newSolutions := handleMergeConflict(err.Conflicts, &merged, stdio)
-				addToSolutions(notesConflictSolution, newSolutions)
-			}
-		default:
-			return fmt.Errorf("failed to merge notes: %w", err)
+	// Without conflicts, there is no need for the browser
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to export backup: %w", err)
 		}
+		return nil
+	default:
 	}
-	fmt.Fprintln(stdio.Out, "Done.")
 
-	fmt.Fprintln(stdio.Out, "🏷  Merging TagMaps")
-	var tagMapsConflictSolution map[string]merger.MergeSolution
-	for {
-		mergedTagMaps, _, err := merger.MergeTagMaps(left.TagMap, right.TagMap, tagMapsConflictSolution)
-		if err == nil {
-			merged.TagMap = mergedTagMaps
-			break
-		}
-		switch err := err.(type) {
-		case merger.MergeConflictError:
-			tagMapsConflictSolution = handleMergeConflict(err.Conflicts, nil, stdio)
-		default:
-			return fmt.Errorf("failed to merge tagMaps: %w", err)
+	listener, err := net.Listen("tcp", MergeUIAddr)
+	if err != nil {
+		return fmt.Errorf("failed to start browser UI: %w", err)
+	}
+	httpServer := &http.Server{Handler: srv}
+	go httpServer.Serve(listener)
+	defer httpServer.Close()
+
+	fmt.Fprintf(stdio.Out, "🌐 Open http://%s/#%s in your browser to resolve the conflicts\n", listener.Addr(), id)
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to export backup: %w", err)
 		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to merge backups: %w", ctx.Err())
 	}
-	fmt.Fprintln(stdio.Out, "Done.")
+}
 
-	fmt.Fprintln(stdio.Out, "🎉 Finished merging!")
+// mergeExportOptions returns the ExportOptions for the merged backup
+// according to the MetadataFrom, BackupName, and DeviceName flags.
+func mergeExportOptions(left *model.Database, right *model.Database) (model.ExportOptions, error) {
+	opts := model.ExportOptions{
+		Name:       BackupName,
+		DeviceName: DeviceName,
+	}
 
-	fmt.Fprintln(stdio.Out, "⌛ Preparing merged database for exporting")
-	if err := merger.PrepareDatabasesPostMerge(&merged); err != nil {
-		return fmt.Errorf("failed to prepare database after merging: %w", err)
+	switch MetadataFrom {
+	case "left":
+		opts.Metadata = left.Metadata
+	case "right":
+		opts.Metadata = right.Metadata
+	case "", "default":
+		opts.Metadata = &model.Metadata{}
+	default:
+		return opts, fmt.Errorf("%s is not a valid option for --metadata. Can be 'left', 'right', or 'default'", MetadataFrom)
 	}
 
-	fmt.Fprintln(stdio.Out, "Exporting merged database")
-	if err = merged.ExportJWLBackup(mergedFilename); err != nil {
-		return fmt.Errorf("failed to export backup: %w", err)
+	// Extra files (like media of playlists) are not merged yet
+	if opts.Metadata != nil {
+		md := *opts.Metadata
+		md.ExtraFiles = nil
+		opts.Metadata = &md
 	}
 
-	return nil
+	return opts, nil
 }
 
 // addToSolutions adds new mergeSolutions to the existing map of mergeSolutions
@@ -259,53 +365,93 @@ func addToSolutions(solutions map[string]merger.MergeSolution, new map[string]me
 	}
 }
 
-func handleMergeConflict(conflicts map[string]merger.MergeConflict, mergedDB *model.Database, stdio terminal.Stdio) map[string]merger.MergeSolution {
+// decideLater is the option of handleMergeConflict to skip a conflict for now
+const decideLater = "Decide later"
+
+// handleMergeConflict asks the user which side should be chosen for each of
+// the conflicts. Conflicts decided before are solved using state, and each
+// new decision is saved to it right away. A skipped conflict is asked again
+// after the remaining ones. If the user interrupts, errMergeInterrupted
+// is returned. If catalog is not nil, it is used to show the publications
+// of the conflicts.
+func handleMergeConflict(conflicts map[string]merger.MergeConflict, mergedDB *model.Database, catalog *publication.Catalog,
+	state *mergeState, stdio terminal.Stdio) (map[string]merger.MergeSolution, error) {
 	helpText := ""
 	for _, val := range conflicts {
 		helpText = mergeConflictHelp(reflect.TypeOf(val.Left).String())
 		break
 	}
 
-	prompt := &survey.Select{
-		Message: "Select which side should be chosen:",
-		Options: []string{"Left", "Right"},
-		Help:    helpText,
+	queue := make([]string, 0, len(conflicts))
+	for key := range conflicts {
+		queue = append(queue, key)
 	}
+	sort.Strings(queue)
 
 	result := make(map[string]merger.MergeSolution, len(conflicts))
-	for key, conflict := range conflicts {
-		t := table.NewWriter()
-		t.SetStyle(table.StyleRounded)
-		t.Style().Options = table.Options{
-			DrawBorder:      true,
-			SeparateColumns: true,
-			SeparateFooter:  true,
-			SeparateHeader:  true,
-			SeparateRows:    true,
-		}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		conflict := conflicts[key]
+
+		side, decided := state.decision(key, conflict)
+		if !decided {
+			t := table.NewWriter()
+			t.SetStyle(table.StyleRounded)
+			t.Style().Options = table.Options{
+				DrawBorder:      true,
+				SeparateColumns: true,
+				SeparateFooter:  true,
+				SeparateHeader:  true,
+				SeparateRows:    true,
+			}
 
-		t.SetOutputMirror(os.Stdout)
-		if goterm.Width() >= 190 {
-			t.AppendHeader(table.Row{"Left", "Right"})
-			t.AppendRow([]interface{}{conflict.Left.PrettyPrint(mergedDB), conflict.Right.PrettyPrint(mergedDB)})
-		} else {
-			t.AppendRows([]table.Row{{"Left"}, {conflict.Left.PrettyPrint(mergedDB)}, {"Right"}, {conflict.Right.PrettyPrint(mergedDB)}})
-		}
+			t.SetOutputMirror(stdio.Out)
+			if goterm.Width() >= 190 {
+				t.AppendHeader(table.Row{"Left", "Right"})
+				t.AppendRow([]interface{}{conflictDetails(conflict.Left, mergedDB, catalog), conflictDetails(conflict.Right, mergedDB, catalog)})
+			} else {
+				t.AppendRows([]table.Row{{"Left"}, {conflictDetails(conflict.Left, mergedDB, catalog)}, {"Right"}, {conflictDetails(conflict.Right, mergedDB, catalog)}})
+			}
+
+			t.Render()
 
-		t.Render()
+			fmt.Fprint(stdio.Out, "\n\n")
+
+			// Skipping only makes sense if there is another conflict left
+			options := []string{"Left", "Right"}
+			if len(queue) > 0 {
+				options = append(options, decideLater)
+			}
+			prompt := &survey.Select{
+				Message: "Select which side should be chosen:",
+				Options: options,
+				Help:    helpText,
+			}
 
-		fmt.Fprint(stdio.Out, "\n\n")
+			var selected string
+			err := survey.AskOne(prompt, &selected, survey.WithStdio(stdio.In, stdio.Out, stdio.Err))
+			if err == terminal.InterruptErr {
+				return nil, errMergeInterrupted
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to ask for conflict solution: %w", err)
+			}
 
-		var selected string
-		err := survey.AskOne(prompt, &selected, survey.WithStdio(stdio.In, stdio.Out, stdio.Err))
-		if err == terminal.InterruptErr {
-			fmt.Fprintln(stdio.Out, "interrupted")
-			os.Exit(0)
-		} else if err != nil {
-			panic(err)
+			switch selected {
+			case decideLater:
+				queue = append(queue, key)
+				continue
+			case "Left":
+				side = merger.LeftSide
+			default:
+				side = merger.RightSide
+			}
+			if err := state.decide(key, conflict, side); err != nil {
+				return nil, err
+			}
 		}
 
-		if selected == "Left" {
+		if side == merger.LeftSide {
 			result[key] = merger.MergeSolution{
 				Side:      merger.LeftSide,
 				Solution:  conflict.Left,
@@ -320,7 +466,42 @@ func handleMergeConflict(conflicts map[string]merger.MergeConflict, mergedDB *mo
 		}
 	}
 
-	return result
+	return result, nil
+}
+
+// conflictDetails pretty prints a side of a conflict. If known, the title of the
+// publication (looked up in catalog if it is not nil) and the verse
+// or paragraph it belongs to are shown in front of it.
+func conflictDetails(m model.Model, mergedDB *model.Database, catalog *publication.Catalog) string {
+	result := m.PrettyPrint(mergedDB)
+
+	related := m.RelatedEntries(mergedDB)
+	location, ok := m.(*model.Location)
+	if !ok {
+		location = related.Location
+	}
+	if location == nil {
+		return result
+	}
+
+	var details []string
+	if catalog != nil {
+		if publ, err := catalog.LookupLocation(location); err == nil {
+			details = append(details, "Publication: "+publ.Describe())
+		}
+	}
+	reference := related.Reference
+	if reference == "" {
+		reference = location.Reference()
+	}
+	if reference != "" {
+		details = append(details, "Reference:   "+reference)
+	}
+	if len(details) == 0 {
+		return result
+	}
+
+	return strings.Join(details, "\n") + "\n\n" + result
 }
 
 func init() {
@@ -329,5 +510,13 @@ func init() {
 	mergeCmd.Flags().StringVar(&MarkingResolver, "markings", "", "Resolve conflicting markings with resolver (can be 'chooseLeft' or 'chooseRight')")
 	mergeCmd.Flags().StringVar(&NoteResolver, "notes", "", "Resolve conflicting notes with resolver (can be 'chooseNewest', 'chooseLeft', or 'chooseRight')")
 	mergeCmd.Flags().StringVar(&InputFieldResolver, "inputFields", "", "Resolve conflicting inputFields with resolver (can be 'chooseLeft', or 'chooseRight')")
+	mergeCmd.Flags().StringVar(&MetadataFrom, "metadata", "default", "Inherit name, device name, and thumbnail of the merged backup from a backup (can be 'left', 'right', or 'default')")
+	mergeCmd.Flags().StringVar(&BackupName, "name", "", "Name of the merged backup")
+	mergeCmd.Flags().StringVar(&DeviceName, "device", "", "Device name of the merged backup")
+	mergeCmd.Flags().BoolVar(&MergeInBrowser, "browser", false, "Resolve conflicts in the browser instead of the terminal")
+	mergeCmd.Flags().StringVar(&MergeUIAddr, "addr", "localhost:0", "Address the browser UI is served on when using --browser")
+	mergeCmd.Flags().StringVar(&MergeStateFile, "state", "", "File the decisions are saved to while merging (default \"<dest-filename>.state.json\")")
+	mergeCmd.Flags().StringVar(&MergeCatalog, "catalog", "", "Path to a catalog.db that is used to show the publications of conflicting entries")
+	mergeCmd.Flags().StringVar(&MergeResume, "resume", "", "Continue an interrupted merge using its state file")
 	mergeCmd.Flags().BoolVar(&SkipPlaylists, "skipPlaylists", false, "Skip playlists when importing backups. It is meant as a temporary workaround until merging of playlists is implemented.")
 }
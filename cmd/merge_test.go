@@ -4,13 +4,21 @@
 package cmd
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/AlecAivazis/survey/v2/terminal"
 	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/AndreasSko/go-jwlm/publication"
 	expect "github.com/Netflix/go-expect"
 	"github.com/hinshun/vt10x"
 	"github.com/stretchr/testify/assert"
@@ -43,7 +51,7 @@ func Test_merge(t *testing.T) {
 			assert.NoError(t, err)
 		},
 		func(t *testing.T, c *expect.Console) {
-			merge(leftFilename, emptyFilename, mergedFilename,
+			merge(context.Background(), leftFilename, emptyFilename, mergedFilename,
 				terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()})
 			merged := &model.Database{}
 			merged.ImportJWLBackup(mergedFilename)
@@ -68,7 +76,7 @@ func Test_merge(t *testing.T) {
 			c.ExpectEOF()
 		},
 		func(t *testing.T, c *expect.Console) {
-			merge(leftFilename, rightFilename, mergedFilename,
+			merge(context.Background(), leftFilename, rightFilename, mergedFilename,
 				terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()})
 			merged := &model.Database{}
 			merged.ImportJWLBackup(mergedFilename)
@@ -93,7 +101,7 @@ func Test_merge(t *testing.T) {
 			c.ExpectEOF()
 		},
 		func(t *testing.T, c *expect.Console) {
-			merge(leftFilename, rightFilename, mergedFilename,
+			merge(context.Background(), leftFilename, rightFilename, mergedFilename,
 				terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()})
 			merged := &model.Database{}
 			merged.ImportJWLBackup(mergedFilename)
@@ -112,7 +120,7 @@ func Test_merge(t *testing.T) {
 			MarkingResolver = "chooseRight"
 			NoteResolver = "chooseNewest"
 			InputFieldResolver = "chooseRight"
-			merge(leftFilename, rightFilename, mergedFilename,
+			merge(context.Background(), leftFilename, rightFilename, mergedFilename,
 				terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()})
 			merged := &model.Database{}
 			merged.ImportJWLBackup(mergedFilename)
@@ -128,7 +136,7 @@ func Test_merge(t *testing.T) {
 		},
 		func(t *testing.T, c *expect.Console) {
 			MarkingResolver = "chooseRight"
-			merge(leftMultiCollisionFilename,
+			merge(context.Background(), leftMultiCollisionFilename,
 				rightMultiCollisionFilename,
 				mergedFilename,
 				terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()})
@@ -160,7 +168,7 @@ func Test_merge(t *testing.T) {
 			assert.NoError(t, leftNwtDB.ExportJWLBackup(leftNwtFilename))
 			assert.NoError(t, rightNwtDB.ExportJWLBackup(rightNwtFilename))
 
-			merge(leftNwtFilename, rightNwtFilename, mergedFilename,
+			merge(context.Background(), leftNwtFilename, rightNwtFilename, mergedFilename,
 				terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()})
 			merged := &model.Database{}
 			merged.ImportJWLBackup(mergedFilename)
@@ -180,7 +188,7 @@ func Test_merge(t *testing.T) {
 			mergedFilename := filepath.Join(tmp, "mergedNwtWithDifferentDocIDFilename.jwlibrary")
 			assert.NoError(t, mergedDBNwtWithDifferentDocID.ExportJWLBackup(mergedFilename))
 
-			merge(leftNwtWithDifferentDocIDFilename, rightNwtWithDifferentDocIDFilename, mergedFilename,
+			merge(context.Background(), leftNwtWithDifferentDocIDFilename, rightNwtWithDifferentDocIDFilename, mergedFilename,
 				terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()})
 			merged := &model.Database{}
 			merged.ImportJWLBackup(mergedFilename)
@@ -214,7 +222,7 @@ func Test_merge(t *testing.T) {
 			mergedAllLeftDBEmptyBRFilename := filepath.Join(tmp, "mergedAllLeftDBEmptyBRFilename.jwlibrary")
 			assert.NoError(t, mergedAllLeftDBEmptyBR.ExportJWLBackup(mergedAllLeftDBEmptyBRFilename))
 
-			merge(leftDBEmptyBRFilename,
+			merge(context.Background(), leftDBEmptyBRFilename,
 				RightDBEmptyBRFilename,
 				mergedFilename,
 				terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()})
@@ -231,7 +239,7 @@ func Test_merge(t *testing.T) {
 			c.ExpectEOF()
 		},
 		func(t *testing.T, c *expect.Console) {
-			merge("../model/testdata/backup_withPlaylist.jwlibrary",
+			merge(context.Background(), "../model/testdata/backup_withPlaylist.jwlibrary",
 				"../model/testdata/backup_withPlaylist.jwlibrary",
 				mergedFilename,
 				terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()})
@@ -247,7 +255,7 @@ func Test_merge(t *testing.T) {
 		func(t *testing.T, c *expect.Console) {
 			SkipPlaylists = true
 			defer func() { SkipPlaylists = false }()
-			merge("../model/testdata/backup_withPlaylist.jwlibrary",
+			merge(context.Background(), "../model/testdata/backup_withPlaylist.jwlibrary",
 				"../model/testdata/backup_withPlaylist.jwlibrary",
 				mergedFilename,
 				terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()})
@@ -2197,3 +2205,192 @@ var mergedAllLeftDBEmptyBR = &model.Database{
 		},
 	},
 }
+
+func Test_mergeInBrowser(t *testing.T) {
+	tmp := t.TempDir()
+
+	leftFilename := filepath.Join(tmp, "left.jwlibrary")
+	rightFilename := filepath.Join(tmp, "right.jwlibrary")
+	mergedFilename := filepath.Join(tmp, "merged.jwlibrary")
+	require.NoError(t, leftDB.ExportJWLBackup(leftFilename))
+	require.NoError(t, rightDB.ExportJWLBackup(rightFilename))
+
+	out, w, err := os.Pipe()
+	require.NoError(t, err)
+	result := make(chan error, 1)
+	go func() {
+		result <- mergeInBrowser(context.Background(), leftFilename, rightFilename, mergedFilename, terminal.Stdio{Out: w, Err: w})
+		w.Close()
+	}()
+
+	// Wait for the URL of the session to resolve all conflicts by choosing the right side
+	var url string
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		if _, after, found := strings.Cut(scanner.Text(), "Open "); found {
+			url = strings.Fields(after)[0]
+			break
+		}
+	}
+	require.NotEmpty(t, url)
+	go io.Copy(io.Discard, out)
+	base, id, _ := strings.Cut(url, "/#")
+
+	for {
+		resp, err := http.Get(base + "/sessions/" + id + "/conflicts")
+		require.NoError(t, err)
+		var conflicts []struct {
+			Key string `json:"key"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&conflicts))
+		resp.Body.Close()
+		if len(conflicts) == 0 {
+			break
+		}
+
+		resolutions := []map[string]string{}
+		for _, conflict := range conflicts {
+			resolutions = append(resolutions, map[string]string{"key": conflict.Key, "side": "rightSide"})
+		}
+		body, err := json.Marshal(resolutions)
+		require.NoError(t, err)
+		resp, err = http.Post(base+"/sessions/"+id+"/resolutions", "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		var st struct {
+			Done bool `json:"done"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&st))
+		resp.Body.Close()
+		if st.Done {
+			break
+		}
+	}
+
+	require.NoError(t, <-result)
+	merged := &model.Database{}
+	require.NoError(t, merged.ImportJWLBackup(mergedFilename))
+	assert.True(t, mergedAllRightDB.Equals(merged))
+
+	// Without conflicts, the browser UI is not needed
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	defer devNull.Close()
+	require.NoError(t, mergeInBrowser(context.Background(), leftFilename, leftFilename, mergedFilename, terminal.Stdio{Out: devNull, Err: devNull}))
+	merged = &model.Database{}
+	require.NoError(t, merged.ImportJWLBackup(mergedFilename))
+	assert.True(t, leftDB.Equals(merged))
+}
+
+func Test_mergeExportOptions(t *testing.T) {
+	left := &model.Database{Metadata: &model.Metadata{
+		Name:       "left",
+		DeviceName: "iPhone",
+		Thumbnail:  []byte("left"),
+		ExtraFiles: map[string][]byte{"playlist.jpg": []byte("image")},
+	}}
+	right := &model.Database{Metadata: &model.Metadata{
+		Name:       "right",
+		DeviceName: "iPad",
+		Thumbnail:  []byte("right"),
+	}}
+
+	tests := []struct {
+		name         string
+		metadataFrom string
+		backupName   string
+		deviceName   string
+		want         model.ExportOptions
+		wantErr      bool
+	}{
+		{
+			name:         "default",
+			metadataFrom: "default",
+			want:         model.ExportOptions{Metadata: &model.Metadata{}},
+		},
+		{
+			name:         "left without extra files",
+			metadataFrom: "left",
+			want: model.ExportOptions{Metadata: &model.Metadata{
+				Name:       "left",
+				DeviceName: "iPhone",
+				Thumbnail:  []byte("left"),
+			}},
+		},
+		{
+			name:         "right with overrides",
+			metadataFrom: "right",
+			backupName:   "merged",
+			deviceName:   "Laptop",
+			want: model.ExportOptions{
+				Metadata: &model.Metadata{
+					Name:       "right",
+					DeviceName: "iPad",
+					Thumbnail:  []byte("right"),
+				},
+				Name:       "merged",
+				DeviceName: "Laptop",
+			},
+		},
+		{
+			name:         "invalid",
+			metadataFrom: "middle",
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			MetadataFrom, BackupName, DeviceName = tt.metadataFrom, tt.backupName, tt.deviceName
+			defer func() { MetadataFrom, BackupName, DeviceName = "default", "", "" }()
+
+			got, err := mergeExportOptions(left, right)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+	assert.NotNil(t, left.Metadata.ExtraFiles)
+}
+
+func Test_conflictDetails(t *testing.T) {
+	db := &model.Database{
+		Location: []*model.Location{
+			nil,
+			{
+				LocationID:    1,
+				BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+				KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+			},
+			{
+				LocationID:   2,
+				DocumentID:   sql.NullInt32{Int32: 1102002020, Valid: true},
+				KeySymbol:    sql.NullString{String: "cl", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+			},
+		},
+	}
+	bibleNote := &model.Note{NoteID: 1, LocationID: sql.NullInt32{Int32: 1, Valid: true}, Title: sql.NullString{String: "Bible", Valid: true}}
+	bookNote := &model.Note{NoteID: 2, LocationID: sql.NullInt32{Int32: 2, Valid: true}, Title: sql.NullString{String: "Book", Valid: true}}
+	verseNote := &model.Note{
+		NoteID:          3,
+		LocationID:      sql.NullInt32{Int32: 1, Valid: true},
+		BlockType:       model.BlockTypeVerse,
+		BlockIdentifier: sql.NullInt32{Int32: 16, Valid: true},
+	}
+	tag := &model.Tag{TagID: 1, Name: "Tag"}
+
+	assert.Equal(t, "Reference:   John 3\n\n"+bibleNote.PrettyPrint(db), conflictDetails(bibleNote, db, nil))
+	assert.Equal(t, "Reference:   John 3:16\n\n"+verseNote.PrettyPrint(db), conflictDetails(verseNote, db, nil))
+	assert.Equal(t, bookNote.PrettyPrint(db), conflictDetails(bookNote, db, nil))
+	assert.Equal(t, tag.PrettyPrint(db), conflictDetails(tag, db, nil))
+
+	catalog, err := publication.OpenCatalog(filepath.Join("..", "publication", "testdata", "catalog.db"))
+	require.NoError(t, err)
+	defer catalog.Close()
+	assert.Equal(t, "Publication: Draw Close to Jehovah (2014)\n\n"+bookNote.PrettyPrint(db), conflictDetails(bookNote, db, catalog))
+	assert.Equal(t, "Reference:   John 3\n\n"+bibleNote.PrettyPrint(db), conflictDetails(bibleNote, db, catalog))
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/AndreasSko/go-jwlm/merger"
+	"github.com/AndreasSko/go-jwlm/model"
+)
+
+// errMergeInterrupted is returned by handleMergeConflict
+// if the user interrupts the merge.
+var errMergeInterrupted = errors.New("merge interrupted")
+
+// mergeState keeps track of the decisions made during an interactive merge.
+// It is stored as a JSON file after every decision, so an interrupted merge
+// can be continued later on using --resume.
+type mergeState struct {
+	Left   string `json:"left"`
+	Right  string `json:"right"`
+	Merged string `json:"merged"`
+	// Resolvers are the names of the resolvers by the name of the
+	// merger.MergeStep they are used for (see mergeResolvers).
+	Resolvers map[string]string        `json:"resolvers,omitempty"`
+	Decisions map[string]mergeDecision `json:"decisions"`
+
+	path string
+}
+
+// mergeDecision is the side chosen for a conflict, together with
+// the hash of the conflict at the time it was decided.
+type mergeDecision struct {
+	Side merger.MergeSide `json:"side"`
+	Hash string           `json:"hash"`
+}
+
+// newMergeState returns an empty mergeState for merging the given backups
+// using resolvers, which is stored at path. If path is empty, it is stored
+// next to the merged backup.
+func newMergeState(path string, leftFilename string, rightFilename string, mergedFilename string, resolvers map[string]string) *mergeState {
+	if path == "" {
+		path = mergedFilename + ".state.json"
+	}
+	return &mergeState{
+		Left:      leftFilename,
+		Right:     rightFilename,
+		Merged:    mergedFilename,
+		Resolvers: resolvers,
+		Decisions: map[string]mergeDecision{},
+		path:      path,
+	}
+}
+
+// loadMergeState loads a mergeState stored at path.
+func loadMergeState(path string) (*mergeState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read merge state: %w", err)
+	}
+
+	state := &mergeState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse merge state: %w", err)
+	}
+	if state.Left == "" || state.Right == "" || state.Merged == "" {
+		return nil, fmt.Errorf("%s is not a valid merge state", path)
+	}
+	if state.Decisions == nil {
+		state.Decisions = map[string]mergeDecision{}
+	}
+	state.path = path
+
+	return state, nil
+}
+
+// decisionKey returns the key a decision for the conflict is stored with.
+// It is prefixed with the type of the conflict, and the changing number in
+// front of the keys of marking conflicts is removed, so the decision is
+// found again when the merge is run another time.
+func decisionKey(key string, conflict merger.MergeConflict) string {
+	if _, ok := conflict.Left.(*model.UserMarkBlockRange); ok {
+		if _, rest, found := strings.Cut(key, "_"); found {
+			key = rest
+		}
+	}
+	return reflect.TypeOf(conflict.Left).Elem().Name() + ":" + key
+}
+
+// conflictHash returns the SHA-256 hash of both sides of the conflict,
+// so a decision is only applied as long as the conflict is unchanged.
+func conflictHash(conflict merger.MergeConflict) string {
+	hash := sha256.New()
+	for _, side := range []model.Model{conflict.Left, conflict.Right} {
+		jsn, err := json.Marshal(side)
+		if err != nil {
+			return ""
+		}
+		hash.Write(jsn)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// decision returns the side that has been chosen for the conflict before.
+// If the left or right side has changed since, the decision is ignored.
+func (s *mergeState) decision(key string, conflict merger.MergeConflict) (merger.MergeSide, bool) {
+	decision, ok := s.Decisions[decisionKey(key, conflict)]
+	if !ok || decision.Hash == "" || decision.Hash != conflictHash(conflict) {
+		return "", false
+	}
+	return decision.Side, true
+}
+
+// decide remembers the side chosen for the conflict and stores the state.
+func (s *mergeState) decide(key string, conflict merger.MergeConflict, side merger.MergeSide) error {
+	s.Decisions[decisionKey(key, conflict)] = mergeDecision{
+		Side: side,
+		Hash: conflictHash(conflict),
+	}
+	return s.save()
+}
+
+// save stores the state at its path. It is written to a temporary file
+// first, so an interruption doesn't destroy a former state.
+func (s *mergeState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode merge state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save merge state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save merge state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save merge state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save merge state: %w", err)
+	}
+
+	return nil
+}
+
+// remove deletes the stored state, as it isn't needed after a successful merge.
+func (s *mergeState) remove() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove merge state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/AndreasSko/go-jwlm/merger"
+	"github.com/AndreasSko/go-jwlm/model"
+	expect "github.com/Netflix/go-expect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_mergeState(t *testing.T) {
+	tmp := t.TempDir()
+
+	resolvers := map[string]string{"notes": "chooseNewest"}
+	state := newMergeState("", "left.jwlibrary", "right.jwlibrary", filepath.Join(tmp, "merged.jwlibrary"), resolvers)
+	assert.Equal(t, filepath.Join(tmp, "merged.jwlibrary.state.json"), state.path)
+
+	bookmarks := merger.MergeConflict{Left: &model.Bookmark{Title: "Left"}, Right: &model.Bookmark{Title: "Right"}}
+	markings := merger.MergeConflict{
+		Left:  &model.UserMarkBlockRange{UserMark: &model.UserMark{}},
+		Right: &model.UserMarkBlockRange{UserMark: &model.UserMark{}},
+	}
+	require.NoError(t, state.decide("1_0", bookmarks, merger.RightSide))
+	require.NoError(t, state.decide("1792361512511523093_1_1R", markings, merger.LeftSide))
+	assert.Equal(t, map[string]mergeDecision{
+		"Bookmark:1_0":            {Side: merger.RightSide, Hash: conflictHash(bookmarks)},
+		"UserMarkBlockRange:1_1R": {Side: merger.LeftSide, Hash: conflictHash(markings)},
+	}, state.Decisions)
+	assert.NotEqual(t, conflictHash(bookmarks), conflictHash(markings))
+
+	loaded, err := loadMergeState(state.path)
+	require.NoError(t, err)
+	assert.Equal(t, state, loaded)
+	assert.Equal(t, resolvers, loaded.Resolvers)
+
+	// Marking conflicts are found again, even though their key changes
+	side, ok := loaded.decision("1792361512599999999_1_1R", markings)
+	assert.True(t, ok)
+	assert.Equal(t, merger.LeftSide, side)
+	_, ok = loaded.decision("1_0", markings)
+	assert.False(t, ok)
+	_, ok = loaded.decision("2_0", bookmarks)
+	assert.False(t, ok)
+
+	// Decisions are ignored if one of the sides has changed since
+	changed := merger.MergeConflict{Left: &model.Bookmark{Title: "Left"}, Right: &model.Bookmark{Title: "Changed"}}
+	_, ok = loaded.decision("1_0", changed)
+	assert.False(t, ok)
+	loaded.Decisions["Bookmark:1_0"] = mergeDecision{Side: merger.RightSide}
+	_, ok = loaded.decision("1_0", bookmarks)
+	assert.False(t, ok)
+
+	require.NoError(t, loaded.remove())
+	assert.NoFileExists(t, state.path)
+	assert.NoError(t, loaded.remove())
+
+	_, err = loadMergeState(state.path)
+	assert.Error(t, err)
+
+	invalid := filepath.Join(tmp, "invalid.json")
+	require.NoError(t, os.WriteFile(invalid, []byte(`{"left": "left.jwlibrary"}`), 0644))
+	_, err = loadMergeState(invalid)
+	assert.EqualError(t, err, invalid+" is not a valid merge state")
+}
+
+func Test_merge_resume(t *testing.T) {
+	tmp := t.TempDir()
+	leftFilename := filepath.Join(tmp, "left.jwlibrary")
+	rightFilename := filepath.Join(tmp, "right.jwlibrary")
+	mergedFilename := filepath.Join(tmp, "merged.jwlibrary")
+	statePath := filepath.Join(tmp, "state.json")
+	require.NoError(t, leftDB.ExportJWLBackup(leftFilename))
+	require.NoError(t, rightDB.ExportJWLBackup(rightFilename))
+
+	// Choose right for the bookmark, then interrupt. Markings
+	// are solved by a resolver, which is kept when resuming.
+	RunCmdTest(t,
+		func(t *testing.T, c *expect.Console) {
+			c.ExpectString("📑 Merging Bookmarks")
+			c.SendLine(string(terminal.KeyArrowDown))
+
+			c.ExpectString("✍️  Merging InputFields")
+			c.ExpectString("Select which side should be chosen")
+			c.Send(string(terminal.KeyInterrupt))
+
+			c.ExpectEOF()
+		},
+		func(t *testing.T, c *expect.Console) {
+			state := newMergeState(statePath, leftFilename, rightFilename, mergedFilename, map[string]string{"markings": "chooseRight"})
+			err := runMerge(context.Background(), state, terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()})
+			assert.ErrorIs(t, err, errMergeInterrupted)
+			assert.ErrorContains(t, err, "go-jwlm merge --resume "+statePath)
+			assert.NoFileExists(t, mergedFilename)
+		})
+
+	state, err := loadMergeState(statePath)
+	require.NoError(t, err)
+	assert.Len(t, state.Decisions, 1)
+	assert.Equal(t, merger.RightSide, state.Decisions["Bookmark:3_0"].Side)
+	assert.Equal(t, map[string]string{"markings": "chooseRight"}, state.Resolvers)
+
+	// The bookmark is not asked again when resuming
+	RunCmdTest(t,
+		func(t *testing.T, c *expect.Console) {
+			c.ExpectString("✍️  Merging InputFields")
+			c.SendLine(string(terminal.KeyArrowDown))
+
+			c.ExpectString("🖍  Merging Markings")
+			c.ExpectString("📝 Merging Notes")
+			c.SendLine(string(terminal.KeyArrowDown))
+
+			c.ExpectEOF()
+		},
+		func(t *testing.T, c *expect.Console) {
+			assert.NoError(t, runMerge(context.Background(), state, terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()}))
+			merged := &model.Database{}
+			require.NoError(t, merged.ImportJWLBackup(mergedFilename))
+			assert.True(t, mergedAllRightDB.Equals(merged))
+			assert.NoFileExists(t, statePath)
+		})
+}
+
+func Test_merge_decideLater(t *testing.T) {
+	tmp := t.TempDir()
+	leftFilename := filepath.Join(tmp, "left.jwlibrary")
+	rightFilename := filepath.Join(tmp, "right.jwlibrary")
+	mergedFilename := filepath.Join(tmp, "merged.jwlibrary")
+
+	// Add a second conflicting bookmark on both sides
+	left := model.MakeDatabaseCopy(leftDB)
+	right := model.MakeDatabaseCopy(rightDB)
+	left.Bookmark = append(left.Bookmark, &model.Bookmark{
+		BookmarkID:            2,
+		LocationID:            1,
+		PublicationLocationID: 2,
+		Slot:                  1,
+		Title:                 "Second left bookmark",
+		Snippet:               sql.NullString{String: "Left", Valid: true},
+		BlockType:             2,
+		BlockIdentifier:       sql.NullInt32{Int32: 1, Valid: true},
+	})
+	right.Bookmark = append(right.Bookmark, &model.Bookmark{
+		BookmarkID:            2,
+		LocationID:            1,
+		PublicationLocationID: 2,
+		Slot:                  1,
+		Title:                 "Second right bookmark",
+		Snippet:               sql.NullString{String: "Right", Valid: true},
+		BlockType:             2,
+		BlockIdentifier:       sql.NullInt32{Int32: 1, Valid: true},
+	})
+	require.NoError(t, left.ExportJWLBackup(leftFilename))
+	require.NoError(t, right.ExportJWLBackup(rightFilename))
+
+	RunCmdTest(t,
+		func(t *testing.T, c *expect.Console) {
+			// Skip the first bookmark
+			c.ExpectString("📑 Merging Bookmarks")
+			c.ExpectString(decideLater)
+			c.Send(string(terminal.KeyArrowDown))
+			c.SendLine(string(terminal.KeyArrowDown))
+
+			c.ExpectString("Second right bookmark")
+			c.SendLine(string(terminal.KeyArrowDown))
+
+			// The skipped one is asked again
+			c.ExpectString("1. Mose 2:1")
+			c.SendLine(string(terminal.KeyArrowDown))
+
+			c.ExpectString("✍️  Merging InputFields")
+			c.SendLine(string(terminal.KeyArrowDown))
+
+			c.ExpectString("🖍  Merging Markings")
+			c.SendLine(string(terminal.KeyArrowDown))
+
+			c.ExpectString("📝 Merging Notes")
+			c.SendLine(string(terminal.KeyArrowDown))
+
+			c.ExpectEOF()
+		},
+		func(t *testing.T, c *expect.Console) {
+			state := newMergeState(filepath.Join(tmp, "state.json"), leftFilename, rightFilename, mergedFilename, nil)
+			assert.NoError(t, runMerge(context.Background(), state, terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()}))
+
+			merged := &model.Database{}
+			require.NoError(t, merged.ImportJWLBackup(mergedFilename))
+			titles := []string{}
+			for _, bookmark := range merged.Bookmark {
+				if bookmark != nil {
+					titles = append(titles, bookmark.Title)
+				}
+			}
+			assert.ElementsMatch(t, []string{"1. Mose 2:1", "Second right bookmark"}, titles)
+		})
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/AndreasSko/go-jwlm/merger"
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/AndreasSko/go-jwlm/publication"
+	"github.com/MakeNowJust/heredoc"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate <input-backup> <output-backup>",
+	Short: "Move entries from one edition of a publication to another",
+	Long: heredoc.Doc(`Move all entries (like markings, notes, or bookmarks) of the input backup
+	from one edition of a publication to another one and store it as output.
+	This can be useful if JW Library replaced a publication with a new edition,
+	but the backup still references the old one.
+
+	Entries referencing a specific document of a publication are only migrated
+	if a catalog.db is given using --catalog, as the documents of both editions
+	have to be looked up in it. Documents are matched by their position within
+	the publication, so the first document of the old edition is moved to the
+	first document of the new one and so on. If both editions don't have the
+	same number of documents, the migration is refused. All other entries
+	(e.g. of Bible chapters) are migrated without it.`),
+	Example: heredoc.Doc(`go-jwlm migrate original.jwlibrary migrated.jwlibrary --from nwt --to nwtsty
+	go-jwlm migrate original.jwlibrary migrated.jwlibrary --from nwt --to nwtsty --language 2 --catalog catalog.db`),
+	Run: func(cmd *cobra.Command, args []string) {
+		inputFilename := args[0]
+		outputFilename := args[1]
+		migrate(inputFilename, outputFilename, terminal.Stdio{In: os.Stdin, Out: os.Stdout, Err: os.Stderr})
+	},
+	Args: cobra.ExactArgs(2),
+}
+
+// MigrateFrom is the KeySymbol of the edition that should be migrated
+var MigrateFrom string
+
+// MigrateTo is the KeySymbol of the edition entries should be migrated to
+var MigrateTo string
+
+// MigrateLanguage limits the migration to the given MepsLanguage. A negative
+// value means that all languages are migrated.
+var MigrateLanguage int
+
+// MigrateCatalog is the path to the catalog.db that is used to look up
+// the documents of both editions
+var MigrateCatalog string
+
+func migrate(inputFilename string, outputFilename string, stdio terminal.Stdio) {
+	migration := merger.EditionMigration{
+		From: MigrateFrom,
+		To:   MigrateTo,
+	}
+	if MigrateLanguage >= 0 {
+		migration.MepsLanguages = []int{MigrateLanguage}
+	}
+
+	if MigrateCatalog != "" {
+		if MigrateLanguage < 0 {
+			log.Fatal("--language is needed to look up documents in the catalog")
+		}
+		documentIDs, err := mapDocumentIDs(MigrateCatalog, MigrateFrom, MigrateTo, MigrateLanguage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		migration.DocumentIDs = documentIDs
+	}
+
+	fmt.Fprintln(stdio.Out, "Importing backup")
+	db := &model.Database{}
+	err := db.ImportJWLBackup(inputFilename)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Fprintf(stdio.Out, "🚚 Migrating from %s to %s\n", MigrateFrom, MigrateTo)
+	migrated := merger.MigrateEditions(db, []merger.EditionMigration{migration})
+	fmt.Fprintf(stdio.Out, "Migrated %d locations\n", migrated)
+
+	fmt.Fprintln(stdio.Out, "💾 Storing backup")
+	if err = db.ExportJWLBackup(outputFilename); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Fprintln(stdio.Out, "🎉 Done")
+}
+
+// mapDocumentIDs looks up the documents of both editions in the catalog and
+// maps the DocumentIDs of the old edition to the ones of the new edition.
+// As the IDs usually change between editions, documents are matched by their
+// position within the publication. This is only reliable if both editions
+// have the same number of documents, so an error listing the documents
+// without a counterpart is returned otherwise.
+func mapDocumentIDs(catalogPath string, from string, to string, mepsLanguage int) (map[int]int, error) {
+	catalog, err := publication.OpenCatalog(catalogPath)
+	if err != nil {
+		return nil, err
+	}
+	defer catalog.Close()
+
+	fromDocs, err := catalog.Documents(publication.Lookup{KeySymbol: from, MepsLanguage: mepsLanguage})
+	if err != nil {
+		return nil, fmt.Errorf("could not look up documents of %s: %w", from, err)
+	}
+	toDocs, err := catalog.Documents(publication.Lookup{KeySymbol: to, MepsLanguage: mepsLanguage})
+	if err != nil {
+		return nil, fmt.Errorf("could not look up documents of %s: %w", to, err)
+	}
+
+	if len(fromDocs) != len(toDocs) {
+		var unmapped []int
+		for _, doc := range fromDocs[min(len(fromDocs), len(toDocs)):] {
+			unmapped = append(unmapped, doc.DocumentID)
+		}
+		for _, doc := range toDocs[min(len(fromDocs), len(toDocs)):] {
+			unmapped = append(unmapped, doc.DocumentID)
+		}
+		return nil, fmt.Errorf("%s has %d documents, but %s has %d, so they can't be matched by their position (unmapped DocumentIDs: %v)",
+			from, len(fromDocs), to, len(toDocs), unmapped)
+	}
+
+	result := make(map[int]int, len(fromDocs))
+	for i, doc := range fromDocs {
+		result[doc.DocumentID] = toDocs[i].DocumentID
+	}
+	return result, nil
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+	migrateCmd.Flags().StringVar(&MigrateFrom, "from", "nwt", "KeySymbol of the edition that should be migrated")
+	migrateCmd.Flags().StringVar(&MigrateTo, "to", "nwtsty", "KeySymbol of the edition the entries should be migrated to")
+	migrateCmd.Flags().IntVar(&MigrateLanguage, "language", -1, "Only migrate entries of the given MepsLanguage (default: all languages)")
+	migrateCmd.Flags().StringVar(&MigrateCatalog, "catalog", "", "Path to a catalog.db that is used to look up the documents of both editions")
+}
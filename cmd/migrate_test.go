@@ -0,0 +1,105 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/AndreasSko/go-jwlm/model"
+	expect "github.com/Netflix/go-expect"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_migrate(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+
+	inputFilename := filepath.Join(tmp, "leftNwt.jwlibrary")
+	assert.NoError(t, leftNwtDB.ExportJWLBackup(inputFilename))
+
+	RunCmdTest(t,
+		func(t *testing.T, c *expect.Console) {
+			_, err := c.ExpectString("Migrated 2 locations")
+			assert.NoError(t, err)
+			_, err = c.ExpectString("🎉 Done")
+			assert.NoError(t, err)
+			_, err = c.ExpectEOF()
+			assert.NoError(t, err)
+		},
+		func(t *testing.T, c *expect.Console) {
+			MigrateFrom = "nwt"
+			MigrateTo = "nwtsty"
+			MigrateLanguage = -1
+
+			outputFilename := filepath.Join(tmp, "migrated.jwlibrary")
+			migrate(inputFilename, outputFilename, terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()})
+
+			want := model.MakeDatabaseCopy(leftNwtDB)
+			want.Location[1].KeySymbol.String = "nwtsty"
+			want.Location[2].KeySymbol.String = "nwtsty"
+			// Locations are renumbered densely when cleaning up duplicates
+			want.Location = []*model.Location{nil, want.Location[1], want.Location[2], want.Location[5]}
+			want.Location[3].LocationID = 3
+			// Empty tables are imported with a nil placeholder at index 0
+			want.Bookmark = []*model.Bookmark{nil}
+			want.InputField = []*model.InputField{nil}
+			want.Note = []*model.Note{nil}
+			want.Tag = []*model.Tag{nil}
+			want.TagMap = []*model.TagMap{nil}
+
+			output := &model.Database{}
+			assert.NoError(t, output.ImportJWLBackup(outputFilename))
+			assert.True(t, want.Equals(output))
+		})
+}
+
+func Test_mapDocumentIDs(t *testing.T) {
+	catalogDB := filepath.Join(t.TempDir(), "catalog.db")
+	data, err := os.ReadFile(filepath.Join("..", "publication", "testdata", "catalog.db"))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(catalogDB, data, 0644))
+
+	// Add a new edition of cl, whose documents have different IDs
+	sqlite, err := sql.Open("sqlite3", catalogDB)
+	assert.NoError(t, err)
+	_, err = sqlite.Exec("INSERT INTO Publication SELECT PublicationRootKeyId, MepsLanguageId, PublicationTypeId, " +
+		"IssueTagNumber, Title, IssueTitle, ShortTitle, CoverTitle, UndatedTitle, UndatedReferenceTitle, Year, " +
+		"'cl2', 'cl2', Reserved, 1000 FROM Publication WHERE Id = 67")
+	assert.NoError(t, err)
+	_, err = sqlite.Exec("INSERT INTO PublicationDocument SELECT DocumentId + 1000, 1000 " +
+		"FROM PublicationDocument WHERE PublicationId = 67")
+	assert.NoError(t, err)
+	// And one that is missing some of the documents
+	_, err = sqlite.Exec("INSERT INTO Publication SELECT PublicationRootKeyId, MepsLanguageId, PublicationTypeId, " +
+		"IssueTagNumber, Title, IssueTitle, ShortTitle, CoverTitle, UndatedTitle, UndatedReferenceTitle, Year, " +
+		"'cl3', 'cl3', Reserved, 1001 FROM Publication WHERE Id = 67")
+	assert.NoError(t, err)
+	_, err = sqlite.Exec("INSERT INTO PublicationDocument SELECT DocumentId + 2000, 1001 " +
+		"FROM PublicationDocument WHERE PublicationId = 67 AND DocumentId < 1102002050")
+	assert.NoError(t, err)
+	assert.NoError(t, sqlite.Close())
+
+	res, err := mapDocumentIDs(catalogDB, "cl", "cl", 0)
+	assert.NoError(t, err)
+	assert.Len(t, res, 40)
+	assert.Equal(t, 1102002020, res[1102002020])
+
+	res, err = mapDocumentIDs(catalogDB, "cl", "cl2", 0)
+	assert.NoError(t, err)
+	assert.Len(t, res, 40)
+	assert.Equal(t, 1102003020, res[1102002020])
+	assert.Equal(t, 1102003059, res[1102002059])
+
+	_, err = mapDocumentIDs(catalogDB, "cl", "cl3", 0)
+	assert.ErrorContains(t, err, "1102002050")
+	assert.ErrorContains(t, err, "1102002059")
+
+	_, err = mapDocumentIDs(catalogDB, "cl", "w", 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/AndreasSko/go-jwlm/model"
+)
+
+// progressBarWidth is the number of characters of the bar drawn by showProgress
+const progressBarWidth = 30
+
+// withProgress runs task while drawing a progress bar to out for
+// every Progress it sends. task is expected to close prgrs when it
+// is finished, like model.Database.ImportJWLBackupContext does.
+func withProgress(out io.Writer, task func(prgrs chan model.Progress) error) error {
+	prgrs := make(chan model.Progress)
+	done := make(chan struct{})
+	go func() {
+		showProgress(out, prgrs)
+		close(done)
+	}()
+
+	err := task(prgrs)
+	<-done
+	return err
+}
+
+// showProgress draws a progress bar for each Progress received from prgrs,
+// overwriting the previous one. Once prgrs is closed, the line is ended.
+func showProgress(out io.Writer, prgrs <-chan model.Progress) {
+	drawn := false
+	lastLen := 0
+	for p := range prgrs {
+		if p.Total == 0 {
+			continue
+		}
+		line := progressBar(p)
+		// Clear the rest of a former, longer line
+		fmt.Fprintf(out, "\r%s%s", line, strings.Repeat(" ", max(0, lastLen-len(line))))
+		lastLen = len(line)
+		drawn = true
+	}
+	if drawn {
+		fmt.Fprintln(out)
+	}
+}
+
+// progressBar renders p as a line like "[=====     ] 5/10 Importing Note".
+func progressBar(p model.Progress) string {
+	filled := progressBarWidth * min(p.Completed, p.Total) / p.Total
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	line := fmt.Sprintf("[%s] %d/%d", bar, p.Completed, p.Total)
+	if p.Step != "" && !p.Done {
+		line += " " + p.Step
+	}
+	return line
+}
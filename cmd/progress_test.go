@@ -0,0 +1,70 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_progressBar(t *testing.T) {
+	assert.Equal(t, "[                              ] 0/10 Reading backup",
+		progressBar(model.Progress{Step: "Reading backup", Completed: 0, Total: 10}))
+	assert.Equal(t, "[===============               ] 5/10 Importing Note",
+		progressBar(model.Progress{Step: "Importing Note", Completed: 5, Total: 10}))
+	assert.Equal(t, "[==============================] 10/10",
+		progressBar(model.Progress{Step: "Writing backup", Completed: 10, Total: 10, Done: true}))
+}
+
+func Test_withProgress(t *testing.T) {
+	var out bytes.Buffer
+	expectedErr := errors.New("failed")
+	err := withProgress(&out, func(prgrs chan model.Progress) error {
+		defer close(prgrs)
+		prgrs <- model.Progress{Step: "Importing Location", Completed: 1, Total: 2}
+		prgrs <- model.Progress{Step: "Note", Completed: 2, Total: 2}
+		return expectedErr
+	})
+	assert.Equal(t, expectedErr, err)
+	assert.Equal(t,
+		"\r[===============               ] 1/2 Importing Location"+
+			"\r[==============================] 2/2 Note              \n",
+		out.String())
+
+	// Nothing is drawn if no Progress is sent
+	out.Reset()
+	assert.NoError(t, withProgress(&out, func(prgrs chan model.Progress) error {
+		close(prgrs)
+		return nil
+	}))
+	assert.Empty(t, out.String())
+}
+
+func Test_merge_canceled(t *testing.T) {
+	tmp := t.TempDir()
+	leftFilename := filepath.Join(tmp, "left.jwlibrary")
+	rightFilename := filepath.Join(tmp, "right.jwlibrary")
+	mergedFilename := filepath.Join(tmp, "merged.jwlibrary")
+	require.NoError(t, leftDB.ExportJWLBackup(leftFilename))
+	require.NoError(t, rightDB.ExportJWLBackup(rightFilename))
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	defer devNull.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = merge(ctx, leftFilename, rightFilename, mergedFilename, terminal.Stdio{Out: devNull, Err: devNull})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoFileExists(t, mergedFilename)
+}
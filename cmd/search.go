@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <backup> <query>",
+	Short: "Search the notes and bookmarks of a JW Library backup file",
+	Long: `search lists all notes and bookmarks of the backup that contain every word
+of the query in their title, content, or snippet (ignoring the case). An empty
+query lists all of them. The results can be narrowed down to a tag, a
+publication, a language, or a date range (see Flags).`,
+	Example: `go-jwlm search backup.jwlibrary "faith"
+go-jwlm search backup.jwlibrary "" --tag Research --publication nwtsty --from 2023-01-01`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := searchOptions()
+		if err != nil {
+			return err
+		}
+		return search(args[0], args[1], opts, terminal.Stdio{In: os.Stdin, Out: os.Stdout, Err: os.Stderr})
+	},
+	Args: cobra.ExactArgs(2),
+}
+
+// SearchTag only shows results with the given tag
+var SearchTag string
+
+// SearchPublication only shows results of the publication with the given KeySymbol
+var SearchPublication string
+
+// SearchLanguage only shows results in the given MEPS language (-1 shows all)
+var SearchLanguage int
+
+// SearchFrom only shows notes modified on or after the given date (YYYY-MM-DD)
+var SearchFrom string
+
+// SearchTo only shows notes modified on or before the given date (YYYY-MM-DD)
+var SearchTo string
+
+// searchOptions returns the model.SearchOptions according to the search flags.
+func searchOptions() (model.SearchOptions, error) {
+	opts := model.SearchOptions{
+		Tag:       SearchTag,
+		KeySymbol: SearchPublication,
+	}
+	if SearchLanguage >= 0 {
+		opts.MepsLanguage = sql.NullInt32{Int32: int32(SearchLanguage), Valid: true}
+	}
+
+	var err error
+	if SearchFrom != "" {
+		if opts.From, err = time.Parse(time.DateOnly, SearchFrom); err != nil {
+			return opts, fmt.Errorf("%s is not a valid date for --from. Use the format YYYY-MM-DD", SearchFrom)
+		}
+	}
+	if SearchTo != "" {
+		if opts.To, err = time.Parse(time.DateOnly, SearchTo); err != nil {
+			return opts, fmt.Errorf("%s is not a valid date for --to. Use the format YYYY-MM-DD", SearchTo)
+		}
+		// Include the whole day
+		opts.To = opts.To.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	return opts, nil
+}
+
+// search prints the notes and bookmarks of the backup that match query and opts.
+func search(backupFilename string, query string, opts model.SearchOptions, stdio terminal.Stdio) error {
+	db := &model.Database{}
+	if err := db.ImportJWLBackup(backupFilename); err != nil {
+		return fmt.Errorf("failed to import backup: %w", err)
+	}
+
+	results, err := db.Search(query, opts)
+	if err != nil {
+		return fmt.Errorf("failed to search backup: %w", err)
+	}
+
+	for _, result := range results {
+		switch entry := result.Model.(type) {
+		case *model.Note:
+			fmt.Fprintf(stdio.Out, "📝 %s\n", entry.Title.String)
+			if entry.Content.String != "" {
+				fmt.Fprintf(stdio.Out, "   %s\n", shorten(entry.Content.String, 100))
+			}
+		case *model.Bookmark:
+			fmt.Fprintf(stdio.Out, "📑 %s\n", entry.Title)
+			if entry.Snippet.String != "" {
+				fmt.Fprintf(stdio.Out, "   %s\n", shorten(entry.Snippet.String, 100))
+			}
+		}
+		if result.Reference != "" {
+			fmt.Fprintf(stdio.Out, "   📖 %s\n", result.Reference)
+		}
+		if result.Location != nil {
+			fmt.Fprintf(stdio.Out, "   📍 %s\n", describeLocation(result.Location))
+		}
+		if len(result.Tags) > 0 {
+			names := make([]string, len(result.Tags))
+			for i, tag := range result.Tags {
+				names[i] = tag.Name
+			}
+			fmt.Fprintf(stdio.Out, "   🏷  %s\n", strings.Join(names, ", "))
+		}
+		fmt.Fprintln(stdio.Out)
+	}
+	fmt.Fprintf(stdio.Out, "Found %d entries\n", len(results))
+
+	return nil
+}
+
+// describeLocation returns a short description of where the Location
+// is found, like "nwtsty 1:3 (language 2)".
+func describeLocation(location *model.Location) string {
+	var sb strings.Builder
+	sb.WriteString(location.KeySymbol.String)
+	switch {
+	case location.BookNumber.Valid:
+		fmt.Fprintf(&sb, " %d:%d", location.BookNumber.Int32, location.ChapterNumber.Int32)
+	case location.IssueTagNumber != 0:
+		fmt.Fprintf(&sb, " %d", location.IssueTagNumber)
+	}
+	if location.DocumentID.Valid {
+		fmt.Fprintf(&sb, " document %d", location.DocumentID.Int32)
+	}
+	if location.MepsLanguage.Valid {
+		fmt.Fprintf(&sb, " (language %d)", location.MepsLanguage.Int32)
+	}
+	if location.Title.String != "" {
+		fmt.Fprintf(&sb, " - %s", location.Title.String)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// shorten cuts text after max characters and puts everything on one line.
+func shorten(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "…"
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	searchCmd.Flags().StringVar(&SearchTag, "tag", "", "Only show entries with this tag")
+	searchCmd.Flags().StringVar(&SearchPublication, "publication", "", "Only show entries of the publication with this symbol (like nwtsty)")
+	searchCmd.Flags().IntVar(&SearchLanguage, "language", -1, "Only show entries in this MEPS language (like 0 for English)")
+	searchCmd.Flags().StringVar(&SearchFrom, "from", "", "Only show notes modified on or after this date (YYYY-MM-DD)")
+	searchCmd.Flags().StringVar(&SearchTo, "to", "", "Only show notes modified on or before this date (YYYY-MM-DD)")
+}
@@ -0,0 +1,92 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/AndreasSko/go-jwlm/model"
+	expect "github.com/Netflix/go-expect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_search(t *testing.T) {
+	t.Parallel()
+
+	backupFilename := filepath.Join(t.TempDir(), "left.jwlibrary")
+	require.NoError(t, leftDB.ExportJWLBackup(backupFilename))
+
+	RunCmdTest(t,
+		func(t *testing.T, c *expect.Console) {
+			_, err := c.ExpectString("📝 Am Anfang erschuf Gott Himmel und Erde.")
+			assert.NoError(t, err)
+			_, err = c.ExpectString("📝 for left version")
+			assert.NoError(t, err)
+			_, err = c.ExpectString("📖 1. Mose 1:1")
+			assert.NoError(t, err)
+			_, err = c.ExpectString("📍 nwtsty 1:1 (language 2) - 1. Mose 1")
+			assert.NoError(t, err)
+			_, err = c.ExpectString("🏷  Left")
+			assert.NoError(t, err)
+			_, err = c.ExpectString("Found 1 entries")
+			assert.NoError(t, err)
+			c.ExpectEOF()
+		},
+		func(t *testing.T, c *expect.Console) {
+			assert.NoError(t, search(backupFilename, "LEFT version", model.SearchOptions{},
+				terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()}))
+			time.Sleep(time.Millisecond * 150) // So it does not finish before go-expect finished
+		})
+
+	RunCmdTest(t,
+		func(t *testing.T, c *expect.Console) {
+			_, err := c.ExpectString("Found 0 entries")
+			assert.NoError(t, err)
+			c.ExpectEOF()
+		},
+		func(t *testing.T, c *expect.Console) {
+			assert.NoError(t, search(backupFilename, "left", model.SearchOptions{Tag: "Same"},
+				terminal.Stdio{In: c.Tty(), Out: c.Tty(), Err: c.Tty()}))
+			time.Sleep(time.Millisecond * 150) // So it does not finish before go-expect finished
+		})
+
+	assert.Error(t, search(filepath.Join(t.TempDir(), "missing.jwlibrary"), "", model.SearchOptions{}, terminal.Stdio{}))
+}
+
+func Test_searchOptions(t *testing.T) {
+	defer func() {
+		SearchTag, SearchPublication, SearchLanguage, SearchFrom, SearchTo = "", "", -1, "", ""
+	}()
+
+	SearchTag = "Favorite"
+	SearchPublication = "nwtsty"
+	SearchLanguage = 0
+	SearchFrom = "2023-01-01"
+	SearchTo = "2023-01-31"
+	opts, err := searchOptions()
+	require.NoError(t, err)
+	assert.Equal(t, model.SearchOptions{
+		Tag:          "Favorite",
+		KeySymbol:    "nwtsty",
+		MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+		From:         time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:           time.Date(2023, 1, 31, 23, 59, 59, 999999999, time.UTC),
+	}, opts)
+
+	SearchLanguage = -1
+	SearchFrom = ""
+	opts, err = searchOptions()
+	require.NoError(t, err)
+	assert.False(t, opts.MepsLanguage.Valid)
+	assert.True(t, opts.From.IsZero())
+
+	SearchTo = "31.01.2023"
+	_, err = searchOptions()
+	assert.EqualError(t, err, "31.01.2023 is not a valid date for --to. Use the format YYYY-MM-DD")
+}
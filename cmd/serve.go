@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/AndreasSko/go-jwlm/server"
+	"github.com/MakeNowJust/heredoc"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run a local HTTP service for merging backups",
+	Long: heredoc.Doc(`Run a local HTTP service that allows to merge backups using a REST API:
+
+	  POST   /sessions                  upload two or more backups (multipart field "backup")
+	  GET    /sessions/{id}             get the status of the merge
+	  GET    /sessions/{id}/conflicts   list the unsolved conflicts
+	  POST   /sessions/{id}/resolutions resolve conflicts, e.g. [{"key": "...", "side": "leftSide"}]
+	  GET    /sessions/{id}/download    download the merged backup
+	  DELETE /sessions/{id}             remove the session
+
+	Conflicts can be resolved automatically by adding the form values bookmarks,
+	markings, notes, or inputFields with the name of a resolver (like chooseLeft)
+	when uploading the backups. Add metadata=left or metadata=right to the download
+	to inherit the name, device name, and thumbnail of the first or last backup.`),
+	Example: `go-jwlm serve --addr localhost:8080`,
+	Run: func(cmd *cobra.Command, args []string) {
+		srv := server.New()
+		srv.SkipPlaylists = ServeSkipPlaylists
+		srv.Addr = ServeAddr
+
+		fmt.Fprintf(os.Stdout, "🚀 Listening on http://%s\n", ServeAddr)
+		if err := http.ListenAndServe(ServeAddr, srv); err != nil {
+			log.Fatal(err)
+		}
+	},
+	Args: cobra.NoArgs,
+}
+
+// ServeAddr is the address the HTTP service listens on
+var ServeAddr string
+
+// ServeSkipPlaylists indicates if playlists should be skipped when importing uploaded backups.
+// It is meant as a temporary workaround until merging of playlists is implemented.
+var ServeSkipPlaylists bool
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&ServeAddr, "addr", "localhost:8080", "Address the HTTP service listens on")
+	serveCmd.Flags().BoolVar(&ServeSkipPlaylists, "skipPlaylists", false, "Skip playlists when importing backups. It is meant as a temporary workaround until merging of playlists is implemented.")
+}
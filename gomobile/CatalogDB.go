@@ -2,14 +2,19 @@ package gomobile
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/AndreasSko/go-jwlm/publication"
 )
 
 // DownloadManager keeps all the information of a running download, enabling it
-// to check progress and also cancel the download if necessary
+// to check progress and also cancel the download if necessary.
+// As the download runs in the background, its state is only accessible
+// through the methods of DownloadManager.
 type DownloadManager struct {
-	Progress  *DownloadProgress
+	mu        sync.Mutex
+	progress  DownloadProgress
 	prgrsChan chan publication.Progress
 	ctx       context.Context
 	cancel    context.CancelFunc
@@ -26,12 +31,33 @@ type DownloadProgress struct {
 	Canceled       bool
 }
 
-// DownloadCatalog downloads the newest catalog.db and saves it at dst. The
-// returned DownloadManager allows to keep track and manage the running download
+// DownloadCatalog downloads the newest catalog.db and saves it at dst, unless
+// it is already up-to-date. The returned DownloadManager allows to keep track
+// and manage the running download
 func DownloadCatalog(dst string) *DownloadManager {
+	return downloadCatalog(dst, publication.DownloadOptions{Retries: 2})
+}
+
+// DownloadCatalogFrom works like DownloadCatalog, but fetches the catalog from
+// source, which can be the URL of a mirror of the JW CDN, a local directory
+// mirroring it, or a single catalog.db.gz.
+func DownloadCatalogFrom(source string, dst string) *DownloadManager {
+	src, err := publication.ParseCatalogSource(source)
+	if err != nil {
+		_, cancel := context.WithCancel(context.Background())
+		return &DownloadManager{
+			progress: DownloadProgress{Done: true},
+			cancel:   cancel,
+			err:      err,
+		}
+	}
+	return downloadCatalog(dst, publication.DownloadOptions{Source: src, Retries: 2})
+}
+
+// downloadCatalog starts the download of the catalog using opts
+func downloadCatalog(dst string, opts publication.DownloadOptions) *DownloadManager {
 	ctx, cancel := context.WithCancel(context.Background())
 	dm := &DownloadManager{
-		Progress:  &DownloadProgress{},
 		prgrsChan: make(chan publication.Progress),
 		ctx:       ctx,
 		cancel:    cancel,
@@ -39,40 +65,57 @@ func DownloadCatalog(dst string) *DownloadManager {
 
 	// Start download in sub-goroutine, while monitoring its progress
 	go func() {
-		done := make(chan struct{})
+		done := make(chan error)
 		go func() {
-			err := publication.DownloadCatalog(dm.ctx, dm.prgrsChan, dst)
-			if err != nil {
-				dm.err = err
-			}
-			done <- struct{}{}
+			done <- publication.DownloadCatalogFrom(dm.ctx, dm.prgrsChan, dst, opts)
 		}()
 		for progress := range dm.prgrsChan {
-			dm.Progress.Size = progress.Size
-			dm.Progress.BytesComplete = progress.BytesComplete
-			dm.Progress.BytesPerSecond = progress.BytesPerSecond
-			dm.Progress.Progress = progress.Progress
+			dm.mu.Lock()
+			dm.progress.Size = progress.Size
+			dm.progress.BytesComplete = progress.BytesComplete
+			dm.progress.BytesPerSecond = progress.BytesPerSecond
+			dm.progress.Progress = progress.Progress
+			dm.mu.Unlock()
 		}
-		<-done
-		dm.Progress.Done = true
+		err := <-done
+
+		dm.mu.Lock()
+		defer dm.mu.Unlock()
+		dm.err = err
+		dm.progress.Done = true
 	}()
 
 	return dm
 }
 
+// Progress returns a snapshot of the current progress of the download.
+func (dm *DownloadManager) Progress() *DownloadProgress {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	progress := dm.progress
+	return &progress
+}
+
 // CancelDownload cancels a running download
 func (dm *DownloadManager) CancelDownload() {
 	dm.cancel()
-	dm.Progress.Canceled = true
+
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.progress.Canceled = true
 }
 
 // DownloadSuccessful indicates if the download has been successful
 func (dm *DownloadManager) DownloadSuccessful() bool {
-	return dm.Progress.Done && dm.err == nil && !dm.Progress.Canceled
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	return dm.progress.Done && dm.err == nil && !dm.progress.Canceled
 }
 
 // Error returns possible errors of a download as a string
 func (dm *DownloadManager) Error() string {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
 	if dm.err != nil {
 		return dm.err.Error()
 	}
@@ -80,12 +123,28 @@ func (dm *DownloadManager) Error() string {
 }
 
 // CatalogNeedsUpdate checks if catalog.db located at path is still up-to-date.
-// For now it just makes sure that it is younger than one month.
-// If it can't find a file at path, it returns true
+// As it doesn't access the network, it just makes sure that it is younger
+// than one month. If it can't find a file at path, it returns true
 func CatalogNeedsUpdate(path string) bool {
 	return publication.CatalogNeedsUpdate(path)
 }
 
+// CheckCatalogUpdate checks if a newer version of the catalog.db located at path
+// is available at the source it has been downloaded from. As it accesses the
+// network for up to 10 seconds, it should not be called from the UI thread.
+// If the version of the catalog is unknown, it returns an error.
+func CheckCatalogUpdate(path string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return publication.CheckCatalogUpdate(ctx, path, nil)
+}
+
+// CatalogVersion returns the version of the catalog.db at path
+// or an empty string if it is unknown
+func CatalogVersion(path string) string {
+	return publication.CatalogVersion(path)
+}
+
 // CatalogExists checks if catalog.db exists at path
 func CatalogExists(path string) bool {
 	return publication.CatalogExists(path)
@@ -38,24 +38,22 @@ func TestDownloadCatalog(t *testing.T) {
 
 	for range []int{0, 1} {
 		dm := DownloadCatalog(filepath.Join(tmp, "catalog.db"))
-		for {
-			assert.NoError(t, dm.err)
-			if dm.Progress.Done == true {
-				break
-			}
+		for !dm.Progress().Done {
+			time.Sleep(10 * time.Millisecond)
 		}
+		assert.Equal(t, "", dm.Error())
 		assert.Equal(t, "7ebe98db8b5edd1ab901b7d6b43647fd35790b2a332c43739efdf9383d590651",
 			hashFile(filepath.Join(tmp, "catalog.db")))
 		assert.True(t, dm.DownloadSuccessful())
+		assert.Equal(t, "164a1c4b-4dbd-4909-8f88-8e7a18c562f2", CatalogVersion(filepath.Join(tmp, "catalog.db")))
 	}
 
 	// Test error
 	publication.CatalogURL = "https://notvaliddomain.com/%s"
 	dm := DownloadCatalog(filepath.Join(tmp, "catalog.db"))
 	time.Sleep(5 * time.Second)
-	assert.Error(t, dm.err)
 	assert.NotEqual(t, "", dm.Error())
-	assert.True(t, dm.Progress.Done)
+	assert.True(t, dm.Progress().Done)
 	assert.False(t, dm.DownloadSuccessful())
 }
 
@@ -65,9 +63,9 @@ func TestDownloadManager_CancelDownload(t *testing.T) {
 	dm := DownloadCatalog(filepath.Join(tmp, "catalog.db"))
 	dm.CancelDownload()
 	time.Sleep(time.Second)
-	assert.True(t, dm.Progress.Done)
+	assert.True(t, dm.Progress().Done)
 	assert.NotEqual(t, "", dm.Error())
-	assert.True(t, dm.Progress.Canceled)
+	assert.True(t, dm.Progress().Canceled)
 	assert.False(t, dm.DownloadSuccessful())
 	assert.False(t, publication.CatalogExists(filepath.Join(tmp, "catalog.db")))
 }
@@ -87,6 +85,22 @@ func TestCatalogNeedsUpdate(t *testing.T) {
 	assert.True(t, CatalogNeedsUpdate(filePath))
 }
 
+func TestCheckCatalogUpdate(t *testing.T) {
+	needsUpdate, err := CheckCatalogUpdate("not-valid-path")
+	assert.NoError(t, err)
+	assert.True(t, needsUpdate)
+
+	filePath := filepath.Join(t.TempDir(), "catalog.db")
+	_, err = os.Create(filePath)
+	assert.NoError(t, err)
+	_, err = CheckCatalogUpdate(filePath)
+	assert.ErrorIs(t, err, publication.ErrCatalogVersionUnknown)
+}
+
+func TestCatalogVersion(t *testing.T) {
+	assert.Equal(t, "", CatalogVersion("not-valid-path"))
+}
+
 func TestCatalogExists(t *testing.T) {
 	tmp := t.TempDir()
 
@@ -109,3 +123,20 @@ func hashFile(path string) string {
 	io.Copy(hasher, f)
 	return fmt.Sprintf("%x", hasher.Sum(nil))
 }
+
+func TestDownloadCatalogFrom(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "catalog.db")
+
+	dm := DownloadCatalogFrom(filepath.Join("../publication/testdata", "catalog.db.gz"), dst)
+	for !dm.Progress().Done {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.True(t, dm.DownloadSuccessful())
+	assert.Equal(t, "7ebe98db8b5edd1ab901b7d6b43647fd35790b2a332c43739efdf9383d590651", hashFile(dst))
+
+	dm = DownloadCatalogFrom("nonexistent", dst)
+	assert.True(t, dm.Progress().Done)
+	assert.False(t, dm.DownloadSuccessful())
+	assert.NotEqual(t, "", dm.Error())
+	dm.CancelDownload()
+}
@@ -1,10 +1,11 @@
 package gomobile
 
 import (
-	"errors"
+	"context"
 
 	"github.com/AndreasSko/go-jwlm/merger"
 	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/pkg/errors"
 )
 
 // DatabaseWrapper wraps the left, right, and merged
@@ -24,17 +25,36 @@ type DatabaseWrapper struct {
 	// times without changing the content of the original databases.
 	leftTmp  *model.Database
 	rightTmp *model.Database
+
+	// stage is the number of merger.MergeSteps that have been completed.
+	stage int
+	// mcw contains the conflicts of the last call to Merge,
+	// so it can be continued after an interruption.
+	mcw *MergeConflictsWrapper
 }
 
 // ImportJWLBackup imports a .jwlibrary backup file into the struct
 // on the given side.
 func (dbw *DatabaseWrapper) ImportJWLBackup(filename string, side string) error {
+	return dbw.importJWLBackup(context.Background(), filename, side, nil)
+}
+
+// importJWLBackup is like ImportJWLBackup, but stops and returns the error of ctx
+// if it is canceled. The progress of the import is sent to prgrs if it is not nil.
+func (dbw *DatabaseWrapper) importJWLBackup(ctx context.Context, filename string, side string, prgrs chan model.Progress) error {
+	if side != "leftSide" && side != "rightSide" {
+		if prgrs != nil {
+			close(prgrs)
+		}
+		return errors.New("only leftSide and rightSide are valid for importing backups")
+	}
+
 	db := &model.Database{
 		TempDir:       dbw.TempDir,
 		SkipPlaylists: dbw.skipPlaylists,
 	}
 
-	if err := db.ImportJWLBackup(filename); err != nil {
+	if err := db.ImportJWLBackupContext(ctx, filename, prgrs); err != nil {
 		return err
 	}
 
@@ -59,12 +79,9 @@ func (dbw *DatabaseWrapper) SkipPlaylists(skipPlaylists bool) {
 // Init initializes the DatabaseWrapper to prepare for subsequent
 // function calls. Should be called after ImportJWLBackup.
 func (dbw *DatabaseWrapper) Init() {
-	dbw.leftTmp = model.MakeDatabaseCopy(dbw.left)
-	dbw.rightTmp = model.MakeDatabaseCopy(dbw.right)
-	dbw.merged = &model.Database{
-		TempDir: dbw.TempDir,
-	}
-	merger.PrepareDatabasesPreMerge(dbw.leftTmp, dbw.rightTmp)
+	dbw.leftTmp, dbw.rightTmp, dbw.merged = merger.PrepareMerge(dbw.left, dbw.right)
+	dbw.merged.TempDir = dbw.TempDir
+	dbw.stage = 0
 }
 
 // DBIsLoaded indicates if a DB on the given side has been loaded.
@@ -99,6 +116,52 @@ func (dbw *DatabaseWrapper) DBContainsPlaylists(side string) bool {
 
 // ExportMerged exports the merged database to filename.
 func (dbw *DatabaseWrapper) ExportMerged(filename string) error {
-	merger.PrepareDatabasesPostMerge(dbw.merged)
+	if err := merger.PrepareDatabasesPostMerge(dbw.merged); err != nil {
+		return errors.Wrap(err, "Error while preparing merged database for export")
+	}
 	return dbw.merged.ExportJWLBackup(filename)
 }
+
+// ExportMergedWithOptions exports the merged database to filename. The name,
+// device name, and thumbnail of the backup are inherited from the backup on
+// metadataSide (leftSide or rightSide), or set to defaults if it is empty.
+// If name or deviceName are not empty, they override the inherited ones.
+func (dbw *DatabaseWrapper) ExportMergedWithOptions(filename string, metadataSide string, name string, deviceName string) error {
+	opts, err := dbw.exportOptions(metadataSide, name, deviceName)
+	if err != nil {
+		return err
+	}
+
+	if err := merger.PrepareDatabasesPostMerge(dbw.merged); err != nil {
+		return errors.Wrap(err, "Error while preparing merged database for export")
+	}
+	return dbw.merged.ExportJWLBackupWithOptions(filename, opts)
+}
+
+// exportOptions returns the ExportOptions for ExportMergedWithOptions.
+func (dbw *DatabaseWrapper) exportOptions(metadataSide string, name string, deviceName string) (model.ExportOptions, error) {
+	opts := model.ExportOptions{
+		Name:       name,
+		DeviceName: deviceName,
+	}
+
+	switch metadataSide {
+	case "leftSide":
+		opts.Metadata = dbw.left.Metadata
+	case "rightSide":
+		opts.Metadata = dbw.right.Metadata
+	case "":
+		opts.Metadata = &model.Metadata{}
+	default:
+		return opts, errors.New("only leftSide, rightSide, or an empty side are valid for inheriting metadata")
+	}
+
+	// Extra files (like media of playlists) are not merged yet
+	if opts.Metadata != nil {
+		md := *opts.Metadata
+		md.ExtraFiles = nil
+		opts.Metadata = &md
+	}
+
+	return opts, nil
+}
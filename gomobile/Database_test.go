@@ -194,6 +194,33 @@ func TestDatabaseWrapper_ExportMerged(t *testing.T) {
 	assert.True(t, dbw.merged.Equals(newDB))
 }
 
+func TestDatabaseWrapper_ExportMergedWithOptions(t *testing.T) {
+	tmp := t.TempDir()
+
+	dbw := &DatabaseWrapper{}
+	assert.NoError(t, dbw.ImportJWLBackup(backupFile, "leftSide"))
+	assert.NoError(t, dbw.ImportJWLBackup(backupFile, "rightSide"))
+	dbw.merged = model.MakeDatabaseCopy(dbw.left)
+	dbw.merged.Metadata = nil
+
+	newBackup := filepath.Join(tmp, "left.jwlibrary")
+	assert.NoError(t, dbw.ExportMergedWithOptions(newBackup, "leftSide", "", "My iPad"))
+	newDB := &model.Database{}
+	assert.NoError(t, newDB.ImportJWLBackup(newBackup))
+	assert.Equal(t, dbw.left.Metadata.Name, newDB.Metadata.Name)
+	assert.Equal(t, "My iPad", newDB.Metadata.DeviceName)
+	assert.Equal(t, dbw.left.Metadata.Thumbnail, newDB.Metadata.Thumbnail)
+
+	newBackup = filepath.Join(tmp, "default.jwlibrary")
+	assert.NoError(t, dbw.ExportMergedWithOptions(newBackup, "", "Merged", ""))
+	newDB = &model.Database{}
+	assert.NoError(t, newDB.ImportJWLBackup(newBackup))
+	assert.Equal(t, "Merged", newDB.Metadata.Name)
+	assert.Equal(t, "go-jwlm", newDB.Metadata.DeviceName)
+
+	assert.Error(t, dbw.ExportMergedWithOptions(newBackup, "wrongSide", "", ""))
+}
+
 func TestDatabaseWrapper_DBContainsPlaylists(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -1,194 +1,223 @@
 package gomobile
 
 import (
+	"context"
+
 	"github.com/AndreasSko/go-jwlm/merger"
 	"github.com/pkg/errors"
 	_ "golang.org/x/mobile/bind"
 )
 
-// MergeLocations merges locations
-func (dbw *DatabaseWrapper) MergeLocations() error {
-	mergedLocations, locationIDChanges, err := merger.MergeLocations(dbw.leftTmp.Location, dbw.rightTmp.Location)
-	if err != nil {
-		return errors.Wrap(err, "Could not merge locations")
+// MergeResolvers contains the names of the resolvers (chooseLeft, chooseRight,
+// or chooseNewest) that are used to automatically solve conflicts of the
+// given type. If a field is empty, the MergeHandler is asked instead.
+type MergeResolvers struct {
+	Bookmarks   string
+	InputFields string
+	Markings    string
+	Notes       string
+}
+
+// MergeHandler is implemented by the app to answer conflicts and receive
+// the progress of a merge started with Merge.
+type MergeHandler interface {
+	// ResolveConflict is called for every conflict that can't be solved
+	// automatically. It returns the side that should be chosen
+	// (leftSide or rightSide).
+	ResolveConflict(conflict *MergeConflict) (string, error)
+	// Progress is called before each step of the merge (like "bookmarks")
+	// with the number of completed steps and the total number of steps.
+	Progress(step string, completed int, total int)
+}
+
+// resolver returns the name of the resolver for the merger.MergeStep with
+// the given name. Steps without a configurable resolver return an empty string.
+func (resolvers *MergeResolvers) resolver(step string) string {
+	switch step {
+	case "bookmarks":
+		return resolvers.Bookmarks
+	case "inputFields":
+		return resolvers.InputFields
+	case "markings":
+		return resolvers.Markings
+	case "notes":
+		return resolvers.Notes
 	}
-	dbw.merged.Location = mergedLocations
-	merger.UpdateLRIDs(dbw.leftTmp.Bookmark, dbw.rightTmp.Bookmark, "LocationID", locationIDChanges)
-	merger.UpdateLRIDs(dbw.leftTmp.Bookmark, dbw.rightTmp.Bookmark, "PublicationLocationID", locationIDChanges)
-	merger.UpdateLRIDs(dbw.leftTmp.InputField, dbw.rightTmp.InputField, "LocationID", locationIDChanges)
-	merger.UpdateLRIDs(dbw.leftTmp.Note, dbw.rightTmp.Note, "LocationID", locationIDChanges)
-	merger.UpdateLRIDs(dbw.leftTmp.TagMap, dbw.rightTmp.TagMap, "LocationID", locationIDChanges)
-	merger.UpdateLRIDs(dbw.leftTmp.UserMark, dbw.rightTmp.UserMark, "LocationID", locationIDChanges)
+	return ""
+}
 
-	return nil
+// Merge merges the left and right backup in one call, so the steps don't have to
+// be called one after the other. Conflicts are solved using the given resolvers
+// or - if there is none for the type of conflict - by asking the handler.
+// It replaces calling Init and the Merge* functions. Afterwards, the result
+// can be exported using ExportMerged. If a former Merge has been interrupted
+// (e.g. because the handler returned an error or the session has been
+// restored using RestoreSession), it continues where it stopped.
+func (dbw *DatabaseWrapper) Merge(resolvers *MergeResolvers, handler MergeHandler) error {
+	return dbw.merge(context.Background(), resolvers, handler, nil)
 }
 
-// MergeBookmarks merges bookmarks
-func (dbw *DatabaseWrapper) MergeBookmarks(conflictSolver string, mcw *MergeConflictsWrapper) error {
-	var conflictSolution = mcw.solutions
-	if conflictSolution == nil {
-		conflictSolution = map[string]merger.MergeSolution{}
+// merge is like Merge, but stops and returns the error of ctx if it is canceled.
+// Besides the handler, the progress is also reported to prgrs if it is not nil.
+func (dbw *DatabaseWrapper) merge(ctx context.Context, resolvers *MergeResolvers, handler MergeHandler, prgrs func(step string, completed int, total int)) error {
+	if dbw.left == nil || dbw.right == nil {
+		return errors.New("Both left and right backup have to be imported before merging")
+	}
+	if handler == nil {
+		return errors.New("A MergeHandler is needed for merging")
 	}
-	for {
-		merged, _, err := merger.MergeBookmarks(dbw.leftTmp.Bookmark, dbw.rightTmp.Bookmark, conflictSolution)
-		if err == nil {
-			dbw.merged.Bookmark = merged
-			break
+	if resolvers == nil {
+		resolvers = &MergeResolvers{}
+	}
+	total := len(merger.MergeSteps)
+	progress := func(step string, completed int) {
+		handler.Progress(step, completed, total)
+		if prgrs != nil {
+			prgrs(step, completed, total)
 		}
-		switch err := err.(type) {
-		case merger.MergeConflictError:
-			if conflictSolver == "" {
-				mcw.addConflicts(err.Conflicts)
-				return MergeConflictError{}
+	}
+
+	if dbw.mcw == nil || dbw.stage == 0 || dbw.stage >= total {
+		dbw.Init()
+		dbw.mcw = &MergeConflictsWrapper{DBWrapper: dbw}
+	}
+	mcw := dbw.mcw
+	defer mcw.Close()
+	// Conflicts left unsolved by the interruption are detected again
+	// when continuing, so they would be asked for twice otherwise.
+	mcw.removeUnsolved()
+	for i := dbw.stage; i < total; i++ {
+		step := merger.MergeSteps[i]
+		progress(step.Name, i)
+		for {
+			err := dbw.runStep(ctx, step.Name, resolvers.resolver(step.Name), mcw)
+			if err == nil {
+				break
+			}
+			if _, ok := err.(MergeConflictError); !ok {
+				return err
 			}
-			var resErr error
-			newSolutions, resErr := merger.AutoResolveConflicts(err.Conflicts, conflictSolver)
-			if resErr != nil {
-				return errors.Wrap(resErr, "Could not automatically solve conflicts for bookmarks")
+			if err := mcw.resolveWith(handler); err != nil {
+				return errors.Wrapf(err, "Could not resolve conflicts while merging %s", step.Name)
 			}
-			addToSolutions(conflictSolution, newSolutions)
-		default:
-			return errors.Wrap(err, "Could not merge bookmarks")
 		}
 	}
+	progress("", total)
 
 	return nil
 }
 
-// MergeInputField merges inputFields
-func (dbw *DatabaseWrapper) MergeInputFields(conflictSolver string, mcw *MergeConflictsWrapper) error {
-	var conflictSolution = mcw.solutions
-	if conflictSolution == nil {
-		conflictSolution = map[string]merger.MergeSolution{}
+// NextMergeStep returns the name of the step that needs to be merged next
+// (like bookmarks) or an empty string if all steps have been merged. After
+// restoring a session, it tells where to continue.
+func (dbw *DatabaseWrapper) NextMergeStep() string {
+	if dbw.stage >= len(merger.MergeSteps) {
+		return ""
 	}
-	for {
-		merged, _, err := merger.MergeInputFields(dbw.leftTmp.InputField, dbw.rightTmp.InputField, conflictSolution)
-		if err == nil {
-			dbw.merged.InputField = merged
-			break
-		}
-		switch err := err.(type) {
-		case merger.MergeConflictError:
-			if conflictSolver == "" {
-				mcw.addConflicts(err.Conflicts)
-				return MergeConflictError{}
-			}
-			var resErr error
-			newSolutions, resErr := merger.AutoResolveConflicts(err.Conflicts, conflictSolver)
-			if resErr != nil {
-				return errors.Wrap(resErr, "Could not automatically solve conflicts for inputFields")
-			}
-			addToSolutions(conflictSolution, newSolutions)
-		default:
-			return errors.Wrap(err, "Could not merge inputFields")
-		}
+	return merger.MergeSteps[dbw.stage].Name
+}
+
+// runStep runs the merger.MergeStep with the given name on the temporary
+// Databases. Conflicts are solved using the solutions of mcw and - if it is
+// not empty - the conflictSolver. Remaining conflicts are added to mcw and
+// a MergeConflictError is returned. Once the step is merged, it is
+// remembered as completed.
+func (dbw *DatabaseWrapper) runStep(ctx context.Context, name string, conflictSolver string, mcw *MergeConflictsWrapper) error {
+	step, ok := merger.LookupMergeStep(name)
+	if !ok {
+		return errors.Errorf("Merge step %s does not exist", name)
 	}
 
+	var solutions map[string]merger.MergeSolution
+	if mcw != nil {
+		solutions = mcw.solutions
+	}
+	err := step.Run(ctx, dbw.leftTmp, dbw.rightTmp, dbw.merged, solutions, conflictSolver)
+	if mcErr, isConflict := err.(merger.MergeConflictError); isConflict && mcw != nil {
+		mcw.addConflicts(mcErr.Conflicts)
+		return MergeConflictError{}
+	}
+	if err != nil {
+		return err
+	}
+
+	if mcw != nil {
+		mcw.markApplied()
+	}
+	for i, s := range merger.MergeSteps {
+		if s.Name == name {
+			dbw.stage = i + 1
+		}
+	}
 	return nil
 }
 
+// MergeLocations merges locations
+func (dbw *DatabaseWrapper) MergeLocations() error {
+	return dbw.mergeLocations(context.Background())
+}
+
+// mergeLocations is like MergeLocations, but stops and returns the error of ctx if it is canceled.
+func (dbw *DatabaseWrapper) mergeLocations(ctx context.Context) error {
+	return dbw.runStep(ctx, "locations", "", nil)
+}
+
+// MergeBookmarks merges bookmarks
+func (dbw *DatabaseWrapper) MergeBookmarks(conflictSolver string, mcw *MergeConflictsWrapper) error {
+	return dbw.mergeBookmarks(context.Background(), conflictSolver, mcw)
+}
+
+// mergeBookmarks is like MergeBookmarks, but stops and returns the error of ctx if it is canceled.
+func (dbw *DatabaseWrapper) mergeBookmarks(ctx context.Context, conflictSolver string, mcw *MergeConflictsWrapper) error {
+	return dbw.runStep(ctx, "bookmarks", conflictSolver, mcw)
+}
+
+// MergeInputField merges inputFields
+func (dbw *DatabaseWrapper) MergeInputFields(conflictSolver string, mcw *MergeConflictsWrapper) error {
+	return dbw.mergeInputFields(context.Background(), conflictSolver, mcw)
+}
+
+// mergeInputFields is like MergeInputFields, but stops and returns the error of ctx if it is canceled.
+func (dbw *DatabaseWrapper) mergeInputFields(ctx context.Context, conflictSolver string, mcw *MergeConflictsWrapper) error {
+	return dbw.runStep(ctx, "inputFields", conflictSolver, mcw)
+}
+
 // MergeTags merges tags
 func (dbw *DatabaseWrapper) MergeTags() error {
-	var conflictSolution map[string]merger.MergeSolution
-	for {
-		merged, idChanges, err := merger.MergeTags(dbw.leftTmp.Tag, dbw.rightTmp.Tag, conflictSolution)
-		if err == nil {
-			dbw.merged.Tag = merged
-			merger.UpdateLRIDs(dbw.leftTmp.TagMap, dbw.rightTmp.TagMap, "TagID", idChanges)
-			break
-		}
-		return errors.Wrap(err, "Could not merge tags")
-	}
+	return dbw.mergeTags(context.Background())
+}
 
-	return nil
+// mergeTags is like MergeTags, but stops and returns the error of ctx if it is canceled.
+func (dbw *DatabaseWrapper) mergeTags(ctx context.Context) error {
+	return dbw.runStep(ctx, "tags", "", nil)
 }
 
 // MergeUserMarkAndBlockRange merges UserMarks and BlockRanges
 func (dbw *DatabaseWrapper) MergeUserMarkAndBlockRange(conflictSolver string, mcw *MergeConflictsWrapper) error {
-	var conflictSolution = mcw.solutions
-	if conflictSolution == nil {
-		conflictSolution = map[string]merger.MergeSolution{}
-	}
-	for {
-		mergedUserMarks, mergedBlockRanges, idChanges, err := merger.MergeUserMarkAndBlockRange(dbw.leftTmp.UserMark, dbw.leftTmp.BlockRange, dbw.rightTmp.UserMark, dbw.rightTmp.BlockRange, conflictSolution)
-		if err == nil {
-			dbw.merged.UserMark = mergedUserMarks
-			dbw.merged.BlockRange = mergedBlockRanges
-			merger.UpdateLRIDs(dbw.leftTmp.Note, dbw.rightTmp.Note, "UserMarkID", idChanges)
-			break
-		}
-		switch err := err.(type) {
-		case merger.MergeConflictError:
-			if conflictSolver == "" {
-				mcw.addConflicts(err.Conflicts)
-				return MergeConflictError{}
-			}
-			var resErr error
-			newSolutions, resErr := merger.AutoResolveConflicts(err.Conflicts, conflictSolver)
-			if resErr != nil {
-				return errors.Wrap(resErr, "Could not automatically solve conflicts for markings")
-			}
-			addToSolutions(conflictSolution, newSolutions)
-		default:
-			return errors.Wrap(err, "Could not merge markings")
-		}
-	}
+	return dbw.mergeUserMarkAndBlockRange(context.Background(), conflictSolver, mcw)
+}
 
-	return nil
+// mergeUserMarkAndBlockRange is like MergeUserMarkAndBlockRange, but stops and returns the error of ctx if it is canceled.
+func (dbw *DatabaseWrapper) mergeUserMarkAndBlockRange(ctx context.Context, conflictSolver string, mcw *MergeConflictsWrapper) error {
+	return dbw.runStep(ctx, "markings", conflictSolver, mcw)
 }
 
 // MergeNotes merges notes
 func (dbw *DatabaseWrapper) MergeNotes(conflictSolver string, mcw *MergeConflictsWrapper) error {
-	var conflictSolution = mcw.solutions
-	if conflictSolution == nil {
-		conflictSolution = map[string]merger.MergeSolution{}
-	}
-	for {
-		merged, idChanges, err := merger.MergeNotes(dbw.leftTmp.Note, dbw.rightTmp.Note, conflictSolution)
-		if err == nil {
-			dbw.merged.Note = merged
-			merger.UpdateLRIDs(dbw.leftTmp.TagMap, dbw.rightTmp.TagMap, "NoteID", idChanges)
-			break
-		}
-		switch err := err.(type) {
-		case merger.MergeConflictError:
-			if conflictSolver == "" {
-				mcw.addConflicts(err.Conflicts)
-				return MergeConflictError{}
-			}
-			var resErr error
-			newSolutions, resErr := merger.AutoResolveConflicts(err.Conflicts, conflictSolver)
-			if resErr != nil {
-				return errors.Wrap(resErr, "Could not automatically solve conflicts for notes")
-			}
-			addToSolutions(conflictSolution, newSolutions)
-		default:
-			return errors.Wrap(err, "Could not merge notes")
-		}
-	}
+	return dbw.mergeNotes(context.Background(), conflictSolver, mcw)
+}
 
-	return nil
+// mergeNotes is like MergeNotes, but stops and returns the error of ctx if it is canceled.
+func (dbw *DatabaseWrapper) mergeNotes(ctx context.Context, conflictSolver string, mcw *MergeConflictsWrapper) error {
+	return dbw.runStep(ctx, "notes", conflictSolver, mcw)
 }
 
 // MergeTagMaps merges tagMaps
 func (dbw *DatabaseWrapper) MergeTagMaps() error {
-	var conflictSolution map[string]merger.MergeSolution
-	for {
-		merged, _, err := merger.MergeTagMaps(dbw.leftTmp.TagMap, dbw.rightTmp.TagMap, conflictSolution)
-		if err == nil {
-			dbw.merged.TagMap = merged
-			break
-		}
-
-		return errors.Wrap(err, "Could not merge tagMaps")
-	}
-
-	return nil
+	return dbw.mergeTagMaps(context.Background())
 }
 
-// addToSolutions adds new mergeSolutions to the existing map of mergeSolutions
-func addToSolutions(solutions map[string]merger.MergeSolution, new map[string]merger.MergeSolution) {
-	for key, value := range new {
-		solutions[key] = value
-	}
+// mergeTagMaps is like MergeTagMaps, but stops and returns the error of ctx if it is canceled.
+func (dbw *DatabaseWrapper) mergeTagMaps(ctx context.Context) error {
+	return dbw.runStep(ctx, "tagMaps", "", nil)
 }
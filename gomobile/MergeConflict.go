@@ -1,13 +1,18 @@
 package gomobile
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"slices"
+	"sort"
 
 	"github.com/pkg/errors"
 
 	"github.com/AndreasSko/go-jwlm/merger"
 	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/AndreasSko/go-jwlm/publication"
 )
 
 // MergeConflictError indicates that a conflict happened while merging. It
@@ -23,10 +28,22 @@ func (e MergeConflictError) Error() string {
 
 // MergeConflictsWrapper wraps mergeConflicts and their solutions
 type MergeConflictsWrapper struct {
-	DBWrapper         *DatabaseWrapper
+	DBWrapper *DatabaseWrapper
+	// CatalogPath is the path to a catalog.db. If it is set, the
+	// publications of conflicting entries are looked up in it.
+	CatalogPath       string
 	conflicts         map[string]merger.MergeConflict
 	unsolvedConflicts map[string]bool
 	solutions         map[string]merger.MergeSolution
+	// applied are the keys of solutions that have already been used
+	// for merging, so they can't be unsolved anymore.
+	applied map[string]bool
+	// sortedKeys caches the result of unsolvedKeys. It is nil if
+	// the unsolved conflicts have changed since.
+	sortedKeys []string
+	// catalog is opened at catalogPath when the first publication is looked up.
+	catalog     *publication.Catalog
+	catalogPath string
 }
 
 // MergeConflict represents two Models that collide. It is equvalent
@@ -38,10 +55,13 @@ type MergeConflict struct {
 	Right string
 }
 
-// modelRelatedTuple contains a model and its related entries
+// modelRelatedTuple contains a model and its related entries, together
+// with the publication and Bible reference of its Location if known.
 type modelRelatedTuple struct {
-	Model   model.Model   `json:"model"`
-	Related model.Related `json:"related"`
+	Model       model.Model              `json:"model"`
+	Related     model.Related            `json:"related"`
+	Publication *publication.Publication `json:"publication,omitempty"`
+	Reference   string                   `json:"reference,omitempty"`
 }
 
 // InitDBWrapper initializes the DatabaseWrapper for the MergeConflictsWrapper
@@ -67,10 +87,26 @@ func (mcw *MergeConflictsWrapper) addConflicts(conflicts map[string]merger.Merge
 		if _, exists := mcw.conflicts[key]; !exists {
 			mcw.conflicts[key] = value
 			mcw.unsolvedConflicts[key] = true
+			mcw.sortedKeys = nil
 		}
 	}
 }
 
+// removeUnsolved removes all unsolved conflicts, so they
+// can be added again by the next merge.
+func (mcw *MergeConflictsWrapper) removeUnsolved() {
+	for key := range mcw.unsolvedConflicts {
+		delete(mcw.conflicts, key)
+		delete(mcw.unsolvedConflicts, key)
+	}
+	mcw.sortedKeys = nil
+}
+
+// UnsolvedConflictCount returns the number of conflicts that still need to be solved
+func (mcw *MergeConflictsWrapper) UnsolvedConflictCount() int {
+	return len(mcw.unsolvedConflicts)
+}
+
 // NextConflict returns the next conflict that should be solved. If there
 // are no left, it returns an error
 func (mcw *MergeConflictsWrapper) NextConflict() (*MergeConflict, error) {
@@ -78,33 +114,122 @@ func (mcw *MergeConflictsWrapper) NextConflict() (*MergeConflict, error) {
 		return nil, errors.New("There are no unsolved conflicts")
 	}
 
-	if mcw.DBWrapper == nil {
-		mcw.DBWrapper = &DatabaseWrapper{merged: nil}
+	return mcw.ConflictAt(0)
+}
+
+// ConflictAt returns the unsolved conflict at index. The unsolved conflicts
+// are sorted by their type, publication, and location, so they can be
+// presented as a list.
+func (mcw *MergeConflictsWrapper) ConflictAt(index int) (*MergeConflict, error) {
+	keys := mcw.unsolvedKeys()
+	if index < 0 || index >= len(keys) {
+		return nil, errors.Errorf("There is no unsolved conflict at index %d", index)
+	}
+
+	return mcw.mergeConflict(keys[index])
+}
+
+// Conflict returns the conflict with the given key, regardless
+// of whether it has been solved already.
+func (mcw *MergeConflictsWrapper) Conflict(key string) (*MergeConflict, error) {
+	if _, exists := mcw.conflicts[key]; !exists {
+		return nil, errors.Errorf("Conflict with key %s does not exist", key)
+	}
+
+	return mcw.mergeConflict(key)
+}
+
+// unsolvedKeys returns the keys of the unsolved conflicts sorted by
+// the type, publication, and location of their left side. The result
+// is cached until the unsolved conflicts change and must not be modified.
+func (mcw *MergeConflictsWrapper) unsolvedKeys() []string {
+	// Comparing the length also detects conflicts that have been
+	// added or removed without going through the MergeConflictsWrapper.
+	if mcw.sortedKeys != nil && len(mcw.sortedKeys) == len(mcw.unsolvedConflicts) {
+		return mcw.sortedKeys
 	}
 
-	// Get any conflict
-	var conflictKey string
+	type conflictOrder struct {
+		key       string
+		modelType string
+		location  *model.Location
+	}
+	orders := make([]conflictOrder, 0, len(mcw.unsolvedConflicts))
 	for key := range mcw.unsolvedConflicts {
-		conflictKey = key
+		left := mcw.conflicts[key].Left
+		orders = append(orders, conflictOrder{
+			key:       key,
+			modelType: reflect.TypeOf(left).Elem().Name(),
+			location:  left.RelatedEntries(mcw.mergedDB()).Location,
+		})
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		if c := cmp.Compare(orders[i].modelType, orders[j].modelType); c != 0 {
+			return c < 0
+		}
+		if c := compareLocations(orders[i].location, orders[j].location); c != 0 {
+			return c < 0
+		}
+		return orders[i].key < orders[j].key
+	})
+
+	keys := make([]string, len(orders))
+	for i, order := range orders {
+		keys[i] = order.key
+	}
+	mcw.sortedKeys = keys
+	return keys
+}
+
+// compareLocations compares two Locations first by their publication and
+// then by their position within it. Conflicts without Location come first.
+func compareLocations(a *model.Location, b *model.Location) int {
+	if a == nil || b == nil {
+		switch {
+		case a == b:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
 	}
+
+	return cmp.Or(
+		cmp.Compare(a.KeySymbol.String, b.KeySymbol.String),
+		cmp.Compare(a.MepsLanguage.Int32, b.MepsLanguage.Int32),
+		cmp.Compare(a.IssueTagNumber, b.IssueTagNumber),
+		cmp.Compare(a.BookNumber.Int32, b.BookNumber.Int32),
+		cmp.Compare(a.ChapterNumber.Int32, b.ChapterNumber.Int32),
+		cmp.Compare(a.DocumentID.Int32, b.DocumentID.Int32),
+		cmp.Compare(a.Track.Int32, b.Track.Int32),
+	)
+}
+
+// mergedDB returns the merged Database, which is used to
+// look up entries related to conflicts.
+func (mcw *MergeConflictsWrapper) mergedDB() *model.Database {
+	if mcw.DBWrapper == nil {
+		return nil
+	}
+	return mcw.DBWrapper.merged
+}
+
+// mergeConflict returns the conflict with the given key, with both
+// sides represented as JSON.
+func (mcw *MergeConflictsWrapper) mergeConflict(conflictKey string) (*MergeConflict, error) {
 	conflict := mcw.conflicts[conflictKey]
 
 	result := &MergeConflict{
 		Key: conflictKey,
 	}
-	jsn, err := json.Marshal(modelRelatedTuple{
-		Model:   conflict.Left,
-		Related: conflict.Left.RelatedEntries(mcw.DBWrapper.merged),
-	})
+	jsn, err := json.Marshal(mcw.modelRelated(conflict.Left))
 	if err != nil {
 		return nil, errors.Wrap(err, "Error while marshalling to JSON")
 	}
 	result.Left = string(jsn)
 
-	jsn, err = json.Marshal(modelRelatedTuple{
-		Model:   conflict.Right,
-		Related: conflict.Right.RelatedEntries(mcw.DBWrapper.merged),
-	})
+	jsn, err = json.Marshal(mcw.modelRelated(conflict.Right))
 	if err != nil {
 		return nil, errors.Wrap(err, "Error while marshalling to JSON")
 	}
@@ -113,6 +238,67 @@ func (mcw *MergeConflictsWrapper) NextConflict() (*MergeConflict, error) {
 	return result, nil
 }
 
+// modelRelated returns the modelRelatedTuple of m. The publication
+// is only looked up if CatalogPath is set.
+func (mcw *MergeConflictsWrapper) modelRelated(m model.Model) modelRelatedTuple {
+	tuple := modelRelatedTuple{
+		Model:   m,
+		Related: m.RelatedEntries(mcw.mergedDB()),
+	}
+
+	location, ok := m.(*model.Location)
+	if !ok {
+		location = tuple.Related.Location
+	}
+	if location == nil {
+		return tuple
+	}
+	if catalog := mcw.openCatalog(); catalog != nil {
+		if publ, err := catalog.LookupLocation(location); err == nil {
+			tuple.Publication = &publ
+		}
+	}
+	tuple.Reference = tuple.Related.Reference
+	if tuple.Reference == "" {
+		tuple.Reference = location.Reference()
+	}
+
+	return tuple
+}
+
+// openCatalog returns the catalog at CatalogPath, which stays open until
+// Close is called. If CatalogPath is empty or it can't be opened, it returns nil.
+func (mcw *MergeConflictsWrapper) openCatalog() *publication.Catalog {
+	if mcw.catalog != nil && mcw.catalogPath == mcw.CatalogPath {
+		return mcw.catalog
+	}
+	mcw.Close()
+	if mcw.CatalogPath == "" {
+		return nil
+	}
+
+	catalog, err := publication.OpenCatalog(mcw.CatalogPath)
+	if err != nil {
+		return nil
+	}
+	mcw.catalog = catalog
+	mcw.catalogPath = mcw.CatalogPath
+	return catalog
+}
+
+// Close closes the catalog opened for looking up the publications of
+// conflicts. Merge does so once it is done. If another conflict is
+// requested afterwards, the catalog is opened again.
+func (mcw *MergeConflictsWrapper) Close() error {
+	if mcw.catalog == nil {
+		return nil
+	}
+	err := mcw.catalog.Close()
+	mcw.catalog = nil
+	mcw.catalogPath = ""
+	return err
+}
+
 // SolveConflict solves a mergeConflict represented by key and chooses the given side
 func (mcw *MergeConflictsWrapper) SolveConflict(key string, side string) error {
 	if mcw.unsolvedConflicts == nil || len(mcw.unsolvedConflicts) == 0 {
@@ -144,6 +330,94 @@ func (mcw *MergeConflictsWrapper) SolveConflict(key string, side string) error {
 	}
 
 	delete(mcw.unsolvedConflicts, key)
+	if mcw.sortedKeys != nil {
+		// Removing the key keeps the others sorted. The slice is copied,
+		// as callers might still iterate over the former one.
+		mcw.sortedKeys = slices.DeleteFunc(slices.Clone(mcw.sortedKeys), func(k string) bool {
+			return k == key
+		})
+	}
+
+	return nil
+}
+
+// SolveConflicts solves all unsolved conflicts of the given type (like Note,
+// Bookmark, InputField, or UserMarkBlockRange) by choosing the given side.
+// If conflictType is empty, all unsolved conflicts are solved. It returns
+// the number of solved conflicts.
+func (mcw *MergeConflictsWrapper) SolveConflicts(conflictType string, side string) (int, error) {
+	if side != string(merger.LeftSide) && side != string(merger.RightSide) {
+		return 0, fmt.Errorf("Side %s is not valid", side)
+	}
+
+	solved := 0
+	for _, key := range mcw.unsolvedKeys() {
+		if conflictType != "" && reflect.TypeOf(mcw.conflicts[key].Left).Elem().Name() != conflictType {
+			continue
+		}
+		if err := mcw.SolveConflict(key, side); err != nil {
+			return solved, err
+		}
+		solved++
+	}
+
+	return solved, nil
+}
+
+// SolvedSide returns the side (leftSide or rightSide) that has been chosen
+// for the conflict with the given key or an empty string if it is unsolved.
+func (mcw *MergeConflictsWrapper) SolvedSide(key string) string {
+	if _, unsolved := mcw.unsolvedConflicts[key]; unsolved {
+		return ""
+	}
+	return string(mcw.solutions[key].Side)
+}
+
+// UnsolveConflict reverts the solution of the conflict with the given key,
+// so it can be solved again. This is only possible as long as the solution
+// hasn't been used for merging yet.
+func (mcw *MergeConflictsWrapper) UnsolveConflict(key string) error {
+	_, exists := mcw.conflicts[key]
+	if _, solved := mcw.solutions[key]; !exists || !solved {
+		return errors.Errorf("Solved conflict with key %s does not exist", key)
+	}
+	if mcw.applied[key] {
+		return errors.Errorf("Conflict with key %s has already been merged", key)
+	}
+
+	delete(mcw.solutions, key)
+	mcw.unsolvedConflicts[key] = true
+	mcw.sortedKeys = nil
+
+	return nil
+}
+
+// markApplied marks all current solutions as used for merging.
+func (mcw *MergeConflictsWrapper) markApplied() {
+	if mcw.applied == nil {
+		mcw.applied = make(map[string]bool, len(mcw.solutions))
+	}
+	for key := range mcw.solutions {
+		mcw.applied[key] = true
+	}
+}
+
+// resolveWith solves all unsolved conflicts by asking the handler
+// which side to choose. The conflicts are passed in the order of ConflictAt.
+func (mcw *MergeConflictsWrapper) resolveWith(handler MergeHandler) error {
+	for _, key := range mcw.unsolvedKeys() {
+		conflict, err := mcw.mergeConflict(key)
+		if err != nil {
+			return err
+		}
+		side, err := handler.ResolveConflict(conflict)
+		if err != nil {
+			return err
+		}
+		if err := mcw.SolveConflict(key, side); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
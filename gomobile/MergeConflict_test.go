@@ -5,11 +5,13 @@ package gomobile
 import (
 	"database/sql"
 	"encoding/json"
+	"path/filepath"
 	"testing"
 
 	"github.com/AndreasSko/go-jwlm/merger"
 	"github.com/AndreasSko/go-jwlm/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMergeConflictsWrapper_InitDBWrapper(t *testing.T) {
@@ -105,11 +107,11 @@ func TestMergeConflictsWrapper_NextConflict(t *testing.T) {
 			Key: "1",
 			Left: jsonMarhshalIgnoreErr(modelRelatedTuple{
 				Model:   mcw.conflicts["1"].Left,
-				Related: model.Related{Location: db.Location[1], PublicationLocation: db.Location[1]},
+				Related: model.Related{Location: db.Location[1]},
 			}),
 			Right: jsonMarhshalIgnoreErr(modelRelatedTuple{
 				Model:   mcw.conflicts["1"].Right,
-				Related: model.Related{Location: db.Location[1], PublicationLocation: db.Location[1]},
+				Related: model.Related{Location: db.Location[1]},
 			}),
 		},
 		"2": {
@@ -210,3 +212,209 @@ func TestMergeConflictsWrapper_SolveConflict(t *testing.T) {
 	_, err := mcw.NextConflict()
 	assert.EqualError(t, err, "There are no unsolved conflicts")
 }
+
+// sortableConflicts returns a MergeConflictsWrapper with conflicts of
+// different types, publications, and locations.
+func sortableConflicts() *MergeConflictsWrapper {
+	db := &model.Database{
+		Location: []*model.Location{
+			nil,
+			{LocationID: 1, KeySymbol: sql.NullString{String: "nwtsty", Valid: true}, MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+				BookNumber: sql.NullInt32{Int32: 43, Valid: true}, ChapterNumber: sql.NullInt32{Int32: 3, Valid: true}},
+			{LocationID: 2, KeySymbol: sql.NullString{String: "nwtsty", Valid: true}, MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+				BookNumber: sql.NullInt32{Int32: 1, Valid: true}, ChapterNumber: sql.NullInt32{Int32: 1, Valid: true}},
+			{LocationID: 3, KeySymbol: sql.NullString{String: "lff", Valid: true}, MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+				DocumentID: sql.NullInt32{Int32: 1102021811, Valid: true}},
+		},
+	}
+	note := func(locationID int, title string) *model.Note {
+		return &model.Note{LocationID: sql.NullInt32{Int32: int32(locationID), Valid: true}, Title: sql.NullString{String: title, Valid: true}}
+	}
+
+	return &MergeConflictsWrapper{
+		DBWrapper: &DatabaseWrapper{merged: db},
+		conflicts: map[string]merger.MergeConflict{
+			"a": {Left: note(1, "John 3"), Right: note(1, "John 3 right")},
+			"b": {Left: note(3, "Lesson"), Right: note(3, "Lesson right")},
+			"c": {Left: note(2, "Genesis 1"), Right: note(2, "Genesis 1 right")},
+			"d": {Left: &model.Bookmark{LocationID: 1, Title: "Left"}, Right: &model.Bookmark{LocationID: 1, Title: "Right"}},
+			"e": {Left: &model.Note{Title: sql.NullString{String: "Without location", Valid: true}}, Right: &model.Note{}},
+		},
+		unsolvedConflicts: map[string]bool{"a": true, "b": true, "c": true, "d": true, "e": true},
+	}
+}
+
+func TestMergeConflictsWrapper_ConflictAt(t *testing.T) {
+	mcw := sortableConflicts()
+	assert.Equal(t, 5, mcw.UnsolvedConflictCount())
+
+	// Sorted by type, publication, and location
+	keys := []string{}
+	for i := 0; i < mcw.UnsolvedConflictCount(); i++ {
+		conflict, err := mcw.ConflictAt(i)
+		assert.NoError(t, err)
+		keys = append(keys, conflict.Key)
+	}
+	assert.Equal(t, []string{"d", "e", "b", "c", "a"}, keys)
+
+	next, err := mcw.NextConflict()
+	assert.NoError(t, err)
+	assert.Equal(t, "d", next.Key)
+
+	_, err = mcw.ConflictAt(5)
+	assert.EqualError(t, err, "There is no unsolved conflict at index 5")
+	_, err = mcw.ConflictAt(-1)
+	assert.Error(t, err)
+
+	// Conflicts can also be fetched by their key, even if they are solved
+	assert.NoError(t, mcw.SolveConflict("c", "leftSide"))
+	conflict, err := mcw.Conflict("c")
+	assert.NoError(t, err)
+	assert.Equal(t, "c", conflict.Key)
+	assert.Contains(t, conflict.Left, "Genesis 1")
+	_, err = mcw.Conflict("z")
+	assert.EqualError(t, err, "Conflict with key z does not exist")
+
+	assert.Equal(t, 4, mcw.UnsolvedConflictCount())
+	conflict, err = mcw.ConflictAt(3)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", conflict.Key)
+
+	// The order is cached, so the related entries are not looked up again
+	// until the unsolved conflicts change
+	mcw.DBWrapper.merged = &model.Database{}
+	assert.Equal(t, []string{"d", "e", "b", "a"}, mcw.unsolvedKeys())
+	assert.NoError(t, mcw.UnsolveConflict("c"))
+	assert.Equal(t, []string{"d", "a", "b", "c", "e"}, mcw.unsolvedKeys())
+
+	assert.Equal(t, 0, (&MergeConflictsWrapper{}).UnsolvedConflictCount())
+}
+
+func TestMergeConflictsWrapper_SolveConflicts(t *testing.T) {
+	mcw := sortableConflicts()
+
+	_, err := mcw.SolveConflicts("Note", "middleSide")
+	assert.EqualError(t, err, "Side middleSide is not valid")
+	assert.Equal(t, 5, mcw.UnsolvedConflictCount())
+
+	solved, err := mcw.SolveConflicts("Note", "leftSide")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, solved)
+	assert.Equal(t, 1, mcw.UnsolvedConflictCount())
+	for _, key := range []string{"a", "b", "c", "e"} {
+		assert.Equal(t, merger.MergeSolution{
+			Side:      merger.LeftSide,
+			Solution:  mcw.conflicts[key].Left,
+			Discarded: mcw.conflicts[key].Right,
+		}, mcw.solutions[key])
+	}
+
+	solved, err = mcw.SolveConflicts("", "rightSide")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, solved)
+	assert.Equal(t, "rightSide", mcw.SolvedSide("d"))
+	assert.Equal(t, 0, mcw.UnsolvedConflictCount())
+
+	solved, err = mcw.SolveConflicts("", "rightSide")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, solved)
+}
+
+func TestMergeConflictsWrapper_UnsolveConflict(t *testing.T) {
+	mcw := sortableConflicts()
+	assert.EqualError(t, mcw.UnsolveConflict("a"), "Solved conflict with key a does not exist")
+	assert.EqualError(t, mcw.UnsolveConflict("z"), "Solved conflict with key z does not exist")
+
+	assert.NoError(t, mcw.SolveConflict("a", "rightSide"))
+	assert.Equal(t, "rightSide", mcw.SolvedSide("a"))
+	assert.Equal(t, "", mcw.SolvedSide("b"))
+	assert.Equal(t, "", mcw.SolvedSide("z"))
+
+	assert.NoError(t, mcw.UnsolveConflict("a"))
+	assert.Equal(t, "", mcw.SolvedSide("a"))
+	assert.Equal(t, 5, mcw.UnsolvedConflictCount())
+	assert.NoError(t, mcw.SolveConflict("a", "leftSide"))
+	assert.Equal(t, "leftSide", mcw.SolvedSide("a"))
+
+	// Once a solution has been used for merging, it can't be reverted
+	dbw := &DatabaseWrapper{
+		left:  model.MakeDatabaseCopy(leftMultiCollision),
+		right: model.MakeDatabaseCopy(rightMultiCollision),
+	}
+	dbw.Init()
+	mcw = &MergeConflictsWrapper{DBWrapper: dbw}
+	assert.NoError(t, dbw.MergeLocations())
+	assert.Error(t, dbw.MergeInputFields("", mcw))
+	conflict, err := mcw.NextConflict()
+	assert.NoError(t, err)
+	assert.NoError(t, mcw.SolveConflict(conflict.Key, "rightSide"))
+	assert.NoError(t, dbw.MergeInputFields("", mcw))
+	assert.EqualError(t, mcw.UnsolveConflict(conflict.Key),
+		"Conflict with key "+conflict.Key+" has already been merged")
+}
+
+func TestMergeConflictsWrapper_modelRelated(t *testing.T) {
+	db := &model.Database{
+		Location: []*model.Location{
+			nil,
+			{
+				LocationID:    1,
+				BookNumber:    sql.NullInt32{Int32: 1, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 1, Valid: true},
+				KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage:  sql.NullInt32{Int32: 2, Valid: true},
+			},
+			{
+				LocationID:   2,
+				DocumentID:   sql.NullInt32{Int32: 1102002020, Valid: true},
+				KeySymbol:    sql.NullString{String: "cl", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+			},
+		},
+	}
+	mcw := MergeConflictsWrapper{DBWrapper: &DatabaseWrapper{merged: db}}
+
+	tuple := mcw.modelRelated(&model.Bookmark{LocationID: 1, PublicationLocationID: 1})
+	assert.Equal(t, "1. Mose 1", tuple.Reference)
+	assert.Nil(t, tuple.Publication)
+
+	tuple = mcw.modelRelated(&model.Bookmark{
+		LocationID:            1,
+		PublicationLocationID: 1,
+		BlockType:             model.BlockTypeVerse,
+		BlockIdentifier:       sql.NullInt32{Int32: 3, Valid: true},
+	})
+	assert.Equal(t, "1. Mose 1:3", tuple.Reference)
+	assert.Equal(t, "1. Mose 1:3", tuple.Related.Reference)
+
+	tuple = mcw.modelRelated(db.Location[2])
+	assert.Empty(t, tuple.Reference)
+	assert.Nil(t, tuple.Publication)
+
+	mcw.CatalogPath = filepath.Join("..", "publication", "testdata", "catalog.db")
+	tuple = mcw.modelRelated(db.Location[2])
+	require.NotNil(t, tuple.Publication)
+	assert.Equal(t, "Draw Close to Jehovah", tuple.Publication.Title)
+	jsn, err := json.Marshal(tuple)
+	require.NoError(t, err)
+	assert.Contains(t, string(jsn), `"publication":{"id":67,`)
+
+	// The catalog is only opened once
+	catalog := mcw.catalog
+	require.NotNil(t, catalog)
+	tuple = mcw.modelRelated(db.Location[2])
+	assert.Equal(t, "Draw Close to Jehovah", tuple.Publication.Title)
+	assert.Same(t, catalog, mcw.catalog)
+
+	tuple = mcw.modelRelated(&model.Tag{TagID: 1, Name: "Tag"})
+	assert.Empty(t, tuple.Reference)
+	assert.Nil(t, tuple.Publication)
+
+	assert.NoError(t, mcw.Close())
+	assert.Nil(t, mcw.catalog)
+	assert.NoError(t, mcw.Close())
+
+	mcw.CatalogPath = "not-existing.db"
+	tuple = mcw.modelRelated(db.Location[2])
+	assert.Nil(t, tuple.Publication)
+}
@@ -5,6 +5,8 @@ package gomobile
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"path/filepath"
 	"testing"
 
@@ -140,6 +142,59 @@ func Test_MergeMultiCollisionAutoSolver(t *testing.T) {
 	assert.True(t, dbw.merged.Equals(rightMultiCollision))
 }
 
+// testMergeHandler chooses side for every conflict and records the progress.
+type testMergeHandler struct {
+	side      string
+	err       error
+	conflicts []string
+	steps     []string
+}
+
+func (h *testMergeHandler) ResolveConflict(conflict *MergeConflict) (string, error) {
+	h.conflicts = append(h.conflicts, conflict.Key)
+	return h.side, h.err
+}
+
+func (h *testMergeHandler) Progress(step string, completed int, total int) {
+	h.steps = append(h.steps, fmt.Sprintf("%s %d/%d", step, completed, total))
+}
+
+func TestDatabaseWrapper_Merge(t *testing.T) {
+	dbw := DatabaseWrapper{
+		left:  model.MakeDatabaseCopy(leftMultiCollision),
+		right: model.MakeDatabaseCopy(rightMultiCollision),
+	}
+	handler := &testMergeHandler{side: "rightSide"}
+	assert.NoError(t, dbw.Merge(nil, handler))
+	assert.True(t, dbw.merged.Equals(rightMultiCollision))
+	assert.Len(t, handler.conflicts, 5)
+	assert.Equal(t, []string{
+		"locations 0/7",
+		"bookmarks 1/7",
+		"inputFields 2/7",
+		"tags 3/7",
+		"markings 4/7",
+		"notes 5/7",
+		"tagMaps 6/7",
+		" 7/7",
+	}, handler.steps)
+
+	// Resolvers are preferred over the handler
+	handler = &testMergeHandler{side: "leftSide"}
+	assert.NoError(t, dbw.Merge(&MergeResolvers{InputFields: "chooseRight", Markings: "chooseRight"}, handler))
+	assert.True(t, dbw.merged.Equals(rightMultiCollision))
+	assert.Empty(t, handler.conflicts)
+
+	handler = &testMergeHandler{err: errors.New("cancelled")}
+	assert.ErrorContains(t, dbw.Merge(nil, handler), "cancelled")
+
+	handler = &testMergeHandler{side: "middleSide"}
+	assert.Error(t, dbw.Merge(nil, handler))
+
+	assert.Error(t, (&DatabaseWrapper{}).Merge(nil, handler))
+	assert.Error(t, dbw.Merge(nil, nil))
+}
+
 func Test_MergeNwt(t *testing.T) {
 	dbw := DatabaseWrapper{
 		left:  model.MakeDatabaseCopy(leftNwtDB),
@@ -0,0 +1,154 @@
+package gomobile
+
+import (
+	"context"
+	"sync"
+
+	"github.com/AndreasSko/go-jwlm/merger"
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/pkg/errors"
+)
+
+// OperationManager keeps all the information of a running import, merge, or
+// export, enabling it to check progress and also cancel it if necessary.
+// As the operation runs in the background, its state is only accessible
+// through the methods of OperationManager.
+type OperationManager struct {
+	mu       sync.Mutex
+	progress OperationProgress
+	ctx      context.Context
+	cancel   context.CancelFunc
+	err      error
+}
+
+// OperationProgress represents the progress of a running operation
+type OperationProgress struct {
+	// Step is the name of the most recent step of the operation
+	Step      string
+	Completed int
+	Total     int
+	Done      bool
+	Canceled  bool
+}
+
+// newOperationManager starts run in a sub-goroutine and returns
+// the OperationManager to keep track of it.
+func newOperationManager(run func(om *OperationManager) error) *OperationManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	om := &OperationManager{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	go func() {
+		err := run(om)
+
+		om.mu.Lock()
+		defer om.mu.Unlock()
+		om.err = err
+		om.progress.Done = true
+	}()
+
+	return om
+}
+
+// watch returns a channel that updates the progress of om with every
+// model.Progress it receives, until it is closed. Afterwards done is closed.
+func (om *OperationManager) watch() (prgrs chan model.Progress, done chan struct{}) {
+	prgrs = make(chan model.Progress)
+	done = make(chan struct{})
+	go func() {
+		for progress := range prgrs {
+			step := om.Progress().Step
+			if !progress.Done {
+				step = progress.Step
+			}
+			om.setProgress(step, progress.Completed, progress.Total)
+		}
+		close(done)
+	}()
+	return prgrs, done
+}
+
+// ImportJWLBackupAsync imports a .jwlibrary backup file into the struct on the
+// given side like ImportJWLBackup, but in the background. The returned
+// OperationManager allows to keep track and cancel the running import
+func (dbw *DatabaseWrapper) ImportJWLBackupAsync(filename string, side string) *OperationManager {
+	return newOperationManager(func(om *OperationManager) error {
+		prgrs, done := om.watch()
+		defer func() { <-done }()
+		return dbw.importJWLBackup(om.ctx, filename, side, prgrs)
+	})
+}
+
+// MergeAsync merges the left and right backup like Merge, but in the background.
+// The returned OperationManager allows to keep track and cancel the running merge.
+// Canceling takes effect before the next step or conflict resolution.
+func (dbw *DatabaseWrapper) MergeAsync(resolvers *MergeResolvers, handler MergeHandler) *OperationManager {
+	return newOperationManager(func(om *OperationManager) error {
+		return dbw.merge(om.ctx, resolvers, handler, om.setProgress)
+	})
+}
+
+// ExportMergedAsync exports the merged database to filename like
+// ExportMergedWithOptions, but in the background. The returned
+// OperationManager allows to keep track and cancel the running export
+func (dbw *DatabaseWrapper) ExportMergedAsync(filename string, metadataSide string, name string, deviceName string) *OperationManager {
+	return newOperationManager(func(om *OperationManager) error {
+		opts, err := dbw.exportOptions(metadataSide, name, deviceName)
+		if err != nil {
+			return err
+		}
+		if err := merger.PrepareDatabasesPostMerge(dbw.merged); err != nil {
+			return errors.Wrap(err, "Error while preparing merged database for export")
+		}
+
+		prgrs, done := om.watch()
+		defer func() { <-done }()
+		return dbw.merged.ExportJWLBackupContext(om.ctx, filename, opts, prgrs)
+	})
+}
+
+// setProgress updates the step and the number of completed and total steps.
+func (om *OperationManager) setProgress(step string, completed int, total int) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.progress.Step = step
+	om.progress.Completed = completed
+	om.progress.Total = total
+}
+
+// Progress returns a snapshot of the current progress of the operation.
+// Once Done is set, the results of the operation can be accessed.
+func (om *OperationManager) Progress() *OperationProgress {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	progress := om.progress
+	return &progress
+}
+
+// Cancel cancels a running operation
+func (om *OperationManager) Cancel() {
+	om.cancel()
+
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.progress.Canceled = true
+}
+
+// Successful indicates if the operation has been successful
+func (om *OperationManager) Successful() bool {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	return om.progress.Done && om.err == nil && !om.progress.Canceled
+}
+
+// Error returns possible errors of an operation as a string
+func (om *OperationManager) Error() string {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	if om.err != nil {
+		return om.err.Error()
+	}
+	return ""
+}
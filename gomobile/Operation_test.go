@@ -0,0 +1,141 @@
+//go:build !windows
+// +build !windows
+
+package gomobile
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForOperation polls om until it is done, like an app would do,
+// and returns its final progress.
+func waitForOperation(t *testing.T, om *OperationManager) *OperationProgress {
+	t.Helper()
+	for i := 0; ; i++ {
+		if progress := om.Progress(); progress.Done {
+			return progress
+		}
+		require.Less(t, i, 1000, "operation did not finish in time")
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDatabaseWrapper_ImportJWLBackupAsync(t *testing.T) {
+	dbw := &DatabaseWrapper{}
+
+	om := dbw.ImportJWLBackupAsync(backupFile, "leftSide")
+	waitForOperation(t, om)
+	assert.True(t, om.Successful())
+	assert.Empty(t, om.Error())
+	assert.True(t, dbw.DBIsLoaded("leftSide"))
+
+	expected := &model.Database{}
+	require.NoError(t, expected.ImportJWLBackup(backupFile))
+	assert.True(t, expected.Equals(dbw.left))
+
+	om = dbw.ImportJWLBackupAsync(backupFile, "middleSide")
+	waitForOperation(t, om)
+	assert.False(t, om.Successful())
+	assert.NotEmpty(t, om.Error())
+
+	om = dbw.ImportJWLBackupAsync("not-existing.jwlibrary", "rightSide")
+	waitForOperation(t, om)
+	assert.False(t, om.Successful())
+	assert.False(t, dbw.DBIsLoaded("rightSide"))
+}
+
+// blockingMergeHandler blocks when asked to resolve a conflict
+// until it is released.
+type blockingMergeHandler struct {
+	testMergeHandler
+	asked   chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingMergeHandler) ResolveConflict(conflict *MergeConflict) (string, error) {
+	h.asked <- struct{}{}
+	<-h.release
+	return h.testMergeHandler.ResolveConflict(conflict)
+}
+
+func TestDatabaseWrapper_MergeAsync(t *testing.T) {
+	dbw := &DatabaseWrapper{
+		left:  model.MakeDatabaseCopy(leftMultiCollision),
+		right: model.MakeDatabaseCopy(rightMultiCollision),
+	}
+
+	om := dbw.MergeAsync(nil, &testMergeHandler{side: "rightSide"})
+	progress := waitForOperation(t, om)
+	assert.True(t, om.Successful())
+	assert.Equal(t, 7, progress.Completed)
+	assert.Equal(t, 7, progress.Total)
+	assert.True(t, dbw.merged.Equals(rightMultiCollision))
+
+	// Canceling while a conflict is resolved stops the merge afterwards
+	handler := &blockingMergeHandler{
+		testMergeHandler: testMergeHandler{side: "rightSide"},
+		asked:            make(chan struct{}),
+		release:          make(chan struct{}),
+	}
+	om = dbw.MergeAsync(nil, handler)
+	<-handler.asked
+	om.Cancel()
+	close(handler.release)
+	progress = waitForOperation(t, om)
+	assert.True(t, progress.Canceled)
+	assert.False(t, om.Successful())
+	assert.Contains(t, om.Error(), "context canceled")
+	assert.Len(t, handler.conflicts, 1)
+}
+
+func TestDatabaseWrapper_ExportMergedAsync(t *testing.T) {
+	dbw := &DatabaseWrapper{}
+	assert.NoError(t, dbw.ImportJWLBackup(backupFile, "leftSide"))
+	assert.NoError(t, dbw.ImportJWLBackup(backupFile, "rightSide"))
+	dbw.Init()
+	dbw.merged = model.MakeDatabaseCopy(dbw.left)
+
+	newBackup := filepath.Join(t.TempDir(), "backup.jwlibrary")
+	om := dbw.ExportMergedAsync(newBackup, "leftSide", "", "")
+	progress := waitForOperation(t, om)
+	assert.True(t, om.Successful())
+	assert.Equal(t, progress.Total, progress.Completed)
+
+	newDB := &model.Database{}
+	assert.NoError(t, newDB.ImportJWLBackup(newBackup))
+	assert.True(t, dbw.left.Equals(newDB))
+	assert.Equal(t, dbw.left.Metadata.Name, newDB.Metadata.Name)
+
+	om = dbw.ExportMergedAsync(newBackup, "middleSide", "", "")
+	waitForOperation(t, om)
+	assert.False(t, om.Successful())
+	assert.NotEmpty(t, om.Error())
+
+	// Duplicate UserMarks that can't be cleaned up stop the export
+	dbw.merged = &model.Database{
+		Location: []*model.Location{nil, {
+			LocationID:   1,
+			DocumentID:   sql.NullInt32{Int32: 1102021811, Valid: true},
+			KeySymbol:    sql.NullString{String: "lffi", Valid: true},
+			MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+		}},
+		UserMark: []*model.UserMark{
+			nil,
+			{UserMarkID: 1, ColorIndex: 1, LocationID: 1, UserMarkGUID: "DUPLICATE", Version: 1},
+			{UserMarkID: 2, ColorIndex: 2, LocationID: 1, UserMarkGUID: "DUPLICATE", Version: 1},
+		},
+	}
+	otherBackup := filepath.Join(t.TempDir(), "other.jwlibrary")
+	om = dbw.ExportMergedAsync(otherBackup, "", "", "")
+	waitForOperation(t, om)
+	assert.False(t, om.Successful())
+	assert.Contains(t, om.Error(), "could not clean up userMark duplicates")
+	assert.NoFileExists(t, otherBackup)
+}
@@ -0,0 +1,104 @@
+package gomobile
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/pkg/errors"
+)
+
+// SearchQuery represents a search within a backup. It maps to
+// model.SearchOptions, where empty fields don't filter the results.
+type SearchQuery struct {
+	Query     string
+	Tag       string
+	KeySymbol string
+	// MepsLanguage only matches entries in this language, -1 matches all
+	MepsLanguage int
+	// From and To are dates in the format YYYY-MM-DD (both inclusive)
+	From string
+	To   string
+}
+
+// searchResult is the JSON representation of a model.SearchResult
+type searchResult struct {
+	Entry     model.Model     `json:"entry"`
+	Location  *model.Location `json:"location"`
+	Reference string          `json:"reference,omitempty"`
+	Tags      []*model.Tag    `json:"tags"`
+}
+
+// NewSearchQuery returns a SearchQuery for the given text without any filters.
+func NewSearchQuery(query string) *SearchQuery {
+	return &SearchQuery{
+		Query:        query,
+		MepsLanguage: -1,
+	}
+}
+
+// Search searches the notes and bookmarks of the backup on the given side
+// (leftSide, rightSide, or mergeSide), so it can be used before merging.
+// It returns a JSON array of the matching entries with their Location,
+// reference (like "John 3:16"), and tags.
+func (dbw *DatabaseWrapper) Search(side string, query *SearchQuery) (string, error) {
+	var db *model.Database
+	switch side {
+	case "leftSide":
+		db = dbw.left
+	case "rightSide":
+		db = dbw.right
+	case "mergeSide":
+		db = dbw.merged
+	default:
+		return "", errors.Errorf("%s is not a valid side", side)
+	}
+	if db == nil {
+		return "", errors.Errorf("Backup on %s has not been loaded", side)
+	}
+	if query == nil {
+		query = NewSearchQuery("")
+	}
+
+	opts := model.SearchOptions{
+		Tag:       query.Tag,
+		KeySymbol: query.KeySymbol,
+	}
+	if query.MepsLanguage >= 0 {
+		opts.MepsLanguage = sql.NullInt32{Int32: int32(query.MepsLanguage), Valid: true}
+	}
+	var err error
+	if query.From != "" {
+		if opts.From, err = time.Parse(time.DateOnly, query.From); err != nil {
+			return "", errors.Wrap(err, "Could not parse From")
+		}
+	}
+	if query.To != "" {
+		if opts.To, err = time.Parse(time.DateOnly, query.To); err != nil {
+			return "", errors.Wrap(err, "Could not parse To")
+		}
+		opts.To = opts.To.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	results, err := db.Search(query.Query, opts)
+	if err != nil {
+		return "", err
+	}
+
+	jsonResults := make([]searchResult, len(results))
+	for i, result := range results {
+		jsonResults[i] = searchResult{
+			Entry:     result.Model,
+			Location:  result.Location,
+			Reference: result.Reference,
+			Tags:      result.Tags,
+		}
+	}
+	jsn, err := json.Marshal(jsonResults)
+	if err != nil {
+		return "", errors.Wrap(err, "Error while marshalling to JSON")
+	}
+
+	return string(jsn), nil
+}
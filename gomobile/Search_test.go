@@ -0,0 +1,51 @@
+//go:build !windows
+// +build !windows
+
+package gomobile
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseWrapper_Search(t *testing.T) {
+	dbw := &DatabaseWrapper{left: leftDB}
+
+	result, err := dbw.Search("leftSide", NewSearchQuery("left VERSION"))
+	require.NoError(t, err)
+	var entries []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(result), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Note", entries[0]["entry"].(map[string]interface{})["type"])
+	assert.Equal(t, float64(1), entries[0]["entry"].(map[string]interface{})["noteId"])
+	assert.Equal(t, float64(1), entries[0]["location"].(map[string]interface{})["locationId"])
+	assert.Equal(t, "1. Mose 1:1", entries[0]["reference"])
+	assert.Equal(t, "Left", entries[0]["tags"].([]interface{})[0].(map[string]interface{})["name"])
+
+	query := NewSearchQuery("")
+	query.Tag = "Same"
+	query.MepsLanguage = 2
+	result, err = dbw.Search("leftSide", query)
+	require.NoError(t, err)
+	assert.Equal(t, "[]", result)
+
+	query = NewSearchQuery("")
+	query.From = "2020-09-15"
+	query.To = "2020-09-15"
+	result, err = dbw.Search("leftSide", query)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(result), &entries))
+	assert.Len(t, entries, 2)
+
+	query.To = "15.09.2020"
+	_, err = dbw.Search("leftSide", query)
+	assert.Error(t, err)
+
+	_, err = dbw.Search("rightSide", nil)
+	assert.EqualError(t, err, "Backup on rightSide has not been loaded")
+	_, err = dbw.Search("wrongSide", nil)
+	assert.EqualError(t, err, "wrongSide is not a valid side")
+}
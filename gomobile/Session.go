@@ -0,0 +1,150 @@
+package gomobile
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+
+	"github.com/AndreasSko/go-jwlm/merger"
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/pkg/errors"
+)
+
+// sessionFilename is the name of the file in TempDir the session is stored in
+const sessionFilename = "merge-session.gob"
+
+// sessionState is everything that is needed to continue a merge later on.
+type sessionState struct {
+	Left          *model.Database
+	Right         *model.Database
+	Merged        *model.Database
+	LeftTmp       *model.Database
+	RightTmp      *model.Database
+	SkipPlaylists bool
+	Stage         int
+
+	Conflicts         map[string]merger.MergeConflict
+	UnsolvedConflicts map[string]bool
+	Solutions         map[string]merger.MergeSolution
+	Applied           map[string]bool
+}
+
+// sessionPath returns the path of the session file in TempDir.
+func (dbw *DatabaseWrapper) sessionPath() (string, error) {
+	if dbw.TempDir == "" {
+		return "", errors.New("TempDir needs to be set for storing the session")
+	}
+	return filepath.Join(dbw.TempDir, sessionFilename), nil
+}
+
+// SaveSession stores the imported databases, the progress of the merge, and
+// the conflicts of mcw together with their solutions in a file in TempDir, so
+// the merge can be continued later on using RestoreSession. If mcw is nil,
+// the conflicts of the last call to Merge are stored.
+func (dbw *DatabaseWrapper) SaveSession(mcw *MergeConflictsWrapper) error {
+	path, err := dbw.sessionPath()
+	if err != nil {
+		return err
+	}
+	if mcw == nil {
+		mcw = dbw.mcw
+	}
+
+	state := sessionState{
+		Left:          dbw.left,
+		Right:         dbw.right,
+		Merged:        dbw.merged,
+		LeftTmp:       dbw.leftTmp,
+		RightTmp:      dbw.rightTmp,
+		SkipPlaylists: dbw.skipPlaylists,
+		Stage:         dbw.stage,
+	}
+	if mcw != nil {
+		state.Conflicts = mcw.conflicts
+		state.UnsolvedConflicts = mcw.unsolvedConflicts
+		state.Solutions = mcw.solutions
+		state.Applied = mcw.applied
+	}
+
+	// Write to a temporary file first, so an interrupted
+	// save doesn't destroy a former session.
+	tmp, err := os.CreateTemp(dbw.TempDir, sessionFilename+".*")
+	if err != nil {
+		return errors.Wrap(err, "Error while creating session file")
+	}
+	defer os.Remove(tmp.Name())
+	if err := gob.NewEncoder(tmp).Encode(state); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "Error while storing session")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "Error while storing session")
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// RestoreSession restores a session stored by SaveSession. The conflicts and
+// their solutions are restored to mcw (if it is not nil) and are also used to
+// continue Merge. NextMergeStep tells which step needs to be merged next.
+func (dbw *DatabaseWrapper) RestoreSession(mcw *MergeConflictsWrapper) error {
+	path, err := dbw.sessionPath()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "Error while opening session file")
+	}
+	defer file.Close()
+
+	var state sessionState
+	if err := gob.NewDecoder(file).Decode(&state); err != nil {
+		return errors.Wrap(err, "Error while restoring session")
+	}
+
+	dbw.left = state.Left
+	dbw.right = state.Right
+	dbw.merged = state.Merged
+	dbw.leftTmp = state.LeftTmp
+	dbw.rightTmp = state.RightTmp
+	dbw.skipPlaylists = state.SkipPlaylists
+	dbw.stage = state.Stage
+
+	if mcw == nil {
+		mcw = &MergeConflictsWrapper{}
+	}
+	mcw.DBWrapper = dbw
+	mcw.conflicts = state.Conflicts
+	mcw.unsolvedConflicts = state.UnsolvedConflicts
+	mcw.sortedKeys = nil
+	mcw.solutions = state.Solutions
+	mcw.applied = state.Applied
+	dbw.mcw = mcw
+
+	return nil
+}
+
+// HasSession indicates if a session stored by SaveSession exists in TempDir.
+func (dbw *DatabaseWrapper) HasSession() bool {
+	path, err := dbw.sessionPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// DeleteSession removes a session stored by SaveSession, e.g. after
+// the merged backup has been exported.
+func (dbw *DatabaseWrapper) DeleteSession() error {
+	path, err := dbw.sessionPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "Error while deleting session file")
+	}
+	return nil
+}
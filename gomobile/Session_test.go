@@ -0,0 +1,112 @@
+//go:build !windows
+// +build !windows
+
+package gomobile
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseWrapper_SaveSession(t *testing.T) {
+	tmp := t.TempDir()
+	dbw := &DatabaseWrapper{
+		TempDir: tmp,
+		left:    model.MakeDatabaseCopy(leftMultiCollision),
+		right:   model.MakeDatabaseCopy(rightMultiCollision),
+	}
+	assert.False(t, dbw.HasSession())
+	dbw.Init()
+	assert.Equal(t, "locations", dbw.NextMergeStep())
+
+	mcw := &MergeConflictsWrapper{DBWrapper: dbw}
+	require.NoError(t, dbw.MergeLocations())
+	require.NoError(t, dbw.MergeBookmarks("", mcw))
+	require.Error(t, dbw.MergeInputFields("", mcw))
+	conflict, err := mcw.NextConflict()
+	require.NoError(t, err)
+	require.NoError(t, mcw.SolveConflict(conflict.Key, "rightSide"))
+	require.NoError(t, dbw.SaveSession(mcw))
+	assert.True(t, dbw.HasSession())
+
+	// Continue with the restored session, as if the app has been restarted
+	restored := &DatabaseWrapper{TempDir: tmp}
+	restoredMcw := &MergeConflictsWrapper{}
+	require.NoError(t, restored.RestoreSession(restoredMcw))
+	assert.Equal(t, "inputFields", restored.NextMergeStep())
+	assert.Equal(t, "rightSide", restoredMcw.SolvedSide(conflict.Key))
+	assert.Equal(t, 0, restoredMcw.UnsolvedConflictCount())
+	assert.True(t, restored.left.Equals(dbw.left))
+	assert.True(t, restored.merged.Equals(dbw.merged))
+
+	require.NoError(t, restored.MergeInputFields("", restoredMcw))
+	require.NoError(t, restored.MergeTags())
+	for restored.MergeUserMarkAndBlockRange("", restoredMcw) != nil {
+		conflict, err := restoredMcw.NextConflict()
+		require.NoError(t, err)
+		require.NoError(t, restoredMcw.SolveConflict(conflict.Key, "rightSide"))
+	}
+	require.NoError(t, restored.MergeNotes("", restoredMcw))
+	require.NoError(t, restored.MergeTagMaps())
+	assert.Equal(t, "", restored.NextMergeStep())
+	expected := model.MakeDatabaseCopy(rightMultiCollision)
+	expected.TempDir = tmp
+	assert.True(t, restored.merged.Equals(expected))
+
+	require.NoError(t, restored.DeleteSession())
+	assert.False(t, restored.HasSession())
+	assert.NoError(t, restored.DeleteSession())
+	assert.Error(t, restored.RestoreSession(nil))
+
+	// A TempDir is needed for storing the session
+	assert.EqualError(t, (&DatabaseWrapper{}).SaveSession(nil), "TempDir needs to be set for storing the session")
+	assert.Error(t, (&DatabaseWrapper{}).RestoreSession(nil))
+	assert.False(t, (&DatabaseWrapper{}).HasSession())
+}
+
+// limitedMergeHandler answers the given number of conflicts
+// with rightSide, before it stops the merge.
+type limitedMergeHandler struct {
+	testMergeHandler
+	limit int
+}
+
+func (h *limitedMergeHandler) ResolveConflict(conflict *MergeConflict) (string, error) {
+	if len(h.conflicts) >= h.limit {
+		return "", errors.New("stopped")
+	}
+	return h.testMergeHandler.ResolveConflict(conflict)
+}
+
+func TestDatabaseWrapper_RestoreSession_Merge(t *testing.T) {
+	tmp := t.TempDir()
+	dbw := &DatabaseWrapper{
+		TempDir: tmp,
+		left:    model.MakeDatabaseCopy(leftMultiCollision),
+		right:   model.MakeDatabaseCopy(rightMultiCollision),
+	}
+	handler := &limitedMergeHandler{testMergeHandler: testMergeHandler{side: "rightSide"}, limit: 2}
+	assert.ErrorContains(t, dbw.Merge(nil, handler), "stopped")
+	assert.Equal(t, "markings", dbw.NextMergeStep())
+	require.NoError(t, dbw.SaveSession(nil))
+
+	// The restored Merge continues with the remaining conflicts
+	restored := &DatabaseWrapper{TempDir: tmp}
+	require.NoError(t, restored.RestoreSession(nil))
+	handler = &limitedMergeHandler{testMergeHandler: testMergeHandler{side: "rightSide"}, limit: 5}
+	require.NoError(t, restored.Merge(nil, handler))
+	assert.Len(t, handler.conflicts, 3)
+	assert.Equal(t, []string{"markings 4/7", "notes 5/7", "tagMaps 6/7", " 7/7"}, handler.steps)
+	expected := model.MakeDatabaseCopy(rightMultiCollision)
+	expected.TempDir = tmp
+	assert.True(t, restored.merged.Equals(expected))
+
+	// Once finished, Merge starts from the beginning again
+	handler = &limitedMergeHandler{testMergeHandler: testMergeHandler{side: "rightSide"}, limit: 5}
+	require.NoError(t, restored.Merge(nil, handler))
+	assert.Len(t, handler.conflicts, 5)
+}
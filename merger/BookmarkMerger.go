@@ -1,13 +1,21 @@
 package merger
 
 import (
+	"context"
+
 	"github.com/AndreasSko/go-jwlm/model"
 )
 
 // MergeBookmarks tries to merge the left and right slices of Bookmarks. If there is a
 // collision, it returns an error asking for specification how it should handle it.
 func MergeBookmarks(left []*model.Bookmark, right []*model.Bookmark, conflictSolution map[string]MergeSolution) ([]*model.Bookmark, IDChanges, error) {
-	result, changes, err := tryMergeWithConflictSolver(left, right, conflictSolution, solveEqualityMergeConflict)
+	return MergeBookmarksContext(context.Background(), left, right, conflictSolution)
+}
+
+// MergeBookmarksContext is like MergeBookmarks, but stops and returns the error
+// of ctx if it is canceled.
+func MergeBookmarksContext(ctx context.Context, left []*model.Bookmark, right []*model.Bookmark, conflictSolution map[string]MergeSolution) ([]*model.Bookmark, IDChanges, error) {
+	result, changes, err := tryMergeWithConflictSolver(ctx, left, right, conflictSolution, solveEqualityMergeConflict)
 
 	return model.Bookmark{}.MakeSlice(result), changes, err
 }
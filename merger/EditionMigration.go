@@ -0,0 +1,307 @@
+package merger
+
+import (
+	"github.com/AndreasSko/go-jwlm/model"
+)
+
+// EditionMigration describes how Locations of one edition of a publication
+// can be moved to another edition of it, like JW Library did when it replaced
+// the Standard Bible (`nwt`) with the Study Edition (`nwtsty`).
+type EditionMigration struct {
+	// From is the KeySymbol of the old edition.
+	From string
+	// To is the KeySymbol of the new edition.
+	To string
+	// MepsLanguages limits the migration to the given languages. If it is
+	// empty, the migration applies to all languages.
+	MepsLanguages []int
+	// DocumentIDs maps the DocumentIDs of the old edition to the ones of the
+	// new edition. Locations pointing to a specific document are only migrated
+	// if their DocumentID is part of this map, as we otherwise can't know where
+	// the document is located in the new edition.
+	DocumentIDs map[int]int
+}
+
+// EditionMigrations contains the migrations that are applied automatically
+// by PrepareDatabasesPreMerge.
+var EditionMigrations = []EditionMigration{
+	{From: "nwt", To: "nwtsty"},
+}
+
+// appliesTo checks if the given Location can be migrated with this EditionMigration.
+func (em EditionMigration) appliesTo(location *model.Location) bool {
+	if location == nil || location.KeySymbol.String != em.From {
+		return false
+	}
+
+	if len(em.MepsLanguages) > 0 {
+		found := false
+		for _, lang := range em.MepsLanguages {
+			if int(location.MepsLanguage.Int32) == lang {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	// Tracks (e.g. of audio recordings) are specific to an edition,
+	// so there is no reliable way to migrate them.
+	if location.Track.Valid {
+		return false
+	}
+
+	if location.DocumentID.Valid {
+		_, ok := em.DocumentIDs[int(location.DocumentID.Int32)]
+		return ok
+	}
+
+	return true
+}
+
+// migrate moves the given Location to the new edition. It expects that
+// appliesTo has been checked before.
+func (em EditionMigration) migrate(location *model.Location) {
+	location.KeySymbol.String = em.To
+	if location.DocumentID.Valid {
+		location.DocumentID.Int32 = int32(em.DocumentIDs[int(location.DocumentID.Int32)])
+	}
+}
+
+// pendingMigration identifies an EditionMigration (by its index within a slice
+// of EditionMigration) that has to be applied to the Locations of a given MepsLanguage.
+type pendingMigration struct {
+	migration    int
+	mepsLanguage int
+}
+
+// MigrateEditions moves all Locations of the given Database that are covered by
+// one of the migrations to their new edition and returns the number of migrated
+// Locations. Locations that exist twice after the migration are cleaned up and
+// all entries pointing to them are updated. Bookmarks, InputFields, and TagMaps
+// that would collide with an entry that already belonged to the new edition
+// are dropped in favor of the existing one.
+func MigrateEditions(db *model.Database, migrations []EditionMigration) int {
+	if db == nil {
+		return 0
+	}
+
+	migrated := map[int]bool{}
+	for _, location := range db.Location {
+		for _, em := range migrations {
+			if !em.appliesTo(location) {
+				continue
+			}
+			em.migrate(location)
+			migrated[location.LocationID] = true
+			break
+		}
+	}
+	if len(migrated) == 0 {
+		return 0
+	}
+
+	locations, changes := cleanupDuplicateLocations(db.Location)
+	db.Location = locations
+
+	dropCollidingBookmarks(db.Bookmark, migrated, changes)
+	dropCollidingInputFields(db.InputField, migrated, changes)
+	dropCollidingTagMaps(db.TagMap, migrated, changes)
+
+	model.UpdateIDs(db.Bookmark, "LocationID", changes)
+	model.UpdateIDs(db.Bookmark, "PublicationLocationID", changes)
+	model.UpdateIDs(db.InputField, "LocationID", changes)
+	model.UpdateIDs(db.Note, "LocationID", changes)
+	model.UpdateIDs(db.TagMap, "LocationID", changes)
+	model.UpdateIDs(db.UserMark, "LocationID", changes)
+
+	return len(migrated)
+}
+
+// dropCollidingBookmarks removes Bookmarks of migrated Locations that would occupy the
+// same slot as a Bookmark of a non-migrated Location once the IDs are changed.
+func dropCollidingBookmarks(bookmarks []*model.Bookmark, migrated map[int]bool, changes map[int]int) {
+	type key struct{ pubLocationID, slot int }
+	existing := map[key]bool{}
+	for _, bm := range bookmarks {
+		if bm == nil || migrated[bm.PublicationLocationID] {
+			continue
+		}
+		existing[key{changedID(bm.PublicationLocationID, changes), bm.Slot}] = true
+	}
+	for i, bm := range bookmarks {
+		if bm == nil || !migrated[bm.PublicationLocationID] {
+			continue
+		}
+		k := key{changedID(bm.PublicationLocationID, changes), bm.Slot}
+		if existing[k] {
+			bookmarks[i] = nil
+			continue
+		}
+		existing[k] = true
+	}
+}
+
+// dropCollidingInputFields removes InputFields of migrated Locations that would
+// collide with an InputField of a non-migrated Location once the IDs are changed.
+func dropCollidingInputFields(inputFields []*model.InputField, migrated map[int]bool, changes map[int]int) {
+	type key struct {
+		locationID int
+		textTag    string
+	}
+	existing := map[key]bool{}
+	for _, inf := range inputFields {
+		if inf == nil || migrated[inf.LocationID] {
+			continue
+		}
+		existing[key{changedID(inf.LocationID, changes), inf.TextTag}] = true
+	}
+	for i, inf := range inputFields {
+		if inf == nil || !migrated[inf.LocationID] {
+			continue
+		}
+		k := key{changedID(inf.LocationID, changes), inf.TextTag}
+		if existing[k] {
+			inputFields[i] = nil
+			continue
+		}
+		existing[k] = true
+	}
+}
+
+// dropCollidingTagMaps removes TagMaps of migrated Locations that would tag the
+// same Location twice with the same Tag once the IDs are changed.
+func dropCollidingTagMaps(tagMaps []*model.TagMap, migrated map[int]bool, changes map[int]int) {
+	type key struct{ locationID, tagID int }
+	existing := map[key]bool{}
+	for _, tm := range tagMaps {
+		if tm == nil || !tm.LocationID.Valid || migrated[int(tm.LocationID.Int32)] {
+			continue
+		}
+		existing[key{changedID(int(tm.LocationID.Int32), changes), tm.TagID}] = true
+	}
+	for i, tm := range tagMaps {
+		if tm == nil || !tm.LocationID.Valid || !migrated[int(tm.LocationID.Int32)] {
+			continue
+		}
+		k := key{changedID(int(tm.LocationID.Int32), changes), tm.TagID}
+		if existing[k] {
+			tagMaps[i] = nil
+			continue
+		}
+		existing[k] = true
+	}
+}
+
+// changedID returns the new ID according to changes or the given one if it didn't change.
+func changedID(id int, changes map[int]int) int {
+	if newID, ok := changes[id]; ok {
+		return newID
+	}
+	return id
+}
+
+// needsEditionMigration checks if one of the sides has been migrated to a new
+// edition of a publication, while the other one hasn't yet. If so, the side with
+// the old edition has to be migrated too, so duplicate markings can still be
+// detected later.
+//
+// Side note: JW Library does these migrations by changing the KeySymbol of the
+// Locations (e.g. from `nwt` to `nwtsty`), without changing the UserMarks
+// themselfs, so their UserMarkGUID stays the same. If two backups with the same
+// markings, but with one of them not migrated yet, are merged, the markings
+// can't be detected as duplicate or overlapping: They techically belong to
+// different locations, though their UserMarkGUID is the same, which, when
+// exporting, results in a unique constraint violation.
+func needsEditionMigration(left *model.Database, right *model.Database, migrations []EditionMigration) map[pendingMigration]MergeSide {
+	// For the conflicting markings, check if one is still in the old edition, while
+	// the other one has been migrated to the new one. If that is the case, we can
+	// simply mark one side to be due for migration
+	leftUMGUIDs := make(map[string]*model.UserMark, len(left.UserMark))
+	for _, um := range left.UserMark {
+		if um == nil {
+			continue
+		}
+		leftUMGUIDs[um.UserMarkGUID] = um
+	}
+
+	result := map[pendingMigration]MergeSide{}
+	for _, rightUM := range right.UserMark {
+		if rightUM == nil {
+			continue
+		}
+
+		leftUM, ok := leftUMGUIDs[rightUM.UserMarkGUID]
+		if !ok {
+			continue
+		}
+
+		leftLocation, ok := left.FetchFromTable("Location", leftUM.LocationID).(*model.Location)
+		if !ok {
+			continue
+		}
+		rightLocation, ok := right.FetchFromTable("Location", rightUM.LocationID).(*model.Location)
+		if !ok {
+			continue
+		}
+
+		for i, em := range migrations {
+			if leftLocation.KeySymbol.String == em.From && rightLocation.KeySymbol.String == em.To {
+				result[pendingMigration{i, int(leftLocation.MepsLanguage.Int32)}] = LeftSide
+				break
+			}
+			if leftLocation.KeySymbol.String == em.To && rightLocation.KeySymbol.String == em.From {
+				result[pendingMigration{i, int(rightLocation.MepsLanguage.Int32)}] = RightSide
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// migrateEditions migrates the locations of the sides and languages mentioned in
+// pending using the corresponding EditionMigration of migrations.
+// This may be needed if both backups were started in an old edition, but only one
+// side has been migrated to the new edition later.
+func migrateEditions(pending map[pendingMigration]MergeSide, migrations []EditionMigration, left []*model.Location, right []*model.Location) {
+	if len(pending) == 0 {
+		return
+	}
+
+	for _, side := range []MergeSide{LeftSide, RightSide} {
+		var locations []*model.Location
+		if side == LeftSide {
+			locations = left
+		} else {
+			locations = right
+		}
+
+		for _, location := range locations {
+			if location == nil {
+				continue
+			}
+			for i, em := range migrations {
+				pendingSide, exists := pending[pendingMigration{i, int(location.MepsLanguage.Int32)}]
+				if !exists || pendingSide != side || !em.appliesTo(location) {
+					continue
+				}
+				em.migrate(location)
+				break
+			}
+		}
+	}
+}
+
+// isEditionMigration checks if the Locations old and new are the same
+// Location before and after one of the given migrations.
+func isEditionMigration(old *model.Location, new *model.Location, migrations []EditionMigration) bool {
+	for _, em := range migrations {
+		if old.KeySymbol.String == em.From && new.KeySymbol.String == em.To {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,877 @@
+package merger
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_needsEditionMigration(t *testing.T) {
+	type args struct {
+		left  *model.Database
+		right *model.Database
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[pendingMigration]MergeSide
+	}{
+		{
+			name: "Nothing to migrate",
+			args: args{
+				left: &model.Database{
+					Location: []*model.Location{
+						nil,
+						{
+							LocationID:   1,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						{
+							LocationID:   2,
+							KeySymbol:    sql.NullString{"somethingElse", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						nil,
+						{
+							LocationID:   4,
+							KeySymbol:    sql.NullString{"bla", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						{
+							LocationID:   5,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						nil,
+						nil,
+					},
+					UserMark: []*model.UserMark{
+						nil,
+						{
+							UserMarkID:   1,
+							LocationID:   1,
+							UserMarkGUID: "1",
+						},
+						nil,
+						nil,
+						{
+							UserMarkID:   4,
+							LocationID:   1,
+							UserMarkGUID: "4",
+						},
+						{
+							UserMarkID:   5,
+							LocationID:   4,
+							UserMarkGUID: "5",
+						},
+					},
+				},
+				right: &model.Database{
+					Location: []*model.Location{
+						nil,
+						{
+							LocationID:   1,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						{
+							LocationID:   2,
+							KeySymbol:    sql.NullString{"somethingElse", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						nil,
+						{
+							LocationID:   4,
+							KeySymbol:    sql.NullString{"bla", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						{
+							LocationID:   5,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   4,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						nil,
+					},
+					UserMark: []*model.UserMark{
+						nil,
+						{
+							UserMarkID:   1,
+							LocationID:   1,
+							UserMarkGUID: "1",
+						},
+						nil,
+						nil,
+						{
+							UserMarkID:   4,
+							LocationID:   1,
+							UserMarkGUID: "4",
+						},
+						{
+							UserMarkID:   5,
+							LocationID:   4,
+							UserMarkGUID: "5",
+						},
+						{
+							UserMarkID:   6,
+							LocationID:   4,
+							UserMarkGUID: "6",
+						},
+					},
+				},
+			},
+			want: map[pendingMigration]MergeSide{},
+		},
+		{
+			name: "Partially migrate",
+			args: args{
+				left: &model.Database{
+					Location: []*model.Location{
+						nil,
+						{
+							LocationID:   1,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						{
+							LocationID:   2,
+							KeySymbol:    sql.NullString{"somethingElse", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						nil,
+						{
+							LocationID:   4,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 3, Valid: true},
+						},
+						{
+							LocationID:   5,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						nil,
+						nil,
+					},
+					UserMark: []*model.UserMark{
+						nil,
+						{
+							UserMarkID:   1,
+							LocationID:   1,
+							UserMarkGUID: "1",
+						},
+						nil,
+						nil,
+						{
+							UserMarkID:   4,
+							LocationID:   1,
+							UserMarkGUID: "4",
+						},
+						{
+							UserMarkID:   5,
+							LocationID:   4,
+							UserMarkGUID: "5",
+						},
+						{
+							UserMarkID:   6,
+							LocationID:   4,
+							UserMarkGUID: "6",
+						},
+					},
+				},
+				right: &model.Database{
+					Location: []*model.Location{
+						nil,
+						{
+							LocationID:   1,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						{
+							LocationID:   2,
+							KeySymbol:    sql.NullString{"somethingElse", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						nil,
+						{
+							LocationID:   4,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 3, Valid: true},
+						},
+						{
+							LocationID:   5,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   4,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						nil,
+					},
+					UserMark: []*model.UserMark{
+						nil,
+						{
+							UserMarkID:   1,
+							LocationID:   1,
+							UserMarkGUID: "1",
+						},
+						nil,
+						nil,
+						{
+							UserMarkID:   4,
+							LocationID:   1,
+							UserMarkGUID: "4",
+						},
+						{
+							UserMarkID:   5,
+							LocationID:   4,
+							UserMarkGUID: "5",
+						},
+					},
+				},
+			},
+			want: map[pendingMigration]MergeSide{
+				{0, 1}: LeftSide,
+				{0, 3}: RightSide,
+			},
+		},
+		{
+			name: "Migrate",
+			args: args{
+				left: &model.Database{
+					Location: []*model.Location{
+						nil,
+						{
+							LocationID:   1,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						{
+							LocationID:   2,
+							KeySymbol:    sql.NullString{"somethingElse", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						{
+							LocationID:   3,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   4,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   5,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 3, Valid: true},
+						},
+						nil,
+						nil,
+					},
+					UserMark: []*model.UserMark{
+						nil,
+						{
+							UserMarkID:   1,
+							LocationID:   1,
+							UserMarkGUID: "1",
+						},
+						nil,
+						nil,
+						{
+							UserMarkID:   4,
+							LocationID:   3,
+							UserMarkGUID: "4",
+						},
+						{
+							UserMarkID:   5,
+							LocationID:   5,
+							UserMarkGUID: "5",
+						},
+					},
+				},
+				right: &model.Database{
+					Location: []*model.Location{
+						nil,
+						{
+							LocationID:   1,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						{
+							LocationID:   2,
+							KeySymbol:    sql.NullString{"somethingElse", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						{
+							LocationID:   3,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   4,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   5,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 3, Valid: true},
+						},
+						nil,
+						nil,
+					},
+					UserMark: []*model.UserMark{
+						nil,
+						{
+							UserMarkID:   1,
+							LocationID:   1,
+							UserMarkGUID: "1",
+						},
+						nil,
+						nil,
+						{
+							UserMarkID:   4,
+							LocationID:   3,
+							UserMarkGUID: "4",
+						},
+						{
+							UserMarkID:   5,
+							LocationID:   5,
+							UserMarkGUID: "5",
+						},
+					},
+				},
+			},
+			want: map[pendingMigration]MergeSide{
+				{0, 1}: LeftSide,
+				{0, 2}: LeftSide,
+				{0, 3}: LeftSide,
+			},
+		},
+		{
+			name: "All right",
+			args: args{
+				left: &model.Database{
+					Location: []*model.Location{
+						nil,
+						{
+							LocationID:   1,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   2,
+							KeySymbol:    sql.NullString{"somethingElse", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						{
+							LocationID:   3,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   4,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   5,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						nil,
+						nil,
+					},
+					UserMark: []*model.UserMark{
+						nil,
+						{
+							UserMarkID:   1,
+							LocationID:   1,
+							UserMarkGUID: "1",
+						},
+						nil,
+						nil,
+						{
+							UserMarkID:   4,
+							LocationID:   3,
+							UserMarkGUID: "4",
+						},
+						{
+							UserMarkID:   5,
+							LocationID:   5,
+							UserMarkGUID: "5",
+						},
+					},
+				},
+				right: &model.Database{
+					Location: []*model.Location{
+						nil,
+						{
+							LocationID:   1,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   2,
+							KeySymbol:    sql.NullString{"somethingElse", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						{
+							LocationID:   3,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   4,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   5,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						nil,
+						nil,
+					},
+					UserMark: []*model.UserMark{
+						nil,
+						{
+							UserMarkID:   1,
+							LocationID:   1,
+							UserMarkGUID: "1",
+						},
+						nil,
+						nil,
+						{
+							UserMarkID:   4,
+							LocationID:   3,
+							UserMarkGUID: "4",
+						},
+						{
+							UserMarkID:   5,
+							LocationID:   5,
+							UserMarkGUID: "5",
+						},
+					},
+				},
+			},
+			want: map[pendingMigration]MergeSide{
+				{0, 2}: RightSide,
+			},
+		},
+		{
+			name: "All left",
+			args: args{
+				left: &model.Database{
+					Location: []*model.Location{
+						nil,
+						{
+							LocationID:   1,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   2,
+							KeySymbol:    sql.NullString{"somethingElse", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						{
+							LocationID:   3,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   4,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   5,
+							KeySymbol:    sql.NullString{"nwt", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						nil,
+						nil,
+					},
+					UserMark: []*model.UserMark{
+						nil,
+						{
+							UserMarkID:   1,
+							LocationID:   1,
+							UserMarkGUID: "1",
+						},
+						nil,
+						nil,
+						{
+							UserMarkID:   4,
+							LocationID:   3,
+							UserMarkGUID: "4",
+						},
+						{
+							UserMarkID:   5,
+							LocationID:   5,
+							UserMarkGUID: "5",
+						},
+					},
+				},
+				right: &model.Database{
+					Location: []*model.Location{
+						nil,
+						{
+							LocationID:   1,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   2,
+							KeySymbol:    sql.NullString{"somethingElse", true},
+							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+						},
+						{
+							LocationID:   3,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   4,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						{
+							LocationID:   5,
+							KeySymbol:    sql.NullString{"nwtsty", true},
+							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+						},
+						nil,
+						nil,
+					},
+					UserMark: []*model.UserMark{
+						nil,
+						{
+							UserMarkID:   1,
+							LocationID:   1,
+							UserMarkGUID: "1",
+						},
+						nil,
+						nil,
+						{
+							UserMarkID:   4,
+							LocationID:   3,
+							UserMarkGUID: "4",
+						},
+						{
+							UserMarkID:   5,
+							LocationID:   5,
+							UserMarkGUID: "5",
+						},
+					},
+				},
+			},
+			want: map[pendingMigration]MergeSide{
+				{0, 2}: LeftSide,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, needsEditionMigration(tt.args.left, tt.args.right, EditionMigrations))
+		})
+	}
+}
+
+func Test_migrateEditions(t *testing.T) {
+	type args struct {
+		pending map[pendingMigration]MergeSide
+		left    []*model.Location
+		right   []*model.Location
+	}
+	tests := []struct {
+		name string
+		args args
+		want args
+	}{
+		{
+			args: args{
+				pending: map[pendingMigration]MergeSide{
+					{0, 0}: LeftSide,
+					{0, 1}: RightSide,
+				},
+				left: []*model.Location{
+					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+				},
+				right: []*model.Location{
+					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+				},
+			},
+			want: args{
+				left: []*model.Location{
+					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+				},
+				right: []*model.Location{
+					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+				},
+			},
+		},
+		{
+			name: "Skip locations with DocID",
+			args: args{
+				pending: map[pendingMigration]MergeSide{
+					{0, 1}: RightSide,
+				},
+				left: []*model.Location{
+					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{DocumentID: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{Track: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+				},
+				right: []*model.Location{
+					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{DocumentID: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{Track: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+				},
+			},
+			want: args{
+				left: []*model.Location{
+					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{DocumentID: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{Track: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+				},
+				right: []*model.Location{
+					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+					{DocumentID: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+					{Track: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		migrateEditions(tt.args.pending, EditionMigrations, tt.args.left, tt.args.right)
+		assert.Equal(t, tt.want.left, tt.args.left, tt.args.left)
+		assert.Equal(t, tt.want.right, tt.args.right, tt.args.right)
+	}
+}
+
+func TestEditionMigration_appliesTo(t *testing.T) {
+	em := EditionMigration{
+		From:          "old",
+		To:            "new",
+		MepsLanguages: []int{0, 2},
+		DocumentIDs:   map[int]int{100: 200},
+	}
+
+	tests := []struct {
+		name     string
+		location *model.Location
+		want     bool
+	}{
+		{
+			name:     "nil",
+			location: nil,
+			want:     false,
+		},
+		{
+			name: "Simple location",
+			location: &model.Location{
+				BookNumber:   sql.NullInt32{Int32: 1, Valid: true},
+				KeySymbol:    sql.NullString{String: "old", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+			},
+			want: true,
+		},
+		{
+			name: "Other KeySymbol",
+			location: &model.Location{
+				KeySymbol:    sql.NullString{String: "new", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+			},
+			want: false,
+		},
+		{
+			name: "Other language",
+			location: &model.Location{
+				KeySymbol:    sql.NullString{String: "old", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+			},
+			want: false,
+		},
+		{
+			name: "Known DocumentID",
+			location: &model.Location{
+				DocumentID:   sql.NullInt32{Int32: 100, Valid: true},
+				KeySymbol:    sql.NullString{String: "old", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+			},
+			want: true,
+		},
+		{
+			name: "Unknown DocumentID",
+			location: &model.Location{
+				DocumentID:   sql.NullInt32{Int32: 101, Valid: true},
+				KeySymbol:    sql.NullString{String: "old", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+			},
+			want: false,
+		},
+		{
+			name: "Track",
+			location: &model.Location{
+				Track:        sql.NullInt32{Int32: 1, Valid: true},
+				KeySymbol:    sql.NullString{String: "old", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, em.appliesTo(tt.location))
+		})
+	}
+
+	location := &model.Location{
+		DocumentID:   sql.NullInt32{Int32: 100, Valid: true},
+		KeySymbol:    sql.NullString{String: "old", Valid: true},
+		MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+	}
+	em.migrate(location)
+	assert.Equal(t, &model.Location{
+		DocumentID:   sql.NullInt32{Int32: 200, Valid: true},
+		KeySymbol:    sql.NullString{String: "new", Valid: true},
+		MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+	}, location)
+}
+
+func TestMigrateEditions(t *testing.T) {
+	db := &model.Database{
+		Bookmark: []*model.Bookmark{
+			nil,
+			{BookmarkID: 1, LocationID: 1, PublicationLocationID: 3, Slot: 0, Title: "Old 0"},
+			{BookmarkID: 2, LocationID: 1, PublicationLocationID: 3, Slot: 1, Title: "Old 1"},
+			{BookmarkID: 3, LocationID: 2, PublicationLocationID: 4, Slot: 0, Title: "New 0"},
+		},
+		InputField: []*model.InputField{
+			nil,
+			{LocationID: 5, TextTag: "tt1", Value: "Old"},
+		},
+		Location: []*model.Location{
+			nil,
+			{
+				LocationID:    1,
+				BookNumber:    sql.NullInt32{Int32: 1, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 1, Valid: true},
+				KeySymbol:     sql.NullString{String: "nwt", Valid: true},
+				MepsLanguage:  sql.NullInt32{Int32: 2, Valid: true},
+			},
+			{
+				LocationID:    2,
+				BookNumber:    sql.NullInt32{Int32: 1, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 1, Valid: true},
+				KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage:  sql.NullInt32{Int32: 2, Valid: true},
+				Title:         sql.NullString{String: "1. Mose 1", Valid: true},
+			},
+			{
+				LocationID:   3,
+				KeySymbol:    sql.NullString{String: "nwt", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+				LocationType: 1,
+			},
+			{
+				LocationID:   4,
+				KeySymbol:    sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+				LocationType: 1,
+			},
+			{
+				LocationID:   5,
+				DocumentID:   sql.NullInt32{Int32: 1, Valid: true},
+				KeySymbol:    sql.NullString{String: "nwt", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+			},
+		},
+		UserMark: []*model.UserMark{
+			nil,
+			{UserMarkID: 1, LocationID: 1, UserMarkGUID: "1"},
+			{UserMarkID: 2, LocationID: 2, UserMarkGUID: "2"},
+		},
+	}
+
+	assert.Equal(t, 0, MigrateEditions(db, []EditionMigration{{From: "other", To: "another"}}))
+	assert.Equal(t, 2, MigrateEditions(db, EditionMigrations))
+
+	expected := &model.Database{
+		Bookmark: []*model.Bookmark{
+			nil,
+			nil,
+			{BookmarkID: 2, LocationID: 2, PublicationLocationID: 3, Slot: 1, Title: "Old 1"},
+			{BookmarkID: 3, LocationID: 2, PublicationLocationID: 3, Slot: 0, Title: "New 0"},
+		},
+		InputField: []*model.InputField{
+			nil,
+			{LocationID: 4, TextTag: "tt1", Value: "Old"},
+		},
+		Location: []*model.Location{
+			nil,
+			nil,
+			{
+				LocationID:    2,
+				BookNumber:    sql.NullInt32{Int32: 1, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 1, Valid: true},
+				KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage:  sql.NullInt32{Int32: 2, Valid: true},
+				Title:         sql.NullString{String: "1. Mose 1", Valid: true},
+			},
+			{
+				LocationID:   3,
+				KeySymbol:    sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+				LocationType: 1,
+			},
+			{
+				LocationID:   4,
+				DocumentID:   sql.NullInt32{Int32: 1, Valid: true},
+				KeySymbol:    sql.NullString{String: "nwt", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+			},
+		},
+		UserMark: []*model.UserMark{
+			nil,
+			{UserMarkID: 1, LocationID: 2, UserMarkGUID: "1"},
+			{UserMarkID: 2, LocationID: 2, UserMarkGUID: "2"},
+		},
+	}
+	assert.True(t, expected.Equals(db))
+}
@@ -0,0 +1,12 @@
+package merger
+
+import "time"
+
+// MergeLastModified merges the LastModified of the left and right Database
+// by choosing the newest one.
+func MergeLastModified(left time.Time, right time.Time) time.Time {
+	if right.After(left) {
+		return right
+	}
+	return left
+}
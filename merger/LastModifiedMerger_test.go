@@ -0,0 +1,19 @@
+package merger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeLastModified(t *testing.T) {
+	older := time.Date(2023, 7, 15, 10, 54, 7, 0, time.UTC)
+	newer := time.Date(2023, 7, 16, 11, 31, 38, 0, time.UTC)
+
+	assert.Equal(t, newer, MergeLastModified(older, newer))
+	assert.Equal(t, newer, MergeLastModified(newer, older))
+	assert.Equal(t, older, MergeLastModified(older, time.Time{}))
+	assert.Equal(t, older, MergeLastModified(time.Time{}, older))
+	assert.True(t, MergeLastModified(time.Time{}, time.Time{}).IsZero())
+}
@@ -1,6 +1,7 @@
 package merger
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/AndreasSko/go-jwlm/model"
@@ -10,7 +11,13 @@ import (
 // the merged locations together with a IDChanges struct indicating
 // if the ID of a location has changed.
 func MergeLocations(left []*model.Location, right []*model.Location) ([]*model.Location, IDChanges, error) {
-	result, changes, err := tryMergeWithConflictSolver(left, right, nil, solveLocationMergeConflict)
+	return MergeLocationsContext(context.Background(), left, right)
+}
+
+// MergeLocationsContext is like MergeLocations, but stops and returns the
+// error of ctx if it is canceled.
+func MergeLocationsContext(ctx context.Context, left []*model.Location, right []*model.Location) ([]*model.Location, IDChanges, error) {
+	result, changes, err := tryMergeWithConflictSolver(ctx, left, right, nil, solveLocationMergeConflict)
 
 	return model.Location{}.MakeSlice(result), changes, err
 }
@@ -0,0 +1,172 @@
+package merger
+
+import (
+	"context"
+
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/pkg/errors"
+)
+
+// MergeStep is a single step of merging a left and a right Database into a
+// merged one, like merging their Bookmarks. Besides merging the entries of
+// its tables, a step updates the IDs referencing them in the tables of both
+// sides, so the steps have to be run in the order of MergeSteps.
+type MergeStep struct {
+	// Name of the step (like "bookmarks"), which is also used
+	// to configure the resolver of its conflicts.
+	Name  string
+	merge func(ctx context.Context, left *model.Database, right *model.Database, merged *model.Database, solutions map[string]MergeSolution) error
+}
+
+// MergeSteps are all steps needed for merging two Databases in the order
+// they have to be run. The CLI, the server, and the gomobile package
+// all drive them, so a table or reference only has to be added here.
+var MergeSteps = []MergeStep{
+	{
+		Name: "locations",
+		merge: func(ctx context.Context, left, right, merged *model.Database, _ map[string]MergeSolution) error {
+			mergedLocations, changes, err := MergeLocationsContext(ctx, left.Location, right.Location)
+			if err != nil {
+				return err
+			}
+			merged.Location = mergedLocations
+			UpdateLRIDs(left.Bookmark, right.Bookmark, "LocationID", changes)
+			UpdateLRIDs(left.Bookmark, right.Bookmark, "PublicationLocationID", changes)
+			UpdateLRIDs(left.InputField, right.InputField, "LocationID", changes)
+			UpdateLRIDs(left.Note, right.Note, "LocationID", changes)
+			UpdateLRIDs(left.TagMap, right.TagMap, "LocationID", changes)
+			UpdateLRIDs(left.UserMark, right.UserMark, "LocationID", changes)
+			return nil
+		},
+	},
+	{
+		Name: "bookmarks",
+		merge: func(ctx context.Context, left, right, merged *model.Database, solutions map[string]MergeSolution) error {
+			mergedBookmarks, _, err := MergeBookmarksContext(ctx, left.Bookmark, right.Bookmark, solutions)
+			if err != nil {
+				return err
+			}
+			merged.Bookmark = mergedBookmarks
+			return nil
+		},
+	},
+	{
+		Name: "inputFields",
+		merge: func(ctx context.Context, left, right, merged *model.Database, solutions map[string]MergeSolution) error {
+			mergedInputFields, _, err := MergeInputFieldsContext(ctx, left.InputField, right.InputField, solutions)
+			if err != nil {
+				return err
+			}
+			merged.InputField = mergedInputFields
+			return nil
+		},
+	},
+	{
+		Name: "tags",
+		merge: func(ctx context.Context, left, right, merged *model.Database, solutions map[string]MergeSolution) error {
+			mergedTags, changes, err := MergeTagsContext(ctx, left.Tag, right.Tag, solutions)
+			if err != nil {
+				return err
+			}
+			merged.Tag = mergedTags
+			UpdateLRIDs(left.TagMap, right.TagMap, "TagID", changes)
+			return nil
+		},
+	},
+	{
+		Name: "markings",
+		merge: func(ctx context.Context, left, right, merged *model.Database, solutions map[string]MergeSolution) error {
+			mergedUserMarks, mergedBlockRanges, changes, err := MergeUserMarkAndBlockRangeContext(ctx,
+				left.UserMark, left.BlockRange, right.UserMark, right.BlockRange, solutions)
+			if err != nil {
+				return err
+			}
+			merged.UserMark = mergedUserMarks
+			merged.BlockRange = mergedBlockRanges
+			UpdateLRIDs(left.Note, right.Note, "UserMarkID", changes)
+			return nil
+		},
+	},
+	{
+		Name: "notes",
+		merge: func(ctx context.Context, left, right, merged *model.Database, solutions map[string]MergeSolution) error {
+			mergedNotes, changes, err := MergeNotesContext(ctx, left.Note, right.Note, solutions)
+			if err != nil {
+				return err
+			}
+			merged.Note = mergedNotes
+			UpdateLRIDs(left.TagMap, right.TagMap, "NoteID", changes)
+			return nil
+		},
+	},
+	{
+		Name: "tagMaps",
+		merge: func(ctx context.Context, left, right, merged *model.Database, solutions map[string]MergeSolution) error {
+			mergedTagMaps, _, err := MergeTagMapsContext(ctx, left.TagMap, right.TagMap, solutions)
+			if err != nil {
+				return err
+			}
+			merged.TagMap = mergedTagMaps
+			return nil
+		},
+	},
+}
+
+// LookupMergeStep returns the MergeStep with the given name.
+func LookupMergeStep(name string) (MergeStep, bool) {
+	for _, step := range MergeSteps {
+		if step.Name == name {
+			return step, true
+		}
+	}
+	return MergeStep{}, false
+}
+
+// PrepareMerge returns copies of left and right that are prepared for being
+// merged by the MergeSteps (see PrepareDatabasesPreMerge) together with the
+// empty Database they are merged into. The given Databases are not changed.
+func PrepareMerge(left *model.Database, right *model.Database) (*model.Database, *model.Database, *model.Database) {
+	leftTmp := model.MakeDatabaseCopy(left)
+	rightTmp := model.MakeDatabaseCopy(right)
+	merged := &model.Database{
+		// Use the newer schema of both backups, so no information gets lost
+		SchemaVersion: max(left.SchemaVersion, right.SchemaVersion),
+		LastModified:  MergeLastModified(left.LastModified, right.LastModified),
+	}
+	PrepareDatabasesPreMerge(leftTmp, rightTmp)
+
+	return leftTmp, rightTmp, merged
+}
+
+// Run merges the tables of the step from left and right into merged. Conflicts
+// are solved using solutions and - if resolver (like chooseLeft) is not
+// empty - automatically. If there are conflicts left, a MergeConflictError
+// is returned, so the caller can add solutions for them and run the step
+// again. If ctx is canceled, its error is returned.
+func (step MergeStep) Run(ctx context.Context, left *model.Database, right *model.Database, merged *model.Database, solutions map[string]MergeSolution, resolver string) error {
+	if solutions == nil {
+		solutions = map[string]MergeSolution{}
+	}
+
+	for {
+		err := step.merge(ctx, left, right, merged, solutions)
+		if err == nil {
+			return nil
+		}
+
+		mcErr, ok := err.(MergeConflictError)
+		if !ok {
+			return errors.Wrapf(err, "Could not merge %s", step.Name)
+		}
+		if resolver == "" {
+			return mcErr
+		}
+		autoSolutions, err := AutoResolveConflicts(mcErr.Conflicts, resolver)
+		if err != nil {
+			return errors.Wrapf(err, "Could not automatically solve conflicts for %s", step.Name)
+		}
+		for key, solution := range autoSolutions {
+			solutions[key] = solution
+		}
+	}
+}
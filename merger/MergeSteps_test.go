@@ -0,0 +1,43 @@
+package merger
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeStep_Run(t *testing.T) {
+	left := &model.Database{
+		Location: []*model.Location{nil, {LocationID: 1, KeySymbol: sql.NullString{String: "nwtsty", Valid: true}}},
+		Bookmark: []*model.Bookmark{nil, {BookmarkID: 1, LocationID: 1, PublicationLocationID: 1, Slot: 1, Title: "Left"}},
+	}
+	right := &model.Database{
+		Location: []*model.Location{nil, {LocationID: 1, KeySymbol: sql.NullString{String: "nwtsty", Valid: true}}},
+		Bookmark: []*model.Bookmark{nil, {BookmarkID: 1, LocationID: 1, PublicationLocationID: 1, Slot: 1, Title: "Right"}},
+	}
+	leftTmp, rightTmp, merged := PrepareMerge(left, right)
+
+	locations, ok := LookupMergeStep("locations")
+	assert.True(t, ok)
+	assert.NoError(t, locations.Run(context.Background(), leftTmp, rightTmp, merged, nil, ""))
+	assert.Len(t, merged.Location, 2)
+
+	bookmarks, ok := LookupMergeStep("bookmarks")
+	assert.True(t, ok)
+	err := bookmarks.Run(context.Background(), leftTmp, rightTmp, merged, nil, "")
+	assert.IsType(t, MergeConflictError{}, err)
+	assert.Len(t, err.(MergeConflictError).Conflicts, 1)
+
+	assert.NoError(t, bookmarks.Run(context.Background(), leftTmp, rightTmp, merged, nil, "chooseRight"))
+	assert.Equal(t, "Right", merged.Bookmark[1].Title)
+
+	// The given Databases are left untouched
+	assert.Equal(t, "Left", left.Bookmark[1].Title)
+	assert.Equal(t, 1, left.Bookmark[1].LocationID)
+
+	_, ok = LookupMergeStep("playlists")
+	assert.False(t, ok)
+}
@@ -1,6 +1,7 @@
 package merger
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"sort"
@@ -44,9 +45,23 @@ func (e MergeConflictError) Error() string {
 	return fmt.Sprintf("There were conflicts while trying to merge: %s", e.Conflicts)
 }
 
+// cancelCheckInterval is the number of entries after which a merge
+// checks if its context has been canceled.
+const cancelCheckInterval = 1000
+
+// canceled returns the error of ctx if it is done. To keep the overhead
+// low, ctx is only checked for every cancelCheckInterval-th entry i.
+func canceled(ctx context.Context, i int) error {
+	if i%cancelCheckInterval != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
+
 // merge merges a left and a right slice of structs implementing the Model interface.
 // If there is a collision in the process, it returns an error asking for specification how it should handle it.
-func merge(left interface{}, right interface{}, conflictSolution map[string]MergeSolution) (map[string]MergeSolution, error) {
+// If ctx is canceled while merging, its error is returned.
+func merge(ctx context.Context, left interface{}, right interface{}, conflictSolution map[string]MergeSolution) (map[string]MergeSolution, error) {
 	maxLen := 0
 	if reflect.ValueOf(left).Len() > reflect.ValueOf(right).Len() {
 		maxLen = reflect.ValueOf(left).Len()
@@ -62,6 +77,9 @@ func merge(left interface{}, right interface{}, conflictSolution map[string]Merg
 	case reflect.Slice:
 		s := reflect.ValueOf(left)
 		for i := 0; i < s.Len(); i++ {
+			if err := canceled(ctx, i); err != nil {
+				return nil, err
+			}
 			// Make sure we don't have a nil-pointer
 			if s.Index(i).IsNil() {
 				continue
@@ -77,6 +95,9 @@ func merge(left interface{}, right interface{}, conflictSolution map[string]Merg
 	case reflect.Slice:
 		s := reflect.ValueOf(right)
 		for i := 0; i < s.Len(); i++ {
+			if err := canceled(ctx, i); err != nil {
+				return nil, err
+			}
 			// Make sure we don't have a nil-pointer
 			if s.Index(i).IsNil() {
 				continue
@@ -112,7 +133,7 @@ func merge(left interface{}, right interface{}, conflictSolution map[string]Merg
 // slice of structs implementing the Model interface. It tries to solve possible
 // conflicts using the given mergeConflictSolver and will return a mergeConflictError
 // if it wasn't able to solve all conflicts on its own.
-func tryMergeWithConflictSolver(left interface{}, right interface{}, conflictSolution map[string]MergeSolution, conflictSolver MergeConflictSolver) ([]model.Model, IDChanges, error) {
+func tryMergeWithConflictSolver(ctx context.Context, left interface{}, right interface{}, conflictSolution map[string]MergeSolution, conflictSolver MergeConflictSolver) ([]model.Model, IDChanges, error) {
 	var solutionMap map[string]MergeSolution
 	var err error
 
@@ -125,7 +146,7 @@ func tryMergeWithConflictSolver(left interface{}, right interface{}, conflictSol
 	prevConflicts := 0
 Loop:
 	for {
-		solutionMap, err = merge(left, right, conflictSolution)
+		solutionMap, err = merge(ctx, left, right, conflictSolution)
 		if err == nil {
 			break
 		}
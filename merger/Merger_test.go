@@ -1,6 +1,7 @@
 package merger
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 
@@ -139,3 +140,36 @@ func Test_sortMergeSolution(t *testing.T) {
 
 	assert.Equal(t, expectedResult, solution)
 }
+
+func TestMergeContext_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	bookmarks := []*model.Bookmark{nil, {BookmarkID: 1, LocationID: 1, Title: "A"}}
+	locations := []*model.Location{nil, {LocationID: 1, KeySymbol: sql.NullString{String: "nwtsty", Valid: true}}}
+	tagMaps := []*model.TagMap{nil, {TagMapID: 1, TagID: 1, NoteID: sql.NullInt32{Int32: 1, Valid: true}}}
+	userMarks := []*model.UserMark{nil, {UserMarkID: 1, LocationID: 1, UserMarkGUID: "GUID"}}
+	blockRanges := []*model.BlockRange{nil, {BlockRangeID: 1, UserMarkID: 1}}
+
+	_, _, err := MergeLocationsContext(ctx, locations, locations)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, _, err = MergeBookmarksContext(ctx, bookmarks, bookmarks, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, _, err = MergeInputFieldsContext(ctx, []*model.InputField{nil}, []*model.InputField{nil}, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, _, err = MergeTagsContext(ctx, []*model.Tag{nil}, []*model.Tag{nil}, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, _, _, err = MergeUserMarkAndBlockRangeContext(ctx, userMarks, blockRanges, userMarks, blockRanges, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, _, err = MergeNotesContext(ctx, []*model.Note{nil}, []*model.Note{nil}, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, _, err = MergeTagMapsContext(ctx, tagMaps, tagMaps, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Without canceling, the Context variants behave like the original ones
+	merged, _, err := MergeBookmarksContext(context.Background(), bookmarks, bookmarks, nil)
+	assert.NoError(t, err)
+	expected, _, err := MergeBookmarks(bookmarks, bookmarks, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, merged)
+}
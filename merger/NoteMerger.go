@@ -1,11 +1,21 @@
 package merger
 
-import "github.com/AndreasSko/go-jwlm/model"
+import (
+	"context"
+
+	"github.com/AndreasSko/go-jwlm/model"
+)
 
 // MergeNotes tries to merge the left and right slice of Note. If there is a
 // collision, it returns an error asking for specification how it should handle it.
 func MergeNotes(left []*model.Note, right []*model.Note, conflictSolution map[string]MergeSolution) ([]*model.Note, IDChanges, error) {
-	result, changes, err := tryMergeWithConflictSolver(left, right, conflictSolution, solveEqualityMergeConflict)
+	return MergeNotesContext(context.Background(), left, right, conflictSolution)
+}
+
+// MergeNotesContext is like MergeNotes, but stops and returns the error
+// of ctx if it is canceled.
+func MergeNotesContext(ctx context.Context, left []*model.Note, right []*model.Note, conflictSolution map[string]MergeSolution) ([]*model.Note, IDChanges, error) {
+	result, changes, err := tryMergeWithConflictSolver(ctx, left, right, conflictSolution, solveEqualityMergeConflict)
 
 	return model.Note{}.MakeSlice(result), changes, err
 }
@@ -9,8 +9,8 @@ import (
 // PrepareDatabasesPreMerge bundles function calls that are necessary for preparing the
 // databases before merging.
 func PrepareDatabasesPreMerge(left *model.Database, right *model.Database) {
-	neededMigrations := needsNwtstyMigration(left, right)
-	moveToNwtsty(neededMigrations, left.Location, right.Location)
+	pendingMigrations := needsEditionMigration(left, right, EditionMigrations)
+	migrateEditions(pendingMigrations, EditionMigrations, left.Location, right.Location)
 
 	// Remove duplicate locations
 	leftLocations, leftIDChanges := cleanupDuplicateLocations(left.Location)
@@ -41,100 +41,6 @@ func PrepareDatabasesPostMerge(merged *model.Database) error {
 	return nil
 }
 
-// needsNwtstyMigration checks if one of the sides have been migrated from the
-// Standard Bible (nwt) to the Study Edition (nwtsty), while the other one hasn't
-// yet. If so, the side with the Standard Edition has to be migrated too, so
-// duplicate markings can still be detected later.
-//
-// Side note: JW Library did the migration by setting KeySymbols from `nwt` to
-// `nwtsty`, without changing the UserMarks themselfs, so their UserMarkGUID stayed the
-// same. If two backups with the same markings, but with one of them not
-// migrated yet, are merged, the markings can't be detected as duplicate or
-// overlapping: They techically belong to different locations, though their
-// UserMarkGUID is the same, which, when exporting, results in a unique
-// constraint violation.
-func needsNwtstyMigration(left *model.Database, right *model.Database) map[int]MergeSide {
-	// For the conflicting markings, check if one is still in `nwt`, while the other one
-	// has been migrated to `nwtsty`. If that is the case, we can simply mark one side
-	// to be due for migration
-	leftUMGUIDs := make(map[string]*model.UserMark, len(left.UserMark))
-	for _, um := range left.UserMark {
-		if um == nil {
-			continue
-		}
-		leftUMGUIDs[um.UserMarkGUID] = um
-	}
-
-	result := map[int]MergeSide{}
-	for _, rightUM := range right.UserMark {
-		if rightUM == nil {
-			continue
-		}
-
-		leftUM, ok := leftUMGUIDs[rightUM.UserMarkGUID]
-		if !ok {
-			continue
-		}
-
-		leftLocation := left.Location[leftUM.LocationID]
-		rightLocation := right.Location[rightUM.LocationID]
-		if leftLocation.KeySymbol.String == "nwt" && rightLocation.KeySymbol.String == "nwtsty" {
-			result[int(leftLocation.MepsLanguage.Int32)] = LeftSide
-			continue
-		}
-		if leftLocation.KeySymbol.String == "nwtsty" && rightLocation.KeySymbol.String == "nwt" {
-			result[int(rightLocation.MepsLanguage.Int32)] = RightSide
-			continue
-		}
-	}
-
-	return result
-}
-
-// moveToNwtsty moves locations from KeySymbol `nwt` to `nwtsty` if they are
-// mentioned in langs by their MepsLanguage and MergeSide.
-// This may be needed if both backups were started in the
-// Regular Edition, but only one side later migrated to the Study Edition.
-func moveToNwtsty(langs map[int]MergeSide, left []*model.Location, right []*model.Location) {
-	if len(langs) == 0 {
-		return
-	}
-
-	for _, side := range []MergeSide{LeftSide, RightSide} {
-		var locations []*model.Location
-		if side == LeftSide {
-			locations = left
-		} else {
-			locations = right
-		}
-
-		for _, location := range locations {
-			if location == nil {
-				continue
-			}
-			if _, exists := langs[int(location.MepsLanguage.Int32)]; !exists {
-				continue
-			}
-			if side != langs[int(location.MepsLanguage.Int32)] {
-				continue
-			}
-			if location.KeySymbol.String != "nwt" {
-				continue
-			}
-
-			// Only "simple" locations for bible books can be easily migrated.
-			// For locations within a Bible that belong to a specific document,
-			// we would need to find the new DocumentID. In this case, go safe
-			// and skip it
-			if location.DocumentID.Valid || location.Track.Valid {
-				continue
-			}
-
-			location.KeySymbol.String = "nwtsty"
-		}
-	}
-}
-
 // cleanupDuplicateLocations looks for duplicates within one side of locations. If it finds one, it will
 // choose the location that contains a title and updates the location accordingly.
 // As it only checks duplicates for one side, it will directly return IDChanges in form of map[int]int.
@@ -205,15 +111,15 @@ func detectDuplicateUserMarks(userMarks []*model.UserMark) map[string][]*model.U
 }
 
 // tryDuplicateUserMarkCleanup tries to clean up duplicate userMarks. Duplicates should only
-// happen for locations that have been previosuly upgrade from nwt to nwtsty. For other cases
-// it will return an error, indicating that the merge process obviously has failed.
+// happen for locations that have been previosuly migrated to a new edition (see EditionMigrations).
+// For other cases it will return an error, indicating that the merge process obviously has failed.
 func tryDuplicateUserMarkCleanup(db *model.Database, duplicates map[string][]*model.UserMark) error {
 	for _, dupls := range duplicates {
 		if len(dupls) != 2 {
 			return fmt.Errorf("there are more than two 2 userMarks with the same GUID: %v", dupls)
 		}
 
-		// Choose userMark belonging to nwtsty
+		// Choose userMark belonging to the new edition
 		loc1, ok := db.FetchFromTable("Location", dupls[0].LocationID).(*model.Location)
 		if !ok || loc1 == nil {
 			return fmt.Errorf("could not fetch location for duplicate userMark #1")
@@ -222,16 +128,16 @@ func tryDuplicateUserMarkCleanup(db *model.Database, duplicates map[string][]*mo
 		if !ok || loc2 == nil {
 			return fmt.Errorf("could not fetch location for duplicate userMark #2")
 		}
-		if loc1.KeySymbol.String == "nwt" && loc2.KeySymbol.String == "nwtsty" {
+		if isEditionMigration(loc1, loc2, EditionMigrations) {
 			deleteUserMark(db, dupls[0])
 			continue
 		}
-		if loc1.KeySymbol.String == "nwtsty" && loc2.KeySymbol.String == "nwt" {
+		if isEditionMigration(loc2, loc1, EditionMigrations) {
 			deleteUserMark(db, dupls[1])
 			continue
 		}
 
-		return fmt.Errorf("there are two userMarks with the same GUID that were not caused by migrating from nwt to nwtsty")
+		return fmt.Errorf("there are two userMarks with the same GUID that were not caused by migrating to a new edition")
 	}
 
 	return nil
@@ -928,682 +928,6 @@ func TestPrepareDatabasesPostMerge(t *testing.T) {
 	}
 }
 
-func Test_needsNwtstyMigration(t *testing.T) {
-	type args struct {
-		left  *model.Database
-		right *model.Database
-	}
-	tests := []struct {
-		name string
-		args args
-		want map[int]MergeSide
-	}{
-		{
-			name: "Nothing to migrate",
-			args: args{
-				left: &model.Database{
-					Location: []*model.Location{
-						nil,
-						{
-							LocationID:   1,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						{
-							LocationID:   2,
-							KeySymbol:    sql.NullString{"somethingElse", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						nil,
-						{
-							LocationID:   4,
-							KeySymbol:    sql.NullString{"bla", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						{
-							LocationID:   5,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						nil,
-						nil,
-					},
-					UserMark: []*model.UserMark{
-						nil,
-						{
-							UserMarkID:   1,
-							LocationID:   1,
-							UserMarkGUID: "1",
-						},
-						nil,
-						nil,
-						{
-							UserMarkID:   4,
-							LocationID:   1,
-							UserMarkGUID: "4",
-						},
-						{
-							UserMarkID:   5,
-							LocationID:   4,
-							UserMarkGUID: "5",
-						},
-					},
-				},
-				right: &model.Database{
-					Location: []*model.Location{
-						nil,
-						{
-							LocationID:   1,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						{
-							LocationID:   2,
-							KeySymbol:    sql.NullString{"somethingElse", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						nil,
-						{
-							LocationID:   4,
-							KeySymbol:    sql.NullString{"bla", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						{
-							LocationID:   5,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   4,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						nil,
-					},
-					UserMark: []*model.UserMark{
-						nil,
-						{
-							UserMarkID:   1,
-							LocationID:   1,
-							UserMarkGUID: "1",
-						},
-						nil,
-						nil,
-						{
-							UserMarkID:   4,
-							LocationID:   1,
-							UserMarkGUID: "4",
-						},
-						{
-							UserMarkID:   5,
-							LocationID:   4,
-							UserMarkGUID: "5",
-						},
-						{
-							UserMarkID:   6,
-							LocationID:   4,
-							UserMarkGUID: "6",
-						},
-					},
-				},
-			},
-			want: map[int]MergeSide{},
-		},
-		{
-			name: "Partially migrate",
-			args: args{
-				left: &model.Database{
-					Location: []*model.Location{
-						nil,
-						{
-							LocationID:   1,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						{
-							LocationID:   2,
-							KeySymbol:    sql.NullString{"somethingElse", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						nil,
-						{
-							LocationID:   4,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 3, Valid: true},
-						},
-						{
-							LocationID:   5,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						nil,
-						nil,
-					},
-					UserMark: []*model.UserMark{
-						nil,
-						{
-							UserMarkID:   1,
-							LocationID:   1,
-							UserMarkGUID: "1",
-						},
-						nil,
-						nil,
-						{
-							UserMarkID:   4,
-							LocationID:   1,
-							UserMarkGUID: "4",
-						},
-						{
-							UserMarkID:   5,
-							LocationID:   4,
-							UserMarkGUID: "5",
-						},
-						{
-							UserMarkID:   6,
-							LocationID:   4,
-							UserMarkGUID: "6",
-						},
-					},
-				},
-				right: &model.Database{
-					Location: []*model.Location{
-						nil,
-						{
-							LocationID:   1,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						{
-							LocationID:   2,
-							KeySymbol:    sql.NullString{"somethingElse", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						nil,
-						{
-							LocationID:   4,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 3, Valid: true},
-						},
-						{
-							LocationID:   5,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   4,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						nil,
-					},
-					UserMark: []*model.UserMark{
-						nil,
-						{
-							UserMarkID:   1,
-							LocationID:   1,
-							UserMarkGUID: "1",
-						},
-						nil,
-						nil,
-						{
-							UserMarkID:   4,
-							LocationID:   1,
-							UserMarkGUID: "4",
-						},
-						{
-							UserMarkID:   5,
-							LocationID:   4,
-							UserMarkGUID: "5",
-						},
-					},
-				},
-			},
-			want: map[int]MergeSide{
-				1: LeftSide,
-				3: RightSide,
-			},
-		},
-		{
-			name: "Migrate",
-			args: args{
-				left: &model.Database{
-					Location: []*model.Location{
-						nil,
-						{
-							LocationID:   1,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						{
-							LocationID:   2,
-							KeySymbol:    sql.NullString{"somethingElse", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						{
-							LocationID:   3,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   4,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   5,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 3, Valid: true},
-						},
-						nil,
-						nil,
-					},
-					UserMark: []*model.UserMark{
-						nil,
-						{
-							UserMarkID:   1,
-							LocationID:   1,
-							UserMarkGUID: "1",
-						},
-						nil,
-						nil,
-						{
-							UserMarkID:   4,
-							LocationID:   3,
-							UserMarkGUID: "4",
-						},
-						{
-							UserMarkID:   5,
-							LocationID:   5,
-							UserMarkGUID: "5",
-						},
-					},
-				},
-				right: &model.Database{
-					Location: []*model.Location{
-						nil,
-						{
-							LocationID:   1,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						{
-							LocationID:   2,
-							KeySymbol:    sql.NullString{"somethingElse", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						{
-							LocationID:   3,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   4,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   5,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 3, Valid: true},
-						},
-						nil,
-						nil,
-					},
-					UserMark: []*model.UserMark{
-						nil,
-						{
-							UserMarkID:   1,
-							LocationID:   1,
-							UserMarkGUID: "1",
-						},
-						nil,
-						nil,
-						{
-							UserMarkID:   4,
-							LocationID:   3,
-							UserMarkGUID: "4",
-						},
-						{
-							UserMarkID:   5,
-							LocationID:   5,
-							UserMarkGUID: "5",
-						},
-					},
-				},
-			},
-			want: map[int]MergeSide{
-				1: LeftSide,
-				2: LeftSide,
-				3: LeftSide,
-			},
-		},
-		{
-			name: "All right",
-			args: args{
-				left: &model.Database{
-					Location: []*model.Location{
-						nil,
-						{
-							LocationID:   1,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   2,
-							KeySymbol:    sql.NullString{"somethingElse", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						{
-							LocationID:   3,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   4,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   5,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						nil,
-						nil,
-					},
-					UserMark: []*model.UserMark{
-						nil,
-						{
-							UserMarkID:   1,
-							LocationID:   1,
-							UserMarkGUID: "1",
-						},
-						nil,
-						nil,
-						{
-							UserMarkID:   4,
-							LocationID:   3,
-							UserMarkGUID: "4",
-						},
-						{
-							UserMarkID:   5,
-							LocationID:   5,
-							UserMarkGUID: "5",
-						},
-					},
-				},
-				right: &model.Database{
-					Location: []*model.Location{
-						nil,
-						{
-							LocationID:   1,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   2,
-							KeySymbol:    sql.NullString{"somethingElse", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						{
-							LocationID:   3,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   4,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   5,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						nil,
-						nil,
-					},
-					UserMark: []*model.UserMark{
-						nil,
-						{
-							UserMarkID:   1,
-							LocationID:   1,
-							UserMarkGUID: "1",
-						},
-						nil,
-						nil,
-						{
-							UserMarkID:   4,
-							LocationID:   3,
-							UserMarkGUID: "4",
-						},
-						{
-							UserMarkID:   5,
-							LocationID:   5,
-							UserMarkGUID: "5",
-						},
-					},
-				},
-			},
-			want: map[int]MergeSide{
-				2: RightSide,
-			},
-		},
-		{
-			name: "All left",
-			args: args{
-				left: &model.Database{
-					Location: []*model.Location{
-						nil,
-						{
-							LocationID:   1,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   2,
-							KeySymbol:    sql.NullString{"somethingElse", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						{
-							LocationID:   3,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   4,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   5,
-							KeySymbol:    sql.NullString{"nwt", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						nil,
-						nil,
-					},
-					UserMark: []*model.UserMark{
-						nil,
-						{
-							UserMarkID:   1,
-							LocationID:   1,
-							UserMarkGUID: "1",
-						},
-						nil,
-						nil,
-						{
-							UserMarkID:   4,
-							LocationID:   3,
-							UserMarkGUID: "4",
-						},
-						{
-							UserMarkID:   5,
-							LocationID:   5,
-							UserMarkGUID: "5",
-						},
-					},
-				},
-				right: &model.Database{
-					Location: []*model.Location{
-						nil,
-						{
-							LocationID:   1,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   2,
-							KeySymbol:    sql.NullString{"somethingElse", true},
-							MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
-						},
-						{
-							LocationID:   3,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   4,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						{
-							LocationID:   5,
-							KeySymbol:    sql.NullString{"nwtsty", true},
-							MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
-						},
-						nil,
-						nil,
-					},
-					UserMark: []*model.UserMark{
-						nil,
-						{
-							UserMarkID:   1,
-							LocationID:   1,
-							UserMarkGUID: "1",
-						},
-						nil,
-						nil,
-						{
-							UserMarkID:   4,
-							LocationID:   3,
-							UserMarkGUID: "4",
-						},
-						{
-							UserMarkID:   5,
-							LocationID:   5,
-							UserMarkGUID: "5",
-						},
-					},
-				},
-			},
-			want: map[int]MergeSide{
-				2: LeftSide,
-			},
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			assert.Equal(t, tt.want, needsNwtstyMigration(tt.args.left, tt.args.right))
-		})
-	}
-}
-
-func Test_moveToNwtsty(t *testing.T) {
-	type args struct {
-		langs map[int]MergeSide
-		left  []*model.Location
-		right []*model.Location
-	}
-	tests := []struct {
-		name string
-		args args
-		want args
-	}{
-		{
-			args: args{
-				langs: map[int]MergeSide{
-					0: LeftSide,
-					1: RightSide,
-				},
-				left: []*model.Location{
-					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-				},
-				right: []*model.Location{
-					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-				},
-			},
-			want: args{
-				left: []*model.Location{
-					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-				},
-				right: []*model.Location{
-					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-				},
-			},
-		},
-		{
-			name: "Skip locations with DocID",
-			args: args{
-				langs: map[int]MergeSide{
-					1: RightSide,
-				},
-				left: []*model.Location{
-					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{DocumentID: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{Track: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-				},
-				right: []*model.Location{
-					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{DocumentID: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{Track: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-				},
-			},
-			want: args{
-				left: []*model.Location{
-					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{KeySymbol: sql.NullString{"other", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{DocumentID: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{Track: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-				},
-				right: []*model.Location{
-					{KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{KeySymbol: sql.NullString{"nwtsty", true}, MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
-					{DocumentID: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-					{Track: sql.NullInt32{1, true}, KeySymbol: sql.NullString{"nwt", true}, MepsLanguage: sql.NullInt32{Int32: 1, Valid: true}},
-				},
-			},
-		},
-	}
-	for _, tt := range tests {
-		moveToNwtsty(tt.args.langs, tt.args.left, tt.args.right)
-		assert.Equal(t, tt.want.left, tt.args.left, tt.args.left)
-		assert.Equal(t, tt.want.right, tt.args.right, tt.args.right)
-	}
-}
-
 func Test_cleanupDuplicateLocations(t *testing.T) {
 	type args struct {
 		entries []*model.Location
@@ -2328,7 +1652,7 @@ func Test_tryDuplicateUserMarkCleanup(t *testing.T) {
 					},
 				},
 			},
-			errContains: "there are two userMarks with the same GUID that were not caused by migrating from nwt to nwtsty",
+			errContains: "there are two userMarks with the same GUID that were not caused by migrating to a new edition",
 		},
 		{
 			name: "Fail because both are nwt",
@@ -2385,7 +1709,7 @@ func Test_tryDuplicateUserMarkCleanup(t *testing.T) {
 					},
 				},
 			},
-			errContains: "there are two userMarks with the same GUID that were not caused by migrating from nwt to nwtsty",
+			errContains: "there are two userMarks with the same GUID that were not caused by migrating to a new edition",
 		},
 	}
 	for _, tt := range tests {
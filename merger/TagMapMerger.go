@@ -1,6 +1,7 @@
 package merger
 
 import (
+	"context"
 	"sort"
 
 	"github.com/AndreasSko/go-jwlm/model"
@@ -10,6 +11,12 @@ import (
 // removes redundant entries and also makes sure that the position-order
 // stays similar.
 func MergeTagMaps(left []*model.TagMap, right []*model.TagMap, conflictSolution map[string]MergeSolution) ([]*model.TagMap, IDChanges, error) {
+	return MergeTagMapsContext(context.Background(), left, right, conflictSolution)
+}
+
+// MergeTagMapsContext is like MergeTagMaps, but stops and returns the
+// error of ctx if it is canceled.
+func MergeTagMapsContext(ctx context.Context, left []*model.TagMap, right []*model.TagMap, conflictSolution map[string]MergeSolution) ([]*model.TagMap, IDChanges, error) {
 	if len(left)+len(right) == 0 {
 		return []*model.TagMap{nil}, IDChanges{}, nil
 	}
@@ -20,7 +27,10 @@ func MergeTagMaps(left []*model.TagMap, right []*model.TagMap, conflictSolution
 	// Per TagID add TagMap entries to map with UniqueKey as the key,
 	// automatically filtering duplicate entries
 	for _, side := range [][]*model.TagMap{left, right} {
-		for _, tm := range side {
+		for i, tm := range side {
+			if err := canceled(ctx, i); err != nil {
+				return nil, IDChanges{}, err
+			}
 			if tm == nil {
 				continue
 			}
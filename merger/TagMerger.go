@@ -1,11 +1,21 @@
 package merger
 
-import "github.com/AndreasSko/go-jwlm/model"
+import (
+	"context"
+
+	"github.com/AndreasSko/go-jwlm/model"
+)
 
 // MergeTags tries to merge the left and right slice of Tag. If there is a
 // collision, it returns an error asking for specification how it should handle it.
 func MergeTags(left []*model.Tag, right []*model.Tag, conflictSolution map[string]MergeSolution) ([]*model.Tag, IDChanges, error) {
-	result, changes, err := tryMergeWithConflictSolver(left, right, conflictSolution, solveEqualityMergeConflict)
+	return MergeTagsContext(context.Background(), left, right, conflictSolution)
+}
+
+// MergeTagsContext is like MergeTags, but stops and returns the error
+// of ctx if it is canceled.
+func MergeTagsContext(ctx context.Context, left []*model.Tag, right []*model.Tag, conflictSolution map[string]MergeSolution) ([]*model.Tag, IDChanges, error) {
+	result, changes, err := tryMergeWithConflictSolver(ctx, left, right, conflictSolution, solveEqualityMergeConflict)
 
 	return model.Tag{}.MakeSlice(result), changes, err
 }
@@ -1,6 +1,7 @@
 package merger
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -34,6 +35,14 @@ type umbrFrom struct {
 // UserMarkBlockRange struct to make it easier representing conflicts.
 // The returned IDChanges indicate if a UserMarkID has changed in the merge process.
 func MergeUserMarkAndBlockRange(leftUM []*model.UserMark, leftBR []*model.BlockRange,
+	rightUM []*model.UserMark, rightBR []*model.BlockRange,
+	conflictSolution map[string]MergeSolution) ([]*model.UserMark, []*model.BlockRange, IDChanges, error) {
+	return MergeUserMarkAndBlockRangeContext(context.Background(), leftUM, leftBR, rightUM, rightBR, conflictSolution)
+}
+
+// MergeUserMarkAndBlockRangeContext is like MergeUserMarkAndBlockRange, but
+// stops and returns the error of ctx if it is canceled.
+func MergeUserMarkAndBlockRangeContext(ctx context.Context, leftUM []*model.UserMark, leftBR []*model.BlockRange,
 	rightUM []*model.UserMark, rightBR []*model.BlockRange,
 	conflictSolution map[string]MergeSolution) ([]*model.UserMark, []*model.BlockRange, IDChanges, error) {
 	if conflictSolution == nil {
@@ -48,6 +57,9 @@ func MergeUserMarkAndBlockRange(leftUM []*model.UserMark, leftBR []*model.BlockR
 	var err error
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, IDChanges{}, err
+		}
 		merged, changes, err = mergeUMBR(left, right, conflictSolution)
 		if err == nil {
 			um, br := splitUserMarkBlockRange(merged)
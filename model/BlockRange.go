@@ -9,12 +9,12 @@ import (
 
 // BlockRange represents the BlockRange table inside the JW Library database
 type BlockRange struct {
-	BlockRangeID int
-	BlockType    int
-	Identifier   int
-	StartToken   sql.NullInt32
-	EndToken     sql.NullInt32
-	UserMarkID   int
+	BlockRangeID int           `sql:"BlockRangeId"`
+	BlockType    int           `sql:"BlockType"`
+	Identifier   int           `sql:"Identifier"`
+	StartToken   sql.NullInt32 `sql:"StartToken"`
+	EndToken     sql.NullInt32 `sql:"EndToken"`
+	UserMarkID   int           `sql:"UserMarkId"`
 }
 
 // ID returns the ID of the entry
@@ -58,8 +58,17 @@ func (m *BlockRange) Equals(m2 Model) bool {
 
 // RelatedEntries returns entries that are related to this one
 func (m *BlockRange) RelatedEntries(db *Database) Related {
-	// We don't need it for now
-	return Related{}
+	result := Related{}
+
+	if userMark := db.FetchFromTable("UserMark", m.UserMarkID); userMark != nil {
+		result.UserMark = userMark.(*UserMark)
+		if location := db.FetchFromTable("Location", result.UserMark.LocationID); location != nil {
+			result.Location = location.(*Location)
+			result.Reference = BlockReference(result.Location, m.BlockType, m.Identifier)
+		}
+	}
+
+	return result
 }
 
 // PrettyPrint prints BlockRange in a human readable format and
@@ -98,11 +107,6 @@ func (m *BlockRange) idName() string {
 	return "BlockRangeId"
 }
 
-func (m *BlockRange) scanRow(rows *sql.Rows) (Model, error) {
-	err := rows.Scan(&m.BlockRangeID, &m.BlockType, &m.Identifier, &m.StartToken, &m.EndToken, &m.UserMarkID)
-	return m, err
-}
-
 // MakeSlice converts a slice of the generice interface model
 func (BlockRange) MakeSlice(mdl []Model) []*BlockRange {
 	result := make([]*BlockRange, len(mdl))
@@ -117,6 +117,23 @@ func TestBlockRange_RelatedEntries(t *testing.T) {
 
 	assert.Equal(t, Related{}, m1.RelatedEntries(nil))
 	assert.Equal(t, Related{}, m1.RelatedEntries(&Database{}))
+
+	db := &Database{
+		Location: []*Location{
+			nil,
+			{
+				LocationID: 1,
+				KeySymbol:  sql.NullString{String: "lffi", Valid: true},
+				Title:      sql.NullString{String: "Location-Title", Valid: true},
+			},
+		},
+		UserMark: []*UserMark{nil, {UserMarkID: 1, LocationID: 1}},
+	}
+	assert.Equal(t, Related{
+		Location:  db.Location[1],
+		UserMark:  db.UserMark[1],
+		Reference: "Location-Title ¶1",
+	}, m1.RelatedEntries(db))
 }
 
 func TestBlockRange_MarshalJSON(t *testing.T) {
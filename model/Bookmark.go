@@ -9,14 +9,14 @@ import (
 
 // Bookmark represents the Bookmark table inside the JW Library database
 type Bookmark struct {
-	BookmarkID            int
-	LocationID            int
-	PublicationLocationID int
-	Slot                  int
-	Title                 string
-	Snippet               sql.NullString
-	BlockType             int
-	BlockIdentifier       sql.NullInt32
+	BookmarkID            int            `sql:"BookmarkId"`
+	LocationID            int            `sql:"LocationId"`
+	PublicationLocationID int            `sql:"PublicationLocationId"`
+	Slot                  int            `sql:"Slot"`
+	Title                 string         `sql:"Title"`
+	Snippet               sql.NullString `sql:"Snippet"`
+	BlockType             int            `sql:"BlockType"`
+	BlockIdentifier       sql.NullInt32  `sql:"BlockIdentifier"`
 }
 
 // ID returns the ID of the entry
@@ -63,9 +63,10 @@ func (m *Bookmark) RelatedEntries(db *Database) Related {
 	if location := db.FetchFromTable("Location", m.LocationID); location != nil {
 		result.Location = location.(*Location)
 	}
-	if pubLocation := db.FetchFromTable("Location", m.LocationID); pubLocation != nil {
+	if pubLocation := db.FetchFromTable("Location", m.PublicationLocationID); pubLocation != nil {
 		result.PublicationLocation = pubLocation.(*Location)
 	}
+	result.Reference = m.Reference(db)
 
 	return result
 }
@@ -117,12 +118,6 @@ func (m *Bookmark) idName() string {
 	return "BookmarkId"
 }
 
-func (m *Bookmark) scanRow(rows *sql.Rows) (Model, error) {
-	err := rows.Scan(&m.BookmarkID, &m.LocationID, &m.PublicationLocationID, &m.Slot, &m.Title,
-		&m.Snippet, &m.BlockType, &m.BlockIdentifier)
-	return m, err
-}
-
 // MakeSlice converts a slice of the generice interface model
 func (Bookmark) MakeSlice(mdl []Model) []*Bookmark {
 	result := make([]*Bookmark, len(mdl))
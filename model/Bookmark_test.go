@@ -140,12 +140,18 @@ func TestBookmark_RelatedEntries(t *testing.T) {
 				LocationID: 1,
 				Title:      sql.NullString{"Location-Title", true},
 			},
+			nil,
+			{
+				LocationID:   3,
+				Title:        sql.NullString{"Publication-Title", true},
+				LocationType: 1,
+			},
 		},
 	}
 
 	assert.Equal(t, Related{}, db.Bookmark[1].RelatedEntries(nil))
 	assert.Equal(t,
-		Related{Location: db.Location[1], PublicationLocation: db.Location[1]},
+		Related{Location: db.Location[1], PublicationLocation: db.Location[3]},
 		db.Bookmark[1].RelatedEntries(db))
 }
 
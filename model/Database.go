@@ -1,7 +1,9 @@
 package model
 
 import (
-	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"database/sql"
 	"fmt"
 	"io"
@@ -10,7 +12,9 @@ import (
 	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/pkg/errors"
@@ -23,9 +27,6 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-//go:embed data/userData.db
-var userDataDatabaseFile []byte
-
 //go:embed data/default_thumbnail.png
 var defaultThumbnailFile []byte
 
@@ -33,6 +34,17 @@ const manifestFilename = "manifest.json"
 const userDataFilename = "userData.db"
 const defaultThumbnailFilename = "default_thumbnail.png"
 
+var (
+	// ErrCorruptBackup is returned if a backup is malformed or contains unsafe entries.
+	ErrCorruptBackup = errors.New("corrupt backup")
+	// ErrHashMismatch is returned if the hash of the database in a backup
+	// doesn't match the one mentioned in its manifest.
+	ErrHashMismatch = errors.New("hash mismatch")
+	// ErrUnsupportedVersion is returned if the version of a backup or
+	// its schema is not supported.
+	ErrUnsupportedVersion = errors.New("unsupported backup version")
+)
+
 // Database represents the JW Library database as a struct
 type Database struct {
 	BlockRange []*BlockRange
@@ -49,8 +61,21 @@ type Database struct {
 	// SkipPlaylists allows to skip prevention of merging if playlists exist in the database.
 	// It is meant as a temporary workaround until merging of playlists is implemented.
 	SkipPlaylists bool
-	// TempDir is used for temporary files. If not set, os.TempDir() will be used.
+	// TempDir is used for temporary files, if any are needed. If not set, os.TempDir() will be used.
 	TempDir string
+	// SchemaVersion is the version of the userData.db schema the Database has been
+	// imported from and is exported with. If not set, the latest supported one is used.
+	// Exporting fails with ErrUnsupportedVersion if there is neither an embedded
+	// template for the version nor has a backup of it been imported before.
+	SchemaVersion int
+	// Metadata of the backup the Database has been imported from. It is used
+	// when exporting the Database, unless other ExportOptions are given.
+	Metadata *Metadata
+	// LastModified is the time the entries of the Database have been changed
+	// the last time, as stored in the LastModified table of the userData.db.
+	// JW Library uses it to determine which backup is newer. If it is not set,
+	// the time of the export is used.
+	LastModified time.Time
 }
 
 // FetchFromTable tries to fetch a entry with the given ID. If it can't find it
@@ -133,8 +158,18 @@ func MakeDatabaseCopy(db *Database) *Database {
 			cpField.Set(cpSlice)
 		case reflect.Bool:
 			cpField.SetBool(field.Bool())
+		case reflect.Int:
+			cpField.SetInt(field.Int())
+		case reflect.Ptr:
+			md, ok := field.Interface().(*Metadata)
+			if !ok {
+				panic(fmt.Sprintf("Field type %s is not supported for copying", field.Type()))
+			}
+			cpField.Set(reflect.ValueOf(md.copy()))
 		case reflect.String:
 			cpField.SetString(field.String())
+		case reflect.Struct:
+			cpField.Set(field)
 		default:
 			panic(fmt.Sprintf("Field type %T is not supported for copying", tp))
 		}
@@ -228,6 +263,10 @@ func (db *Database) Equals(other *Database) bool {
 			if dbFields.Field(i).String() != otherFields.Field(i).String() {
 				return false
 			}
+		case reflect.Int, reflect.Ptr, reflect.Struct:
+			// The SchemaVersion, Metadata, and LastModified only describe how and
+			// when entries are stored, so they don't affect whether two Databases are equal.
+			continue
 		default:
 			panic(fmt.Sprintf("field type %T is not supported for checking equality", tp))
 		}
@@ -239,44 +278,74 @@ func (db *Database) Equals(other *Database) bool {
 // ImportJWLBackup unzips a given JW Library Backup file and imports the
 // included SQLite DB to the Database struct
 func (db *Database) ImportJWLBackup(filename string) error {
-	// Create tmp folder and place all files there
-	tmp, err := os.MkdirTemp(db.TempDir, "go-jwlm")
-	if err != nil {
-		return errors.Wrap(err, "Error while creating temporary directory")
+	return db.ImportJWLBackupContext(context.Background(), filename, nil)
+}
+
+// ImportJWLBackupContext is like ImportJWLBackup, but stops and returns the
+// error of ctx if it is canceled. The prgrs channel (if not nil) informs
+// about the progress of the import and is closed when it is finished.
+// It has to be received from until it is closed.
+func (db *Database) ImportJWLBackupContext(ctx context.Context, filename string, prgrs chan Progress) error {
+	if prgrs != nil {
+		defer close(prgrs)
 	}
-	defer os.RemoveAll(tmp)
 
-	r, err := zip.OpenReader(filename)
+	file, err := os.Open(filename)
 	if err != nil {
 		return err
 	}
-	defer r.Close()
+	defer file.Close()
 
-	for _, file := range r.File {
-		fileReader, err := file.Open()
-		if err != nil {
-			return fmt.Errorf("failed to open file: %w", err)
-		}
-		defer fileReader.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "Error while reading %s", filename)
+	}
 
-		path := filepath.Join(tmp, file.Name)
-		targetFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
-		if err != nil {
-			return fmt.Errorf("failed to open target file: %w", err)
-		}
-		defer targetFile.Close()
+	return db.importJWLBackup(ctx, file, info.Size(), &progressReporter{prgrs: prgrs})
+}
 
-		if _, err := io.Copy(targetFile, fileReader); err != nil {
-			return errors.Wrap(err, "Error while copying files from backup to temporary folder")
-		}
+// ImportJWLBackupFrom imports a JW Library backup of the given size from r
+// to the Database struct. The backup is processed in memory, so no temporary
+// files are written. Only the entries needed for the import are read.
+//
+// If the backup is malformed, an error wrapping ErrCorruptBackup is returned,
+// if its database doesn't match the manifest, one wrapping ErrHashMismatch, and
+// if it is not supported, one wrapping ErrUnsupportedVersion.
+func (db *Database) ImportJWLBackupFrom(r io.ReaderAt, size int64) error {
+	return db.ImportJWLBackupFromContext(context.Background(), r, size, nil)
+}
+
+// ImportJWLBackupFromContext is like ImportJWLBackupFrom, but stops and returns
+// the error of ctx if it is canceled. The prgrs channel (if not nil) informs
+// about the progress of the import and is closed when it is finished.
+// It has to be received from until it is closed.
+func (db *Database) ImportJWLBackupFromContext(ctx context.Context, r io.ReaderAt, size int64, prgrs chan Progress) error {
+	if prgrs != nil {
+		defer close(prgrs)
+	}
+
+	return db.importJWLBackup(ctx, r, size, &progressReporter{prgrs: prgrs})
+}
+
+// importJWLBackup imports the backup from r and reports its progress to prgrs.
+func (db *Database) importJWLBackup(ctx context.Context, r io.ReaderAt, size int64, prgrs *progressReporter) error {
+	// Reading the backup, importing each of its tables and its remaining files
+	prgrs.total = 2 + len(importedModels)
+
+	archive, err := openBackupArchive(r, size)
+	if err != nil {
+		return err
 	}
 
 	// Import manifest
-	path := filepath.Join(tmp, manifestFilename)
 	manifest := manifest{}
-	if err := manifest.importManifest(path); err != nil {
+	manifestContent, err := archive.readEntry(manifestFilename, maxManifestSize)
+	if err != nil {
 		return errors.Wrap(err, "Error while importing manifest")
 	}
+	if err := manifest.importManifest(bytes.NewReader(manifestContent)); err != nil {
+		return fmt.Errorf("%w: %v", ErrCorruptBackup, err)
+	}
 
 	// Make sure that we support this backup version
 	if err := manifest.validateManifest(); err != nil {
@@ -284,111 +353,114 @@ func (db *Database) ImportJWLBackup(filename string) error {
 	}
 
 	// Fill the Database with actual data
-	path = filepath.Join(tmp, manifest.UserDataBackup.DatabaseName)
-	return db.importSQLite(path)
-}
+	dbName := manifest.UserDataBackup.DatabaseName
+	if !isSafeEntryName(dbName) || filepath.Base(dbName) != dbName {
+		return fmt.Errorf("%w: invalid database name %q", ErrCorruptBackup, dbName)
+	}
+	content, err := archive.readEntry(dbName, maxDatabaseSize)
+	if err != nil {
+		return errors.Wrap(err, "Error while reading database of backup")
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256(content))
+	if !strings.EqualFold(hash, manifest.UserDataBackup.Hash) {
+		return fmt.Errorf("%w: manifest expects %q, database has %q", ErrHashMismatch, manifest.UserDataBackup.Hash, hash)
+	}
+	prgrs.step("Reading backup")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-// importSQLite imports a given SQLite DB into the Database struct
-func (db *Database) importSQLite(filename string) error {
-	// Open SQLite file as immutable to avoid locks (and therefore speed up import)
-	sqlite, err := sql.Open("sqlite3", filename+"?immutable=1")
+	sqlite, err := openInMemorySQLite(content)
 	if err != nil {
-		return errors.Wrap(err, "Error while opening SQLite database")
+		return err
 	}
 	defer sqlite.Close()
+	if err := db.importSQLiteDB(ctx, sqlite, prgrs); err != nil {
+		return err
+	}
 
-	var wg sync.WaitGroup
-	wg.Add(8)
-	errors := make(chan error, 10)
-
-	// Fill each table struct separately (did not find a DRYer solution yet..)
-	go func() {
-		mdl, err := fetchFromSQLite(sqlite, &BlockRange{})
-		if err != nil {
-			errors <- err
-			wg.Done()
-			return
+	// Keep the thumbnail and the media files of the playlists, so we can
+	// export them again. All other entries of the backup are ignored.
+	md := &Metadata{
+		Name:         manifest.Name,
+		DeviceName:   manifest.UserDataBackup.DeviceName,
+		CreationDate: manifest.CreationDate,
+	}
+	mediaFiles, err := fetchMediaFilenames(sqlite)
+	if err != nil {
+		return err
+	}
+	for _, file := range archive.zr.File {
+		if file.FileInfo().IsDir() || (file.Name != defaultThumbnailFilename && !mediaFiles[file.Name]) {
+			continue
 		}
-		db.BlockRange = BlockRange{}.MakeSlice(mdl)
-		wg.Done()
-	}()
-
-	go func() {
-		mdl, err := fetchFromSQLite(sqlite, &Bookmark{})
+		content, err := archive.readEntry(file.Name, maxDatabaseSize)
 		if err != nil {
-			errors <- err
-			wg.Done()
-			return
+			return errors.Wrapf(err, "Error while reading %s", file.Name)
 		}
-		db.Bookmark = Bookmark{}.MakeSlice(mdl)
-		wg.Done()
-	}()
-
-	go func() {
-		mdl, err := fetchFromSQLite(sqlite, &InputField{})
-		if err != nil {
-			errors <- err
-			wg.Done()
-			return
+		if file.Name == defaultThumbnailFilename {
+			md.Thumbnail = content
+			continue
 		}
-		db.InputField = InputField{}.MakeSlice(mdl)
-		wg.Done()
-	}()
-
-	go func() {
-		mdl, err := fetchFromSQLite(sqlite, &Location{})
-		if err != nil {
-			errors <- err
-			wg.Done()
-			return
+		if md.ExtraFiles == nil {
+			md.ExtraFiles = map[string][]byte{}
 		}
-		db.Location = Location{}.MakeSlice(mdl)
-		wg.Done()
-	}()
+		md.ExtraFiles[file.Name] = content
+	}
+	db.Metadata = md
+	prgrs.step("Reading attachments")
 
-	go func() {
-		mdl, err := fetchFromSQLite(sqlite, &Note{})
-		if err != nil {
-			errors <- err
-			wg.Done()
-			return
-		}
-		db.Note = Note{}.MakeSlice(mdl)
-		wg.Done()
-	}()
+	// Remember the schema version, so we are able to export the Database with it.
+	// If we don't ship a template for it, we derive one from the imported backup.
+	db.SchemaVersion = manifest.UserDataBackup.SchemaVersion
+	if err := registerSchemaFromSQLite(db.SchemaVersion, content); err != nil {
+		return errors.Wrapf(err, "Error while registering schema version %d", db.SchemaVersion)
+	}
+	prgrs.done()
 
-	go func() {
-		mdl, err := fetchFromSQLite(sqlite, &Tag{})
-		if err != nil {
-			errors <- err
-			wg.Done()
-			return
-		}
-		db.Tag = Tag{}.MakeSlice(mdl)
-		wg.Done()
-	}()
+	return nil
+}
 
-	go func() {
-		mdl, err := fetchFromSQLite(sqlite, &TagMap{})
-		if err != nil {
-			errors <- err
-			wg.Done()
-			return
-		}
-		db.TagMap = TagMap{}.MakeSlice(mdl)
-		wg.Done()
-	}()
+// importSQLite imports a given SQLite DB into the Database struct
+func (db *Database) importSQLite(filename string) error {
+	// Open SQLite file as immutable to avoid locks (and therefore speed up import)
+	sqlite, err := sql.Open("sqlite3", filename+"?immutable=1")
+	if err != nil {
+		return errors.Wrap(err, "Error while opening SQLite database")
+	}
+	defer sqlite.Close()
 
-	go func() {
-		mdl, err := fetchFromSQLite(sqlite, &UserMark{})
-		if err != nil {
-			errors <- err
-			wg.Done()
-			return
-		}
-		db.UserMark = UserMark{}.MakeSlice(mdl)
-		wg.Done()
-	}()
+	return db.importSQLiteDB(context.Background(), sqlite, nil)
+}
+
+// importedModels are the Models that are imported from a backup,
+// each one from its own table.
+var importedModels = []Model{
+	&BlockRange{}, &Bookmark{}, &InputField{}, &Location{},
+	&Note{}, &Tag{}, &TagMap{}, &UserMark{},
+}
+
+// importSQLiteDB imports the entries of an opened SQLite DB into the Database struct.
+// The import of each table is reported to prgrs.
+func (db *Database) importSQLiteDB(ctx context.Context, sqlite *sql.DB, prgrs *progressReporter) error {
+	var wg sync.WaitGroup
+	wg.Add(len(importedModels))
+	errors := make(chan error, len(importedModels))
+
+	// Fetch each table separately and fill the corresponding slice afterwards
+	fetched := make([][]Model, len(importedModels))
+	for i, modelType := range importedModels {
+		go func() {
+			defer wg.Done()
+			mdl, err := fetchFromSQLite(ctx, sqlite, modelType)
+			if err != nil {
+				errors <- err
+				return
+			}
+			fetched[i] = mdl
+			prgrs.step("Importing " + modelType.tableName())
+		}()
+	}
 
 	wg.Wait()
 
@@ -398,6 +470,21 @@ func (db *Database) importSQLite(filename string) error {
 	default:
 	}
 
+	db.BlockRange = BlockRange{}.MakeSlice(fetched[0])
+	db.Bookmark = Bookmark{}.MakeSlice(fetched[1])
+	db.InputField = InputField{}.MakeSlice(fetched[2])
+	db.Location = Location{}.MakeSlice(fetched[3])
+	db.Note = Note{}.MakeSlice(fetched[4])
+	db.Tag = Tag{}.MakeSlice(fetched[5])
+	db.TagMap = TagMap{}.MakeSlice(fetched[6])
+	db.UserMark = UserMark{}.MakeSlice(fetched[7])
+
+	lastModified, err := fetchLastModified(sqlite)
+	if err != nil {
+		return err
+	}
+	db.LastModified = lastModified
+
 	// Make sure these tables are empty as we are not able to merge them yet.
 	// Better to fail, than to risk losing data..
 	emptyTables := []string{
@@ -449,7 +536,7 @@ func (db *Database) removePlaylists() {
 
 // fetchFromSQLite fetches the entries for a given modelType and returns a slice
 // of entries, for which the index corresponds to the ID in the SQLite DB
-func fetchFromSQLite(sqlite *sql.DB, modelType Model) ([]Model, error) {
+func fetchFromSQLite(ctx context.Context, sqlite *sql.DB, modelType Model) ([]Model, error) {
 	// Create slice of correct size (number of entries)
 	capacity, err := getSliceCapacity(sqlite, modelType)
 	if err != nil {
@@ -457,7 +544,15 @@ func fetchFromSQLite(sqlite *sql.DB, modelType Model) ([]Model, error) {
 	}
 	result := make([]Model, capacity)
 
-	rows, err := sqlite.Query(fmt.Sprintf("SELECT * FROM %s", modelType.tableName()))
+	// Make sure the table contains all columns we need
+	if err := checkColumns(sqlite, modelType, false); err != nil {
+		return nil, err
+	}
+
+	// Select columns by their name, so columns added by newer schema versions
+	// or a changed column order don't affect the import
+	rows, err := sqlite.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s",
+		strings.Join(columnNames(modelType), ", "), modelType.tableName()))
 	if err != nil {
 		return nil, errors.Wrap(err, "Error while querying SQLite database")
 	}
@@ -487,8 +582,7 @@ func fetchFromSQLite(sqlite *sql.DB, modelType Model) ([]Model, error) {
 		default:
 			panic(fmt.Sprintf("Fetching %T is not supported!", tp))
 		}
-		mn, err := m.scanRow(rows)
-		if err != nil {
+		if err := scanColumns(rows, m); err != nil {
 			// For some reason a row might contain NULL entries, even though the schema
 			// shouldn't allow this. Instead of failing the whole import, we can simply skip
 			// this entry as the data anyway wouldn't be valid.
@@ -500,7 +594,7 @@ func fetchFromSQLite(sqlite *sql.DB, modelType Model) ([]Model, error) {
 			i++
 			continue
 		}
-		result[mn.ID()] = mn
+		result[m.ID()] = m
 		i++
 	}
 	err = rows.Err()
@@ -569,6 +663,30 @@ func getTableEntryCount(sqlite *sql.DB, tableName string) (int, error) {
 	return count, nil
 }
 
+// fetchMediaFilenames returns the names of the media files that are referenced
+// by the IndependentMedia table. They are stored next to the database in a backup.
+func fetchMediaFilenames(sqlite *sql.DB) (map[string]bool, error) {
+	rows, err := sqlite.Query("SELECT FilePath FROM IndependentMedia")
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while fetching media files")
+	}
+	defer rows.Close()
+
+	result := map[string]bool{}
+	for rows.Next() {
+		var filePath string
+		if err := rows.Scan(&filePath); err != nil {
+			return nil, errors.Wrap(err, "Error while fetching media files")
+		}
+		result[filePath] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "Error while fetching media files")
+	}
+
+	return result, nil
+}
+
 // getSliceCapacity determines the needed capacity for a slice from a table
 // by looking at the highest ID in the DB. If the table does not have a ID
 // column, it will simply count the number of entries.
@@ -599,53 +717,133 @@ func getSliceCapacity(sqlite *sql.DB, modelType Model) (int, error) {
 
 // ExportJWLBackup creates a .jwlibrary backup file out of a Database{} struct
 func (db *Database) ExportJWLBackup(filename string) error {
-	// Create tmp folder and place all files there
-	tmp, err := os.MkdirTemp(db.TempDir, "go-jwlm")
+	return db.ExportJWLBackupWithOptions(filename, ExportOptions{})
+}
+
+// ExportJWLBackupWithOptions creates a .jwlibrary backup file out of a
+// Database{} struct using the given ExportOptions.
+func (db *Database) ExportJWLBackupWithOptions(filename string, opts ExportOptions) error {
+	return db.ExportJWLBackupContext(context.Background(), filename, opts, nil)
+}
+
+// ExportJWLBackupContext is like ExportJWLBackupWithOptions, but stops and
+// returns the error of ctx if it is canceled. In that case, no file is left
+// at filename. The prgrs channel (if not nil) informs about the progress of
+// the export and is closed when it is finished.
+// It has to be received from until it is closed.
+func (db *Database) ExportJWLBackupContext(ctx context.Context, filename string, opts ExportOptions, prgrs chan Progress) error {
+	if prgrs != nil {
+		defer close(prgrs)
+	}
+
+	file, err := os.Create(filename)
 	if err != nil {
-		return errors.Wrap(err, "Error while creating temporary directory")
+		return errors.Wrapf(err, "Error while creating %s", filename)
+	}
+
+	if err := db.exportJWLBackup(ctx, file, opts, &progressReporter{prgrs: prgrs}); err != nil {
+		file.Close()
+		os.Remove(filename)
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return errors.Wrapf(err, "Error while storing %s", filename)
+	}
+	return nil
+}
+
+// ExportJWLBackupTo writes a .jwlibrary backup of the Database{} struct to w.
+// The backup is created in memory, so no temporary files are written.
+func (db *Database) ExportJWLBackupTo(w io.Writer) error {
+	return db.ExportJWLBackupToWithOptions(w, ExportOptions{})
+}
+
+// ExportJWLBackupToWithOptions writes a .jwlibrary backup of the Database{}
+// struct to w using the given ExportOptions.
+func (db *Database) ExportJWLBackupToWithOptions(w io.Writer, opts ExportOptions) error {
+	return db.ExportJWLBackupToContext(context.Background(), w, opts, nil)
+}
+
+// ExportJWLBackupToContext is like ExportJWLBackupToWithOptions, but stops and
+// returns the error of ctx if it is canceled. The prgrs channel (if not nil)
+// informs about the progress of the export and is closed when it is finished.
+// It has to be received from until it is closed.
+func (db *Database) ExportJWLBackupToContext(ctx context.Context, w io.Writer, opts ExportOptions, prgrs chan Progress) error {
+	if prgrs != nil {
+		defer close(prgrs)
 	}
-	defer os.RemoveAll(tmp)
+
+	return db.exportJWLBackup(ctx, w, opts, &progressReporter{prgrs: prgrs})
+}
+
+// exportJWLBackup writes the backup to w and reports its progress to prgrs.
+func (db *Database) exportJWLBackup(ctx context.Context, w io.Writer, opts ExportOptions, prgrs *progressReporter) error {
+	// Fail before doing any work if we aren't able to write the schema version
+	if _, err := schemaTemplate(db.SchemaVersion); err != nil {
+		return err
+	}
+	if opts.Compact {
+		db = MakeDatabaseCopy(db)
+		db.Compact()
+	}
+
+	// Exporting each table, vacuuming and writing the backup
+	prgrs.total = len(importedModels) + 2
 
 	// Create userData.db
-	dbPath := filepath.Join(tmp, userDataFilename)
-	if err := db.saveToNewSQLite(dbPath); err != nil {
+	lastModified := opts.lastModified(db)
+	content, err := db.exportSQLite(ctx, lastModified, prgrs)
+	if err != nil {
 		return errors.Wrap(err, "Could not create SQLite database for exporting")
 	}
 
 	// Create manifest.json
-	manifestPath := filepath.Join(tmp, manifestFilename)
-	mfst, err := generateManifest("go-jwlm", dbPath)
-	if err != nil {
-		return errors.Wrap(err, "Error while generating manifest")
-	}
-	if err := mfst.exportManifest(manifestPath); err != nil {
+	schemaVersion := db.SchemaVersion
+	if schemaVersion == 0 {
+		schemaVersion = latestSchemaVersion
+	}
+	md := opts.metadata(db)
+	mfst := generateManifest(md, userDataFilename, content, schemaVersion, lastModified)
+	var manifestContent bytes.Buffer
+	if err := mfst.exportManifest(&manifestContent); err != nil {
 		return errors.Wrap(err, "Error while creating manifest.json")
 	}
 
-	defaultThumbnailPath := filepath.Join(tmp, defaultThumbnailFilename)
-	if err := os.WriteFile(defaultThumbnailPath, defaultThumbnailFile, 0644); err != nil {
-		return fmt.Errorf("writing default thumbnail to %s: %w", defaultThumbnailPath, err)
-	}
-
 	// Store files in .jwlibrary (zip)-file
-	files := []string{dbPath, manifestPath, defaultThumbnailPath}
-	if err := zipFiles(filename, files); err != nil {
-		return errors.Wrap(err, fmt.Sprintf("Error while storing files in zip archive %s", filename))
+	files := []zipEntry{
+		{name: userDataFilename, content: content},
+		{name: manifestFilename, content: manifestContent.Bytes()},
+		{name: defaultThumbnailFilename, content: md.Thumbnail},
+	}
+	for _, name := range md.extraFileNames() {
+		if name == userDataFilename || name == manifestFilename || name == defaultThumbnailFilename {
+			continue
+		}
+		files = append(files, zipEntry{name: name, content: md.ExtraFiles[name]})
+	}
+	if err := writeZip(w, files); err != nil {
+		return errors.Wrap(err, "Error while storing files in zip archive")
 	}
+	prgrs.step("Writing backup")
+	prgrs.done()
 
 	return nil
 }
 
-// saveToNewSQLite creates a new SQLite database with the JW Library scheme
-// and saves all entries of the Database{} struct to it
-func (db *Database) saveToNewSQLite(filename string) error {
-	if err := createEmptySQLiteDB(filename); err != nil {
-		return errors.Wrap(err, "Error while creating new empty SQLite database")
+// exportSQLite creates a new SQLite database with the JW Library scheme,
+// saves all entries of the Database{} struct to it and returns its content.
+// The LastModified table is set to lastModified and the export of each
+// table is reported to prgrs.
+func (db *Database) exportSQLite(ctx context.Context, lastModified time.Time, prgrs *progressReporter) ([]byte, error) {
+	template, err := schemaTemplate(db.SchemaVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while creating new empty SQLite database")
 	}
 
-	sqlite, err := sql.Open("sqlite3", filename)
+	sqlite, err := openInMemorySQLite(template)
 	if err != nil {
-		return errors.Wrap(err, "Error while opening SQLite database")
+		return nil, err
 	}
 	defer sqlite.Close()
 
@@ -659,71 +857,69 @@ func (db *Database) saveToNewSQLite(filename string) error {
 		slice := dbFields.Field(j).Interface()
 		mdl, err := MakeModelSlice(slice)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if err := insertEntries(sqlite, mdl); err != nil {
-			return errors.Wrapf(err, "Error while inserting entries of field %d", j)
+		if err := insertEntries(ctx, sqlite, mdl); err != nil {
+			return nil, errors.Wrapf(err, "Error while inserting entries of field %d", j)
 		}
+		prgrs.step("Exporting " + dbFields.Type().Field(j).Name)
+	}
+
+	// Inserting entries updates the LastModified table, so we set it afterwards
+	if err := storeLastModified(sqlite, lastModified); err != nil {
+		return nil, err
 	}
 
 	// Vacuum to clean up SQLite DB
-	_, err = sqlite.Exec("VACUUM")
+	_, err = sqlite.ExecContext(ctx, "VACUUM")
 	if err != nil {
-		return errors.Wrap(err, "Error while vacuuming SQLite DB")
+		return nil, errors.Wrap(err, "Error while vacuuming SQLite DB")
 	}
+	prgrs.step("Compacting database")
 
-	return nil
+	return serializeSQLite(sqlite)
 }
 
 // insertEntries INSERTs entries of []model into a given SQLite database.
-// It does it by dynamically parsing all fields of a struct implementing
+// It does it by dynamically parsing the column tags of a struct implementing
 // model using reflection and creating a query for SQLite out of it.
-func insertEntries(sqlite *sql.DB, m []Model) error {
+func insertEntries(ctx context.Context, sqlite *sql.DB, m []Model) error {
 	if len(m) == 0 {
 		return nil
 	}
 
-	// Figure out tableName and rowCount. As we don't know for sure,
+	// Figure out the model we are inserting. As we don't know for sure,
 	// which entry will be nil-pointer, we just try until we find a non-empty
 	// one and call the functions there.
-	tableName := ""
-	rowCount := 0
-	foundEntry := false
+	var mdlType Model
 	for _, mdl := range m {
 		if reflect.ValueOf(mdl).Elem().IsValid() {
-			tableName = mdl.tableName()
-			foundEntry = true
-
-			// Count number of fields that don't have the "ignore" tag set
-			reflTypes := reflect.TypeOf(mdl).Elem()
-			for j := 0; j < reflTypes.NumField(); j++ {
-				if _, ignore := reflTypes.Field(j).Tag.Lookup("ignore"); ignore {
-					continue
-				}
-				rowCount++
-			}
+			mdlType = mdl
 			break
 		}
 	}
 	// If slice is empty, we don't need to continue
-	if !foundEntry {
+	if mdlType == nil {
 		return nil
 	}
 
-	tx, err := sqlite.Begin()
-	if err != nil {
+	// Make sure we are able to fill all columns of the table
+	if err := checkColumns(sqlite, mdlType, true); err != nil {
 		return err
 	}
 
-	// Dynamically add all column-names of the struct to the query
-	query := fmt.Sprintf("INSERT INTO %s VALUES (", tableName)
-	for i := 0; i < rowCount; i++ {
-		query += "?"
-		if i+1 < rowCount {
-			query += ", "
-		}
+	tx, err := sqlite.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
-	query += ")"
+	defer tx.Rollback()
+
+	// Dynamically add all column-names of the struct to the query. Naming the
+	// columns explicitly makes sure that columns added by newer schema
+	// versions are filled with their default value.
+	columns := columnNames(mdlType)
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", mdlType.tableName(),
+		strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
 
 	stmt, err := tx.Prepare(query)
 	if err != nil {
@@ -732,27 +928,12 @@ func insertEntries(sqlite *sql.DB, m []Model) error {
 	defer stmt.Close()
 
 	for _, entry := range m {
-		// Prepare struct for ingestion with stmt.Exec
-		values := make([]interface{}, rowCount)
-		reflValues := reflect.ValueOf(entry).Elem()
-		reflTypes := reflect.TypeOf(entry).Elem()
-
 		// Check if entry is actually a nil-pointer and shouldn't be considered
-		if !reflValues.IsValid() {
+		if !reflect.ValueOf(entry).Elem().IsValid() {
 			continue
 		}
 
-		// Add all fields of the struct to the values slice, so we can ingest them later
-		for j := 0; j < reflValues.NumField(); j++ {
-			// If struct field has `ignore` tag then skip it
-			if _, ignore := reflTypes.Field(j).Tag.Lookup("ignore"); ignore {
-				continue
-			}
-			v := reflValues.Field(j).Interface()
-			values[j] = v
-		}
-
-		if _, err := stmt.Exec(values...); err != nil {
+		if _, err := stmt.ExecContext(ctx, columnValues(entry)...); err != nil {
 			return errors.Wrap(err, fmt.Sprintf("Could not insert entry %v", entry))
 		}
 	}
@@ -764,15 +945,6 @@ func insertEntries(sqlite *sql.DB, m []Model) error {
 	return nil
 }
 
-// createEmptySQLiteDB creates a new SQLite database at filename with the base userData.db from JWLibrary
-func createEmptySQLiteDB(filename string) error {
-	if err := os.WriteFile(filename, userDataDatabaseFile, 0644); err != nil {
-		return errors.Wrap(err, fmt.Sprintf("Error while saving new SQLite database at %s", filename))
-	}
-
-	return nil
-}
-
 // maps a DatabaseTypeName to a go type name
 var dbTypeToGoType = map[string]string{
 	"INTEGER": "int",
@@ -2,16 +2,18 @@ package model
 
 import (
 	"archive/zip"
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"database/sql"
 	_ "embed"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	_ "github.com/mattn/go-sqlite3"
@@ -203,14 +205,20 @@ func TestDatabase_PurgeTables(t *testing.T) {
 
 func TestMakeDatabaseCopy(t *testing.T) {
 	db := &Database{
-		TempDir: "a-temp-dir",
+		TempDir:       "a-temp-dir",
+		SchemaVersion: 15,
 	}
 
 	path := filepath.Join("testdata", userDataFilename)
 	assert.NoError(t, db.importSQLite(path))
 
+	db.Metadata = &Metadata{Name: "name", Thumbnail: []byte("thumbnail")}
+
 	dbCp := MakeDatabaseCopy(db)
 	assert.Equal(t, db.TempDir, dbCp.TempDir)
+	assert.Equal(t, db.SchemaVersion, dbCp.SchemaVersion)
+	assert.Equal(t, db.Metadata, dbCp.Metadata)
+	assert.NotSame(t, db.Metadata, dbCp.Metadata)
 	assertEqualNotDeepSame(t, db.BlockRange, dbCp.BlockRange)
 	assertEqualNotDeepSame(t, db.Bookmark, dbCp.Bookmark)
 	assertEqualNotDeepSame(t, db.InputField, dbCp.InputField)
@@ -293,42 +301,42 @@ func Test_fetchFromSQLite(t *testing.T) {
 	}
 	defer sqlite.Close()
 
-	blockRange, err := fetchFromSQLite(sqlite, &BlockRange{})
+	blockRange, err := fetchFromSQLite(context.Background(), sqlite, &BlockRange{})
 	assert.NoError(t, err)
 	assert.Len(t, blockRange, 5)
 	assert.Equal(t, &BlockRange{3, 2, 13, sql.NullInt32{Int32: 0, Valid: true}, sql.NullInt32{Int32: 14, Valid: true}, 3}, blockRange[3])
 
-	bookmark, err := fetchFromSQLite(sqlite, &Bookmark{})
+	bookmark, err := fetchFromSQLite(context.Background(), sqlite, &Bookmark{})
 	assert.NoError(t, err)
 	assert.Len(t, bookmark, 4)
 	assert.Equal(t, &Bookmark{2, 3, 7, 4, "Philippians 4", sql.NullString{String: "12I know how to be low on provisions and how to have an abundance. In everything and in all circumstances I have learned the secret of both how to be full and how to hunger, both how to have an abundance and how to do without. ", Valid: true}, 0, sql.NullInt32{}}, bookmark[2])
 
-	inputField, err := fetchFromSQLite(sqlite, &InputField{})
+	inputField, err := fetchFromSQLite(context.Background(), sqlite, &InputField{})
 	assert.NoError(t, err)
 	assert.Len(t, inputField, 4)
 	assert.Equal(t, &InputField{8, "tt71", "First other..", 3}, inputField[3])
 
-	location, err := fetchFromSQLite(sqlite, &Location{})
+	location, err := fetchFromSQLite(context.Background(), sqlite, &Location{})
 	assert.NoError(t, err)
 	assert.Len(t, location, 9)
 	assert.Equal(t, &Location{4, sql.NullInt32{Int32: 66, Valid: true}, sql.NullInt32{Int32: 21, Valid: true}, sql.NullInt32{}, sql.NullInt32{}, 0, sql.NullString{String: "nwtsty", Valid: true}, sql.NullInt32{Int32: 2, Valid: true}, 0, sql.NullString{String: "Offenbarung 21", Valid: true}}, location[4])
 
-	note, err := fetchFromSQLite(sqlite, &Note{})
+	note, err := fetchFromSQLite(context.Background(), sqlite, &Note{})
 	assert.NoError(t, err)
 	assert.Len(t, note, 3)
 	assert.Equal(t, &Note{2, "F75A18EE-FC17-4E0B-ABB6-CC16DABE9610", sql.NullInt32{Int32: 3, Valid: true}, sql.NullInt32{Int32: 3, Valid: true}, sql.NullString{String: "For all things I have the strength through the one who gives me power.", Valid: true}, sql.NullString{String: "", Valid: true}, "2020-04-14T18:42:14+00:00", "2020-04-14T18:42:14+00:00", 2, sql.NullInt32{Int32: 13, Valid: true}}, note[2])
 
-	tag, err := fetchFromSQLite(sqlite, &Tag{})
+	tag, err := fetchFromSQLite(context.Background(), sqlite, &Tag{})
 	assert.NoError(t, err)
 	assert.Len(t, tag, 3)
 	assert.Equal(t, &Tag{2, 1, "Strengthening"}, tag[2])
 
-	tagMap, err := fetchFromSQLite(sqlite, &TagMap{})
+	tagMap, err := fetchFromSQLite(context.Background(), sqlite, &TagMap{})
 	assert.NoError(t, err)
 	assert.Len(t, tagMap, 3)
 	assert.Equal(t, &TagMap{2, sql.NullInt32{Int32: 0, Valid: false}, sql.NullInt32{Int32: 0, Valid: false}, sql.NullInt32{Int32: 2, Valid: true}, 2, 1}, tagMap[2])
 
-	userMark, err := fetchFromSQLite(sqlite, &UserMark{})
+	userMark, err := fetchFromSQLite(context.Background(), sqlite, &UserMark{})
 	assert.NoError(t, err)
 	assert.Len(t, userMark, 5)
 	assert.Equal(t, &UserMark{2, 1, 2, 0, "2C5E7B4A-4997-4EDA-9CFF-38A7599C487B", 1}, userMark[2])
@@ -858,32 +866,141 @@ func TestDatabase_ExportJWLBackup(t *testing.T) {
 	assert.Equal(t, &UserMark{2, 1, 2, 0, "2C5E7B4A-4997-4EDA-9CFF-38A7599C487B", 1}, db.UserMark[2])
 }
 
-func Test_createEmptySQLiteDB(t *testing.T) {
-	// Create tmp folder and place all files there
-	tmp := t.TempDir()
+func TestDatabase_ExportJWLBackupTo(t *testing.T) {
+	db := &Database{}
+	path := filepath.Join("testdata", "backup.jwlibrary")
+	assert.NoError(t, db.ImportJWLBackup(path))
 
-	path := filepath.Join(tmp, userDataFilename)
-	assert.NoError(t, createEmptySQLiteDB(path))
+	var buf bytes.Buffer
+	assert.NoError(t, db.ExportJWLBackupTo(&buf))
 
-	// Test if file has correct hash
-	f, err := os.Open(path)
-	if err != nil {
-		errors.Wrap(err, "Error while opening SQLite file to calculate hash")
+	filenames, err := filenamesInZipReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"userData.db", "manifest.json", "default_thumbnail.png"}, filenames)
+
+	db2 := &Database{}
+	assert.NoError(t, db2.ImportJWLBackupFrom(bytes.NewReader(buf.Bytes()), int64(buf.Len())))
+	assert.True(t, db.Equals(db2))
+
+	// Not a zip archive
+	assert.Error(t, db2.ImportJWLBackupFrom(bytes.NewReader([]byte("invalid")), 7))
+}
+
+func TestDatabase_ImportJWLBackupFrom(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testdata", userDataFilename))
+	assert.NoError(t, err)
+
+	manifestWith := func(change func(mfst *manifest)) []byte {
+		mfst := generateManifest(testMetadata(), userDataFilename, content, 14, time.Now())
+		change(mfst)
+		var buf bytes.Buffer
+		assert.NoError(t, mfst.exportManifest(&buf))
+		return buf.Bytes()
 	}
-	defer f.Close()
-	hasher := sha256.New()
-	if _, err := io.Copy(hasher, f); err != nil {
-		log.Fatal(err)
+	validManifest := manifestWith(func(mfst *manifest) {})
+
+	tests := []struct {
+		name    string
+		entries []zipEntry
+		wantErr error
+	}{
+		{
+			name: "Valid backup with unexpected entries",
+			entries: []zipEntry{
+				{name: manifestFilename, content: validManifest},
+				{name: userDataFilename, content: content},
+				{name: "something/else.txt", content: []byte("ignored")},
+			},
+		},
+		{
+			name: "Path traversal",
+			entries: []zipEntry{
+				{name: manifestFilename, content: validManifest},
+				{name: userDataFilename, content: content},
+				{name: "../../evil.sh", content: []byte("evil")},
+			},
+			wantErr: ErrCorruptBackup,
+		},
+		{
+			name: "Missing manifest",
+			entries: []zipEntry{
+				{name: userDataFilename, content: content},
+			},
+			wantErr: ErrCorruptBackup,
+		},
+		{
+			name: "Invalid manifest",
+			entries: []zipEntry{
+				{name: manifestFilename, content: []byte("{invalid")},
+				{name: userDataFilename, content: content},
+			},
+			wantErr: ErrCorruptBackup,
+		},
+		{
+			name: "Database outside of backup",
+			entries: []zipEntry{
+				{name: manifestFilename, content: manifestWith(func(mfst *manifest) {
+					mfst.UserDataBackup.DatabaseName = "../" + userDataFilename
+				})},
+				{name: userDataFilename, content: content},
+			},
+			wantErr: ErrCorruptBackup,
+		},
+		{
+			name: "Missing database",
+			entries: []zipEntry{
+				{name: manifestFilename, content: validManifest},
+			},
+			wantErr: ErrCorruptBackup,
+		},
+		{
+			name: "Hash mismatch",
+			entries: []zipEntry{
+				{name: manifestFilename, content: manifestWith(func(mfst *manifest) {
+					mfst.UserDataBackup.Hash = "0123"
+				})},
+				{name: userDataFilename, content: content},
+			},
+			wantErr: ErrHashMismatch,
+		},
+		{
+			name: "Unsupported version",
+			entries: []zipEntry{
+				{name: manifestFilename, content: manifestWith(func(mfst *manifest) {
+					mfst.Version = 2
+				})},
+				{name: userDataFilename, content: content},
+			},
+			wantErr: ErrUnsupportedVersion,
+		},
 	}
-	hash := fmt.Sprintf("%x", hasher.Sum(nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, writeZip(&buf, tt.entries))
 
-	assert.Equal(t, "78edd07c0b04212dcc2dd59be0a5d2edf91088136986378147cd8aa04cf4965c", hash)
+			db := &Database{}
+			err := db.ImportJWLBackupFrom(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.Len(t, db.Location, 9)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
 }
 
-func TestDatabase_saveToNewSQLite(t *testing.T) {
-	// Create tmp folder and place all files there
-	tmp := t.TempDir()
+func Test_emptySchemaTemplate(t *testing.T) {
+	template, err := schemaTemplate(0)
+	assert.NoError(t, err)
+
+	// Test if template has correct hash
+	hash := fmt.Sprintf("%x", sha256.Sum256(template))
+	assert.Equal(t, "78edd07c0b04212dcc2dd59be0a5d2edf91088136986378147cd8aa04cf4965c", hash)
+}
 
+func TestDatabase_exportSQLite(t *testing.T) {
 	db := Database{
 		BlockRange: []*BlockRange{{3, 2, 13, sql.NullInt32{Int32: 0, Valid: true}, sql.NullInt32{Int32: 14, Valid: true}, 3}},
 		Bookmark:   []*Bookmark{{2, 3, 7, 4, "Philippians 4", sql.NullString{String: "12 I know how to be low on provisions and how to have an abundance. In everything and in all circumstances I have learned the secret of both how to be full and how to hunger, both how to have an abundance and how to do without. ", Valid: true}, 0, sql.NullInt32{}}},
@@ -894,11 +1011,15 @@ func TestDatabase_saveToNewSQLite(t *testing.T) {
 		TagMap:     []*TagMap{{2, sql.NullInt32{Int32: 0, Valid: false}, sql.NullInt32{Int32: 0, Valid: false}, sql.NullInt32{Int32: 2, Valid: true}, 2, 1}},
 		UserMark:   []*UserMark{{2, 1, 2, 0, "2C5E7B4A-4997-4EDA-9CFF-38A7599C487B", 1}},
 	}
-	path := filepath.Join(tmp, userDataFilename)
-	assert.NoError(t, db.saveToNewSQLite(path))
+	lastModified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	content, err := db.exportSQLite(context.Background(), lastModified, nil)
+	assert.NoError(t, err)
 
+	sqlite, err := openInMemorySQLite(content)
+	assert.NoError(t, err)
+	defer sqlite.Close()
 	db2 := Database{}
-	assert.NoError(t, db2.importSQLite(path))
+	assert.NoError(t, db2.importSQLiteDB(context.Background(), sqlite, nil))
 
 	assert.Equal(t, db.BlockRange[0], db2.BlockRange[3])
 	assert.Equal(t, db.Bookmark[0], db2.Bookmark[2])
@@ -907,13 +1028,15 @@ func TestDatabase_saveToNewSQLite(t *testing.T) {
 	assert.Equal(t, db.Note[0], db2.Note[2])
 	assert.Equal(t, db.TagMap[0], db2.TagMap[2])
 	assert.Equal(t, db.UserMark[0], db2.UserMark[2])
+	assert.Equal(t, lastModified, db2.LastModified)
 
 	// Check if saving empty tables is possible
 	db = Database{
 		BlockRange: []*BlockRange{{3, 2, 13, sql.NullInt32{Int32: 0, Valid: true}, sql.NullInt32{Int32: 14, Valid: true}, 3}},
 		Bookmark:   []*Bookmark{nil},
 	}
-	assert.NoError(t, db.saveToNewSQLite(path))
+	_, err = db.exportSQLite(context.Background(), time.Now(), nil)
+	assert.NoError(t, err)
 }
 
 func TestDatabase_Equals(t *testing.T) {
@@ -940,14 +1063,23 @@ func TestDatabase_Equals(t *testing.T) {
 
 // filenamesInZip returns the names of all files contained in a zip file.
 func filenamesInZip(path string) ([]string, error) {
-	r, err := zip.OpenReader(path)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return filenamesInZipReader(bytes.NewReader(content), int64(len(content)))
+}
+
+// filenamesInZipReader returns the names of all files contained in a zip archive of the given size.
+func filenamesInZipReader(r io.ReaderAt, size int64) ([]string, error) {
+	zr, err := zip.NewReader(r, size)
 	if err != nil {
 		return nil, err
 	}
-	defer r.Close()
 
-	filenames := make([]string, len(r.File))
-	for i, f := range r.File {
+	filenames := make([]string, len(zr.File))
+	for i, f := range zr.File {
 		filenames[i] = f.Name
 	}
 
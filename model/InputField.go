@@ -1,7 +1,8 @@
 package model
 
 import (
-	"database/sql"
+	"bytes"
+	"encoding/gob"
 	"encoding/json"
 	"strconv"
 	"strings"
@@ -9,10 +10,10 @@ import (
 
 // InputField represents the InputField table inside the JW Library database
 type InputField struct {
-	LocationID int
-	TextTag    string
-	Value      string
-	pseudoID   int `ignore:"true"`
+	LocationID int    `sql:"LocationId"`
+	TextTag    string `sql:"TextTag"`
+	Value      string `sql:"Value"`
+	pseudoID   int
 }
 
 // ID returns the ID of the entry. As the InputField table does not have
@@ -55,6 +56,7 @@ func (m *InputField) RelatedEntries(db *Database) Related {
 
 	if location := db.FetchFromTable("Location", int(m.LocationID)); location != nil {
 		result.Location = location.(*Location)
+		result.Reference = result.Location.Reference()
 	}
 
 	return result
@@ -89,6 +91,42 @@ func (m InputField) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// gobInputField is the gob representation of an InputField.
+type gobInputField struct {
+	LocationID int
+	TextTag    string
+	Value      string
+	PseudoID   int
+}
+
+// GobEncode encodes the InputField including its pseudoID, which
+// would otherwise get lost as it is not exported.
+func (m *InputField) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(gobInputField{
+		LocationID: m.LocationID,
+		TextTag:    m.TextTag,
+		Value:      m.Value,
+		PseudoID:   m.pseudoID,
+	})
+	return buf.Bytes(), err
+}
+
+// GobDecode restores an InputField encoded by GobEncode.
+func (m *InputField) GobDecode(data []byte) error {
+	var gif gobInputField
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&gif); err != nil {
+		return err
+	}
+	*m = InputField{
+		LocationID: gif.LocationID,
+		TextTag:    gif.TextTag,
+		Value:      gif.Value,
+		pseudoID:   gif.PseudoID,
+	}
+	return nil
+}
+
 func (m *InputField) tableName() string {
 	return "InputField"
 }
@@ -97,11 +135,6 @@ func (m *InputField) idName() string {
 	return ""
 }
 
-func (m *InputField) scanRow(rows *sql.Rows) (Model, error) {
-	err := rows.Scan(&m.LocationID, &m.TextTag, &m.Value)
-	return m, err
-}
-
 // MakeSlice converts a slice of the generice interface model.
 func (InputField) MakeSlice(mdl []Model) []*InputField {
 	result := make([]*InputField, len(mdl))
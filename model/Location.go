@@ -9,16 +9,16 @@ import (
 
 // Location represents the Location table inside the JW Library database
 type Location struct {
-	LocationID     int
-	BookNumber     sql.NullInt32
-	ChapterNumber  sql.NullInt32
-	DocumentID     sql.NullInt32
-	Track          sql.NullInt32
-	IssueTagNumber int
-	KeySymbol      sql.NullString
-	MepsLanguage   sql.NullInt32
-	LocationType   int
-	Title          sql.NullString
+	LocationID     int            `sql:"LocationId"`
+	BookNumber     sql.NullInt32  `sql:"BookNumber"`
+	ChapterNumber  sql.NullInt32  `sql:"ChapterNumber"`
+	DocumentID     sql.NullInt32  `sql:"DocumentId"`
+	Track          sql.NullInt32  `sql:"Track"`
+	IssueTagNumber int            `sql:"IssueTagNumber"`
+	KeySymbol      sql.NullString `sql:"KeySymbol"`
+	MepsLanguage   sql.NullInt32  `sql:"MepsLanguage"`
+	LocationType   int            `sql:"Type"`
+	Title          sql.NullString `sql:"Title"`
 }
 
 // ID returns the ID of the entry
@@ -73,9 +73,10 @@ func (m *Location) Equals(m2 Model) bool {
 	return false
 }
 
-// RelatedEntries returns entries that are related to this one
+// RelatedEntries returns entries that are related to this one. As a
+// Location doesn't reference any other entries, it is always empty.
+// Use Database.Dependents for the entries referencing the Location.
 func (m *Location) RelatedEntries(db *Database) Related {
-	// We don't need it for now
 	return Related{}
 }
 
@@ -124,12 +125,6 @@ func (m *Location) idName() string {
 	return "LocationId"
 }
 
-func (m *Location) scanRow(rows *sql.Rows) (Model, error) {
-	err := rows.Scan(&m.LocationID, &m.BookNumber, &m.ChapterNumber, &m.DocumentID, &m.Track,
-		&m.IssueTagNumber, &m.KeySymbol, &m.MepsLanguage, &m.LocationType, &m.Title)
-	return m, err
-}
-
 // MakeSlice converts a slice of the generice interface model
 func (Location) MakeSlice(mdl []Model) []*Location {
 	result := make([]*Location, len(mdl))
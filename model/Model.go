@@ -23,7 +23,6 @@ type Model interface {
 	PrettyPrint(db *Database) string
 	tableName() string
 	idName() string
-	scanRow(row *sql.Rows) (Model, error)
 }
 
 // Related combines entries that are related to a given model
@@ -37,6 +36,9 @@ type Related struct {
 	TagMap              *TagMap             `json:"tagMap"`
 	UserMark            *UserMark           `json:"userMark"`
 	UserMarkBlockRange  *UserMarkBlockRange `json:"userMarkBlockRange"`
+	// Reference is a human readable reference of the verse or
+	// paragraph the model belongs to, like "Matthew 24:14"
+	Reference string `json:"reference,omitempty"`
 }
 
 // MakeModelSlice converts a slice of pointers of model-implementing structs to []model
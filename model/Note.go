@@ -7,16 +7,16 @@ import (
 
 // Note represents the Note table inside the JW Library database
 type Note struct {
-	NoteID          int
-	GUID            string
-	UserMarkID      sql.NullInt32
-	LocationID      sql.NullInt32
-	Title           sql.NullString
-	Content         sql.NullString
-	LastModified    string
-	Created         string
-	BlockType       int
-	BlockIdentifier sql.NullInt32
+	NoteID          int            `sql:"NoteId"`
+	GUID            string         `sql:"Guid"`
+	UserMarkID      sql.NullInt32  `sql:"UserMarkId"`
+	LocationID      sql.NullInt32  `sql:"LocationId"`
+	Title           sql.NullString `sql:"Title"`
+	Content         sql.NullString `sql:"Content"`
+	LastModified    string         `sql:"LastModified"`
+	Created         string         `sql:"Created"`
+	BlockType       int            `sql:"BlockType"`
+	BlockIdentifier sql.NullInt32  `sql:"BlockIdentifier"`
 }
 
 // ID returns the ID of the entry
@@ -57,6 +57,7 @@ func (m *Note) RelatedEntries(db *Database) Related {
 	if userMark := db.FetchFromTable("UserMark", int(m.UserMarkID.Int32)); userMark != nil {
 		result.UserMark = userMark.(*UserMark)
 	}
+	result.Reference = m.Reference(db)
 
 	return result
 }
@@ -118,12 +119,6 @@ func (m *Note) idName() string {
 	return "NoteId"
 }
 
-func (m *Note) scanRow(rows *sql.Rows) (Model, error) {
-	err := rows.Scan(&m.NoteID, &m.GUID, &m.UserMarkID, &m.LocationID, &m.Title, &m.Content,
-		&m.LastModified, &m.Created, &m.BlockType, &m.BlockIdentifier)
-	return m, err
-}
-
 // MakeSlice converts a slice of the generice interface model
 func (Note) MakeSlice(mdl []Model) []*Note {
 	result := make([]*Note, len(mdl))
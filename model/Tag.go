@@ -1,7 +1,6 @@
 package model
 
 import (
-	"database/sql"
 	"encoding/json"
 	"strconv"
 	"strings"
@@ -9,9 +8,9 @@ import (
 
 // Tag represents the Tag table inside the JW Library database
 type Tag struct {
-	TagID   int
-	TagType int
-	Name    string
+	TagID   int    `sql:"TagId"`
+	TagType int    `sql:"Type"`
+	Name    string `sql:"Name"`
 }
 
 // ID returns the ID of the entry
@@ -46,7 +45,9 @@ func (m *Tag) Equals(m2 Model) bool {
 	return false
 }
 
-// RelatedEntries returns entries that are related to this one
+// RelatedEntries returns entries that are related to this one. As a
+// Tag doesn't reference any other entries, it is always empty.
+// Use Database.Dependents for the TagMaps referencing the Tag.
 func (m *Tag) RelatedEntries(db *Database) Related {
 	return Related{}
 }
@@ -81,11 +82,6 @@ func (m *Tag) idName() string {
 	return "TagId"
 }
 
-func (m *Tag) scanRow(rows *sql.Rows) (Model, error) {
-	err := rows.Scan(&m.TagID, &m.TagType, &m.Name)
-	return m, err
-}
-
 // MakeSlice converts a slice of the generice interface model
 func (Tag) MakeSlice(mdl []Model) []*Tag {
 	result := make([]*Tag, len(mdl))
@@ -9,12 +9,12 @@ import (
 
 // TagMap represents the TagMap table inside the JW Library database
 type TagMap struct {
-	TagMapID       int
-	PlaylistItemID sql.NullInt32
-	LocationID     sql.NullInt32
-	NoteID         sql.NullInt32
-	TagID          int
-	Position       int
+	TagMapID       int           `sql:"TagMapId"`
+	PlaylistItemID sql.NullInt32 `sql:"PlaylistItemId"`
+	LocationID     sql.NullInt32 `sql:"LocationId"`
+	NoteID         sql.NullInt32 `sql:"NoteId"`
+	TagID          int           `sql:"TagId"`
+	Position       int           `sql:"Position"`
 }
 
 // ID returns the ID of the entry
@@ -57,8 +57,19 @@ func (m *TagMap) Equals(m2 Model) bool {
 
 // RelatedEntries returns entries that are related to this one
 func (m *TagMap) RelatedEntries(db *Database) Related {
-	// We don't need it for now, so just return empty slice
-	return Related{}
+	result := Related{}
+
+	if tag := db.FetchFromTable("Tag", m.TagID); tag != nil {
+		result.Tag = tag.(*Tag)
+	}
+	if note := db.FetchFromTable("Note", int(m.NoteID.Int32)); note != nil {
+		result.Note = note.(*Note)
+	}
+	if location := db.FetchFromTable("Location", int(m.LocationID.Int32)); location != nil {
+		result.Location = location.(*Location)
+	}
+
+	return result
 }
 
 // PrettyPrint prints TagMap in a human readable format and
@@ -96,11 +107,6 @@ func (m *TagMap) idName() string {
 	return "TagMapId"
 }
 
-func (m *TagMap) scanRow(rows *sql.Rows) (Model, error) {
-	err := rows.Scan(&m.TagMapID, &m.PlaylistItemID, &m.LocationID, &m.NoteID, &m.TagID, &m.Position)
-	return m, err
-}
-
 // MakeSlice converts a slice of the generice interface model
 func (TagMap) MakeSlice(mdl []Model) []*TagMap {
 	result := make([]*TagMap, len(mdl))
@@ -88,6 +88,17 @@ func TestTagMap_RelatedEntries(t *testing.T) {
 
 	assert.Equal(t, Related{}, m1.RelatedEntries(nil))
 	assert.Equal(t, Related{}, m1.RelatedEntries(&Database{}))
+
+	db := &Database{
+		Location: []*Location{nil, {LocationID: 1}},
+		Note:     []*Note{nil, {NoteID: 1}},
+		Tag:      []*Tag{nil, {TagID: 1, Name: "Tag"}},
+	}
+	assert.Equal(t, Related{
+		Location: db.Location[1],
+		Note:     db.Note[1],
+		Tag:      db.Tag[1],
+	}, m1.RelatedEntries(db))
 }
 
 func TestTagMap_MarshalJSON(t *testing.T) {
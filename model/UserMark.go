@@ -1,18 +1,17 @@
 package model
 
 import (
-	"database/sql"
 	"encoding/json"
 )
 
 // UserMark represents the UserMark table inside the JW Library database
 type UserMark struct {
-	UserMarkID   int
-	ColorIndex   int
-	LocationID   int
-	StyleIndex   int
-	UserMarkGUID string
-	Version      int
+	UserMarkID   int    `sql:"UserMarkId"`
+	ColorIndex   int    `sql:"ColorIndex"`
+	LocationID   int    `sql:"LocationId"`
+	StyleIndex   int    `sql:"StyleIndex"`
+	UserMarkGUID string `sql:"UserMarkGuid"`
+	Version      int    `sql:"Version"`
 }
 
 // ID returns the ID of the entry
@@ -44,8 +43,24 @@ func (m *UserMark) Equals(m2 Model) bool {
 
 // RelatedEntries returns entries that are related to this one
 func (m *UserMark) RelatedEntries(db *Database) Related {
-	// We don't need it for now, so just return empty slice
-	return Related{}
+	result := Related{}
+	if db == nil {
+		return result
+	}
+
+	if location := db.FetchFromTable("Location", m.LocationID); location != nil {
+		result.Location = location.(*Location)
+	}
+	for _, br := range db.BlockRange {
+		if br != nil && br.UserMarkID == m.UserMarkID {
+			result.BlockRange = append(result.BlockRange, br)
+		}
+	}
+	if len(result.BlockRange) > 0 {
+		result.Reference = (&UserMarkBlockRange{UserMark: m, BlockRanges: result.BlockRange}).Reference(db)
+	}
+
+	return result
 }
 
 // PrettyPrint prints UserMark in a human readable format and
@@ -86,11 +101,6 @@ func (m *UserMark) idName() string {
 	return "UserMarkId"
 }
 
-func (m *UserMark) scanRow(rows *sql.Rows) (Model, error) {
-	err := rows.Scan(&m.UserMarkID, &m.ColorIndex, &m.LocationID, &m.StyleIndex, &m.UserMarkGUID, &m.Version)
-	return m, err
-}
-
 // MakeSlice converts a slice of the generice interface model
 func (UserMark) MakeSlice(mdl []Model) []*UserMark {
 	result := make([]*UserMark, len(mdl))
@@ -1,7 +1,6 @@
 package model
 
 import (
-	"database/sql"
 	"encoding/json"
 	"reflect"
 	"regexp"
@@ -73,6 +72,7 @@ func (m *UserMarkBlockRange) RelatedEntries(db *Database) Related {
 	if location := db.FetchFromTable("Location", m.UserMark.LocationID); location != nil {
 		result.Location = location.(*Location)
 	}
+	result.Reference = m.Reference(db)
 
 	return result
 }
@@ -119,10 +119,6 @@ func (m *UserMarkBlockRange) idName() string {
 	panic("Not supported!")
 }
 
-func (m *UserMarkBlockRange) scanRow(rows *sql.Rows) (Model, error) {
-	panic("Not supported!")
-}
-
 // MakeSlice converts a slice of the generice interface model
 func (UserMarkBlockRange) MakeSlice(mdl []Model) []*UserMarkBlockRange {
 	panic("Not supported!")
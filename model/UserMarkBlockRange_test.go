@@ -343,7 +343,7 @@ func TestUserMarkBlockRange_RelatedEntries(t *testing.T) {
 	}
 
 	assert.Equal(t, Related{}, m1.RelatedEntries(nil))
-	assert.Equal(t, Related{Location: db.Location[1]}, m1.RelatedEntries(db))
+	assert.Equal(t, Related{Location: db.Location[1], Reference: "Location-Title ¶1-3"}, m1.RelatedEntries(db))
 }
 
 func TestUserMarkBlockRange_MarshalJSON(t *testing.T) {
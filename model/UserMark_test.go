@@ -1,6 +1,7 @@
 package model
 
 import (
+	"database/sql"
 	"encoding/json"
 	"testing"
 
@@ -72,6 +73,29 @@ func TestUserMark_RelatedEntries(t *testing.T) {
 
 	assert.Equal(t, Related{}, m1.RelatedEntries(nil))
 	assert.Equal(t, Related{}, m1.RelatedEntries(&Database{}))
+
+	db := &Database{
+		BlockRange: []*BlockRange{
+			nil,
+			{BlockRangeID: 1, BlockType: 2, Identifier: 16, UserMarkID: 1},
+			{BlockRangeID: 2, BlockType: 2, Identifier: 17, UserMarkID: 2},
+			{BlockRangeID: 3, BlockType: 2, Identifier: 17, UserMarkID: 1},
+		},
+		Location: []*Location{
+			nil,
+			{
+				LocationID:    1,
+				BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+				MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+			},
+		},
+	}
+	assert.Equal(t, Related{
+		BlockRange: []*BlockRange{db.BlockRange[1], db.BlockRange[3]},
+		Location:   db.Location[1],
+		Reference:  "John 3:16-17",
+	}, m1.RelatedEntries(db))
 }
 
 func TestUserMark_MarshalJSON(t *testing.T) {
@@ -0,0 +1,122 @@
+package model
+
+import "strconv"
+
+// bibleBookNames contains the names of the 66 Bible books
+// (in the order of their BookNumber) by MEPS language.
+var bibleBookNames = map[int][]string{
+	// English
+	0: {
+		"Genesis", "Exodus", "Leviticus", "Numbers", "Deuteronomy", "Joshua", "Judges",
+		"Ruth", "1 Samuel", "2 Samuel", "1 Kings", "2 Kings", "1 Chronicles",
+		"2 Chronicles", "Ezra", "Nehemiah", "Esther", "Job", "Psalms", "Proverbs",
+		"Ecclesiastes", "Song of Solomon", "Isaiah", "Jeremiah", "Lamentations",
+		"Ezekiel", "Daniel", "Hosea", "Joel", "Amos", "Obadiah", "Jonah", "Micah",
+		"Nahum", "Habakkuk", "Zephaniah", "Haggai", "Zechariah", "Malachi",
+		"Matthew", "Mark", "Luke", "John", "Acts", "Romans", "1 Corinthians",
+		"2 Corinthians", "Galatians", "Ephesians", "Philippians", "Colossians",
+		"1 Thessalonians", "2 Thessalonians", "1 Timothy", "2 Timothy", "Titus",
+		"Philemon", "Hebrews", "James", "1 Peter", "2 Peter", "1 John", "2 John",
+		"3 John", "Jude", "Revelation",
+	},
+	// Spanish
+	1: {
+		"Génesis", "Éxodo", "Levítico", "Números", "Deuteronomio", "Josué", "Jueces",
+		"Rut", "1 Samuel", "2 Samuel", "1 Reyes", "2 Reyes", "1 Crónicas",
+		"2 Crónicas", "Esdras", "Nehemías", "Ester", "Job", "Salmos", "Proverbios",
+		"Eclesiastés", "El Cantar de los Cantares", "Isaías", "Jeremías",
+		"Lamentaciones", "Ezequiel", "Daniel", "Oseas", "Joel", "Amós", "Abdías",
+		"Jonás", "Miqueas", "Nahúm", "Habacuc", "Sofonías", "Ageo", "Zacarías",
+		"Malaquías", "Mateo", "Marcos", "Lucas", "Juan", "Hechos", "Romanos",
+		"1 Corintios", "2 Corintios", "Gálatas", "Efesios", "Filipenses",
+		"Colosenses", "1 Tesalonicenses", "2 Tesalonicenses", "1 Timoteo",
+		"2 Timoteo", "Tito", "Filemón", "Hebreos", "Santiago", "1 Pedro", "2 Pedro",
+		"1 Juan", "2 Juan", "3 Juan", "Judas", "Apocalipsis",
+	},
+	// German
+	2: {
+		"1. Mose", "2. Mose", "3. Mose", "4. Mose", "5. Mose", "Josua", "Richter",
+		"Ruth", "1. Samuel", "2. Samuel", "1. Könige", "2. Könige", "1. Chronika",
+		"2. Chronika", "Esra", "Nehemia", "Esther", "Hiob", "Psalm", "Sprüche",
+		"Prediger", "Hohes Lied", "Jesaja", "Jeremia", "Klagelieder", "Hesekiel",
+		"Daniel", "Hosea", "Joel", "Amos", "Obadja", "Jona", "Micha", "Nahum",
+		"Habakuk", "Zephanja", "Haggai", "Sacharja", "Maleachi", "Matthäus",
+		"Markus", "Lukas", "Johannes", "Apostelgeschichte", "Römer", "1. Korinther",
+		"2. Korinther", "Galater", "Epheser", "Philipper", "Kolosser",
+		"1. Thessalonicher", "2. Thessalonicher", "1. Timotheus", "2. Timotheus",
+		"Titus", "Philemon", "Hebräer", "Jakobus", "1. Petrus", "2. Petrus",
+		"1. Johannes", "2. Johannes", "3. Johannes", "Judas", "Offenbarung",
+	},
+	// French
+	3: {
+		"Genèse", "Exode", "Lévitique", "Nombres", "Deutéronome", "Josué", "Juges",
+		"Ruth", "1 Samuel", "2 Samuel", "1 Rois", "2 Rois", "1 Chroniques",
+		"2 Chroniques", "Esdras", "Néhémie", "Esther", "Job", "Psaumes", "Proverbes",
+		"Ecclésiaste", "Chant de Salomon", "Isaïe", "Jérémie", "Lamentations",
+		"Ézéchiel", "Daniel", "Osée", "Joël", "Amos", "Abdias", "Jonas", "Michée",
+		"Nahum", "Habacuc", "Sophonie", "Aggée", "Zacharie", "Malachie", "Matthieu",
+		"Marc", "Luc", "Jean", "Actes", "Romains", "1 Corinthiens", "2 Corinthiens",
+		"Galates", "Éphésiens", "Philippiens", "Colossiens", "1 Thessaloniciens",
+		"2 Thessaloniciens", "1 Timothée", "2 Timothée", "Tite", "Philémon",
+		"Hébreux", "Jacques", "1 Pierre", "2 Pierre", "1 Jean", "2 Jean", "3 Jean",
+		"Jude", "Révélation",
+	},
+	// Italian
+	4: {
+		"Genesi", "Esodo", "Levitico", "Numeri", "Deuteronomio", "Giosuè", "Giudici",
+		"Rut", "1 Samuele", "2 Samuele", "1 Re", "2 Re", "1 Cronache", "2 Cronache",
+		"Esdra", "Neemia", "Ester", "Giobbe", "Salmi", "Proverbi", "Ecclesiaste",
+		"Cantico dei Cantici", "Isaia", "Geremia", "Lamentazioni", "Ezechiele",
+		"Daniele", "Osea", "Gioele", "Amos", "Abdia", "Giona", "Michea", "Naum",
+		"Abacuc", "Sofonia", "Aggeo", "Zaccaria", "Malachia", "Matteo", "Marco",
+		"Luca", "Giovanni", "Atti", "Romani", "1 Corinti", "2 Corinti", "Galati",
+		"Efesini", "Filippesi", "Colossesi", "1 Tessalonicesi", "2 Tessalonicesi",
+		"1 Timoteo", "2 Timoteo", "Tito", "Filemone", "Ebrei", "Giacomo", "1 Pietro",
+		"2 Pietro", "1 Giovanni", "2 Giovanni", "3 Giovanni", "Giuda", "Rivelazione",
+	},
+	// Portuguese
+	5: {
+		"Gênesis", "Êxodo", "Levítico", "Números", "Deuteronômio", "Josué", "Juízes",
+		"Rute", "1 Samuel", "2 Samuel", "1 Reis", "2 Reis", "1 Crônicas",
+		"2 Crônicas", "Esdras", "Neemias", "Ester", "Jó", "Salmos", "Provérbios",
+		"Eclesiastes", "Cântico de Salomão", "Isaías", "Jeremias", "Lamentações",
+		"Ezequiel", "Daniel", "Oseias", "Joel", "Amós", "Obadias", "Jonas",
+		"Miqueias", "Naum", "Habacuque", "Sofonias", "Ageu", "Zacarias", "Malaquias",
+		"Mateus", "Marcos", "Lucas", "João", "Atos", "Romanos", "1 Coríntios",
+		"2 Coríntios", "Gálatas", "Efésios", "Filipenses", "Colossenses",
+		"1 Tessalonicenses", "2 Tessalonicenses", "1 Timóteo", "2 Timóteo", "Tito",
+		"Filêmon", "Hebreus", "Tiago", "1 Pedro", "2 Pedro", "1 João", "2 João",
+		"3 João", "Judas", "Apocalipse",
+	},
+}
+
+// BibleBookName returns the name of the Bible book with the given number in
+// the given MEPS language. If there are no names for the language, the
+// English one is returned. For invalid book numbers, it returns an empty string.
+func BibleBookName(book int, mepsLanguage int) string {
+	names, ok := bibleBookNames[mepsLanguage]
+	if !ok {
+		names = bibleBookNames[0]
+	}
+	if book < 1 || book > len(names) {
+		return ""
+	}
+	return names[book-1]
+}
+
+// Reference returns a human readable reference of a Location within
+// the Bible, like "John 3", in the language of the Location. For
+// other Locations it returns an empty string.
+func (m *Location) Reference() string {
+	if !m.BookNumber.Valid {
+		return ""
+	}
+	name := BibleBookName(int(m.BookNumber.Int32), int(m.MepsLanguage.Int32))
+	if name == "" {
+		return ""
+	}
+	if !m.ChapterNumber.Valid {
+		return name
+	}
+	return name + " " + strconv.Itoa(int(m.ChapterNumber.Int32))
+}
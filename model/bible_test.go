@@ -0,0 +1,81 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBibleBookName(t *testing.T) {
+	assert.Equal(t, "Genesis", BibleBookName(1, 0))
+	assert.Equal(t, "John", BibleBookName(43, 0))
+	assert.Equal(t, "Revelation", BibleBookName(66, 0))
+	assert.Equal(t, "Johannes", BibleBookName(43, 2))
+	// Unknown languages fall back to English
+	assert.Equal(t, "John", BibleBookName(43, 999))
+	assert.Equal(t, "", BibleBookName(0, 0))
+	assert.Equal(t, "", BibleBookName(67, 2))
+
+	for lang, names := range bibleBookNames {
+		assert.Len(t, names, 66, "language %d", lang)
+	}
+}
+
+func TestLocation_Reference(t *testing.T) {
+	tests := []struct {
+		name     string
+		location *Location
+		want     string
+	}{
+		{
+			name: "Chapter",
+			location: &Location{
+				BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+				KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+			},
+			want: "John 3",
+		},
+		{
+			name: "Other language",
+			location: &Location{
+				BookNumber:    sql.NullInt32{Int32: 1, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 1, Valid: true},
+				MepsLanguage:  sql.NullInt32{Int32: 2, Valid: true},
+			},
+			want: "1. Mose 1",
+		},
+		{
+			name: "Book",
+			location: &Location{
+				BookNumber:   sql.NullInt32{Int32: 19, Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+			},
+			want: "Psalms",
+		},
+		{
+			name: "Invalid book",
+			location: &Location{
+				BookNumber:    sql.NullInt32{Int32: 70, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 1, Valid: true},
+			},
+			want: "",
+		},
+		{
+			name: "No Bible",
+			location: &Location{
+				DocumentID:   sql.NullInt32{Int32: 1102002020, Valid: true},
+				KeySymbol:    sql.NullString{String: "cl", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.location.Reference())
+		})
+	}
+}
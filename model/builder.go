@@ -0,0 +1,330 @@
+package model
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Types of Locations
+const (
+	// LocationTypeDocument is a Location of a document or Bible chapter
+	LocationTypeDocument = 0
+	// LocationTypePublication is a Location of a publication as a whole
+	LocationTypePublication = 1
+)
+
+// Types of Tags
+const (
+	// TagTypeFavorite is the Tag for favorites
+	TagTypeFavorite = 0
+	// TagTypeUser is a Tag created by the user
+	TagTypeUser = 1
+	// TagTypePlaylist is a playlist
+	TagTypePlaylist = 2
+)
+
+// Range of ColorIndex of UserMarks and Slot of Bookmarks known by JW Library
+const (
+	minColorIndex = 1
+	maxColorIndex = 6
+	maxSlot       = 9
+)
+
+// timestampLayout is the format of timestamps like Note.Created.
+const timestampLayout = "2006-01-02T15:04:05+00:00"
+
+// AddNote adds a Note with the given title and content to the Location and
+// assigns it the Tags with the given names. The Location is reused if the
+// Database already contains one with the same UniqueKey and created otherwise.
+// If location is nil, the Note isn't attached to any publication. Tags that
+// don't exist yet are created, and names given multiple times are only
+// assigned once. The returned Note can be adjusted afterwards,
+// for example to attach it to a verse using BlockType and BlockIdentifier.
+func (db *Database) AddNote(location *Location, title string, content string, tags []string) (*Note, error) {
+	if db == nil {
+		return nil, errors.New("Database is nil")
+	}
+	if title == "" && content == "" {
+		return nil, errors.New("Note needs a title or content")
+	}
+	for _, name := range tags {
+		if strings.TrimSpace(name) == "" {
+			return nil, errors.New("Tag names must not be empty")
+		}
+	}
+
+	note := &Note{
+		GUID:    newGUID(),
+		Title:   sql.NullString{String: title, Valid: true},
+		Content: sql.NullString{String: content, Valid: true},
+	}
+	if location != nil {
+		loc, err := db.addLocation(location)
+		if err != nil {
+			return nil, err
+		}
+		note.LocationID = sql.NullInt32{Int32: int32(loc.LocationID), Valid: true}
+	}
+
+	now := time.Now().UTC()
+	note.Created = now.Format(timestampLayout)
+	note.LastModified = note.Created
+	note.NoteID = nextID(&db.Note)
+	db.Note = append(db.Note, note)
+
+	assigned := make(map[string]bool, len(tags))
+	for _, name := range tags {
+		if assigned[name] {
+			continue
+		}
+		assigned[name] = true
+
+		tag := db.addTag(name)
+		tagMap := &TagMap{
+			NoteID:   sql.NullInt32{Int32: int32(note.NoteID), Valid: true},
+			TagID:    tag.TagID,
+			Position: db.nextTagPosition(tag.TagID),
+		}
+		tagMap.TagMapID = nextID(&db.TagMap)
+		db.TagMap = append(db.TagMap, tagMap)
+	}
+	db.LastModified = now
+
+	return note, nil
+}
+
+// AddHighlight adds a UserMark with the given color that highlights
+// the given BlockRanges within the Location. The Location is reused if
+// the Database already contains one with the same UniqueKey and created
+// otherwise. The IDs of the BlockRanges are assigned automatically.
+func (db *Database) AddHighlight(location *Location, ranges []*BlockRange, colorIndex int) (*UserMark, error) {
+	if db == nil {
+		return nil, errors.New("Database is nil")
+	}
+	if location == nil {
+		return nil, errors.New("Highlight needs a location")
+	}
+	if location.LocationType != LocationTypeDocument {
+		return nil, errors.Errorf("Highlights can only be added to locations of type %d", LocationTypeDocument)
+	}
+	if colorIndex < minColorIndex || colorIndex > maxColorIndex {
+		return nil, errors.Errorf("ColorIndex %d is not between %d and %d", colorIndex, minColorIndex, maxColorIndex)
+	}
+	if len(ranges) == 0 {
+		return nil, errors.New("Highlight needs at least one BlockRange")
+	}
+	identifiers := make(map[int]bool, len(ranges))
+	for _, br := range ranges {
+		if br == nil {
+			return nil, errors.New("BlockRange must not be nil")
+		}
+		if br.BlockType != BlockTypeParagraph && br.BlockType != BlockTypeVerse {
+			return nil, errors.Errorf("BlockType %d of BlockRange is not supported", br.BlockType)
+		}
+		if br.BlockType != ranges[0].BlockType {
+			return nil, errors.New("All BlockRanges of a highlight must have the same BlockType")
+		}
+		if identifiers[br.Identifier] {
+			return nil, errors.Errorf("There are multiple BlockRanges for identifier %d", br.Identifier)
+		}
+		identifiers[br.Identifier] = true
+		if br.StartToken.Valid && br.EndToken.Valid && br.StartToken.Int32 > br.EndToken.Int32 {
+			return nil, errors.Errorf("StartToken of BlockRange for identifier %d is after its EndToken", br.Identifier)
+		}
+	}
+
+	loc, err := db.addLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	userMark := &UserMark{
+		ColorIndex:   colorIndex,
+		LocationID:   loc.LocationID,
+		UserMarkGUID: newGUID(),
+		Version:      1,
+	}
+	userMark.UserMarkID = nextID(&db.UserMark)
+	db.UserMark = append(db.UserMark, userMark)
+
+	for _, br := range ranges {
+		blockRange := *br
+		blockRange.UserMarkID = userMark.UserMarkID
+		blockRange.BlockRangeID = nextID(&db.BlockRange)
+		db.BlockRange = append(db.BlockRange, &blockRange)
+	}
+	db.LastModified = time.Now().UTC()
+
+	return userMark, nil
+}
+
+// AddBookmark adds a Bookmark with the given title to the Location, stored in
+// the given slot of its publication. The Location and the Location of the
+// publication are reused if the Database already contains ones with the same
+// UniqueKey and created otherwise. Each slot of a publication can only be
+// used by one Bookmark.
+func (db *Database) AddBookmark(location *Location, slot int, title string) (*Bookmark, error) {
+	if db == nil {
+		return nil, errors.New("Database is nil")
+	}
+	if location == nil {
+		return nil, errors.New("Bookmark needs a location")
+	}
+	if slot < 0 || slot > maxSlot {
+		return nil, errors.Errorf("Slot %d is not between 0 and %d", slot, maxSlot)
+	}
+	if title == "" {
+		return nil, errors.New("Bookmark needs a title")
+	}
+	if location.KeySymbol.String == "" {
+		return nil, errors.New("Bookmark needs a location with a KeySymbol")
+	}
+	if err := validateLocation(location); err != nil {
+		return nil, err
+	}
+
+	pubLocation, err := db.addLocation(&Location{
+		IssueTagNumber: location.IssueTagNumber,
+		KeySymbol:      location.KeySymbol,
+		MepsLanguage:   location.MepsLanguage,
+		LocationType:   LocationTypePublication,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, bm := range db.Bookmark {
+		if bm != nil && bm.PublicationLocationID == pubLocation.LocationID && bm.Slot == slot {
+			return nil, errors.Errorf("Slot %d of %s is already used by bookmark %d", slot, location.KeySymbol.String, bm.BookmarkID)
+		}
+	}
+	loc, err := db.addLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	bookmark := &Bookmark{
+		LocationID:            loc.LocationID,
+		PublicationLocationID: pubLocation.LocationID,
+		Slot:                  slot,
+		Title:                 title,
+	}
+	bookmark.BookmarkID = nextID(&db.Bookmark)
+	db.Bookmark = append(db.Bookmark, bookmark)
+	db.LastModified = time.Now().UTC()
+
+	return bookmark, nil
+}
+
+// addLocation returns the Location of the Database with the same UniqueKey
+// as location. If there is none, a copy of location is added to the Database.
+func (db *Database) addLocation(location *Location) (*Location, error) {
+	if err := validateLocation(location); err != nil {
+		return nil, err
+	}
+
+	key := location.UniqueKey()
+	for _, loc := range db.Location {
+		if loc != nil && loc.UniqueKey() == key {
+			return loc, nil
+		}
+	}
+
+	loc := *location
+	loc.LocationID = nextID(&db.Location)
+	db.Location = append(db.Location, &loc)
+	return &loc, nil
+}
+
+// validateLocation checks if location can be added to a Database. It mirrors
+// the CHECK constraints of the Location table, so the Database can be exported.
+func validateLocation(location *Location) error {
+	if !location.MepsLanguage.Valid {
+		return errors.New("Location needs a MepsLanguage")
+	}
+	if location.ChapterNumber.Valid && !location.BookNumber.Valid {
+		return errors.New("Location with a ChapterNumber needs a BookNumber")
+	}
+
+	hasBook := location.BookNumber.Valid && location.BookNumber.Int32 != 0
+	hasDocument := location.DocumentID.Valid && location.DocumentID.Int32 != 0
+	hasKeySymbol := location.KeySymbol.String != ""
+	switch location.LocationType {
+	case LocationTypeDocument:
+		switch {
+		case hasDocument:
+		case location.Track.Valid:
+			if !hasKeySymbol {
+				return errors.New("Location of a track needs a DocumentID or KeySymbol")
+			}
+		case hasBook:
+			if !hasKeySymbol {
+				return errors.New("Location of a Bible book or chapter needs a KeySymbol")
+			}
+		default:
+			return errors.New("Location of a document needs a DocumentID, BookNumber, or Track")
+		}
+	case LocationTypePublication:
+		if !hasKeySymbol {
+			return errors.New("Location of a publication needs a KeySymbol")
+		}
+		if hasBook || location.ChapterNumber.Valid && location.ChapterNumber.Int32 != 0 || hasDocument || location.Track.Valid {
+			return errors.New("Location of a publication must not have a BookNumber, ChapterNumber, DocumentID, or Track")
+		}
+	default:
+		return errors.Errorf("Type %d of location is not supported", location.LocationType)
+	}
+	return nil
+}
+
+// addTag returns the Tag of the user with the given name and creates it if
+// it doesn't exist yet.
+func (db *Database) addTag(name string) *Tag {
+	for _, tag := range db.Tag {
+		if tag != nil && tag.TagType == TagTypeUser && tag.Name == name {
+			return tag
+		}
+	}
+
+	tag := &Tag{TagType: TagTypeUser, Name: name}
+	tag.TagID = nextID(&db.Tag)
+	db.Tag = append(db.Tag, tag)
+	return tag
+}
+
+// nextTagPosition returns the Position for a new TagMap of the given
+// Tag, so it is added after all other entries of the Tag.
+func (db *Database) nextTagPosition(tagID int) int {
+	position := 0
+	for _, tm := range db.TagMap {
+		if tm != nil && tm.TagID == tagID && tm.Position >= position {
+			position = tm.Position + 1
+		}
+	}
+	return position
+}
+
+// nextID returns the ID for a new entry of the given table. As the ID of an
+// entry equals its index, a nil placeholder is added to empty tables first.
+func nextID[T any](table *[]*T) int {
+	if len(*table) == 0 {
+		*table = append(*table, nil)
+	}
+	return len(*table)
+}
+
+// newGUID returns a random (version 4) UUID in the upper case
+// format used by JW Library.
+func newGUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
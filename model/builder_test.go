@@ -0,0 +1,398 @@
+package model
+
+import (
+	"bytes"
+	"database/sql"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var guidRegexp = regexp.MustCompile(`^[0-9A-F]{8}-[0-9A-F]{4}-4[0-9A-F]{3}-[89AB][0-9A-F]{3}-[0-9A-F]{12}$`)
+
+func TestDatabase_AddNote(t *testing.T) {
+	db := &Database{
+		Location: []*Location{nil, {
+			LocationID:    1,
+			BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+			ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+			KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+			MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+		}},
+		Tag: []*Tag{nil, {TagID: 1, TagType: TagTypeUser, Name: "Existing"}},
+		TagMap: []*TagMap{nil, {
+			TagMapID:   1,
+			LocationID: sql.NullInt32{Int32: 1, Valid: true},
+			TagID:      1,
+			Position:   3,
+		}},
+	}
+	location := &Location{
+		BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+		ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+		KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+		MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+	}
+
+	note, err := db.AddNote(location, "Title", "Content", []string{"Existing", "New"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, note.NoteID)
+	assert.Same(t, note, db.Note[1])
+	assert.Regexp(t, guidRegexp, note.GUID)
+	assert.Equal(t, sql.NullInt32{Int32: 1, Valid: true}, note.LocationID)
+	assert.Len(t, db.Location, 2)
+	assert.Equal(t, "Title", note.Title.String)
+	assert.Equal(t, "Content", note.Content.String)
+	assert.NotEmpty(t, note.Created)
+	assert.Equal(t, note.Created, note.LastModified)
+	assert.False(t, db.LastModified.IsZero())
+
+	assert.Equal(t, []*Tag{
+		nil,
+		{TagID: 1, TagType: TagTypeUser, Name: "Existing"},
+		{TagID: 2, TagType: TagTypeUser, Name: "New"},
+	}, db.Tag)
+	assert.Equal(t, &TagMap{TagMapID: 2, NoteID: sql.NullInt32{Int32: 1, Valid: true}, TagID: 1, Position: 4}, db.TagMap[2])
+	assert.Equal(t, &TagMap{TagMapID: 3, NoteID: sql.NullInt32{Int32: 1, Valid: true}, TagID: 2, Position: 0}, db.TagMap[3])
+
+	note, err = db.AddNote(nil, "", "Without location", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, note.NoteID)
+	assert.False(t, note.LocationID.Valid)
+	assert.NotEqual(t, db.Note[1].GUID, note.GUID)
+	assert.Len(t, db.TagMap, 4)
+
+	// Duplicate tag names are only assigned once
+	note, err = db.AddNote(nil, "", "Duplicate tags", []string{"New", "New"})
+	require.NoError(t, err)
+	assert.Len(t, db.TagMap, 5)
+	assert.Equal(t, &TagMap{TagMapID: 4, NoteID: sql.NullInt32{Int32: 3, Valid: true}, TagID: 2, Position: 1}, db.TagMap[4])
+}
+
+func TestDatabase_AddNote_invalid(t *testing.T) {
+	location := &Location{
+		BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+		ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+		KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+		MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+	}
+
+	tests := []struct {
+		name     string
+		location *Location
+		title    string
+		content  string
+		tags     []string
+	}{
+		{name: "Empty", location: location},
+		{name: "Empty tag", location: location, title: "Title", tags: []string{" "}},
+		{name: "Location without language", location: &Location{KeySymbol: sql.NullString{String: "w", Valid: true}}, title: "Title"},
+		{name: "Location without publication", location: &Location{MepsLanguage: sql.NullInt32{Valid: true}}, title: "Title"},
+		{name: "Chapter without book", location: &Location{
+			ChapterNumber: sql.NullInt32{Int32: 1, Valid: true},
+			KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+			MepsLanguage:  sql.NullInt32{Valid: true},
+		}, title: "Title"},
+		{name: "Chapter without KeySymbol", location: &Location{
+			BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+			ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+			MepsLanguage:  sql.NullInt32{Valid: true},
+		}, title: "Title"},
+		{name: "Document without DocumentID", location: &Location{
+			KeySymbol:    sql.NullString{String: "w", Valid: true},
+			MepsLanguage: sql.NullInt32{Valid: true},
+		}, title: "Title"},
+		{name: "Unknown location type", location: &Location{
+			KeySymbol:    sql.NullString{String: "w", Valid: true},
+			MepsLanguage: sql.NullInt32{Valid: true},
+			LocationType: 5,
+		}, title: "Title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &Database{}
+			_, err := db.AddNote(tt.location, tt.title, tt.content, tt.tags)
+			assert.Error(t, err)
+			assert.Empty(t, db.Note)
+			assert.Empty(t, db.Location)
+			assert.Empty(t, db.Tag)
+		})
+	}
+
+	var db *Database
+	_, err := db.AddNote(nil, "Title", "", nil)
+	assert.Error(t, err)
+}
+
+func TestDatabase_AddHighlight(t *testing.T) {
+	location := &Location{
+		BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+		ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+		KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+		MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+	}
+	db := &Database{}
+
+	ranges := []*BlockRange{
+		{BlockType: BlockTypeVerse, Identifier: 16, StartToken: sql.NullInt32{Int32: 0, Valid: true}, EndToken: sql.NullInt32{Int32: 10, Valid: true}},
+		{BlockType: BlockTypeVerse, Identifier: 17, StartToken: sql.NullInt32{Int32: 0, Valid: true}, EndToken: sql.NullInt32{Int32: 5, Valid: true}},
+	}
+	userMark, err := db.AddHighlight(location, ranges, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, userMark.UserMarkID)
+	assert.Equal(t, 1, userMark.LocationID)
+	assert.Equal(t, 2, userMark.ColorIndex)
+	assert.Equal(t, 1, userMark.Version)
+	assert.Regexp(t, guidRegexp, userMark.UserMarkGUID)
+	assert.Len(t, db.Location, 2)
+	assert.Equal(t, 1, db.Location[1].LocationID)
+
+	require.Len(t, db.BlockRange, 3)
+	assert.Equal(t, 1, db.BlockRange[1].BlockRangeID)
+	assert.Equal(t, 2, db.BlockRange[2].BlockRangeID)
+	assert.Equal(t, 1, db.BlockRange[2].UserMarkID)
+	assert.Equal(t, 17, db.BlockRange[2].Identifier)
+	// The given BlockRanges are copied
+	assert.Equal(t, 0, ranges[0].BlockRangeID)
+
+	umbr := &UserMarkBlockRange{UserMark: userMark, BlockRanges: db.BlockRange[1:]}
+	assert.Equal(t, "John 3:16-17", umbr.Reference(db))
+
+	// The Location is reused
+	userMark, err = db.AddHighlight(location, ranges[:1], 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, userMark.UserMarkID)
+	assert.Equal(t, 1, userMark.LocationID)
+	assert.Len(t, db.Location, 2)
+	assert.Len(t, db.BlockRange, 4)
+}
+
+func TestDatabase_AddHighlight_invalid(t *testing.T) {
+	location := &Location{
+		BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+		ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+		KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+		MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+	}
+	verse := func(identifier int) *BlockRange {
+		return &BlockRange{BlockType: BlockTypeVerse, Identifier: identifier}
+	}
+	publicationLocation := *location
+	publicationLocation.LocationType = LocationTypePublication
+
+	tests := []struct {
+		name       string
+		location   *Location
+		ranges     []*BlockRange
+		colorIndex int
+	}{
+		{name: "No location", ranges: []*BlockRange{verse(1)}, colorIndex: 1},
+		{name: "Publication location", location: &publicationLocation, ranges: []*BlockRange{verse(1)}, colorIndex: 1},
+		{name: "Color too low", location: location, ranges: []*BlockRange{verse(1)}, colorIndex: 0},
+		{name: "Color too high", location: location, ranges: []*BlockRange{verse(1)}, colorIndex: 7},
+		{name: "No ranges", location: location, colorIndex: 1},
+		{name: "Nil range", location: location, ranges: []*BlockRange{nil}, colorIndex: 1},
+		{name: "Unknown BlockType", location: location, ranges: []*BlockRange{{BlockType: 3}}, colorIndex: 1},
+		{name: "Mixed BlockTypes", location: location, ranges: []*BlockRange{
+			verse(1), {BlockType: BlockTypeParagraph, Identifier: 2},
+		}, colorIndex: 1},
+		{name: "Duplicate identifier", location: location, ranges: []*BlockRange{verse(1), verse(1)}, colorIndex: 1},
+		{name: "StartToken after EndToken", location: location, ranges: []*BlockRange{{
+			BlockType:  BlockTypeVerse,
+			Identifier: 1,
+			StartToken: sql.NullInt32{Int32: 5, Valid: true},
+			EndToken:   sql.NullInt32{Int32: 4, Valid: true},
+		}}, colorIndex: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &Database{}
+			_, err := db.AddHighlight(tt.location, tt.ranges, tt.colorIndex)
+			assert.Error(t, err)
+			assert.Empty(t, db.UserMark)
+			assert.Empty(t, db.BlockRange)
+			assert.Empty(t, db.Location)
+		})
+	}
+}
+
+func TestDatabase_AddBookmark(t *testing.T) {
+	location := &Location{
+		BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+		ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+		KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+		MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+	}
+	db := &Database{}
+
+	bookmark, err := db.AddBookmark(location, 3, "John 3")
+	require.NoError(t, err)
+	assert.Equal(t, &Bookmark{
+		BookmarkID:            1,
+		LocationID:            2,
+		PublicationLocationID: 1,
+		Slot:                  3,
+		Title:                 "John 3",
+	}, bookmark)
+	assert.Equal(t, &Location{
+		LocationID:   1,
+		KeySymbol:    sql.NullString{String: "nwtsty", Valid: true},
+		MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+		LocationType: LocationTypePublication,
+	}, db.Location[1])
+	assert.Equal(t, "John 3", db.Location[2].Reference())
+
+	_, err = db.AddBookmark(location, 3, "Slot already used")
+	assert.Error(t, err)
+	assert.Len(t, db.Bookmark, 2)
+
+	other := *location
+	other.ChapterNumber.Int32 = 4
+	bookmark, err = db.AddBookmark(&other, 4, "John 4")
+	require.NoError(t, err)
+	assert.Equal(t, 2, bookmark.BookmarkID)
+	assert.Equal(t, 1, bookmark.PublicationLocationID)
+	assert.Equal(t, 3, bookmark.LocationID)
+	assert.Len(t, db.Location, 4)
+
+	_, err = db.AddBookmark(nil, 0, "Title")
+	assert.Error(t, err)
+	_, err = db.AddBookmark(location, 10, "Title")
+	assert.Error(t, err)
+	_, err = db.AddBookmark(location, 0, "")
+	assert.Error(t, err)
+	_, err = db.AddBookmark(&Location{DocumentID: sql.NullInt32{Int32: 1, Valid: true}, MepsLanguage: sql.NullInt32{Valid: true}}, 0, "Title")
+	assert.Error(t, err)
+}
+
+func TestDatabase_Add_export(t *testing.T) {
+	location := &Location{
+		BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+		ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+		KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+		MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+	}
+	db := &Database{}
+	userMark, err := db.AddHighlight(location, []*BlockRange{{BlockType: BlockTypeVerse, Identifier: 16}}, 1)
+	require.NoError(t, err)
+	note, err := db.AddNote(location, "Title", "Content", []string{"Tag", "Tag"})
+	require.NoError(t, err)
+	note.UserMarkID = sql.NullInt32{Int32: int32(userMark.UserMarkID), Valid: true}
+	note.BlockType = BlockTypeVerse
+	note.BlockIdentifier = sql.NullInt32{Int32: 16, Valid: true}
+	_, err = db.AddBookmark(location, 0, "John 3")
+	require.NoError(t, err)
+	_, err = db.AddNote(&Location{
+		DocumentID:   sql.NullInt32{Int32: 1102002020, Valid: true},
+		MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+	}, "Document", "", nil)
+	require.NoError(t, err)
+	_, err = db.AddNote(&Location{
+		KeySymbol:    sql.NullString{String: "w", Valid: true},
+		MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+		LocationType: LocationTypePublication,
+	}, "Publication", "", nil)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "backup.jwlibrary")
+	require.NoError(t, db.ExportJWLBackup(path))
+
+	imported := &Database{}
+	require.NoError(t, imported.ImportJWLBackup(path))
+	assert.Equal(t, "John 3:16", imported.Note[1].Reference(imported))
+	assert.Len(t, imported.UserMark, 2)
+	assert.Len(t, imported.BlockRange, 2)
+	assert.Len(t, imported.Bookmark, 2)
+	assert.Len(t, imported.TagMap, 2)
+	assert.Equal(t, "Tag", imported.Tag[1].Name)
+	assert.Len(t, imported.Note, 4)
+	assert.Len(t, imported.Location, 5)
+}
+
+func Test_validateLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		location Location
+		wantErr  bool
+	}{
+		{name: "Document", location: Location{
+			DocumentID:   sql.NullInt32{Int32: 1102002020, Valid: true},
+			MepsLanguage: sql.NullInt32{Valid: true},
+		}},
+		{name: "Bible book", location: Location{
+			BookNumber:   sql.NullInt32{Int32: 43, Valid: true},
+			KeySymbol:    sql.NullString{String: "nwtsty", Valid: true},
+			MepsLanguage: sql.NullInt32{Valid: true},
+		}},
+		{name: "Bible chapter", location: Location{
+			BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+			ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+			KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+			MepsLanguage:  sql.NullInt32{Valid: true},
+		}},
+		{name: "Track", location: Location{
+			Track:        sql.NullInt32{Int32: 1, Valid: true},
+			KeySymbol:    sql.NullString{String: "sjjm", Valid: true},
+			MepsLanguage: sql.NullInt32{Valid: true},
+		}},
+		{name: "Publication", location: Location{
+			IssueTagNumber: 20210200,
+			KeySymbol:      sql.NullString{String: "w", Valid: true},
+			MepsLanguage:   sql.NullInt32{Valid: true},
+			LocationType:   LocationTypePublication,
+		}},
+		{name: "Document with KeySymbol only", location: Location{
+			KeySymbol:    sql.NullString{String: "w", Valid: true},
+			MepsLanguage: sql.NullInt32{Valid: true},
+		}, wantErr: true},
+		{name: "Bible book without KeySymbol", location: Location{
+			BookNumber:   sql.NullInt32{Int32: 43, Valid: true},
+			MepsLanguage: sql.NullInt32{Valid: true},
+		}, wantErr: true},
+		{name: "Bible chapter without KeySymbol", location: Location{
+			BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+			ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+			MepsLanguage:  sql.NullInt32{Valid: true},
+		}, wantErr: true},
+		{name: "Track without KeySymbol", location: Location{
+			Track:        sql.NullInt32{Int32: 1, Valid: true},
+			MepsLanguage: sql.NullInt32{Valid: true},
+		}, wantErr: true},
+		{name: "Publication without KeySymbol", location: Location{
+			IssueTagNumber: 20210200,
+			MepsLanguage:   sql.NullInt32{Valid: true},
+			LocationType:   LocationTypePublication,
+		}, wantErr: true},
+		{name: "Publication with DocumentID", location: Location{
+			DocumentID:   sql.NullInt32{Int32: 1102002020, Valid: true},
+			KeySymbol:    sql.NullString{String: "cl", Valid: true},
+			MepsLanguage: sql.NullInt32{Valid: true},
+			LocationType: LocationTypePublication,
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLocation(&tt.location)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			// The validation has to match the constraints of the schema
+			location := tt.location
+			location.LocationID = 1
+			db := &Database{Location: []*Location{nil, &location}}
+			var buf bytes.Buffer
+			err = db.ExportJWLBackupTo(&buf)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
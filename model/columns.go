@@ -0,0 +1,188 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// columnTag is the struct tag that maps a field of a Model to the
+// name of its column in the SQLite database. Fields without it are
+// not stored in the database.
+const columnTag = "sql"
+
+// SchemaMismatchError is returned if the columns of a table in the SQLite
+// database don't match the ones of the corresponding Model, so entries
+// can't be read or written without losing data.
+type SchemaMismatchError struct {
+	Table string
+	// Missing are columns the Model needs, but that don't exist in the table.
+	Missing []string
+	// Unknown are columns of the table that need a value, which
+	// the Model is not able to provide.
+	Unknown []string
+}
+
+func (e *SchemaMismatchError) Error() string {
+	var problems []string
+	if len(e.Missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing columns %s", strings.Join(e.Missing, ", ")))
+	}
+	if len(e.Unknown) > 0 {
+		problems = append(problems, fmt.Sprintf("unknown required columns %s", strings.Join(e.Unknown, ", ")))
+	}
+	return fmt.Sprintf("schema of table %s is incompatible: %s", e.Table, strings.Join(problems, "; "))
+}
+
+// modelColumn maps a field (by its index) of a Model to a column.
+type modelColumn struct {
+	field int
+	name  string
+}
+
+// modelColumnsCache caches the modelColumns of each Model type,
+// so we don't need to parse the struct tags for every entry.
+var modelColumnsCache sync.Map
+
+// modelColumns returns the columns of the given Model in the order
+// of the fields of its struct.
+func modelColumns(m Model) []modelColumn {
+	tp := reflect.TypeOf(m).Elem()
+	if cached, ok := modelColumnsCache.Load(tp); ok {
+		return cached.([]modelColumn)
+	}
+
+	columns := []modelColumn{}
+	for i := 0; i < tp.NumField(); i++ {
+		name, ok := tp.Field(i).Tag.Lookup(columnTag)
+		if !ok {
+			continue
+		}
+		columns = append(columns, modelColumn{field: i, name: name})
+	}
+
+	modelColumnsCache.Store(tp, columns)
+	return columns
+}
+
+// columnNames returns the names of the columns of the given Model.
+func columnNames(m Model) []string {
+	columns := modelColumns(m)
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+	return names
+}
+
+// columnPointers returns pointers to the fields of the given Model
+// in the order of columnNames, so they can be used for scanning rows.
+func columnPointers(m Model) []interface{} {
+	value := reflect.ValueOf(m).Elem()
+	columns := modelColumns(m)
+	pointers := make([]interface{}, len(columns))
+	for i, column := range columns {
+		pointers[i] = value.Field(column.field).Addr().Interface()
+	}
+	return pointers
+}
+
+// columnValues returns the values of the fields of the given Model
+// in the order of columnNames, so they can be used for inserting rows.
+func columnValues(m Model) []interface{} {
+	value := reflect.ValueOf(m).Elem()
+	columns := modelColumns(m)
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = value.Field(column.field).Interface()
+	}
+	return values
+}
+
+// scanColumns scans the current row into the given Model. The
+// row is expected to contain the columns in the order of columnNames.
+func scanColumns(rows *sql.Rows, m Model) error {
+	return rows.Scan(columnPointers(m)...)
+}
+
+// tableColumn describes a column of a table in the SQLite database.
+type tableColumn struct {
+	name       string
+	notNull    bool
+	hasDefault bool
+	primaryKey bool
+}
+
+// tableColumns returns the columns of the given table in the SQLite database.
+func tableColumns(sqlite *sql.DB, tableName string) ([]tableColumn, error) {
+	rows, err := sqlite.Query("SELECT name, \"notnull\", dflt_value IS NOT NULL, pk FROM pragma_table_info(?)", tableName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while fetching columns of table %s", tableName)
+	}
+	defer rows.Close()
+
+	result := []tableColumn{}
+	for rows.Next() {
+		var column tableColumn
+		var pk int
+		if err := rows.Scan(&column.name, &column.notNull, &column.hasDefault, &pk); err != nil {
+			return nil, errors.Wrapf(err, "Error while scanning columns of table %s", tableName)
+		}
+		column.primaryKey = pk > 0
+		result = append(result, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(err, "Error while scanning columns of table %s", tableName)
+	}
+
+	return result, nil
+}
+
+// checkColumns checks if the table of the given Model in the SQLite database has
+// all columns the Model needs. If forInsert is true, it also makes sure that the
+// table doesn't contain columns we can't provide a value for when inserting entries.
+// If they don't match, a *SchemaMismatchError is returned.
+func checkColumns(sqlite *sql.DB, m Model, forInsert bool) error {
+	columns, err := tableColumns(sqlite, m.tableName())
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]tableColumn, len(columns))
+	for _, column := range columns {
+		existing[strings.ToLower(column.name)] = column
+	}
+
+	mismatch := &SchemaMismatchError{Table: m.tableName()}
+	known := map[string]bool{}
+	for _, name := range columnNames(m) {
+		known[strings.ToLower(name)] = true
+		if _, ok := existing[strings.ToLower(name)]; !ok {
+			mismatch.Missing = append(mismatch.Missing, name)
+		}
+	}
+
+	if forInsert {
+		for _, column := range columns {
+			if known[strings.ToLower(column.name)] {
+				continue
+			}
+			// An INTEGER PRIMARY KEY is filled by SQLite itself
+			if column.notNull && !column.hasDefault && !column.primaryKey {
+				mismatch.Unknown = append(mismatch.Unknown, column.name)
+			}
+		}
+	}
+
+	if len(mismatch.Missing) == 0 && len(mismatch.Unknown) == 0 {
+		return nil
+	}
+	sort.Strings(mismatch.Missing)
+	sort.Strings(mismatch.Unknown)
+	return mismatch
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_columnNames(t *testing.T) {
+	assert.Equal(t,
+		[]string{"LocationId", "BookNumber", "ChapterNumber", "DocumentId", "Track",
+			"IssueTagNumber", "KeySymbol", "MepsLanguage", "Type", "Title"},
+		columnNames(&Location{}))
+	assert.Equal(t, []string{"TagId", "Type", "Name"}, columnNames(&Tag{}))
+	// pseudoID is not stored in the database
+	assert.Equal(t, []string{"LocationId", "TextTag", "Value"}, columnNames(&InputField{}))
+}
+
+func Test_columnValuesAndPointers(t *testing.T) {
+	tag := &Tag{TagID: 1, TagType: 2, Name: "Name"}
+	assert.Equal(t, []interface{}{1, 2, "Name"}, columnValues(tag))
+
+	other := &Tag{}
+	pointers := columnPointers(other)
+	*pointers[0].(*int) = 1
+	*pointers[1].(*int) = 2
+	*pointers[2].(*string) = "Name"
+	assert.Equal(t, tag, other)
+}
+
+func Test_checkColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mismatch.db")
+	sqlite, err := sql.Open("sqlite3", path)
+	assert.NoError(t, err)
+	defer sqlite.Close()
+
+	_, err = sqlite.Exec("CREATE TABLE Tag (TagId INTEGER NOT NULL PRIMARY KEY, Name TEXT NOT NULL, Extra TEXT NOT NULL)")
+	assert.NoError(t, err)
+	_, err = sqlite.Exec("CREATE TABLE UserMark (Version INTEGER NOT NULL, UserMarkGuid TEXT NOT NULL, StyleIndex INTEGER NOT NULL, " +
+		"LocationId INTEGER NOT NULL, ColorIndex INTEGER NOT NULL, UserMarkId INTEGER NOT NULL PRIMARY KEY, Extra TEXT NOT NULL DEFAULT '')")
+	assert.NoError(t, err)
+
+	err = checkColumns(sqlite, &Tag{}, false)
+	var mismatch *SchemaMismatchError
+	assert.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, &SchemaMismatchError{Table: "Tag", Missing: []string{"Type"}}, mismatch)
+	assert.EqualError(t, err, "schema of table Tag is incompatible: missing columns Type")
+
+	err = checkColumns(sqlite, &Tag{}, true)
+	assert.EqualError(t, err, "schema of table Tag is incompatible: missing columns Type; unknown required columns Extra")
+
+	_, err = fetchFromSQLite(context.Background(), sqlite, &Tag{})
+	assert.True(t, errors.As(err, &mismatch))
+
+	// Reordered columns and additional columns with a default are fine
+	assert.NoError(t, checkColumns(sqlite, &UserMark{}, false))
+	assert.NoError(t, checkColumns(sqlite, &UserMark{}, true))
+
+	userMark := &UserMark{UserMarkID: 1, ColorIndex: 2, LocationID: 3, StyleIndex: 4, UserMarkGUID: "GUID", Version: 5}
+	assert.NoError(t, insertEntries(context.Background(), sqlite, []Model{userMark}))
+	result, err := fetchFromSQLite(context.Background(), sqlite, &UserMark{})
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, userMark, result[1])
+}
@@ -0,0 +1,179 @@
+package model
+
+// Compact removes orphaned entries from the Database and renumbers the IDs
+// of all tables densely, starting at 1 and keeping the order of the entries.
+// References between the tables are updated accordingly.
+//
+// Orphans are entries whose required references point to entries that don't
+// exist (anymore), like BlockRanges of a removed UserMark, as they are left
+// by merges or PurgeTables. This includes UserMarks without BlockRanges,
+// TagMaps without an entry they tag, and Locations that aren't referenced at
+// all. Notes are never removed, but only detached from a missing UserMark
+// or Location. Tags are kept, even if they aren't used.
+func (db *Database) Compact() {
+	if db == nil {
+		return
+	}
+
+	db.removeOrphans()
+
+	locIDChanges := compactTable(&db.Location)
+	UpdateIDs(db.Bookmark, "LocationID", locIDChanges)
+	UpdateIDs(db.Bookmark, "PublicationLocationID", locIDChanges)
+	UpdateIDs(db.InputField, "LocationID", locIDChanges)
+	UpdateIDs(db.Note, "LocationID", locIDChanges)
+	UpdateIDs(db.TagMap, "LocationID", locIDChanges)
+	UpdateIDs(db.UserMark, "LocationID", locIDChanges)
+
+	compactTable(&db.Bookmark)
+	compactTable(&db.InputField)
+	for i, inf := range db.InputField {
+		if inf != nil {
+			inf.pseudoID = i
+		}
+	}
+
+	tagIDChanges := compactTable(&db.Tag)
+	UpdateIDs(db.TagMap, "TagID", tagIDChanges)
+
+	umIDChanges := compactTable(&db.UserMark)
+	UpdateIDs(db.BlockRange, "UserMarkID", umIDChanges)
+	UpdateIDs(db.Note, "UserMarkID", umIDChanges)
+
+	compactTable(&db.BlockRange)
+
+	noteIDChanges := compactTable(&db.Note)
+	UpdateIDs(db.TagMap, "NoteID", noteIDChanges)
+
+	compactTable(&db.TagMap)
+}
+
+// removeOrphans removes all entries that reference entries which don't exist.
+// The tables are checked in order of their dependencies, so entries that
+// become orphans by removing others are removed as well.
+func (db *Database) removeOrphans() {
+	exists := func(table string, id int) bool {
+		return id > 0 && db.FetchFromTable(table, id) != nil
+	}
+
+	for i, um := range db.UserMark {
+		if um != nil && !exists("Location", um.LocationID) {
+			db.UserMark[i] = nil
+		}
+	}
+	withBlockRange := map[int]bool{}
+	for i, br := range db.BlockRange {
+		if br == nil {
+			continue
+		}
+		if !exists("UserMark", br.UserMarkID) {
+			db.BlockRange[i] = nil
+			continue
+		}
+		withBlockRange[br.UserMarkID] = true
+	}
+	for i, um := range db.UserMark {
+		if um != nil && !withBlockRange[um.UserMarkID] {
+			db.UserMark[i] = nil
+		}
+	}
+
+	for _, note := range db.Note {
+		if note == nil {
+			continue
+		}
+		if note.UserMarkID.Valid && !exists("UserMark", int(note.UserMarkID.Int32)) {
+			note.UserMarkID.Valid = false
+			note.UserMarkID.Int32 = 0
+		}
+		if note.LocationID.Valid && !exists("Location", int(note.LocationID.Int32)) {
+			note.LocationID.Valid = false
+			note.LocationID.Int32 = 0
+		}
+	}
+
+	for i, tm := range db.TagMap {
+		if tm == nil {
+			continue
+		}
+		tagsNote := tm.NoteID.Valid && exists("Note", int(tm.NoteID.Int32))
+		tagsLocation := tm.LocationID.Valid && exists("Location", int(tm.LocationID.Int32))
+		tagsPlaylistItem := tm.PlaylistItemID.Valid && tm.PlaylistItemID.Int32 != 0
+		if !exists("Tag", tm.TagID) || !(tagsNote || tagsLocation || tagsPlaylistItem) {
+			db.TagMap[i] = nil
+			continue
+		}
+		if tm.NoteID.Valid && !tagsNote {
+			tm.NoteID.Valid = false
+			tm.NoteID.Int32 = 0
+		}
+	}
+	for i, bm := range db.Bookmark {
+		if bm != nil && (!exists("Location", bm.LocationID) || !exists("Location", bm.PublicationLocationID)) {
+			db.Bookmark[i] = nil
+		}
+	}
+	for i, inf := range db.InputField {
+		if inf != nil && !exists("Location", inf.LocationID) {
+			db.InputField[i] = nil
+		}
+	}
+
+	referenced := map[int]bool{}
+	for _, um := range db.UserMark {
+		if um != nil {
+			referenced[um.LocationID] = true
+		}
+	}
+	for _, note := range db.Note {
+		if note != nil && note.LocationID.Valid {
+			referenced[int(note.LocationID.Int32)] = true
+		}
+	}
+	for _, tm := range db.TagMap {
+		if tm != nil && tm.LocationID.Valid {
+			referenced[int(tm.LocationID.Int32)] = true
+		}
+	}
+	for _, bm := range db.Bookmark {
+		if bm != nil {
+			referenced[bm.LocationID] = true
+			referenced[bm.PublicationLocationID] = true
+		}
+	}
+	for _, inf := range db.InputField {
+		if inf != nil {
+			referenced[inf.LocationID] = true
+		}
+	}
+	for i, loc := range db.Location {
+		if loc != nil && !referenced[loc.LocationID] {
+			db.Location[i] = nil
+		}
+	}
+}
+
+// compactTable removes all nil entries from the table except for the
+// placeholder at index 0 and sets the ID of each entry to its index.
+// It returns the changed IDs as a map of old ID to new ID.
+func compactTable[T any, PT interface {
+	*T
+	Model
+}](table *[]PT) map[int]int {
+	changes := map[int]int{}
+
+	result := make([]PT, 1, max(len(*table), 1))
+	for _, entry := range *table {
+		if entry == nil {
+			continue
+		}
+		if id := len(result); entry.ID() != id {
+			changes[entry.ID()] = id
+			entry.SetID(id)
+		}
+		result = append(result, entry)
+	}
+	*table = result
+
+	return changes
+}
@@ -0,0 +1,244 @@
+package model
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabase_Compact(t *testing.T) {
+	db := &Database{
+		BlockRange: []*BlockRange{
+			nil,
+			{BlockRangeID: 1, BlockType: 2, Identifier: 16, UserMarkID: 2},
+			nil,
+			{BlockRangeID: 3, BlockType: 2, Identifier: 1, UserMarkID: 9},
+			{BlockRangeID: 4, BlockType: 2, Identifier: 17, UserMarkID: 2},
+		},
+		Bookmark: []*Bookmark{
+			nil,
+			nil,
+			{BookmarkID: 2, LocationID: 2, PublicationLocationID: 3, Slot: 0, Title: "John 3"},
+			{BookmarkID: 3, LocationID: 2, PublicationLocationID: 4, Slot: 1, Title: "Missing publication"},
+		},
+		InputField: []*InputField{
+			nil,
+			{LocationID: 5, TextTag: "tt1", Value: "Value", pseudoID: 1},
+			{LocationID: 8, TextTag: "tt2", Value: "Missing location", pseudoID: 2},
+		},
+		Location: []*Location{
+			nil,
+			nil,
+			{
+				LocationID:    2,
+				BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+				KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+			},
+			{
+				LocationID:   3,
+				KeySymbol:    sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+				LocationType: 1,
+			},
+			nil,
+			{
+				LocationID:   5,
+				DocumentID:   sql.NullInt32{Int32: 1102021811, Valid: true},
+				KeySymbol:    sql.NullString{String: "lffi", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+			},
+			{
+				LocationID:   6,
+				KeySymbol:    sql.NullString{String: "unreferenced", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+			},
+		},
+		Note: []*Note{
+			nil,
+			nil,
+			{
+				NoteID:     2,
+				GUID:       "NOTE-2",
+				UserMarkID: sql.NullInt32{Int32: 2, Valid: true},
+				LocationID: sql.NullInt32{Int32: 2, Valid: true},
+				Title:      sql.NullString{String: "With highlight", Valid: true},
+			},
+			{
+				NoteID:     3,
+				GUID:       "NOTE-3",
+				UserMarkID: sql.NullInt32{Int32: 3, Valid: true},
+				LocationID: sql.NullInt32{Int32: 5, Valid: true},
+				Title:      sql.NullString{String: "With empty highlight", Valid: true},
+			},
+			{
+				NoteID:     4,
+				GUID:       "NOTE-4",
+				LocationID: sql.NullInt32{Int32: 7, Valid: true},
+				Title:      sql.NullString{String: "Missing location", Valid: true},
+			},
+		},
+		Tag: []*Tag{
+			nil,
+			nil,
+			{TagID: 2, TagType: 1, Name: "Tag"},
+		},
+		TagMap: []*TagMap{
+			nil,
+			{TagMapID: 1, NoteID: sql.NullInt32{Int32: 2, Valid: true}, TagID: 2, Position: 0},
+			{TagMapID: 2, NoteID: sql.NullInt32{Int32: 9, Valid: true}, TagID: 2, Position: 1},
+			{TagMapID: 3, NoteID: sql.NullInt32{Int32: 2, Valid: true}, TagID: 1, Position: 0},
+			{TagMapID: 4, LocationID: sql.NullInt32{Int32: 5, Valid: true}, TagID: 2, Position: 2},
+			{TagMapID: 5, NoteID: sql.NullInt32{Int32: 9, Valid: true}, LocationID: sql.NullInt32{Int32: 2, Valid: true}, TagID: 2, Position: 3},
+		},
+		UserMark: []*UserMark{
+			nil,
+			nil,
+			{UserMarkID: 2, ColorIndex: 1, LocationID: 2, UserMarkGUID: "UM-2", Version: 1},
+			{UserMarkID: 3, ColorIndex: 2, LocationID: 5, UserMarkGUID: "UM-3", Version: 1},
+			{UserMarkID: 4, ColorIndex: 3, LocationID: 4, UserMarkGUID: "UM-4", Version: 1},
+		},
+	}
+	db.Compact()
+
+	want := &Database{
+		BlockRange: []*BlockRange{
+			nil,
+			{BlockRangeID: 1, BlockType: 2, Identifier: 16, UserMarkID: 1},
+			{BlockRangeID: 2, BlockType: 2, Identifier: 17, UserMarkID: 1},
+		},
+		Bookmark: []*Bookmark{
+			nil,
+			{BookmarkID: 1, LocationID: 1, PublicationLocationID: 2, Slot: 0, Title: "John 3"},
+		},
+		InputField: []*InputField{
+			nil,
+			{LocationID: 3, TextTag: "tt1", Value: "Value", pseudoID: 1},
+		},
+		Location: []*Location{
+			nil,
+			{
+				LocationID:    1,
+				BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+				KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+			},
+			{
+				LocationID:   2,
+				KeySymbol:    sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+				LocationType: 1,
+			},
+			{
+				LocationID:   3,
+				DocumentID:   sql.NullInt32{Int32: 1102021811, Valid: true},
+				KeySymbol:    sql.NullString{String: "lffi", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+			},
+		},
+		Note: []*Note{
+			nil,
+			{
+				NoteID:     1,
+				GUID:       "NOTE-2",
+				UserMarkID: sql.NullInt32{Int32: 1, Valid: true},
+				LocationID: sql.NullInt32{Int32: 1, Valid: true},
+				Title:      sql.NullString{String: "With highlight", Valid: true},
+			},
+			{
+				NoteID:     2,
+				GUID:       "NOTE-3",
+				LocationID: sql.NullInt32{Int32: 3, Valid: true},
+				Title:      sql.NullString{String: "With empty highlight", Valid: true},
+			},
+			{
+				NoteID: 3,
+				GUID:   "NOTE-4",
+				Title:  sql.NullString{String: "Missing location", Valid: true},
+			},
+		},
+		Tag: []*Tag{
+			nil,
+			{TagID: 1, TagType: 1, Name: "Tag"},
+		},
+		TagMap: []*TagMap{
+			nil,
+			{TagMapID: 1, NoteID: sql.NullInt32{Int32: 1, Valid: true}, TagID: 1, Position: 0},
+			{TagMapID: 2, LocationID: sql.NullInt32{Int32: 3, Valid: true}, TagID: 1, Position: 2},
+			{TagMapID: 3, LocationID: sql.NullInt32{Int32: 1, Valid: true}, TagID: 1, Position: 3},
+		},
+		UserMark: []*UserMark{
+			nil,
+			{UserMarkID: 1, ColorIndex: 1, LocationID: 1, UserMarkGUID: "UM-2", Version: 1},
+		},
+	}
+	assert.Equal(t, want, db)
+
+	// Compacting again doesn't change anything
+	db.Compact()
+	assert.Equal(t, want, db)
+
+	var nilDB *Database
+	assert.NotPanics(t, nilDB.Compact)
+}
+
+func TestDatabase_Compact_empty(t *testing.T) {
+	db := &Database{}
+	db.Compact()
+	assert.Equal(t, []*Location{nil}, db.Location)
+	assert.Equal(t, []*Note{nil}, db.Note)
+}
+
+func TestDatabase_ExportJWLBackup_compact(t *testing.T) {
+	db := &Database{
+		BlockRange: []*BlockRange{nil, nil, {BlockRangeID: 2, BlockType: 2, Identifier: 16, UserMarkID: 2}},
+		Location: []*Location{
+			nil,
+			nil,
+			{
+				LocationID:    2,
+				BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+				KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+			},
+			{
+				LocationID:   3,
+				KeySymbol:    sql.NullString{String: "unreferenced", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+			},
+		},
+		Note: []*Note{nil, nil, {
+			NoteID:          2,
+			GUID:            "NOTE-2",
+			UserMarkID:      sql.NullInt32{Int32: 2, Valid: true},
+			LocationID:      sql.NullInt32{Int32: 2, Valid: true},
+			Title:           sql.NullString{String: "With highlight", Valid: true},
+			BlockType:       2,
+			BlockIdentifier: sql.NullInt32{Int32: 16, Valid: true},
+		}},
+		UserMark: []*UserMark{nil, nil, {UserMarkID: 2, ColorIndex: 1, LocationID: 2, UserMarkGUID: "UM-2", Version: 1}},
+	}
+	path := filepath.Join(t.TempDir(), "backup.jwlibrary")
+	require.NoError(t, db.ExportJWLBackupWithOptions(path, ExportOptions{Compact: true}))
+
+	// The exported Database is left unchanged
+	assert.Len(t, db.Location, 4)
+	assert.Equal(t, 2, db.Note[2].NoteID)
+	assert.Equal(t, 2, db.UserMark[2].LocationID)
+
+	imported := &Database{}
+	require.NoError(t, imported.ImportJWLBackup(path))
+	assert.Len(t, imported.Location, 2)
+	assert.Len(t, imported.Note, 2)
+	assert.Equal(t, 1, imported.Note[1].NoteID)
+	assert.Equal(t, int32(1), imported.Note[1].UserMarkID.Int32)
+	assert.Equal(t, int32(1), imported.Note[1].LocationID.Int32)
+	assert.Equal(t, 1, imported.BlockRange[1].UserMarkID)
+	assert.Equal(t, "John 3:16", imported.Note[1].Reference(imported))
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrHasDependents is returned by Database.Delete if the entry is
+// still referenced by other entries and cascade is not set.
+var ErrHasDependents = errors.New("entry is referenced by other entries")
+
+// Dependents returns all entries of the Database that directly reference
+// the given one, like the BlockRanges and Notes of a UserMark or the TagMaps
+// of a Tag. Together with RelatedEntries, which returns the entries a model
+// references, they describe the relationships between all entries.
+func (db *Database) Dependents(m Model) []Model {
+	if db == nil || m == nil {
+		return nil
+	}
+
+	var result []Model
+	switch m := m.(type) {
+	case *Location:
+		for _, um := range db.UserMark {
+			if um != nil && um.LocationID == m.LocationID {
+				result = append(result, um)
+			}
+		}
+		for _, note := range db.Note {
+			if note != nil && note.LocationID.Valid && int(note.LocationID.Int32) == m.LocationID {
+				result = append(result, note)
+			}
+		}
+		for _, bm := range db.Bookmark {
+			if bm != nil && (bm.LocationID == m.LocationID || bm.PublicationLocationID == m.LocationID) {
+				result = append(result, bm)
+			}
+		}
+		for _, tm := range db.TagMap {
+			if tm != nil && tm.LocationID.Valid && int(tm.LocationID.Int32) == m.LocationID {
+				result = append(result, tm)
+			}
+		}
+		for _, inf := range db.InputField {
+			if inf != nil && inf.LocationID == m.LocationID {
+				result = append(result, inf)
+			}
+		}
+	case *UserMarkBlockRange:
+		if m.UserMark != nil {
+			return db.Dependents(m.UserMark)
+		}
+	case *UserMark:
+		for _, br := range db.BlockRange {
+			if br != nil && br.UserMarkID == m.UserMarkID {
+				result = append(result, br)
+			}
+		}
+		for _, note := range db.Note {
+			if note != nil && note.UserMarkID.Valid && int(note.UserMarkID.Int32) == m.UserMarkID {
+				result = append(result, note)
+			}
+		}
+	case *Note:
+		for _, tm := range db.TagMap {
+			if tm != nil && tm.NoteID.Valid && int(tm.NoteID.Int32) == m.NoteID {
+				result = append(result, tm)
+			}
+		}
+	case *Tag:
+		for _, tm := range db.TagMap {
+			if tm != nil && tm.TagID == m.TagID {
+				result = append(result, tm)
+			}
+		}
+	}
+
+	return result
+}
+
+// Delete removes the given entry from the Database. If other entries still
+// reference it, ErrHasDependents is returned, unless cascade is set. Then
+// all of them are removed as well: the BlockRanges of a UserMark (while Notes
+// belonging to it are only detached from it), the TagMaps of a Note or Tag,
+// and all entries of a Location. Afterwards, Locations that were referenced by
+// removed entries and aren't referenced anymore are removed, too.
+func (db *Database) Delete(m Model, cascade bool) error {
+	if db == nil {
+		return errors.New("Database is nil")
+	}
+	if umbr, ok := m.(*UserMarkBlockRange); ok {
+		m = umbr.UserMark
+	}
+	if m == nil || reflect.ValueOf(m).IsNil() {
+		return errors.New("Entry is nil")
+	}
+
+	var entry Model
+	if m.ID() > 0 {
+		entry = db.FetchFromTable(m.tableName(), m.ID())
+	}
+	if entry == nil || entry.UniqueKey() != m.UniqueKey() {
+		return errors.Errorf("%s with ID %d does not exist in Database", m.tableName(), m.ID())
+	}
+	if dependents := db.Dependents(entry); len(dependents) > 0 && !cascade {
+		return errors.Wrapf(ErrHasDependents, "%s with ID %d is referenced by %d entries", m.tableName(), m.ID(), len(dependents))
+	}
+
+	locations := map[int]bool{}
+	db.delete(entry, locations)
+	for id := range locations {
+		if location := db.FetchFromTable("Location", id); location != nil && len(db.Dependents(location)) == 0 {
+			db.Location[id] = nil
+		}
+	}
+	db.LastModified = time.Now().UTC()
+
+	return nil
+}
+
+// delete removes entry and its dependents from the Database and collects
+// the IDs of the Locations referenced by removed entries in locations.
+func (db *Database) delete(entry Model, locations map[int]bool) {
+	for _, dependent := range db.Dependents(entry) {
+		if note, ok := dependent.(*Note); ok {
+			if _, ok := entry.(*UserMark); ok {
+				note.UserMarkID.Valid = false
+				note.UserMarkID.Int32 = 0
+				continue
+			}
+		}
+		db.delete(dependent, locations)
+	}
+
+	switch entry := entry.(type) {
+	case *UserMark:
+		locations[entry.LocationID] = true
+	case *Note:
+		if entry.LocationID.Valid {
+			locations[int(entry.LocationID.Int32)] = true
+		}
+	case *Bookmark:
+		locations[entry.LocationID] = true
+		locations[entry.PublicationLocationID] = true
+	case *TagMap:
+		if entry.LocationID.Valid {
+			locations[int(entry.LocationID.Int32)] = true
+		}
+	case *InputField:
+		locations[entry.LocationID] = true
+	}
+
+	table := reflect.ValueOf(db).Elem().FieldByName(entry.tableName())
+	table.Index(entry.ID()).Set(reflect.Zero(table.Type().Elem()))
+}
@@ -0,0 +1,392 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabase_Dependents(t *testing.T) {
+	db := &Database{
+		BlockRange: []*BlockRange{
+			nil,
+			{BlockRangeID: 1, BlockType: 2, Identifier: 16, UserMarkID: 1},
+			{BlockRangeID: 2, BlockType: 2, Identifier: 17, UserMarkID: 1},
+			{BlockRangeID: 3, BlockType: 1, Identifier: 4, UserMarkID: 2},
+		},
+		Bookmark: []*Bookmark{
+			nil,
+			{BookmarkID: 1, LocationID: 1, PublicationLocationID: 2, Slot: 0, Title: "John 3"},
+		},
+		InputField: []*InputField{
+			nil,
+			{LocationID: 3, TextTag: "tt1", Value: "Value", pseudoID: 1},
+		},
+		Location: []*Location{
+			nil,
+			{
+				LocationID:    1,
+				BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+				KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+			},
+			{
+				LocationID:   2,
+				KeySymbol:    sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+				LocationType: 1,
+			},
+			{
+				LocationID:   3,
+				DocumentID:   sql.NullInt32{Int32: 1102021811, Valid: true},
+				KeySymbol:    sql.NullString{String: "lffi", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+			},
+		},
+		Note: []*Note{
+			nil,
+			{
+				NoteID:          1,
+				GUID:            "NOTE-1",
+				UserMarkID:      sql.NullInt32{Int32: 1, Valid: true},
+				LocationID:      sql.NullInt32{Int32: 1, Valid: true},
+				Title:           sql.NullString{String: "First", Valid: true},
+				BlockType:       2,
+				BlockIdentifier: sql.NullInt32{Int32: 16, Valid: true},
+			},
+			{
+				NoteID:     2,
+				GUID:       "NOTE-2",
+				LocationID: sql.NullInt32{Int32: 3, Valid: true},
+				Title:      sql.NullString{String: "Second", Valid: true},
+			},
+		},
+		Tag: []*Tag{
+			nil,
+			{TagID: 1, TagType: 1, Name: "Tag"},
+		},
+		TagMap: []*TagMap{
+			nil,
+			{TagMapID: 1, NoteID: sql.NullInt32{Int32: 1, Valid: true}, TagID: 1, Position: 0},
+			{TagMapID: 2, LocationID: sql.NullInt32{Int32: 3, Valid: true}, TagID: 1, Position: 1},
+		},
+		UserMark: []*UserMark{
+			nil,
+			{UserMarkID: 1, ColorIndex: 1, LocationID: 1, UserMarkGUID: "UM-1", Version: 1},
+			{UserMarkID: 2, ColorIndex: 2, LocationID: 3, UserMarkGUID: "UM-2", Version: 1},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		model Model
+		want  []Model
+	}{
+		{
+			name:  "Location",
+			model: db.Location[3],
+			want:  []Model{db.UserMark[2], db.Note[2], db.TagMap[2], db.InputField[1]},
+		},
+		{
+			name:  "Publication Location",
+			model: db.Location[2],
+			want:  []Model{db.Bookmark[1]},
+		},
+		{
+			name:  "UserMark",
+			model: db.UserMark[1],
+			want:  []Model{db.BlockRange[1], db.BlockRange[2], db.Note[1]},
+		},
+		{
+			name:  "UserMarkBlockRange",
+			model: &UserMarkBlockRange{UserMark: db.UserMark[2]},
+			want:  []Model{db.BlockRange[3]},
+		},
+		{
+			name:  "Note",
+			model: db.Note[1],
+			want:  []Model{db.TagMap[1]},
+		},
+		{
+			name:  "Tag",
+			model: db.Tag[1],
+			want:  []Model{db.TagMap[1], db.TagMap[2]},
+		},
+		{
+			name:  "Without dependents",
+			model: db.Bookmark[1],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, db.Dependents(tt.model))
+		})
+	}
+
+	var nilDB *Database
+	assert.Nil(t, nilDB.Dependents(&Tag{TagID: 1}))
+}
+
+func TestDatabase_Delete(t *testing.T) {
+	verse := &Location{
+		LocationID:    1,
+		BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+		ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+		KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+		MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+	}
+	publication := &Location{
+		LocationID:   2,
+		KeySymbol:    sql.NullString{String: "nwtsty", Valid: true},
+		MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+		LocationType: 1,
+	}
+
+	tests := []struct {
+		name    string
+		db      *Database
+		model   func(db *Database) Model
+		cascade bool
+		want    *Database
+	}{
+		{
+			name: "UserMark",
+			db: &Database{
+				BlockRange: []*BlockRange{
+					nil,
+					{BlockRangeID: 1, BlockType: 2, Identifier: 16, UserMarkID: 1},
+					{BlockRangeID: 2, BlockType: 2, Identifier: 17, UserMarkID: 1},
+				},
+				Location: []*Location{nil, verse},
+				Note: []*Note{nil, {
+					NoteID:     1,
+					GUID:       "NOTE-1",
+					UserMarkID: sql.NullInt32{Int32: 1, Valid: true},
+					LocationID: sql.NullInt32{Int32: 1, Valid: true},
+				}},
+				UserMark: []*UserMark{nil, {UserMarkID: 1, ColorIndex: 1, LocationID: 1, UserMarkGUID: "UM-1", Version: 1}},
+			},
+			model:   func(db *Database) Model { return db.UserMark[1] },
+			cascade: true,
+			want: &Database{
+				BlockRange: []*BlockRange{nil, nil, nil},
+				Location:   []*Location{nil, verse},
+				Note: []*Note{nil, {
+					NoteID:     1,
+					GUID:       "NOTE-1",
+					LocationID: sql.NullInt32{Int32: 1, Valid: true},
+				}},
+				UserMark: []*UserMark{nil, nil},
+			},
+		},
+		{
+			name: "UserMarkBlockRange",
+			db: &Database{
+				BlockRange: []*BlockRange{nil, {BlockRangeID: 1, BlockType: 1, Identifier: 4, UserMarkID: 1}},
+				Location:   []*Location{nil, verse},
+				UserMark:   []*UserMark{nil, {UserMarkID: 1, ColorIndex: 2, LocationID: 1, UserMarkGUID: "UM-1", Version: 1}},
+			},
+			model:   func(db *Database) Model { return &UserMarkBlockRange{UserMark: db.UserMark[1]} },
+			cascade: true,
+			want: &Database{
+				BlockRange: []*BlockRange{nil, nil},
+				Location:   []*Location{nil, nil},
+				UserMark:   []*UserMark{nil, nil},
+			},
+		},
+		{
+			name: "Note",
+			db: &Database{
+				Location: []*Location{nil, verse},
+				Note:     []*Note{nil, {NoteID: 1, GUID: "NOTE-1", LocationID: sql.NullInt32{Int32: 1, Valid: true}}},
+				Tag:      []*Tag{nil, {TagID: 1, TagType: 1, Name: "Tag"}},
+				TagMap:   []*TagMap{nil, {TagMapID: 1, NoteID: sql.NullInt32{Int32: 1, Valid: true}, TagID: 1}},
+			},
+			model:   func(db *Database) Model { return db.Note[1] },
+			cascade: true,
+			want: &Database{
+				Location: []*Location{nil, nil},
+				Note:     []*Note{nil, nil},
+				Tag:      []*Tag{nil, {TagID: 1, TagType: 1, Name: "Tag"}},
+				TagMap:   []*TagMap{nil, nil},
+			},
+		},
+		{
+			name: "Note without dependents",
+			db: &Database{
+				Note: []*Note{nil, {NoteID: 1, GUID: "NOTE-1", Title: sql.NullString{String: "Without location", Valid: true}}},
+			},
+			model: func(db *Database) Model { return db.Note[1] },
+			want: &Database{
+				Note: []*Note{nil, nil},
+			},
+		},
+		{
+			name: "Tag",
+			db: &Database{
+				Location: []*Location{nil, verse},
+				Note:     []*Note{nil, {NoteID: 1, GUID: "NOTE-1", LocationID: sql.NullInt32{Int32: 1, Valid: true}}},
+				Tag:      []*Tag{nil, {TagID: 1, TagType: 1, Name: "Tag"}},
+				TagMap: []*TagMap{
+					nil,
+					{TagMapID: 1, NoteID: sql.NullInt32{Int32: 1, Valid: true}, TagID: 1, Position: 0},
+					{TagMapID: 2, LocationID: sql.NullInt32{Int32: 1, Valid: true}, TagID: 1, Position: 1},
+				},
+			},
+			model:   func(db *Database) Model { return db.Tag[1] },
+			cascade: true,
+			want: &Database{
+				Location: []*Location{nil, verse},
+				Note:     []*Note{nil, {NoteID: 1, GUID: "NOTE-1", LocationID: sql.NullInt32{Int32: 1, Valid: true}}},
+				Tag:      []*Tag{nil, nil},
+				TagMap:   []*TagMap{nil, nil, nil},
+			},
+		},
+		{
+			name: "Bookmark removes unreferenced publication Location",
+			db: &Database{
+				Bookmark: []*Bookmark{nil, {BookmarkID: 1, LocationID: 1, PublicationLocationID: 2, Slot: 0, Title: "John 3"}},
+				Location: []*Location{nil, verse, publication},
+				Note:     []*Note{nil, {NoteID: 1, GUID: "NOTE-1", LocationID: sql.NullInt32{Int32: 1, Valid: true}}},
+			},
+			model: func(db *Database) Model { return db.Bookmark[1] },
+			want: &Database{
+				Bookmark: []*Bookmark{nil, nil},
+				Location: []*Location{nil, verse, nil},
+				Note:     []*Note{nil, {NoteID: 1, GUID: "NOTE-1", LocationID: sql.NullInt32{Int32: 1, Valid: true}}},
+			},
+		},
+		{
+			name: "Location",
+			db: &Database{
+				BlockRange: []*BlockRange{nil, {BlockRangeID: 1, BlockType: 2, Identifier: 16, UserMarkID: 1}},
+				InputField: []*InputField{nil, {LocationID: 1, TextTag: "tt1", Value: "Value", pseudoID: 1}},
+				Location:   []*Location{nil, verse},
+				Note:       []*Note{nil, {NoteID: 1, GUID: "NOTE-1", LocationID: sql.NullInt32{Int32: 1, Valid: true}}},
+				Tag:        []*Tag{nil, {TagID: 1, TagType: 1, Name: "Tag"}},
+				TagMap:     []*TagMap{nil, {TagMapID: 1, LocationID: sql.NullInt32{Int32: 1, Valid: true}, TagID: 1}},
+				UserMark:   []*UserMark{nil, {UserMarkID: 1, ColorIndex: 1, LocationID: 1, UserMarkGUID: "UM-1", Version: 1}},
+			},
+			model:   func(db *Database) Model { return db.Location[1] },
+			cascade: true,
+			want: &Database{
+				BlockRange: []*BlockRange{nil, nil},
+				InputField: []*InputField{nil, nil},
+				Location:   []*Location{nil, nil},
+				Note:       []*Note{nil, nil},
+				Tag:        []*Tag{nil, {TagID: 1, TagType: 1, Name: "Tag"}},
+				TagMap:     []*TagMap{nil, nil},
+				UserMark:   []*UserMark{nil, nil},
+			},
+		},
+		{
+			name: "InputField",
+			db: &Database{
+				InputField: []*InputField{nil, {LocationID: 1, TextTag: "tt1", Value: "Value", pseudoID: 1}},
+				Location:   []*Location{nil, verse},
+				Note:       []*Note{nil, {NoteID: 1, GUID: "NOTE-1", LocationID: sql.NullInt32{Int32: 1, Valid: true}}},
+			},
+			model: func(db *Database) Model { return db.InputField[1] },
+			want: &Database{
+				InputField: []*InputField{nil, nil},
+				Location:   []*Location{nil, verse},
+				Note:       []*Note{nil, {NoteID: 1, GUID: "NOTE-1", LocationID: sql.NullInt32{Int32: 1, Valid: true}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, tt.db.Delete(tt.model(tt.db), tt.cascade))
+			assert.False(t, tt.db.LastModified.IsZero())
+			tt.want.LastModified = tt.db.LastModified
+			assert.Equal(t, tt.want, tt.db)
+		})
+	}
+}
+
+func TestDatabase_Delete_lastReference(t *testing.T) {
+	db := &Database{
+		BlockRange: []*BlockRange{nil, {BlockRangeID: 1, BlockType: 2, Identifier: 16, UserMarkID: 1}},
+		Bookmark:   []*Bookmark{nil, {BookmarkID: 1, LocationID: 1, PublicationLocationID: 2, Slot: 0, Title: "John 3"}},
+		Location: []*Location{
+			nil,
+			{
+				LocationID:    1,
+				BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+				ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+				KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+			},
+			{
+				LocationID:   2,
+				KeySymbol:    sql.NullString{String: "nwtsty", Valid: true},
+				MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+				LocationType: 1,
+			},
+		},
+		Note: []*Note{nil, {
+			NoteID:     1,
+			GUID:       "NOTE-1",
+			UserMarkID: sql.NullInt32{Int32: 1, Valid: true},
+			LocationID: sql.NullInt32{Int32: 1, Valid: true},
+		}},
+		UserMark: []*UserMark{nil, {UserMarkID: 1, ColorIndex: 1, LocationID: 1, UserMarkGUID: "UM-1", Version: 1}},
+	}
+
+	assert.NoError(t, db.Delete(db.Note[1], true))
+	assert.NoError(t, db.Delete(db.UserMark[1], true))
+	assert.NotNil(t, db.Location[1])
+	// The Bookmark was the last entry referencing both Locations
+	assert.NoError(t, db.Delete(db.Bookmark[1], false))
+	assert.Nil(t, db.Location[1])
+	assert.Nil(t, db.Location[2])
+}
+
+func TestDatabase_Delete_error(t *testing.T) {
+	db := &Database{
+		BlockRange: []*BlockRange{nil, {BlockRangeID: 1, BlockType: 2, Identifier: 16, UserMarkID: 1}},
+		Location: []*Location{nil, {
+			LocationID:   1,
+			DocumentID:   sql.NullInt32{Int32: 1102021811, Valid: true},
+			KeySymbol:    sql.NullString{String: "lffi", Valid: true},
+			MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+		}},
+		Tag:      []*Tag{nil, {TagID: 1, TagType: 1, Name: "Tag"}},
+		UserMark: []*UserMark{nil, {UserMarkID: 1, ColorIndex: 1, LocationID: 1, UserMarkGUID: "UM-1", Version: 1}},
+	}
+
+	tests := []struct {
+		name  string
+		model Model
+		isErr error
+	}{
+		{name: "Dependents without cascade", model: db.UserMark[1], isErr: ErrHasDependents},
+		{name: "Location without cascade", model: db.Location[1], isErr: ErrHasDependents},
+		{name: "Nonexistent", model: &Note{NoteID: 5}},
+		{name: "Different entry", model: &Tag{TagID: 1, TagType: 1, Name: "Other"}},
+		{name: "Zero ID", model: &Tag{}},
+		{name: "Nil"},
+		{name: "Nil pointer", model: (*Note)(nil)},
+		{name: "Empty UserMarkBlockRange", model: &UserMarkBlockRange{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := db.Delete(tt.model, false)
+			assert.Error(t, err)
+			if tt.isErr != nil {
+				assert.ErrorIs(t, err, tt.isErr)
+			}
+			// The Database is left unchanged
+			assert.NotNil(t, db.BlockRange[1])
+			assert.NotNil(t, db.Location[1])
+			assert.NotNil(t, db.Tag[1])
+			assert.NotNil(t, db.UserMark[1])
+			assert.True(t, db.LastModified.IsZero())
+		})
+	}
+
+	var nilDB *Database
+	assert.Error(t, nilDB.Delete(&Tag{TagID: 1}, true))
+}
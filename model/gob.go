@@ -0,0 +1,117 @@
+package model
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	// Register all Models, so they can be encoded as the Model interface,
+	// like within the conflicts of a merge.
+	gob.Register(&BlockRange{})
+	gob.Register(&Bookmark{})
+	gob.Register(&InputField{})
+	gob.Register(&Location{})
+	gob.Register(&Note{})
+	gob.Register(&Tag{})
+	gob.Register(&TagMap{})
+	gob.Register(&UserMark{})
+	gob.Register(&UserMarkBlockRange{})
+}
+
+// gobDatabase is the gob representation of a Database. It is needed
+// as gob is not able to encode the nil entries of its slices.
+type gobDatabase struct {
+	Tables            map[string]gobTable
+	ContainsPlaylists bool
+	SkipPlaylists     bool
+	TempDir           string
+	SchemaVersion     int
+	Metadata          *Metadata
+	LastModified      time.Time
+}
+
+// gobTable contains the non-nil entries of a Database slice by their index.
+type gobTable struct {
+	Len     int
+	Entries map[int]Model
+}
+
+// GobEncode encodes the Database, so it can be stored
+// and restored later, e.g. to continue a merge.
+func (db *Database) GobEncode() ([]byte, error) {
+	gdb := gobDatabase{
+		Tables:            map[string]gobTable{},
+		ContainsPlaylists: db.ContainsPlaylists,
+		SkipPlaylists:     db.SkipPlaylists,
+		TempDir:           db.TempDir,
+		SchemaVersion:     db.SchemaVersion,
+		Metadata:          db.Metadata,
+		LastModified:      db.LastModified,
+	}
+
+	dbFields := reflect.ValueOf(db).Elem()
+	for i := 0; i < dbFields.NumField(); i++ {
+		if dbFields.Field(i).Kind() != reflect.Slice || dbFields.Field(i).IsNil() {
+			continue
+		}
+		mdls, err := MakeModelSlice(dbFields.Field(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		table := gobTable{Len: len(mdls), Entries: make(map[int]Model, len(mdls))}
+		for j, mdl := range mdls {
+			if !reflect.ValueOf(mdl).IsNil() {
+				table.Entries[j] = mdl
+			}
+		}
+		gdb.Tables[dbFields.Type().Field(i).Name] = table
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(gdb); err != nil {
+		return nil, errors.Wrap(err, "Error while encoding Database")
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode restores a Database encoded by GobEncode.
+func (db *Database) GobDecode(data []byte) error {
+	var gdb gobDatabase
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&gdb); err != nil {
+		return errors.Wrap(err, "Error while decoding Database")
+	}
+
+	*db = Database{
+		ContainsPlaylists: gdb.ContainsPlaylists,
+		SkipPlaylists:     gdb.SkipPlaylists,
+		TempDir:           gdb.TempDir,
+		SchemaVersion:     gdb.SchemaVersion,
+		Metadata:          gdb.Metadata,
+		LastModified:      gdb.LastModified,
+	}
+
+	dbFields := reflect.ValueOf(db).Elem()
+	for name, table := range gdb.Tables {
+		field := dbFields.FieldByName(name)
+		if !field.IsValid() || field.Kind() != reflect.Slice {
+			return fmt.Errorf("table %s does not exist in Database", name)
+		}
+		slice := reflect.MakeSlice(field.Type(), table.Len, table.Len)
+		for i, mdl := range table.Entries {
+			entry := reflect.ValueOf(mdl)
+			if i < 0 || i >= table.Len || entry.Type() != field.Type().Elem() {
+				return fmt.Errorf("invalid entry %d of table %s", i, name)
+			}
+			slice.Index(i).Set(entry)
+		}
+		field.Set(slice)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/gob"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabase_GobEncode(t *testing.T) {
+	db := &Database{}
+	require.NoError(t, db.ImportJWLBackup(filepath.Join("testdata", "backup.jwlibrary")))
+	db.TempDir = "tmp"
+
+	for _, original := range []*Database{db, {}, {Note: []*Note{nil}}} {
+		var buf bytes.Buffer
+		require.NoError(t, gob.NewEncoder(&buf).Encode(original))
+		decoded := &Database{}
+		require.NoError(t, gob.NewDecoder(&buf).Decode(decoded))
+		assert.Equal(t, original, decoded)
+	}
+
+	// The pseudoID of InputFields is kept
+	require.NotEmpty(t, db.InputField)
+	data, err := db.GobEncode()
+	require.NoError(t, err)
+	decoded := &Database{}
+	require.NoError(t, decoded.GobDecode(data))
+	for i, inputField := range decoded.InputField {
+		if inputField != nil {
+			assert.Equal(t, i, inputField.ID())
+		}
+	}
+
+	assert.Error(t, decoded.GobDecode([]byte("invalid")))
+}
+
+func TestModel_gob(t *testing.T) {
+	// Models can be encoded as the Model interface
+	models := []Model{
+		&InputField{LocationID: 1, TextTag: "tt", Value: "value", pseudoID: 5},
+		&UserMarkBlockRange{
+			UserMark:    &UserMark{UserMarkID: 1, LocationID: 2, UserMarkGUID: "GUID"},
+			BlockRanges: []*BlockRange{{BlockRangeID: 1, UserMarkID: 1, StartToken: sql.NullInt32{Int32: 1, Valid: true}}},
+		},
+		&Note{NoteID: 3, Title: sql.NullString{String: "Title", Valid: true}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(models))
+	var decoded []Model
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+	assert.Equal(t, models, decoded)
+	assert.Equal(t, 5, decoded[0].ID())
+}
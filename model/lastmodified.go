@@ -0,0 +1,61 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// lastModifiedLayout is the format of the timestamp in the LastModified table.
+const lastModifiedLayout = "2006-01-02T15:04:05Z"
+
+// hasLastModifiedTable checks if the SQLite database contains a LastModified table.
+func hasLastModifiedTable(sqlite *sql.DB) (bool, error) {
+	var count int
+	err := sqlite.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'LastModified'").Scan(&count)
+	if err != nil {
+		return false, errors.Wrap(err, "Error while checking for LastModified table")
+	}
+	return count > 0, nil
+}
+
+// fetchLastModified returns the timestamp stored in the LastModified table.
+// If the table doesn't exist or is empty, the zero time is returned.
+func fetchLastModified(sqlite *sql.DB) (time.Time, error) {
+	exists, err := hasLastModifiedTable(sqlite)
+	if err != nil || !exists {
+		return time.Time{}, err
+	}
+
+	var value string
+	err = sqlite.QueryRow("SELECT LastModified FROM LastModified").Scan(&value)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "Error while fetching LastModified")
+	}
+
+	lastModified, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "Error while parsing LastModified %s", value)
+	}
+	return lastModified, nil
+}
+
+// storeLastModified sets the timestamp of the LastModified table. As the triggers
+// of the schema update it whenever entries are inserted, it has to be called
+// after all entries have been stored.
+func storeLastModified(sqlite *sql.DB, lastModified time.Time) error {
+	exists, err := hasLastModifiedTable(sqlite)
+	if err != nil || !exists {
+		return err
+	}
+
+	_, err = sqlite.Exec("UPDATE LastModified SET LastModified = ?", lastModified.UTC().Format(lastModifiedLayout))
+	if err != nil {
+		return errors.Wrap(err, "Error while updating LastModified")
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabase_ExportJWLBackup_lastModified(t *testing.T) {
+	db := &Database{}
+	require.NoError(t, db.ImportJWLBackup(filepath.Join("testdata", "backup.jwlibrary")))
+	assert.Equal(t, time.Date(2023, 7, 16, 11, 31, 38, 0, time.UTC), db.LastModified)
+
+	// exportAndImport exports db with the given options and returns the
+	// imported result together with the lastModifiedDate of its manifest.
+	exportAndImport := func(db *Database, opts ExportOptions) (*Database, time.Time) {
+		var buf bytes.Buffer
+		require.NoError(t, db.ExportJWLBackupToWithOptions(&buf, opts))
+
+		archive, err := openBackupArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		content, err := archive.readEntry(manifestFilename, maxManifestSize)
+		require.NoError(t, err)
+		mfst := &manifest{}
+		require.NoError(t, mfst.importManifest(bytes.NewReader(content)))
+		manifestDate, err := time.Parse("2006-01-02T15:04:05-07:00", mfst.UserDataBackup.LastModifiedDate)
+		require.NoError(t, err)
+
+		result := &Database{}
+		require.NoError(t, result.ImportJWLBackupFrom(bytes.NewReader(buf.Bytes()), int64(buf.Len())))
+		return result, manifestDate
+	}
+
+	// Export keeps the imported value
+	exported, manifestDate := exportAndImport(db, ExportOptions{})
+	assert.Equal(t, db.LastModified, exported.LastModified)
+	assert.True(t, db.LastModified.Equal(manifestDate))
+
+	// Or uses the given one
+	override := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	exported, manifestDate = exportAndImport(db, ExportOptions{LastModified: override})
+	assert.Equal(t, override, exported.LastModified)
+	assert.True(t, override.Equal(manifestDate))
+
+	// Or the time of the export if none is set
+	before := time.Now().Truncate(time.Second)
+	exported, manifestDate = exportAndImport(&Database{}, ExportOptions{})
+	assert.False(t, exported.LastModified.Before(before))
+	assert.WithinDuration(t, time.Now(), exported.LastModified, time.Minute)
+	assert.True(t, exported.LastModified.Equal(manifestDate))
+}
@@ -5,9 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/pkg/errors"
@@ -15,7 +12,6 @@ import (
 
 const version = 1
 const supportedSchemaVersionMin = 13
-const supportedSchemaVersionMax = 14
 
 type manifest struct {
 	CreationDate   string         `json:"creationDate"`
@@ -32,17 +28,14 @@ type userDataBackup struct {
 	DeviceName       string `json:"deviceName"`
 }
 
-// importManifest imports a manifest.json at path
-func (mfst *manifest) importManifest(path string) error {
-	file, err := os.Open(path)
+// importManifest imports a manifest.json from r
+func (mfst *manifest) importManifest(r io.Reader) error {
+	blob, err := io.ReadAll(r)
 	if err != nil {
-		return err
+		return errors.Wrap(err, "Could not read backup manifest file")
 	}
-	defer file.Close()
 
-	blob, _ := io.ReadAll(file)
-
-	err = json.Unmarshal([]byte(blob), &mfst)
+	err = json.Unmarshal(blob, &mfst)
 	if err != nil {
 		return errors.Wrap(err, "Could not unmarshall backup manifest file")
 	}
@@ -50,70 +43,60 @@ func (mfst *manifest) importManifest(path string) error {
 	return nil
 }
 
-// validateManifest checks if the backup file is compatible by validating the manifest
+// validateManifest checks if the backup file is compatible by validating the manifest.
+// Newer schema versions are accepted, as columns are mapped by their names and a
+// template for writing them can be derived from the imported backup.
 func (mfst *manifest) validateManifest() error {
 	if mfst.Version > version {
-		return fmt.Errorf("manifest version is too new. Should be %d is %d. "+
-			"Make sure you use the latest version of the merger", version, mfst.Version)
+		return fmt.Errorf("%w: manifest version is too new. Should be %d is %d. "+
+			"Make sure you use the latest version of the merger", ErrUnsupportedVersion, version, mfst.Version)
 	}
 	if mfst.Version < version {
-		return fmt.Errorf("manifest version is too old. Should be %d is %d. "+
-			"You might need to upgrade to a newer version of JW Library first", version, mfst.Version)
+		return fmt.Errorf("%w: manifest version is too old. Should be %d is %d. "+
+			"You might need to upgrade to a newer version of JW Library first", ErrUnsupportedVersion, version, mfst.Version)
 	}
 
-	if mfst.UserDataBackup.SchemaVersion > supportedSchemaVersionMax {
-		return fmt.Errorf("schema version is too new. Should be up to %d is %d. "+
-			"Make sure you use the latest version of the merger", supportedSchemaVersionMax, mfst.UserDataBackup.SchemaVersion)
-	}
 	if mfst.UserDataBackup.SchemaVersion < supportedSchemaVersionMin {
-		return fmt.Errorf("schema version is too old. Should be at least %d is %d. "+
-			"You might need to upgrade to a newer version of JW Library first", supportedSchemaVersionMin, mfst.UserDataBackup.SchemaVersion)
+		return fmt.Errorf("%w: schema version is too old. Should be at least %d is %d. "+
+			"You might need to upgrade to a newer version of JW Library first", ErrUnsupportedVersion, supportedSchemaVersionMin, mfst.UserDataBackup.SchemaVersion)
 	}
 
 	return nil
 }
 
-// generateManifest generates a manifest from the given information, which can
-// later be exported
-func generateManifest(backupName string, dbFile string) (*manifest, error) {
+// generateManifest generates a manifest with the given Metadata for the database
+// with the given name and content, which can later be exported. The lastModifiedDate
+// is set to lastModified, so it matches the LastModified table of the database.
+func generateManifest(md *Metadata, dbName string, dbContent []byte, schemaVersion int, lastModified time.Time) *manifest {
 	// Get SHA256 of SQLite file
-	f, err := os.Open(dbFile)
-	if err != nil {
-		return nil, errors.Wrapf(err, "Error while opening SQLite file %s to calculate hash", dbFile)
-	}
-	defer f.Close()
-	hasher := sha256.New()
-	if _, err := io.Copy(hasher, f); err != nil {
-		log.Fatal(err)
-	}
-	hash := fmt.Sprintf("%x", hasher.Sum(nil))
+	hash := fmt.Sprintf("%x", sha256.Sum256(dbContent))
 
 	mfst := &manifest{
-		CreationDate: time.Now().Format("2006-01-02"),
+		CreationDate: md.CreationDate,
 		UserDataBackup: userDataBackup{
-			LastModifiedDate: time.Now().Format("2006-01-02T15:04:05-07:00"),
+			LastModifiedDate: lastModified.Local().Format("2006-01-02T15:04:05-07:00"),
 			Hash:             hash,
-			DatabaseName:     filepath.Base(dbFile),
-			SchemaVersion:    supportedSchemaVersionMax,
-			DeviceName:       "go-jwlm",
+			DatabaseName:     dbName,
+			SchemaVersion:    schemaVersion,
+			DeviceName:       md.DeviceName,
 		},
-		Name:    backupName,
+		Name:    md.Name,
 		Type:    0,
 		Version: version,
 	}
 
-	return mfst, nil
+	return mfst
 }
 
-// exportManifest exports a manifest at path
-func (mfst *manifest) exportManifest(path string) error {
+// exportManifest writes the manifest to w
+func (mfst *manifest) exportManifest(w io.Writer) error {
 	bytes, err := json.Marshal(mfst)
 	if err != nil {
 		return errors.Wrap(err, "Error while marshalling manifest")
 	}
 
-	if err := os.WriteFile(path, bytes, 0644); err != nil {
-		return errors.Wrap(err, fmt.Sprintf("Error while saving manifest file at %v", path))
+	if _, err := w.Write(bytes); err != nil {
+		return errors.Wrap(err, "Error while writing manifest")
 	}
 
 	return nil
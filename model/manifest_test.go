@@ -1,8 +1,10 @@
 package model
 
 import (
-	"fmt"
+	"bytes"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -27,7 +29,7 @@ func Test_manifest_importManifest(t *testing.T) {
 	path := filepath.Join("testdata", "manifest_correct.json")
 
 	mfst := &manifest{}
-	assert.NoError(t, mfst.importManifest(path))
+	assert.NoError(t, mfst.importManifest(openFile(t, path)))
 
 	expectedMfst := &manifest{
 		CreationDate: "2020-04-11",
@@ -44,19 +46,19 @@ func Test_manifest_importManifest(t *testing.T) {
 	}
 	assert.Equal(t, expectedMfst, mfst)
 
-	assert.Error(t, mfst.importManifest("nonexistentpath"))
+	assert.Error(t, mfst.importManifest(strings.NewReader("{invalid")))
 }
 
 func Test_validateManifest1(t *testing.T) {
 	path := filepath.Join("testdata", "manifest_correct.json")
 
 	mfst := manifest{}
-	assert.NoError(t, mfst.importManifest(path))
+	assert.NoError(t, mfst.importManifest(openFile(t, path)))
 	assert.NoError(t, mfst.validateManifest())
 
 	path = filepath.Join("testdata", "manifest_outdated.json")
 	mfst = manifest{}
-	assert.NoError(t, mfst.importManifest(path))
+	assert.NoError(t, mfst.importManifest(openFile(t, path)))
 	assert.Error(t, mfst.validateManifest())
 }
 
@@ -99,16 +101,14 @@ func Test_manifest_validateManifest2(t *testing.T) {
 			},
 		},
 		{
-			name: "Schema version too new",
+			name: "Newer schema version",
 			mfst: &manifest{
 				UserDataBackup: userDataBackup{
 					SchemaVersion: 15,
 				},
 				Version: 1,
 			},
-			wantErr: func(tt assert.TestingT, err error, i ...interface{}) bool {
-				return assert.ErrorContains(tt, err, "schema version is too new. Should be up to 14 is 15")
-			},
+			wantErr: assert.NoError,
 		},
 		{
 			name: "Manifest version too old",
@@ -144,29 +144,40 @@ func Test_manifest_validateManifest2(t *testing.T) {
 }
 
 func Test_generateManifest(t *testing.T) {
-	dbPath := filepath.Join("testdata", userDataFilename)
-
-	mfst, err := generateManifest("test", dbPath)
-	exampleManifest.UserDataBackup.LastModifiedDate = time.Now().Format("2006-01-02T15:04:05-07:00") // Could have changed in the last second..
+	content, err := os.ReadFile(filepath.Join("testdata", userDataFilename))
 	assert.NoError(t, err)
-	assert.Equal(t, exampleManifest, mfst)
 
-	_, err = generateManifest("test", "nonexistent.db")
-	assert.Error(t, err)
+	lastModified := time.Date(2023, 7, 15, 10, 54, 7, 0, time.UTC)
+	mfst := generateManifest(testMetadata(), userDataFilename, content, 14, lastModified)
+	expected := *exampleManifest
+	expected.UserDataBackup.LastModifiedDate = lastModified.Local().Format("2006-01-02T15:04:05-07:00")
+	assert.Equal(t, &expected, mfst)
 }
 
 func Test_exportManifest(t *testing.T) {
-	tmp := t.TempDir()
-
-	path := filepath.Join(tmp, "test_manifest.json")
-	fmt.Println(path)
-	err := exampleManifest.exportManifest(path)
+	var buf bytes.Buffer
+	err := exampleManifest.exportManifest(&buf)
 	assert.NoError(t, err)
-	assert.FileExists(t, path)
 
 	otherMfst := &manifest{}
-	err = otherMfst.importManifest(path)
+	err = otherMfst.importManifest(&buf)
 	assert.NoError(t, err)
 	assert.Equal(t, exampleManifest, otherMfst)
+}
 
+// testMetadata returns the Metadata matching exampleManifest.
+func testMetadata() *Metadata {
+	return &Metadata{
+		Name:         "test",
+		DeviceName:   "go-jwlm",
+		CreationDate: time.Now().Format("2006-01-02"),
+	}
+}
+
+// openFile opens the file at path and closes it when the test has finished.
+func openFile(t *testing.T, path string) *os.File {
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	t.Cleanup(func() { file.Close() })
+	return file
 }
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+const defaultBackupName = "go-jwlm"
+const defaultDeviceName = "go-jwlm"
+
+// Metadata contains information about a backup that is not part of its
+// database, like the identity stored in its manifest and its thumbnail.
+type Metadata struct {
+	// Name of the backup.
+	Name string
+	// DeviceName is the name of the device that created the backup.
+	DeviceName string
+	// CreationDate of the backup as stored in its manifest.
+	CreationDate string
+	// Thumbnail is the content of the thumbnail (default_thumbnail.png) of the backup.
+	Thumbnail []byte
+	// ExtraFiles contains the media files of the playlists (as referenced
+	// by the IndependentMedia table) by their name.
+	ExtraFiles map[string][]byte
+}
+
+// ExportOptions configure how a backup is exported.
+type ExportOptions struct {
+	// Metadata is used for the manifest and the thumbnail of the backup, which
+	// allows to inherit them from another Database (e.g. the left or right one
+	// of a merge). If it is nil, the Metadata of the exported Database is used.
+	// Empty fields are filled with defaults, so passing &Metadata{} results
+	// in a backup with the default name, device name, and thumbnail.
+	Metadata *Metadata
+	// Name overrides the name of the backup, if set.
+	Name string
+	// DeviceName overrides the device name of the backup, if set.
+	DeviceName string
+	// LastModified overrides the LastModified of the exported Database, if set.
+	LastModified time.Time
+	// Compact removes orphaned entries and renumbers the IDs of the exported
+	// backup (see Database.Compact). The Database itself is left unchanged.
+	Compact bool
+}
+
+// lastModified returns the time that should be stored as LastModified
+// when exporting db with the given options.
+func (opts ExportOptions) lastModified(db *Database) time.Time {
+	if !opts.LastModified.IsZero() {
+		return opts.LastModified
+	}
+	if !db.LastModified.IsZero() {
+		return db.LastModified
+	}
+	return time.Now()
+}
+
+// metadata returns the Metadata that should be used for exporting db with
+// the given options. Empty fields are filled with defaults.
+func (opts ExportOptions) metadata(db *Database) *Metadata {
+	md := opts.Metadata
+	if md == nil {
+		md = db.Metadata
+	}
+
+	result := md.copy()
+	if result == nil {
+		result = &Metadata{}
+	}
+	if opts.Name != "" {
+		result.Name = opts.Name
+	}
+	if opts.DeviceName != "" {
+		result.DeviceName = opts.DeviceName
+	}
+
+	if result.Name == "" {
+		result.Name = defaultBackupName
+	}
+	if result.DeviceName == "" {
+		result.DeviceName = defaultDeviceName
+	}
+	if result.CreationDate == "" {
+		result.CreationDate = time.Now().Format("2006-01-02")
+	}
+	if result.Thumbnail == nil {
+		result.Thumbnail = defaultThumbnailFile
+	}
+
+	return result
+}
+
+// copy creates a deep copy of the Metadata.
+func (md *Metadata) copy() *Metadata {
+	if md == nil {
+		return nil
+	}
+
+	result := &Metadata{
+		Name:         md.Name,
+		DeviceName:   md.DeviceName,
+		CreationDate: md.CreationDate,
+	}
+	if md.Thumbnail != nil {
+		result.Thumbnail = append([]byte{}, md.Thumbnail...)
+	}
+	if md.ExtraFiles != nil {
+		result.ExtraFiles = make(map[string][]byte, len(md.ExtraFiles))
+		for name, content := range md.ExtraFiles {
+			result.ExtraFiles[name] = append([]byte{}, content...)
+		}
+	}
+
+	return result
+}
+
+// extraFileNames returns the sorted names of the ExtraFiles.
+func (md *Metadata) extraFileNames() []string {
+	names := make([]string, 0, len(md.ExtraFiles))
+	for name := range md.ExtraFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
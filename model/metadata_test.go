@@ -0,0 +1,146 @@
+package model
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportOptions_metadata(t *testing.T) {
+	imported := &Metadata{
+		Name:         "imported",
+		DeviceName:   "iPhone",
+		CreationDate: "2023-07-15T12:54:25+0200",
+		Thumbnail:    []byte("thumbnail"),
+	}
+	other := &Metadata{
+		Name:       "other",
+		DeviceName: "Android",
+	}
+	today := time.Now().Format("2006-01-02")
+
+	tests := []struct {
+		name string
+		db   *Database
+		opts ExportOptions
+		want *Metadata
+	}{
+		{
+			name: "Defaults",
+			db:   &Database{},
+			want: &Metadata{Name: "go-jwlm", DeviceName: "go-jwlm", CreationDate: today, Thumbnail: defaultThumbnailFile},
+		},
+		{
+			name: "Imported metadata",
+			db:   &Database{Metadata: imported},
+			want: imported,
+		},
+		{
+			name: "Inherit from other",
+			db:   &Database{Metadata: imported},
+			opts: ExportOptions{Metadata: other},
+			want: &Metadata{Name: "other", DeviceName: "Android", CreationDate: today, Thumbnail: defaultThumbnailFile},
+		},
+		{
+			name: "Use defaults although imported",
+			db:   &Database{Metadata: imported},
+			opts: ExportOptions{Metadata: &Metadata{}},
+			want: &Metadata{Name: "go-jwlm", DeviceName: "go-jwlm", CreationDate: today, Thumbnail: defaultThumbnailFile},
+		},
+		{
+			name: "Override name and device",
+			db:   &Database{Metadata: imported},
+			opts: ExportOptions{Name: "merged", DeviceName: "Computer"},
+			want: &Metadata{Name: "merged", DeviceName: "Computer", CreationDate: imported.CreationDate, Thumbnail: imported.Thumbnail},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.opts.metadata(tt.db))
+		})
+	}
+
+	// The original Metadata must not be changed
+	assert.Equal(t, "imported", imported.Name)
+}
+
+func TestDatabase_ExportJWLBackup_metadata(t *testing.T) {
+	db := &Database{}
+	assert.NoError(t, db.ImportJWLBackup(filepath.Join("testdata", "backup.jwlibrary")))
+
+	assert.Equal(t, "backup.jwlibrary", db.Metadata.Name)
+	assert.Equal(t, "iPhone", db.Metadata.DeviceName)
+	assert.Equal(t, "2023-07-15T12:54:25+0200", db.Metadata.CreationDate)
+	assert.Len(t, db.Metadata.Thumbnail, 542)
+	assert.Empty(t, db.Metadata.ExtraFiles)
+
+	db.Metadata.ExtraFiles = map[string][]byte{"media/video.mp4": []byte("video")}
+
+	// Export keeps the imported metadata, but files that aren't
+	// referenced by the backup are ignored when importing it again
+	var buf bytes.Buffer
+	assert.NoError(t, db.ExportJWLBackupTo(&buf))
+	db2 := &Database{}
+	assert.NoError(t, db2.ImportJWLBackupFrom(bytes.NewReader(buf.Bytes()), int64(buf.Len())))
+	assert.Equal(t, db.Metadata.Name, db2.Metadata.Name)
+	assert.Equal(t, db.Metadata.DeviceName, db2.Metadata.DeviceName)
+	assert.Equal(t, db.Metadata.CreationDate, db2.Metadata.CreationDate)
+	assert.Equal(t, db.Metadata.Thumbnail, db2.Metadata.Thumbnail)
+	assert.Empty(t, db2.Metadata.ExtraFiles)
+
+	// Or uses the given ones
+	buf.Reset()
+	assert.NoError(t, db.ExportJWLBackupToWithOptions(&buf, ExportOptions{Metadata: &Metadata{}, Name: "merged"}))
+	db2 = &Database{}
+	assert.NoError(t, db2.ImportJWLBackupFrom(bytes.NewReader(buf.Bytes()), int64(buf.Len())))
+	assert.Equal(t, "merged", db2.Metadata.Name)
+	assert.Equal(t, "go-jwlm", db2.Metadata.DeviceName)
+	assert.Equal(t, defaultThumbnailFile, db2.Metadata.Thumbnail)
+	assert.Empty(t, db2.Metadata.ExtraFiles)
+}
+
+func TestDatabase_ImportJWLBackup_mediaFiles(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testdata", "userData_withPlaylist.db"))
+	assert.NoError(t, err)
+	mfst := generateManifest(testMetadata(), userDataFilename, content, 14, time.Now())
+	var mfstContent bytes.Buffer
+	assert.NoError(t, mfst.exportManifest(&mfstContent))
+	var backup bytes.Buffer
+	assert.NoError(t, writeZip(&backup, []zipEntry{
+		{name: userDataFilename, content: content},
+		{name: manifestFilename, content: mfstContent.Bytes()},
+		{name: "1c6b3a9c-c73e-43cf-b323-0064c3fb0b0c", content: []byte("image")},
+		{name: "855e8f4b-80e8-417a-8386-628a55729d95_thumbnail", content: []byte("thumbnail")},
+		{name: "unexpected.bin", content: []byte("payload")},
+	}))
+
+	db := &Database{SkipPlaylists: true}
+	require.NoError(t, db.ImportJWLBackupFrom(bytes.NewReader(backup.Bytes()), int64(backup.Len())))
+	assert.Equal(t, map[string][]byte{
+		"1c6b3a9c-c73e-43cf-b323-0064c3fb0b0c":           []byte("image"),
+		"855e8f4b-80e8-417a-8386-628a55729d95_thumbnail": []byte("thumbnail"),
+	}, db.Metadata.ExtraFiles)
+}
+
+func TestMetadata_copy(t *testing.T) {
+	var md *Metadata
+	assert.Nil(t, md.copy())
+
+	md = &Metadata{
+		Name:       "name",
+		Thumbnail:  []byte("thumbnail"),
+		ExtraFiles: map[string][]byte{"file": []byte("content")},
+	}
+	cp := md.copy()
+	assert.Equal(t, md, cp)
+
+	cp.Thumbnail[0] = 'T'
+	cp.ExtraFiles["file"][0] = 'C'
+	assert.Equal(t, []byte("thumbnail"), md.Thumbnail)
+	assert.Equal(t, []byte("content"), md.ExtraFiles["file"])
+}
@@ -0,0 +1,52 @@
+package model
+
+import "sync"
+
+// Progress represents the progress of a running import or export. It is
+// sent after each completed step, like importing the Notes of a backup.
+type Progress struct {
+	// Step is the name of the step that has been completed last
+	Step      string
+	Completed int
+	Total     int
+	Done      bool
+}
+
+// progressReporter sends Progress over a channel. It is safe to use
+// concurrently and to call its methods on a nil progressReporter.
+type progressReporter struct {
+	sync.Mutex
+	prgrs     chan Progress
+	completed int
+	total     int
+}
+
+// step reports that the step with the given name has been completed.
+func (r *progressReporter) step(name string) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.completed++
+	r.send(Progress{Step: name, Completed: r.completed, Total: r.total})
+}
+
+// done reports that all steps have been completed.
+func (r *progressReporter) done() {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.send(Progress{Completed: r.completed, Total: r.total, Done: true})
+}
+
+// send sends p over the channel. As there are only a few steps, they are
+// all delivered, so the receiver has to read until the channel is closed.
+func (r *progressReporter) send(p Progress) {
+	if r.prgrs == nil {
+		return
+	}
+	r.prgrs <- p
+}
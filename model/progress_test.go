@@ -0,0 +1,124 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectProgress receives all Progress sent over prgrs until it is closed.
+func collectProgress(prgrs chan Progress) <-chan []Progress {
+	result := make(chan []Progress)
+	go func() {
+		var progress []Progress
+		for p := range prgrs {
+			progress = append(progress, p)
+		}
+		result <- progress
+	}()
+	return result
+}
+
+func TestDatabase_ImportJWLBackupContext(t *testing.T) {
+	path := filepath.Join("testdata", "backup.jwlibrary")
+
+	prgrs := make(chan Progress)
+	collected := collectProgress(prgrs)
+	db := &Database{}
+	require.NoError(t, db.ImportJWLBackupContext(context.Background(), path, prgrs))
+	progress := <-collected
+
+	expected := &Database{}
+	require.NoError(t, expected.ImportJWLBackup(path))
+	assert.True(t, expected.Equals(db))
+
+	require.Len(t, progress, 11)
+	assert.Equal(t, Progress{Step: "Reading backup", Completed: 1, Total: 10}, progress[0])
+	steps := map[string]bool{}
+	for i, p := range progress[1:9] {
+		assert.Equal(t, i+2, p.Completed)
+		steps[p.Step] = true
+	}
+	for _, mdl := range importedModels {
+		assert.True(t, steps["Importing "+mdl.tableName()], mdl.tableName())
+	}
+	assert.Equal(t, Progress{Step: "Reading attachments", Completed: 10, Total: 10}, progress[9])
+	assert.Equal(t, Progress{Completed: 10, Total: 10, Done: true}, progress[10])
+
+	// Canceling stops the import
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	prgrs = make(chan Progress)
+	collected = collectProgress(prgrs)
+	err := (&Database{}).ImportJWLBackupContext(ctx, path, prgrs)
+	assert.ErrorIs(t, err, context.Canceled)
+	progress = <-collected
+	assert.NotContains(t, progress, Progress{Completed: 10, Total: 10, Done: true})
+
+	// The prgrs channel is optional
+	assert.NoError(t, (&Database{}).ImportJWLBackupContext(context.Background(), path, nil))
+}
+
+func TestDatabase_ExportJWLBackupContext(t *testing.T) {
+	db := &Database{}
+	require.NoError(t, db.ImportJWLBackup(filepath.Join("testdata", "backup.jwlibrary")))
+	path := filepath.Join(t.TempDir(), "backup.jwlibrary")
+
+	prgrs := make(chan Progress)
+	collected := collectProgress(prgrs)
+	require.NoError(t, db.ExportJWLBackupContext(context.Background(), path, ExportOptions{}, prgrs))
+	progress := <-collected
+
+	exported := &Database{}
+	require.NoError(t, exported.ImportJWLBackup(path))
+	assert.True(t, db.Equals(exported))
+
+	assert.Equal(t, []Progress{
+		{Step: "Exporting BlockRange", Completed: 1, Total: 10},
+		{Step: "Exporting Bookmark", Completed: 2, Total: 10},
+		{Step: "Exporting InputField", Completed: 3, Total: 10},
+		{Step: "Exporting Location", Completed: 4, Total: 10},
+		{Step: "Exporting Note", Completed: 5, Total: 10},
+		{Step: "Exporting Tag", Completed: 6, Total: 10},
+		{Step: "Exporting TagMap", Completed: 7, Total: 10},
+		{Step: "Exporting UserMark", Completed: 8, Total: 10},
+		{Step: "Compacting database", Completed: 9, Total: 10},
+		{Step: "Writing backup", Completed: 10, Total: 10},
+		{Completed: 10, Total: 10, Done: true},
+	}, progress)
+
+	// Canceling stops the export and doesn't leave a file behind
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	canceledPath := filepath.Join(t.TempDir(), "canceled.jwlibrary")
+	err := db.ExportJWLBackupContext(ctx, canceledPath, ExportOptions{}, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoFileExists(t, canceledPath)
+
+	var buf bytes.Buffer
+	err = db.ExportJWLBackupToContext(ctx, &buf, ExportOptions{}, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, buf.Len())
+}
+
+func Test_progressReporter(t *testing.T) {
+	// A nil progressReporter or one without channel doesn't report anything
+	var r *progressReporter
+	r.step("step")
+	r.done()
+	(&progressReporter{}).step("step")
+
+	prgrs := make(chan Progress, 2)
+	r = &progressReporter{prgrs: prgrs, total: 1}
+	r.step("first")
+	r.done()
+	close(prgrs)
+	assert.Equal(t, []Progress{
+		{Step: "first", Completed: 1, Total: 1},
+		{Completed: 1, Total: 1, Done: true},
+	}, <-collectProgress(prgrs))
+}
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Types of blocks a BlockRange, Note, or Bookmark can refer to
+const (
+	// BlockTypeParagraph identifies a paragraph within a publication
+	BlockTypeParagraph = 1
+	// BlockTypeVerse identifies a verse within a Bible chapter
+	BlockTypeVerse = 2
+)
+
+// BlockReference returns a human readable reference of the blocks with the
+// given identifiers within location. For verses of a Bible chapter it looks
+// like "Matthew 24:14-16", for paragraphs like "Location-Title ¶3". Without
+// identifiers, only Bible chapters are referenced. If the blocks can't be
+// referenced, it returns an empty string.
+func BlockReference(location *Location, blockType int, identifiers ...int) string {
+	if location == nil {
+		return ""
+	}
+
+	blocks := formatIdentifiers(identifiers)
+	base := location.Reference()
+	if base == "" && blockType == BlockTypeParagraph && blocks != "" {
+		base = location.Title.String
+		if base == "" {
+			base = location.KeySymbol.String
+		}
+	}
+	if base == "" || blocks == "" {
+		return base
+	}
+
+	switch blockType {
+	case BlockTypeVerse:
+		if !location.ChapterNumber.Valid {
+			return base
+		}
+		return base + ":" + blocks
+	case BlockTypeParagraph:
+		return base + " ¶" + blocks
+	default:
+		return base
+	}
+}
+
+// Reference returns a human readable reference of the verses
+// or paragraphs the UserMarkBlockRange is marking.
+func (m *UserMarkBlockRange) Reference(db *Database) string {
+	if m.UserMark == nil {
+		return ""
+	}
+	location, ok := db.FetchFromTable("Location", m.UserMark.LocationID).(*Location)
+	if !ok {
+		return ""
+	}
+
+	blockType := 0
+	identifiers := make([]int, 0, len(m.BlockRanges))
+	for _, br := range m.BlockRanges {
+		if br == nil {
+			continue
+		}
+		blockType = br.BlockType
+		identifiers = append(identifiers, br.Identifier)
+	}
+
+	return BlockReference(location, blockType, identifiers...)
+}
+
+// Reference returns a human readable reference of the verse or
+// paragraph the Note belongs to.
+func (m *Note) Reference(db *Database) string {
+	location, ok := db.FetchFromTable("Location", int(m.LocationID.Int32)).(*Location)
+	if !ok {
+		return ""
+	}
+	if !m.BlockIdentifier.Valid {
+		return BlockReference(location, m.BlockType)
+	}
+	return BlockReference(location, m.BlockType, int(m.BlockIdentifier.Int32))
+}
+
+// Reference returns a human readable reference of the verse or
+// paragraph the Bookmark points to.
+func (m *Bookmark) Reference(db *Database) string {
+	location, ok := db.FetchFromTable("Location", m.LocationID).(*Location)
+	if !ok {
+		return ""
+	}
+	if !m.BlockIdentifier.Valid {
+		return BlockReference(location, m.BlockType)
+	}
+	return BlockReference(location, m.BlockType, int(m.BlockIdentifier.Int32))
+}
+
+// formatIdentifiers joins the given identifiers to a list like "1-3, 5",
+// where consecutive identifiers are combined to a range.
+func formatIdentifiers(identifiers []int) string {
+	if len(identifiers) == 0 {
+		return ""
+	}
+	ids := append([]int(nil), identifiers...)
+	sort.Ints(ids)
+
+	var parts []string
+	start := ids[0]
+	for i := 1; i <= len(ids); i++ {
+		if i < len(ids) && ids[i] <= ids[i-1]+1 {
+			continue
+		}
+		end := ids[i-1]
+		if start == end {
+			parts = append(parts, strconv.Itoa(start))
+		} else {
+			parts = append(parts, strconv.Itoa(start)+"-"+strconv.Itoa(end))
+		}
+		if i < len(ids) {
+			start = ids[i]
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,139 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var referenceDB = &Database{
+	Location: []*Location{
+		nil,
+		{
+			LocationID:    1,
+			BookNumber:    sql.NullInt32{Int32: 40, Valid: true},
+			ChapterNumber: sql.NullInt32{Int32: 24, Valid: true},
+			KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+			MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+		},
+		{
+			LocationID:   2,
+			DocumentID:   sql.NullInt32{Int32: 1102002020, Valid: true},
+			Title:        sql.NullString{String: "Draw Close to Jehovah", Valid: true},
+			KeySymbol:    sql.NullString{String: "cl", Valid: true},
+			MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+		},
+		{
+			LocationID:   3,
+			BookNumber:   sql.NullInt32{Int32: 43, Valid: true},
+			KeySymbol:    sql.NullString{String: "nwtsty", Valid: true},
+			MepsLanguage: sql.NullInt32{Int32: 2, Valid: true},
+		},
+	},
+}
+
+func TestBlockReference(t *testing.T) {
+	tests := []struct {
+		name        string
+		location    *Location
+		blockType   int
+		identifiers []int
+		want        string
+	}{
+		{
+			name:        "Verse",
+			location:    referenceDB.Location[1],
+			blockType:   BlockTypeVerse,
+			identifiers: []int{14},
+			want:        "Matthew 24:14",
+		},
+		{
+			name:        "Verses",
+			location:    referenceDB.Location[1],
+			blockType:   BlockTypeVerse,
+			identifiers: []int{16, 14, 15, 20, 14},
+			want:        "Matthew 24:14-16, 20",
+		},
+		{
+			name:      "Chapter",
+			location:  referenceDB.Location[1],
+			blockType: 0,
+			want:      "Matthew 24",
+		},
+		{
+			name:        "Book without chapter",
+			location:    referenceDB.Location[3],
+			blockType:   BlockTypeVerse,
+			identifiers: []int{1},
+			want:        "Johannes",
+		},
+		{
+			name:        "Paragraphs",
+			location:    referenceDB.Location[2],
+			blockType:   BlockTypeParagraph,
+			identifiers: []int{3, 4},
+			want:        "Draw Close to Jehovah ¶3-4",
+		},
+		{
+			name:        "Paragraph without title",
+			location:    &Location{KeySymbol: sql.NullString{String: "w", Valid: true}},
+			blockType:   BlockTypeParagraph,
+			identifiers: []int{1},
+			want:        "w ¶1",
+		},
+		{
+			name:      "Document",
+			location:  referenceDB.Location[2],
+			blockType: BlockTypeParagraph,
+			want:      "",
+		},
+		{
+			name:        "No location",
+			blockType:   BlockTypeVerse,
+			identifiers: []int{1},
+			want:        "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, BlockReference(tt.location, tt.blockType, tt.identifiers...))
+		})
+	}
+}
+
+func TestUserMarkBlockRange_Reference(t *testing.T) {
+	umbr := &UserMarkBlockRange{
+		UserMark: &UserMark{UserMarkID: 1, LocationID: 1},
+		BlockRanges: []*BlockRange{
+			{BlockRangeID: 1, BlockType: BlockTypeVerse, Identifier: 14, UserMarkID: 1},
+			{BlockRangeID: 2, BlockType: BlockTypeVerse, Identifier: 15, UserMarkID: 1},
+		},
+	}
+	assert.Equal(t, "Matthew 24:14-15", umbr.Reference(referenceDB))
+	assert.Equal(t, "", umbr.Reference(nil))
+	assert.Equal(t, "", (&UserMarkBlockRange{}).Reference(referenceDB))
+}
+
+func TestNote_Reference(t *testing.T) {
+	note := &Note{
+		LocationID:      sql.NullInt32{Int32: 2, Valid: true},
+		BlockType:       BlockTypeParagraph,
+		BlockIdentifier: sql.NullInt32{Int32: 7, Valid: true},
+	}
+	assert.Equal(t, "Draw Close to Jehovah ¶7", note.Reference(referenceDB))
+
+	note = &Note{LocationID: sql.NullInt32{Int32: 1, Valid: true}}
+	assert.Equal(t, "Matthew 24", note.Reference(referenceDB))
+	assert.Equal(t, "", (&Note{}).Reference(referenceDB))
+}
+
+func TestBookmark_Reference(t *testing.T) {
+	bookmark := &Bookmark{
+		LocationID:      1,
+		BlockType:       BlockTypeVerse,
+		BlockIdentifier: sql.NullInt32{Int32: 14, Valid: true},
+	}
+	assert.Equal(t, "Matthew 24:14", bookmark.Reference(referenceDB))
+	assert.Equal(t, "", bookmark.Reference(nil))
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// schemaTemplateFiles contains an empty userData.db for every schema
+// version we ship a template for (currently 13 and 14). The files are named
+// userData_v<version>.db. Backups of other versions can only be exported
+// after a backup of the same version has been imported, as their template
+// is derived from it.
+//
+//go:embed data/userData_v*.db
+var schemaTemplateFiles embed.FS
+
+var schemaTemplateFilename = regexp.MustCompile(`^userData_v(\d+)\.db$`)
+
+// schemas holds the templates (empty userData.db files) of all known
+// schema versions. Besides the embedded templates, it contains templates
+// that have been derived from imported backups of versions we don't
+// ship a template for.
+var schemas = struct {
+	sync.RWMutex
+	templates map[int][]byte
+}{templates: map[int][]byte{}}
+
+// latestSchemaVersion is the newest schema version we ship a template for.
+// It is used for Databases that haven't been imported from a backup.
+var latestSchemaVersion int
+
+func init() {
+	entries, err := schemaTemplateFiles.ReadDir("data")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		matches := schemaTemplateFilename.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+		version, err := strconv.Atoi(matches[1])
+		if err != nil {
+			panic(err)
+		}
+		template, err := schemaTemplateFiles.ReadFile(path.Join("data", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		schemas.templates[version] = template
+		if version > latestSchemaVersion {
+			latestSchemaVersion = version
+		}
+	}
+}
+
+// schemaTemplate returns an empty userData.db of the given schema version.
+// If version is 0, the template of the latest embedded version is returned.
+// If there is no template for the version, an error wrapping
+// ErrUnsupportedVersion is returned.
+func schemaTemplate(version int) ([]byte, error) {
+	if version == 0 {
+		version = latestSchemaVersion
+	}
+
+	schemas.RLock()
+	defer schemas.RUnlock()
+	template, ok := schemas.templates[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: no template for schema version %d available. "+
+			"Import a backup of this version first or export it with version %d",
+			ErrUnsupportedVersion, version, latestSchemaVersion)
+	}
+	return template, nil
+}
+
+// hasSchemaTemplate checks if a template for the given schema version exists.
+func hasSchemaTemplate(version int) bool {
+	schemas.RLock()
+	defer schemas.RUnlock()
+	_, ok := schemas.templates[version]
+	return ok
+}
+
+// registerSchemaFromSQLite derives a template for the given schema version from
+// content (a userData.db) by removing all of its entries. This way, we are
+// able to write backups of schema versions that we don't ship a template for.
+// If a template for the version already exists, nothing is done.
+func registerSchemaFromSQLite(version int, content []byte) error {
+	if hasSchemaTemplate(version) {
+		return nil
+	}
+
+	sqlite, err := openInMemorySQLite(content)
+	if err != nil {
+		return err
+	}
+	defer sqlite.Close()
+
+	if err := clearSQLite(sqlite); err != nil {
+		return errors.Wrapf(err, "Error while deriving template for schema version %d", version)
+	}
+
+	template, err := serializeSQLite(sqlite)
+	if err != nil {
+		return err
+	}
+
+	schemas.Lock()
+	defer schemas.Unlock()
+	if _, ok := schemas.templates[version]; !ok {
+		schemas.templates[version] = template
+	}
+
+	return nil
+}
+
+// tablesKeptInTemplate are tables whose content is part of the schema itself
+// and therefore must not be removed when deriving a template.
+var tablesKeptInTemplate = map[string]bool{
+	"grdb_migrations": true,
+	"LastModified":    true,
+}
+
+// clearSQLite removes all user entries from the given SQLite database.
+func clearSQLite(sqlite *sql.DB) error {
+	rows, err := sqlite.Query("SELECT name FROM sqlite_master WHERE type = 'table'")
+	if err != nil {
+		return errors.Wrap(err, "Error while listing tables")
+	}
+	tables := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return errors.Wrap(err, "Error while listing tables")
+		}
+		if tablesKeptInTemplate[name] || strings.HasPrefix(name, "sqlite_") {
+			continue
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "Error while listing tables")
+	}
+
+	for _, table := range tables {
+		if _, err := sqlite.Exec(fmt.Sprintf("DELETE FROM \"%s\"", table)); err != nil {
+			return errors.Wrapf(err, "Error while clearing table %s", table)
+		}
+	}
+	if _, err := sqlite.Exec("VACUUM"); err != nil {
+		return errors.Wrap(err, "Error while vacuuming SQLite DB")
+	}
+
+	return nil
+}
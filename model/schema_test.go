@@ -0,0 +1,190 @@
+package model
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_schemaTemplate(t *testing.T) {
+	template, err := schemaTemplate(0)
+	assert.NoError(t, err)
+	latest, err := schemaTemplate(latestSchemaVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, latest, template)
+
+	_, err = schemaTemplate(13)
+	assert.NoError(t, err)
+	_, err = schemaTemplate(14)
+	assert.NoError(t, err)
+
+	_, err = schemaTemplate(1)
+	assert.ErrorContains(t, err, "no template for schema version 1 available")
+}
+
+func Test_registerSchemaFromSQLite(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testdata", userDataFilename))
+	assert.NoError(t, err)
+	original := append([]byte{}, content...)
+
+	assert.False(t, hasSchemaTemplate(1001))
+	assert.NoError(t, registerSchemaFromSQLite(1001, content))
+	assert.True(t, hasSchemaTemplate(1001))
+
+	// Input should not be touched
+	assert.Equal(t, original, content)
+
+	// Template should be empty
+	template, err := schemaTemplate(1001)
+	assert.NoError(t, err)
+	sqlite, err := openInMemorySQLite(template)
+	assert.NoError(t, err)
+	defer sqlite.Close()
+	for _, table := range []string{"BlockRange", "Bookmark", "InputField", "Location", "Note", "Tag", "TagMap", "UserMark"} {
+		count, err := getTableEntryCount(sqlite, table)
+		assert.NoError(t, err)
+		assert.Zero(t, count, table)
+	}
+	count, err := getTableEntryCount(sqlite, "grdb_migrations")
+	assert.NoError(t, err)
+	assert.NotZero(t, count)
+
+	// Existing templates are not overwritten
+	embedded, err := schemaTemplate(14)
+	assert.NoError(t, err)
+	assert.NoError(t, registerSchemaFromSQLite(14, content))
+	template, err = schemaTemplate(14)
+	assert.NoError(t, err)
+	assert.Equal(t, embedded, template)
+}
+
+func TestDatabase_newerSchemaVersion(t *testing.T) {
+	tmp := t.TempDir()
+
+	// Simulate a newer schema version that added a column
+	// to Location and changed the order of the columns in Tag
+	dbPath := filepath.Join(tmp, userDataFilename)
+	content, err := os.ReadFile(filepath.Join("testdata", userDataFilename))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(dbPath, content, 0644))
+	sqlite, err := sql.Open("sqlite3", dbPath)
+	assert.NoError(t, err)
+	for _, stmt := range []string{
+		"ALTER TABLE Location ADD COLUMN Extra TEXT NOT NULL DEFAULT 'extra'",
+		"CREATE TABLE TagNew (Name TEXT NOT NULL, TagId INTEGER NOT NULL PRIMARY KEY, Type INTEGER NOT NULL)",
+		"INSERT INTO TagNew (Name, TagId, Type) SELECT Name, TagId, Type FROM Tag",
+		"DROP TABLE Tag",
+		"ALTER TABLE TagNew RENAME TO Tag",
+	} {
+		_, err := sqlite.Exec(stmt)
+		assert.NoError(t, err, stmt)
+	}
+	assert.NoError(t, sqlite.Close())
+	content, err = os.ReadFile(dbPath)
+	assert.NoError(t, err)
+
+	mfst := generateManifest(testMetadata(), userDataFilename, content, 1002, time.Now())
+	var mfstContent bytes.Buffer
+	assert.NoError(t, mfst.exportManifest(&mfstContent))
+	var backup bytes.Buffer
+	assert.NoError(t, writeZip(&backup, []zipEntry{
+		{name: userDataFilename, content: content},
+		{name: manifestFilename, content: mfstContent.Bytes()},
+	}))
+
+	db := &Database{}
+	assert.NoError(t, db.ImportJWLBackupFrom(bytes.NewReader(backup.Bytes()), int64(backup.Len())))
+	assert.Equal(t, 1002, db.SchemaVersion)
+	assert.Len(t, db.Location, 9)
+	assert.Equal(t, &Tag{2, 1, "Strengthening"}, db.Tag[2])
+
+	// Exporting should keep the schema version and columns of the input
+	var exported bytes.Buffer
+	assert.NoError(t, db.ExportJWLBackupTo(&exported))
+
+	db2 := &Database{}
+	assert.NoError(t, db2.ImportJWLBackupFrom(bytes.NewReader(exported.Bytes()), int64(exported.Len())))
+	assert.Equal(t, 1002, db2.SchemaVersion)
+	assert.True(t, db.Equals(db2))
+}
+
+func TestDatabase_schemaVersionRoundTrip(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testdata", userDataFilename))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		schemaVersion int
+	}{
+		{name: "Oldest supported version", schemaVersion: supportedSchemaVersionMin},
+		{name: "Latest embedded version", schemaVersion: latestSchemaVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mfst := generateManifest(testMetadata(), userDataFilename, content, tt.schemaVersion, time.Now())
+			var mfstContent bytes.Buffer
+			assert.NoError(t, mfst.exportManifest(&mfstContent))
+			var backup bytes.Buffer
+			assert.NoError(t, writeZip(&backup, []zipEntry{
+				{name: userDataFilename, content: content},
+				{name: manifestFilename, content: mfstContent.Bytes()},
+			}))
+
+			db := &Database{}
+			assert.NoError(t, db.ImportJWLBackupFrom(bytes.NewReader(backup.Bytes()), int64(backup.Len())))
+			assert.Equal(t, tt.schemaVersion, db.SchemaVersion)
+
+			var exported bytes.Buffer
+			assert.NoError(t, db.ExportJWLBackupTo(&exported))
+
+			db2 := &Database{}
+			assert.NoError(t, db2.ImportJWLBackupFrom(bytes.NewReader(exported.Bytes()), int64(exported.Len())))
+			assert.Equal(t, tt.schemaVersion, db2.SchemaVersion)
+			assert.True(t, db.Equals(db2))
+		})
+	}
+}
+
+func TestDatabase_ExportJWLBackup_embeddedSchemaVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		schemaVersion int
+		wantVersion   int
+	}{
+		{name: "Default", wantVersion: latestSchemaVersion},
+		{name: "13", schemaVersion: 13, wantVersion: 13},
+		{name: "14", schemaVersion: 14, wantVersion: 14},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A fresh Database that hasn't been imported from a backup
+			db := &Database{
+				SchemaVersion: tt.schemaVersion,
+				Tag:           []*Tag{nil, {TagID: 1, TagType: 1, Name: "Tag"}},
+			}
+
+			var exported bytes.Buffer
+			assert.NoError(t, db.ExportJWLBackupTo(&exported))
+
+			db2 := &Database{}
+			assert.NoError(t, db2.ImportJWLBackupFrom(bytes.NewReader(exported.Bytes()), int64(exported.Len())))
+			assert.Equal(t, tt.wantVersion, db2.SchemaVersion)
+			assert.Equal(t, db.Tag, db2.Tag)
+		})
+	}
+}
+
+func TestDatabase_ExportJWLBackup_unsupportedSchemaVersion(t *testing.T) {
+	db := &Database{SchemaVersion: 1003}
+	path := filepath.Join(t.TempDir(), "backup.jwlibrary")
+
+	err := db.ExportJWLBackup(path)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+	assert.ErrorContains(t, err, "no template for schema version 1003 available")
+	assert.NoFileExists(t, path)
+}
@@ -0,0 +1,165 @@
+package model
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SearchOptions narrow down the results of Database.Search. Empty
+// fields don't filter the results.
+type SearchOptions struct {
+	// Tag only matches entries tagged with a tag of this name
+	Tag string
+	// KeySymbol only matches entries of this publication (like nwtsty)
+	KeySymbol string
+	// MepsLanguage only matches entries of publications in this language
+	MepsLanguage sql.NullInt32
+	// From and To only match notes that have been modified within the range.
+	// As bookmarks don't have a date, they are not matched if one is set.
+	From time.Time
+	To   time.Time
+}
+
+// SearchResult is an entry (a *Note or *Bookmark) matching a search
+// together with its Location, the verse or paragraph it belongs to, and tags.
+type SearchResult struct {
+	Model     Model
+	Location  *Location
+	Reference string
+	Tags      []*Tag
+}
+
+// Search returns the notes (by their title and content) and bookmarks (by their
+// title and snippet) that contain all words of query, ignoring the case.
+// An empty query matches all entries that fit the options. Notes are
+// returned first, both ordered by their ID.
+func (db *Database) Search(query string, opts SearchOptions) ([]SearchResult, error) {
+	if !opts.From.IsZero() && !opts.To.IsZero() && opts.To.Before(opts.From) {
+		return nil, errors.New("The end of the date range must not be before its start")
+	}
+	words := strings.Fields(strings.ToLower(query))
+	noteTags, locationTags := db.tagsByEntry()
+
+	var result []SearchResult
+	for _, note := range db.Note {
+		if note == nil || !containsAll(words, note.Title.String, note.Content.String) {
+			continue
+		}
+		if !opts.From.IsZero() || !opts.To.IsZero() {
+			modified, err := parseSearchDate(note.LastModified)
+			if err != nil || (!opts.From.IsZero() && modified.Before(opts.From)) ||
+				(!opts.To.IsZero() && modified.After(opts.To)) {
+				continue
+			}
+		}
+		sr := SearchResult{Model: note, Reference: note.Reference(db), Tags: noteTags[note.NoteID]}
+		if note.LocationID.Valid {
+			sr.Location = db.searchLocation(int(note.LocationID.Int32))
+		}
+		if opts.matches(sr) {
+			result = append(result, sr)
+		}
+	}
+
+	if opts.From.IsZero() && opts.To.IsZero() {
+		for _, bookmark := range db.Bookmark {
+			if bookmark == nil || !containsAll(words, bookmark.Title, bookmark.Snippet.String) {
+				continue
+			}
+			sr := SearchResult{
+				Model:     bookmark,
+				Location:  db.searchLocation(bookmark.LocationID),
+				Reference: bookmark.Reference(db),
+				Tags:      locationTags[bookmark.LocationID],
+			}
+			if opts.matches(sr) {
+				result = append(result, sr)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// matches checks if the Location and tags of sr fit the options.
+func (opts SearchOptions) matches(sr SearchResult) bool {
+	if opts.KeySymbol != "" && (sr.Location == nil || !strings.EqualFold(sr.Location.KeySymbol.String, opts.KeySymbol)) {
+		return false
+	}
+	if opts.MepsLanguage.Valid && (sr.Location == nil || sr.Location.MepsLanguage != opts.MepsLanguage) {
+		return false
+	}
+	if opts.Tag != "" {
+		for _, tag := range sr.Tags {
+			if strings.EqualFold(tag.Name, opts.Tag) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// searchLocation returns the Location with the given ID or nil if it doesn't exist.
+func (db *Database) searchLocation(id int) *Location {
+	if location := db.FetchFromTable("Location", id); location != nil {
+		return location.(*Location)
+	}
+	return nil
+}
+
+// tagsByEntry returns the tags of each note and location
+// by their ID, ordered by their position.
+func (db *Database) tagsByEntry() (map[int][]*Tag, map[int][]*Tag) {
+	tagMaps := make([]*TagMap, 0, len(db.TagMap))
+	for _, tm := range db.TagMap {
+		if tm != nil {
+			tagMaps = append(tagMaps, tm)
+		}
+	}
+	sort.SliceStable(tagMaps, func(i, j int) bool {
+		return tagMaps[i].Position < tagMaps[j].Position
+	})
+
+	noteTags := map[int][]*Tag{}
+	locationTags := map[int][]*Tag{}
+	for _, tm := range tagMaps {
+		tag, ok := db.FetchFromTable("Tag", tm.TagID).(*Tag)
+		if !ok {
+			continue
+		}
+		if tm.NoteID.Valid {
+			noteTags[int(tm.NoteID.Int32)] = append(noteTags[int(tm.NoteID.Int32)], tag)
+		}
+		if tm.LocationID.Valid {
+			locationTags[int(tm.LocationID.Int32)] = append(locationTags[int(tm.LocationID.Int32)], tag)
+		}
+	}
+	return noteTags, locationTags
+}
+
+// containsAll checks if all (lowercase) words are contained in one of texts.
+func containsAll(words []string, texts ...string) bool {
+	text := strings.ToLower(strings.Join(texts, "\n"))
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSearchDate parses the LastModified timestamp of a Note, which
+// comes in different layouts depending on the version of JW Library.
+func parseSearchDate(date string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02T15:04:05-0700", time.RFC3339} {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("Could not parse date %s", date)
+}
@@ -0,0 +1,171 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var searchDB = &Database{
+	Bookmark: []*Bookmark{
+		nil,
+		{
+			BookmarkID:            1,
+			LocationID:            2,
+			PublicationLocationID: 1,
+			Title:                 "Genesis 1",
+			Snippet:               sql.NullString{String: "In the beginning God created the heavens", Valid: true},
+		},
+	},
+	Location: []*Location{
+		nil,
+		{
+			LocationID:    1,
+			BookNumber:    sql.NullInt32{Int32: 1, Valid: true},
+			ChapterNumber: sql.NullInt32{Int32: 1, Valid: true},
+			KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+			MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+		},
+		{
+			LocationID:    2,
+			BookNumber:    sql.NullInt32{Int32: 1, Valid: true},
+			ChapterNumber: sql.NullInt32{Int32: 1, Valid: true},
+			KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+			MepsLanguage:  sql.NullInt32{Int32: 2, Valid: true},
+		},
+		{
+			LocationID:     3,
+			IssueTagNumber: 20200100,
+			KeySymbol:      sql.NullString{String: "w", Valid: true},
+			MepsLanguage:   sql.NullInt32{Int32: 0, Valid: true},
+		},
+	},
+	Note: []*Note{
+		nil,
+		{
+			NoteID:          1,
+			GUID:            "1",
+			LocationID:      sql.NullInt32{Int32: 1, Valid: true},
+			Title:           sql.NullString{String: "Creation", Valid: true},
+			Content:         sql.NullString{String: "God created the heavens and the earth", Valid: true},
+			LastModified:    "2020-01-10T10:00:00+00:00",
+			BlockType:       BlockTypeVerse,
+			BlockIdentifier: sql.NullInt32{Int32: 1, Valid: true},
+		},
+		{
+			NoteID:       2,
+			GUID:         "2",
+			LocationID:   sql.NullInt32{Int32: 3, Valid: true},
+			Title:        sql.NullString{String: "Study article", Valid: true},
+			Content:      sql.NullString{String: "Why did God create the EARTH?", Valid: true},
+			LastModified: "2021-03-01T10:00:00+0100",
+		},
+		{
+			NoteID:       3,
+			GUID:         "3",
+			Title:        sql.NullString{String: "Without location", Valid: true},
+			LastModified: "2022-05-01T10:00:00Z",
+		},
+	},
+	Tag: []*Tag{
+		nil,
+		{TagID: 1, TagType: 1, Name: "Favorite"},
+		{TagID: 2, TagType: 1, Name: "Research"},
+	},
+	TagMap: []*TagMap{
+		nil,
+		{TagMapID: 1, NoteID: sql.NullInt32{Int32: 1, Valid: true}, TagID: 2, Position: 1},
+		{TagMapID: 2, NoteID: sql.NullInt32{Int32: 1, Valid: true}, TagID: 1, Position: 0},
+		{TagMapID: 3, LocationID: sql.NullInt32{Int32: 2, Valid: true}, TagID: 1, Position: 0},
+		{TagMapID: 4, NoteID: sql.NullInt32{Int32: 2, Valid: true}, TagID: 2, Position: 0},
+	},
+}
+
+func TestDatabase_Search(t *testing.T) {
+	date := func(value string) time.Time {
+		d, err := time.Parse(time.DateOnly, value)
+		require.NoError(t, err)
+		return d
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		opts  SearchOptions
+		want  []Model
+	}{
+		{
+			name:  "Words of title and content",
+			query: "creation EARTH",
+			want:  []Model{searchDB.Note[1]},
+		},
+		{
+			name:  "Notes and bookmarks",
+			query: "heavens",
+			want:  []Model{searchDB.Note[1], searchDB.Bookmark[1]},
+		},
+		{
+			name: "Empty query",
+			want: []Model{searchDB.Note[1], searchDB.Note[2], searchDB.Note[3], searchDB.Bookmark[1]},
+		},
+		{
+			name:  "No match",
+			query: "nothing",
+		},
+		{
+			name:  "Tag",
+			query: "god",
+			opts:  SearchOptions{Tag: "favorite"},
+			want:  []Model{searchDB.Note[1], searchDB.Bookmark[1]},
+		},
+		{
+			name: "KeySymbol",
+			opts: SearchOptions{KeySymbol: "w"},
+			want: []Model{searchDB.Note[2]},
+		},
+		{
+			name: "Language",
+			opts: SearchOptions{MepsLanguage: sql.NullInt32{Int32: 0, Valid: true}},
+			want: []Model{searchDB.Note[1], searchDB.Note[2]},
+		},
+		{
+			name: "Date range",
+			opts: SearchOptions{From: date("2020-02-01"), To: date("2022-01-01")},
+			want: []Model{searchDB.Note[2]},
+		},
+		{
+			name: "Start of date range",
+			opts: SearchOptions{From: date("2021-01-01")},
+			want: []Model{searchDB.Note[2], searchDB.Note[3]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := searchDB.Search(tt.query, tt.opts)
+			require.NoError(t, err)
+			var got []Model
+			for _, result := range results {
+				got = append(got, result.Model)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// Locations and tags are returned with the results
+	results, err := searchDB.Search("creation", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, searchDB.Location[1], results[0].Location)
+	assert.Equal(t, "Genesis 1:1", results[0].Reference)
+	assert.Equal(t, []*Tag{searchDB.Tag[1], searchDB.Tag[2]}, results[0].Tags)
+
+	_, err = searchDB.Search("", SearchOptions{From: date("2022-01-01"), To: date("2021-01-01")})
+	assert.Error(t, err)
+
+	results, err = (&Database{}).Search("", SearchOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// openInMemorySQLite opens a new in-memory SQLite database containing a copy
+// of content, which is expected to be the content of a SQLite database file.
+// This allows us to work with the userData.db without writing it to disk.
+func openInMemorySQLite(content []byte) (*sql.DB, error) {
+	sqlite, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while opening in-memory SQLite database")
+	}
+	// Every connection has its own in-memory database, so we have
+	// to make sure that all queries are using the same one.
+	sqlite.SetMaxOpenConns(1)
+	sqlite.SetConnMaxLifetime(0)
+
+	conn, err := sqlite.Conn(context.Background())
+	if err != nil {
+		sqlite.Close()
+		return nil, errors.Wrap(err, "Error while connecting to in-memory SQLite database")
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(driverConn interface{}) error {
+		dst, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected SQLite connection %T", driverConn)
+		}
+
+		// A deserialized database can't grow, so we only use it as
+		// the source for copying it to a regular in-memory database.
+		drvConn, err := (&sqlite3.SQLiteDriver{}).Open(":memory:")
+		if err != nil {
+			return err
+		}
+		src := drvConn.(*sqlite3.SQLiteConn)
+		defer src.Close()
+		if err := src.Deserialize(withoutWAL(content), "main"); err != nil {
+			return err
+		}
+
+		backup, err := dst.Backup("main", src, "main")
+		if err != nil {
+			return err
+		}
+		if _, err := backup.Step(-1); err != nil {
+			backup.Finish()
+			return err
+		}
+		return backup.Finish()
+	})
+	if err != nil {
+		sqlite.Close()
+		return nil, errors.Wrap(err, "Error while loading SQLite database into memory")
+	}
+
+	return sqlite, nil
+}
+
+// serializeSQLite returns the content of the given SQLite database
+// as it would be stored in a file.
+func serializeSQLite(sqlite *sql.DB) ([]byte, error) {
+	conn, err := sqlite.Conn(context.Background())
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while connecting to SQLite database")
+	}
+	defer conn.Close()
+
+	var content []byte
+	err = conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected SQLite connection %T", driverConn)
+		}
+		serialized, err := c.Serialize("main")
+		content = serialized
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while serializing SQLite database")
+	}
+
+	return content, nil
+}
+
+// withoutWAL returns content with the WAL mode disabled in its header. A database
+// in WAL mode can't be deserialized, as SQLite would expect a separate WAL file.
+// See https://www.sqlite.org/fileformat.html#the_database_header
+func withoutWAL(content []byte) []byte {
+	if len(content) < 20 || (content[18] != 2 && content[19] != 2) {
+		return content
+	}
+
+	result := make([]byte, len(content))
+	copy(result, content)
+	result[18] = 1
+	result[19] = 1
+	return result
+}
@@ -1,58 +1,134 @@
 package model
 
 import (
+	"fmt"
 	"io"
-	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/klauspost/compress/zip"
 )
 
-// https://golangcode.com/create-zip-files-in-go/
-func zipFiles(filename string, files []string) error {
-	newZipFile, err := os.Create(filename)
-	if err != nil {
-		return err
-	}
-	defer newZipFile.Close()
+// zipEntry is a file that should be stored in a zip archive.
+type zipEntry struct {
+	name    string
+	content []byte
+}
 
-	zipWriter := zip.NewWriter(newZipFile)
-	defer zipWriter.Close()
+// writeZip writes a zip archive containing the given files to w.
+func writeZip(w io.Writer, files []zipEntry) error {
+	zipWriter := zip.NewWriter(w)
 
 	// Add files to zip
 	for _, file := range files {
-		if err = addFileToZip(zipWriter, file); err != nil {
+		header := &zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		}
+		header.SetMode(0644)
+
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(file.content); err != nil {
 			return err
 		}
 	}
-	return nil
+
+	return zipWriter.Close()
+}
+
+// Limits for the content we read from a backup, so a malicious archive
+// (e.g. a zip bomb) can't exhaust the memory.
+const (
+	maxManifestSize  = 1 << 20 // 1 MiB
+	maxDatabaseSize  = 1 << 30 // 1 GiB
+	maxExtractedSize = 1<<30 + 1<<28
+)
+
+// backupArchive reads entries of a backup (zip) archive while
+// enforcing size limits on them.
+type backupArchive struct {
+	zr *zip.Reader
+	// extracted is the number of bytes that have been read so far.
+	extracted int64
 }
 
-func addFileToZip(zipWriter *zip.Writer, filename string) error {
-	fileToZip, err := os.Open(filename)
+// openBackupArchive opens the zip archive of the given size from r. It
+// returns an ErrCorruptBackup if it isn't a valid archive or contains
+// entries with unsafe names.
+func openBackupArchive(r io.ReaderAt, size int64) (*backupArchive, error) {
+	zr, err := zip.NewReader(r, size)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("%w: %v", ErrCorruptBackup, err)
 	}
-	defer fileToZip.Close()
 
-	// Get the file information
-	info, err := fileToZip.Stat()
-	if err != nil {
-		return err
+	for _, file := range zr.File {
+		if !isSafeEntryName(file.Name) {
+			return nil, fmt.Errorf("%w: unsafe entry name %q", ErrCorruptBackup, file.Name)
+		}
 	}
 
-	header, err := zip.FileInfoHeader(info)
-	if err != nil {
-		return err
+	return &backupArchive{zr: zr}, nil
+}
+
+// readEntry returns the content of the file with the given name in the archive.
+// If the entry doesn't exist or it is bigger than limit, an ErrCorruptBackup is returned.
+func (ba *backupArchive) readEntry(name string, limit int64) ([]byte, error) {
+	for _, file := range ba.zr.File {
+		if file.Name != name {
+			continue
+		}
+
+		if file.UncompressedSize64 > uint64(limit) {
+			return nil, fmt.Errorf("%w: %s exceeds the size limit of %d bytes", ErrCorruptBackup, name, limit)
+		}
+
+		fileReader, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to open %s: %v", ErrCorruptBackup, name, err)
+		}
+		defer fileReader.Close()
+
+		// Don't trust the size in the header, but stop reading once we reach the limit
+		content, err := io.ReadAll(io.LimitReader(fileReader, limit+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read %s: %v", ErrCorruptBackup, name, err)
+		}
+		if int64(len(content)) > limit {
+			return nil, fmt.Errorf("%w: %s exceeds the size limit of %d bytes", ErrCorruptBackup, name, limit)
+		}
+
+		ba.extracted += int64(len(content))
+		if ba.extracted > maxExtractedSize {
+			return nil, fmt.Errorf("%w: content exceeds the size limit of %d bytes", ErrCorruptBackup, int64(maxExtractedSize))
+		}
+
+		return content, nil
 	}
 
-	header.Name = filepath.Base(filename)
-	header.Method = zip.Deflate
+	return nil, fmt.Errorf("%w: backup does not contain %s", ErrCorruptBackup, name)
+}
 
-	writer, err := zipWriter.CreateHeader(header)
-	if err != nil {
-		return err
+// isSafeEntryName checks if name is a relative path that stays inside the
+// directory the archive would be extracted to.
+func isSafeEntryName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "/") || strings.HasPrefix(name, "\\") || filepath.IsAbs(name) {
+		return false
+	}
+	// Windows drive letters like C:
+	if len(name) >= 2 && name[1] == ':' {
+		return false
 	}
-	_, err = io.Copy(writer, fileToZip)
-	return err
+
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return false
+		}
+	}
+
+	return true
 }
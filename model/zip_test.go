@@ -0,0 +1,62 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_isSafeEntryName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "userData.db", want: true},
+		{name: "folder/file.png", want: true},
+		{name: "file..png", want: true},
+		{name: "", want: false},
+		{name: "/etc/passwd", want: false},
+		{name: "../userData.db", want: false},
+		{name: "folder/../../userData.db", want: false},
+		{name: "..\\userData.db", want: false},
+		{name: "\\\\server\\share", want: false},
+		{name: "C:\\Windows", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isSafeEntryName(tt.name))
+		})
+	}
+}
+
+func Test_backupArchive_readEntry(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, writeZip(&buf, []zipEntry{
+		{name: "small", content: []byte("small")},
+		{name: "big", content: bytes.Repeat([]byte("a"), 100)},
+	}))
+
+	archive, err := openBackupArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	content, err := archive.readEntry("small", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("small"), content)
+
+	_, err = archive.readEntry("big", 10)
+	assert.True(t, errors.Is(err, ErrCorruptBackup))
+	assert.ErrorContains(t, err, "big exceeds the size limit of 10 bytes")
+
+	_, err = archive.readEntry("nonexistent", 10)
+	assert.True(t, errors.Is(err, ErrCorruptBackup))
+
+	buf.Reset()
+	assert.NoError(t, writeZip(&buf, []zipEntry{{name: "../evil", content: []byte("evil")}}))
+	_, err = openBackupArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.True(t, errors.Is(err, ErrCorruptBackup))
+
+	_, err = openBackupArchive(bytes.NewReader([]byte("no zip")), 6)
+	assert.True(t, errors.Is(err, ErrCorruptBackup))
+}
@@ -0,0 +1,239 @@
+package publication
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/pkg/errors"
+)
+
+// Queries used to look up publications and documents
+const (
+	publicationByDocumentQuery = "SELECT P.* " +
+		"FROM Publication AS P, PublicationDocument AS PD " +
+		"WHERE P.Id = PD.PublicationId AND PD.DocumentId = ? AND P.MepsLanguageId = ?"
+	publicationQuery = "SELECT * FROM Publication WHERE KeySymbol = ? AND MepsLanguageId = ? AND IssueTagNumber = ?"
+	documentIDsQuery = "SELECT PD.DocumentId " +
+		"FROM Publication AS P, PublicationDocument AS PD " +
+		"WHERE P.Id = PD.PublicationId AND P.KeySymbol = ? AND P.MepsLanguageId = ? AND P.IssueTagNumber = ? " +
+		"ORDER BY PD.DocumentId"
+	searchPublicationsQuery = "SELECT * FROM Publication " +
+		"WHERE (Title LIKE ?1 OR IssueTitle LIKE ?1 OR ShortTitle LIKE ?1 OR Symbol LIKE ?1 OR KeySymbol LIKE ?1) " +
+		"AND (?2 < 0 OR MepsLanguageId = ?2) " +
+		"ORDER BY Year DESC, IssueTagNumber DESC, MepsLanguageId, Title, Id LIMIT ?3"
+)
+
+// Catalog is an opened catalogDB. In contrast to the functions like
+// LookupPublication, it keeps the database and its prepared statements
+// open, so it is suited for running many queries. It is safe for
+// concurrent use and has to be closed once it is not needed anymore.
+type Catalog struct {
+	db    *sql.DB
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+// Document is a document (like a chapter or an article) of a publication.
+// As the catalogDB does not contain the titles of documents, it is described
+// by its publication and its position within it.
+type Document struct {
+	DocumentID  int
+	Publication Publication
+	// Section is the position of the document within the publication, starting at 1
+	Section int
+}
+
+// Describe returns a description of the document, like
+// "Draw Close to Jehovah (2014), document 3".
+func (d Document) Describe() string {
+	return fmt.Sprintf("%s, document %d", d.Publication.Describe(), d.Section)
+}
+
+// OpenCatalog opens the catalogDB located at dbPath.
+func OpenCatalog(dbPath string) (*Catalog, error) {
+	// Check if file exists
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("CatalogDB does not exist at %s", dbPath)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath+"?immutable=1")
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while opening SQLite database")
+	}
+
+	return &Catalog{db: db, stmts: map[string]*sql.Stmt{}}, nil
+}
+
+// Close closes the catalogDB and all prepared statements.
+func (c *Catalog) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, stmt := range c.stmts {
+		stmt.Close()
+	}
+	c.stmts = map[string]*sql.Stmt{}
+
+	return c.db.Close()
+}
+
+// LookupPublication looks up the publication described by query.
+func (c *Catalog) LookupPublication(query Lookup) (Publication, error) {
+	var row *sql.Row
+	if query.DocumentID != 0 {
+		stmt, err := c.prepare(publicationByDocumentQuery)
+		if err != nil {
+			return Publication{}, err
+		}
+		row = stmt.QueryRow(query.DocumentID, query.MepsLanguage)
+	} else {
+		stmt, err := c.prepare(publicationQuery)
+		if err != nil {
+			return Publication{}, err
+		}
+		row = stmt.QueryRow(query.KeySymbol, query.MepsLanguage, query.IssueTagNumber)
+	}
+
+	return scanPublication(row)
+}
+
+// LookupPublications looks up the publications of all queries at once.
+// Queries without a matching publication are left out of the result.
+func (c *Catalog) LookupPublications(queries []Lookup) (map[Lookup]Publication, error) {
+	result := make(map[Lookup]Publication, len(queries))
+	for _, query := range queries {
+		if _, ok := result[query]; ok {
+			continue
+		}
+		publ, err := c.LookupPublication(query)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result[query] = publ
+	}
+
+	return result, nil
+}
+
+// LookupLocation looks up the publication the given Location belongs to.
+func (c *Catalog) LookupLocation(location *model.Location) (Publication, error) {
+	return c.LookupPublication(LocationLookup(location))
+}
+
+// LookupDocumentIDs returns the IDs of all documents of the publication
+// described by query (using its KeySymbol, IssueTagNumber, and MepsLanguage).
+func (c *Catalog) LookupDocumentIDs(query Lookup) ([]int, error) {
+	stmt, err := c.prepare(documentIDsQuery)
+	if err != nil {
+		return nil, err
+	}
+	return queryDocumentIDs(stmt, query)
+}
+
+// Documents returns all documents of the publication described by query
+// (using its KeySymbol, IssueTagNumber, and MepsLanguage).
+func (c *Catalog) Documents(query Lookup) ([]Document, error) {
+	publ, err := c.LookupPublication(Lookup{
+		KeySymbol:      query.KeySymbol,
+		IssueTagNumber: query.IssueTagNumber,
+		MepsLanguage:   query.MepsLanguage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ids, err := c.LookupDocumentIDs(query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Document, len(ids))
+	for i, id := range ids {
+		result[i] = Document{DocumentID: id, Publication: publ, Section: i + 1}
+	}
+	return result, nil
+}
+
+// LookupDocument resolves the document with the given ID in the given
+// language to its publication and position within it.
+func (c *Catalog) LookupDocument(documentID int, mepsLanguage int) (Document, error) {
+	publ, err := c.LookupPublication(Lookup{DocumentID: documentID, MepsLanguage: mepsLanguage})
+	if err != nil {
+		return Document{}, err
+	}
+
+	stmt, err := c.prepare("SELECT COUNT(*) FROM PublicationDocument WHERE PublicationId = ? AND DocumentId <= ?")
+	if err != nil {
+		return Document{}, err
+	}
+	doc := Document{DocumentID: documentID, Publication: publ}
+	if err := stmt.QueryRow(publ.ID, documentID).Scan(&doc.Section); err != nil {
+		return Document{}, errors.Wrap(err, "Error while scanning position of document")
+	}
+
+	return doc, nil
+}
+
+// Languages returns the MEPS languages the publication with the given KeySymbol
+// and IssueTagNumber is available in. If keySymbol is empty, all languages
+// of the catalog are returned.
+func (c *Catalog) Languages(keySymbol string, issueTagNumber int) ([]int, error) {
+	stmt, err := c.prepare("SELECT DISTINCT MepsLanguageId FROM Publication " +
+		"WHERE ?1 = '' OR (KeySymbol = ?1 AND IssueTagNumber = ?2) ORDER BY MepsLanguageId")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := stmt.Query(keySymbol, issueTagNumber)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while querying languages")
+	}
+	defer rows.Close()
+
+	result := []int{}
+	for rows.Next() {
+		var lang int
+		if err := rows.Scan(&lang); err != nil {
+			return nil, errors.Wrap(err, "Error while scanning row for language")
+		}
+		result = append(result, lang)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "Error while scanning languages")
+	}
+
+	return result, nil
+}
+
+// SearchPublications returns the publications whose title, issue title,
+// short title, or symbol contains query. See SearchPublications for details.
+func (c *Catalog) SearchPublications(query string, mepsLanguage int, limit int) ([]Publication, error) {
+	stmt, err := c.prepare(searchPublicationsQuery)
+	if err != nil {
+		return nil, err
+	}
+	return querySearchPublications(stmt, query, mepsLanguage, limit)
+}
+
+// prepare returns the prepared statement for query, which is
+// only prepared once and reused afterwards.
+func (c *Catalog) prepare(query string) (*sql.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if stmt, ok := c.stmts[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := c.db.Prepare(query)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while preparing query")
+	}
+	c.stmts[query] = stmt
+
+	return stmt, nil
+}
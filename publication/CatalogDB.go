@@ -3,22 +3,25 @@ package publication
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/cavaliercoder/grab"
 	"github.com/codeclysm/extract/v3"
 	"github.com/pkg/errors"
 )
 
-// ManifestURL is the URL to the publication manifest
+// ManifestURL is the URL to the publication manifest used by DefaultCatalogSource
 var ManifestURL = "https://app.jw-cdn.org/catalogs/publications/v4/manifest.json"
 
-// CatalogURL is the URL to the publication catalog
+// CatalogURL is the URL to the publication catalog used by DefaultCatalogSource
 var CatalogURL = "https://app.jw-cdn.org/catalogs/publications/v4/%s/catalog.db.gz"
 
 // Progress represents the progress of a running download
@@ -37,8 +40,23 @@ type catalogManifest struct {
 	Current string `json:"current"`
 }
 
+// ErrCatalogVersionUnknown is returned by CheckCatalogUpdate if it is
+// unknown which version of the catalog has been downloaded from where.
+var ErrCatalogVersionUnknown = errors.New("Version of catalog is unknown")
+
+// catalogVersion is stored next to catalog.db and describes
+// which version of the catalog has been downloaded.
+type catalogVersion struct {
+	Current string `json:"current"`
+	// Source the catalog has been downloaded from (see sourceName)
+	Source       string `json:"source,omitempty"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
 // CatalogNeedsUpdate checks if catalog.db located at path is still up-to-date.
-// For now it just makes sure that it is younger than one month.
+// As it doesn't access the network, it just makes sure that it is younger
+// than one month. Use CheckCatalogUpdate to compare it to its source.
 // If it can't find a file at path, it returns true
 func CatalogNeedsUpdate(path string) bool {
 	stat, err := os.Stat(path)
@@ -51,6 +69,75 @@ func CatalogNeedsUpdate(path string) bool {
 	return true
 }
 
+// CheckCatalogUpdate checks if a newer version of the catalog.db located at
+// path is available by asking src for its current version. If src is nil,
+// the source the catalog has been downloaded from is asked. If the version
+// or the source of the catalog is unknown, ErrCatalogVersionUnknown is
+// returned. If it can't find a file at path, it returns true.
+func CheckCatalogUpdate(ctx context.Context, path string, src CatalogSource) (bool, error) {
+	if !CatalogExists(path) {
+		return true, nil
+	}
+
+	version, err := readCatalogVersion(path)
+	if err != nil || version.Current == "" {
+		return false, ErrCatalogVersionUnknown
+	}
+	if src == nil {
+		if src, err = storedCatalogSource(version.Source); err != nil {
+			return false, err
+		}
+	}
+
+	current, err := src.Current(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "Could not fetch current version of catalog")
+	}
+	return current != version.Current, nil
+}
+
+// sourceName returns the name of src that is stored in the catalogVersion,
+// so it can be restored by storedCatalogSource. For unknown kinds of
+// CatalogSources, it returns an empty string.
+func sourceName(src CatalogSource) string {
+	switch src := src.(type) {
+	case *HTTPCatalogSource:
+		return src.ManifestURL
+	case *DirCatalogSource:
+		if dir, err := filepath.Abs(src.Dir); err == nil {
+			return dir
+		}
+	case *FileCatalogSource:
+		if path, err := filepath.Abs(src.Path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// storedCatalogSource returns the CatalogSource with the given sourceName.
+// It is only meant for asking for the current version of the catalog.
+func storedCatalogSource(name string) (CatalogSource, error) {
+	if name == "" {
+		return nil, ErrCatalogVersionUnknown
+	}
+	if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+		return &HTTPCatalogSource{ManifestURL: name}, nil
+	}
+	return ParseCatalogSource(name)
+}
+
+// CatalogVersion returns the version of the catalog.db at path as given by
+// the manifest it has been downloaded with. If it is unknown, it returns an
+// empty string.
+func CatalogVersion(path string) string {
+	version, err := readCatalogVersion(path)
+	if err != nil {
+		return ""
+	}
+	return version.Current
+}
+
 // CatalogExists checks if catalog.db exists at path
 func CatalogExists(path string) bool {
 	_, err := os.Stat(path)
@@ -67,116 +154,300 @@ func CatalogSize(path string) int64 {
 	return info.Size()
 }
 
-// DownloadCatalog downloads the newest catalog.db and saves it at dst.
-// The prgrs channel informs about the progress of the download.
+// DownloadOptions configure how DownloadCatalogFrom fetches the catalog
+type DownloadOptions struct {
+	// Source the catalog is fetched from. If nil, DefaultCatalogSource is used.
+	Source CatalogSource
+	// Checksum is the expected SHA-256 checksum (hex encoded) of the
+	// catalog.db.gz. If empty, the checksum is not verified.
+	Checksum string
+	// Retries is how often a failed fetch is retried
+	Retries int
+}
+
+// defaultRetries is how often DownloadCatalog retries a failed fetch
+const defaultRetries = 2
+
+// retryDelay is the delay before the first retry, which grows with every attempt
+var retryDelay = 500 * time.Millisecond
+
+// DownloadCatalog downloads the newest catalog.db from DefaultCatalogSource
+// and saves it at dst. See DownloadCatalogFrom for details.
 func DownloadCatalog(ctx context.Context, prgrs chan Progress, dst string) error {
+	return DownloadCatalogFrom(ctx, prgrs, dst, DownloadOptions{Retries: defaultRetries})
+}
+
+// DownloadCatalogFrom fetches the newest catalog.db from the source given by
+// opts and saves it at dst. If the catalog at dst has the same version as the
+// newest one, the source is asked to only send it if it has been modified since.
+// The existing catalog is only replaced once the new one has been fetched,
+// verified, and extracted successfully. The prgrs channel informs about the
+// progress of the download.
+func DownloadCatalogFrom(ctx context.Context, prgrs chan Progress, dst string, opts DownloadOptions) error {
 	if prgrs != nil {
 		defer close(prgrs)
 	}
+	src := opts.Source
+	if src == nil {
+		src = DefaultCatalogSource()
+	}
 
-	// Create tmp folder and place all files there
-	tmp, err := os.MkdirTemp("", "go-jwlm")
+	var current string
+	err := retry(ctx, opts.Retries, func() error {
+		var err error
+		current, err = src.Current(ctx)
+		return err
+	})
 	if err != nil {
-		return errors.Wrap(err, "Error while creating temporary directory")
+		sendProgress(prgrs, Progress{Done: true})
+		return errors.Wrap(err, "Could not fetch catalog manifest")
+	}
+
+	revision := CatalogRevision{}
+	if version, err := readCatalogVersion(dst); err == nil && version.Current == current &&
+		version.Source == sourceName(src) && CatalogExists(dst) {
+		revision = CatalogRevision{ETag: version.ETag, LastModified: version.LastModified}
 	}
-	defer os.RemoveAll(tmp)
 
-	// Fetch manifest, so we can generate the catalogURL
-	mfst, err := fetchManifest(ctx)
+	var fetched CatalogRevision
+	err = retry(ctx, opts.Retries, func() error {
+		var err error
+		fetched, err = fetchCatalog(ctx, src, current, revision, opts.Checksum, dst, prgrs)
+		return err
+	})
+	if errors.Is(err, ErrCatalogNotModified) {
+		// The catalog at dst is still up-to-date
+		sendProgress(prgrs, Progress{Done: true})
+		return nil
+	}
 	if err != nil {
-		return errors.Wrap(err, "Could not fetch catalog manifest")
+		sendProgress(prgrs, Progress{Done: true})
+		return err
+	}
+
+	err = writeCatalogVersion(dst, catalogVersion{
+		Current:      current,
+		Source:       sourceName(src),
+		ETag:         fetched.ETag,
+		LastModified: fetched.LastModified,
+	})
+	if err != nil {
+		return err
 	}
-	url := fmt.Sprintf(CatalogURL, mfst.Current)
+	sendProgress(prgrs, Progress{Done: true})
 
-	client := grab.NewClient()
-	req, err := grab.NewRequest(tmp, url)
+	return nil
+}
+
+// fetchCatalog fetches the catalog.db.gz of the given version from src, verifies
+// its checksum (if given), and replaces the catalog.db at dst with it.
+func fetchCatalog(ctx context.Context, src CatalogSource, current string, revision CatalogRevision,
+	checksum string, dst string, prgrs chan Progress) (CatalogRevision, error) {
+	file, err := src.Open(ctx, current, revision)
 	if err != nil {
-		return errors.Wrapf(err, "Error while creating request for %s", url)
+		return CatalogRevision{}, err
 	}
-	req = req.WithContext(ctx)
-	resp := client.Do(req)
+	defer file.Body.Close()
 
-	progress := Progress{}
+	tmp, err := os.CreateTemp("", "go-jwlm-catalog-*.db.gz")
+	if err != nil {
+		return CatalogRevision{}, errors.Wrap(err, "Error while creating temporary file")
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
 
+	counter := &progressCounter{size: file.Size, start: time.Now()}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
 	// Send a progress over the prgrsChan every 250 milliseconds
-	t := time.NewTicker(250 * time.Millisecond)
-	defer t.Stop()
-Loop:
-	for {
-		progress := Progress{
-			Size:           resp.Size(),
-			BytesComplete:  resp.BytesComplete(),
-			BytesPerSecond: resp.BytesPerSecond(),
-			Progress:       resp.Progress(),
-			Duration:       resp.Duration(),
-			ETA:            resp.ETA(),
-		}
-		select {
-		case <-t.C:
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(250 * time.Millisecond)
+		defer t.Stop()
+		for {
 			select {
-			case prgrs <- progress:
-				continue
-			default:
-				continue
+			case <-t.C:
+				sendProgress(prgrs, counter.progress())
+			case <-stop:
+				return
 			}
-		case <-resp.Done:
-			break Loop
 		}
+	}()
+
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hasher, counter), contextReader{ctx: ctx, r: file.Body})
+	close(stop)
+	<-stopped
+	if err != nil {
+		return CatalogRevision{}, errors.Wrap(err, "Error while downloading catalog")
+	}
+	if err := tmp.Close(); err != nil {
+		return CatalogRevision{}, errors.Wrap(err, "Error while downloading catalog")
 	}
-	if err := resp.Err(); err != nil {
-		progress.Done = true
+
+	if sum := fmt.Sprintf("%x", hasher.Sum(nil)); checksum != "" && !strings.EqualFold(sum, checksum) {
+		return CatalogRevision{}, errors.Errorf("Checksum of catalog.db.gz is %s instead of %s", sum, checksum)
+	}
+
+	if err := replaceCatalog(ctx, tmp.Name(), dst); err != nil {
+		return CatalogRevision{}, err
+	}
+
+	return file.Revision, nil
+}
+
+// retry calls fn until it succeeds, but at most retries+1 times. Errors
+// that won't go away by trying again are returned immediately.
+func retry(ctx context.Context, retries int, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= retries || !retryable(ctx, err) {
+			return err
+		}
+
 		select {
-		case prgrs <- progress:
-		default:
+		case <-ctx.Done():
+			return err
+		case <-time.After(retryDelay * time.Duration(attempt+1)):
 		}
-		return errors.Wrapf(err, "Error while downloading catalog from %s", url)
 	}
+}
 
-	// Extract and save at dst
-	data, err := os.ReadFile(resp.Filename)
-	if err != nil {
-		return errors.Wrap(err, "Error while reading catalog.db.gz")
+// retryable checks if it is worth trying again after err occurred
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrCatalogNotModified) || errors.Is(err, os.ErrNotExist) {
+		return false
 	}
-	buffer := bytes.NewBuffer(data)
-	err = extract.Gz(ctx, buffer, dst, nil)
-	if err != nil {
-		return errors.Wrap(err, "Error while extracting catalog.db")
+	var status statusError
+	if errors.As(err, &status) {
+		return status >= 500 || status == http.StatusTooManyRequests
 	}
+	return true
+}
 
-	progress.Done = true
+// sendProgress sends progress over prgrs without blocking
+func sendProgress(prgrs chan Progress, progress Progress) {
 	select {
 	case prgrs <- progress:
 	default:
 	}
+}
 
-	return nil
+// progressCounter counts the bytes written to it, so the
+// progress of a download can be calculated.
+type progressCounter struct {
+	size     int64
+	start    time.Time
+	complete atomic.Int64
+}
+
+func (c *progressCounter) Write(p []byte) (int, error) {
+	c.complete.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// progress returns the current Progress of the download
+func (c *progressCounter) progress() Progress {
+	progress := Progress{
+		Size:          c.size,
+		BytesComplete: c.complete.Load(),
+		Duration:      time.Since(c.start),
+	}
+	if seconds := progress.Duration.Seconds(); seconds > 0 {
+		progress.BytesPerSecond = float64(progress.BytesComplete) / seconds
+	}
+	if progress.Size > 0 {
+		progress.Progress = float64(progress.BytesComplete) / float64(progress.Size)
+		if progress.BytesPerSecond > 0 {
+			remaining := float64(progress.Size-progress.BytesComplete) / progress.BytesPerSecond
+			progress.ETA = time.Now().Add(time.Duration(remaining * float64(time.Second)))
+		}
+	}
+	return progress
+}
+
+// contextReader stops reading from r once ctx is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
 }
 
-// fetchManifest fetches the latest manifest from manifestURL
-func fetchManifest(ctx context.Context) (catalogManifest, error) {
-	req, err := http.NewRequest(http.MethodGet, ManifestURL, nil)
+// replaceCatalog extracts the catalog.db.gz at src next to dst and
+// then replaces dst with it, so an existing catalog is never left
+// half-written.
+func replaceCatalog(ctx context.Context, src string, dst string) error {
+	data, err := os.ReadFile(src)
 	if err != nil {
-		return catalogManifest{}, errors.Wrapf(err, "Error while creating new request for %s", ManifestURL)
+		return errors.Wrap(err, "Error while reading catalog.db.gz")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "Error while creating temporary catalog.db")
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	err = extract.Gz(ctx, bytes.NewBuffer(data), tmp.Name(), nil)
+	if err != nil {
+		return errors.Wrap(err, "Error while extracting catalog.db")
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return errors.Wrap(err, "Error while replacing catalog.db")
 	}
-	req.WithContext(ctx)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	return nil
+}
+
+// readCatalogVersion reads the catalogVersion stored next to the catalog.db at path.
+func readCatalogVersion(path string) (catalogVersion, error) {
+	data, err := os.ReadFile(catalogVersionPath(path))
 	if err != nil {
-		return catalogManifest{}, errors.Wrapf(err, "Could not download catalog manifest from %s", ManifestURL)
+		return catalogVersion{}, errors.Wrap(err, "Error while reading catalog version")
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	version := catalogVersion{}
+	if err := json.Unmarshal(data, &version); err != nil {
+		return catalogVersion{}, errors.Wrap(err, "Could not unmarshall catalog version")
+	}
+
+	return version, nil
+}
+
+// writeCatalogVersion stores version next to the catalog.db at path.
+func writeCatalogVersion(path string, version catalogVersion) error {
+	data, err := json.Marshal(version)
 	if err != nil {
-		return catalogManifest{}, errors.Wrap(err, "Error while reading response body for catalog manifest")
+		return errors.Wrap(err, "Error while marshalling catalog version")
 	}
 
-	mfst := catalogManifest{}
-	err = json.Unmarshal([]byte(body), &mfst)
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
 	if err != nil {
-		return catalogManifest{}, errors.Wrap(err, "Could not unmarshall catalog manifest file")
+		return errors.Wrap(err, "Error while creating temporary catalog version")
 	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "Error while writing catalog version")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "Error while writing catalog version")
+	}
+	if err := os.Rename(tmp.Name(), catalogVersionPath(path)); err != nil {
+		return errors.Wrap(err, "Error while saving catalog version")
+	}
+
+	return nil
+}
 
-	return mfst, nil
+// catalogVersionPath returns the path of the catalogVersion for the catalog.db at path.
+func catalogVersionPath(path string) string {
+	return path + ".version"
 }
@@ -32,6 +32,72 @@ func TestCatalogNeedsUpdate(t *testing.T) {
 	assert.True(t, CatalogNeedsUpdate(filePath))
 }
 
+func TestCheckCatalogUpdate(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "catalog.db")
+	needsUpdate, err := CheckCatalogUpdate(context.Background(), filePath, nil)
+	assert.NoError(t, err)
+	assert.True(t, needsUpdate)
+
+	f, err := os.Create(filePath)
+	assert.NoError(t, err)
+	defer f.Close()
+	_, err = CheckCatalogUpdate(context.Background(), filePath, nil)
+	assert.ErrorIs(t, err, ErrCatalogVersionUnknown)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(`{"version": 1, "current": "164a1c4b-4dbd-4909-8f88-8e7a18c562f2"}`))
+	}))
+	defer server.Close()
+
+	// The version is compared to the one of the source it has been downloaded from
+	assert.NoError(t, writeCatalogVersion(filePath, catalogVersion{Current: "164a1c4b-4dbd-4909-8f88-8e7a18c562f2", Source: server.URL}))
+	needsUpdate, err = CheckCatalogUpdate(context.Background(), filePath, nil)
+	assert.NoError(t, err)
+	assert.False(t, needsUpdate)
+
+	assert.NoError(t, writeCatalogVersion(filePath, catalogVersion{Current: "older", Source: server.URL}))
+	needsUpdate, err = CheckCatalogUpdate(context.Background(), filePath, nil)
+	assert.NoError(t, err)
+	assert.True(t, needsUpdate)
+
+	// A catalog from a single catalog.db.gz is compared to its checksum
+	gz := filepath.Join("testdata", "catalog.db.gz")
+	src := &FileCatalogSource{Path: gz}
+	assert.NoError(t, writeCatalogVersion(filePath, catalogVersion{Current: hashFile(gz), Source: sourceName(src)}))
+	needsUpdate, err = CheckCatalogUpdate(context.Background(), filePath, nil)
+	assert.NoError(t, err)
+	assert.False(t, needsUpdate)
+
+	// A given source is asked instead
+	needsUpdate, err = CheckCatalogUpdate(context.Background(), filePath, &HTTPCatalogSource{ManifestURL: server.URL})
+	assert.NoError(t, err)
+	assert.True(t, needsUpdate)
+
+	assert.NoError(t, writeCatalogVersion(filePath, catalogVersion{Current: "164a1c4b-4dbd-4909-8f88-8e7a18c562f2"}))
+	_, err = CheckCatalogUpdate(context.Background(), filePath, nil)
+	assert.ErrorIs(t, err, ErrCatalogVersionUnknown)
+
+	server.Close()
+	assert.NoError(t, writeCatalogVersion(filePath, catalogVersion{Current: "older", Source: server.URL}))
+	_, err = CheckCatalogUpdate(context.Background(), filePath, nil)
+	assert.Error(t, err)
+}
+
+func TestCatalogVersion(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "catalog.db")
+	assert.Equal(t, "", CatalogVersion(filePath))
+
+	assert.NoError(t, writeCatalogVersion(filePath, catalogVersion{Current: "164a1c4b", ETag: `"abc"`}))
+	assert.Equal(t, "164a1c4b", CatalogVersion(filePath))
+
+	version, err := readCatalogVersion(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, catalogVersion{Current: "164a1c4b", ETag: `"abc"`}, version)
+
+	assert.NoError(t, os.WriteFile(catalogVersionPath(filePath), []byte("ERROR"), 0644))
+	assert.Equal(t, "", CatalogVersion(filePath))
+}
+
 func TestCatalogExists(t *testing.T) {
 	tmp := t.TempDir()
 
@@ -114,6 +180,74 @@ func Test_DownloadCatalog(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_DownloadCatalog_conditional(t *testing.T) {
+	tmp := t.TempDir()
+	dst := filepath.Join(tmp, "catalog.db")
+
+	current := "164a1c4b-4dbd-4909-8f88-8e7a18c562f2"
+	corrupt := false
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if strings.Contains(req.URL.String(), "manifest.json") {
+			rw.Write([]byte(`{"version": 1, "current": "` + current + `"}`))
+			return
+		}
+		rw.Header().Set("ETag", `"`+current+`"`)
+		if req.Header.Get("If-None-Match") == `"`+current+`"` {
+			rw.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if req.Method == http.MethodGet {
+			downloads++
+		}
+		if corrupt {
+			rw.Write([]byte("not a gzip file"))
+			return
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", "catalog.db.gz"))
+		assert.NoError(t, err)
+		rw.Write(data)
+	}))
+	defer server.Close()
+
+	manifestURL, catalogURL := ManifestURL, CatalogURL
+	t.Cleanup(func() { ManifestURL, CatalogURL = manifestURL, catalogURL })
+	ManifestURL = server.URL + "/catalogs/publications/v4/manifest.json"
+	CatalogURL = server.URL + "/catalogs/publications/v4/%s/catalog.db.gz"
+
+	assert.NoError(t, DownloadCatalog(context.Background(), nil, dst))
+	assert.Equal(t, 1, downloads)
+	assert.Equal(t, current, CatalogVersion(dst))
+	version, err := readCatalogVersion(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, `"`+current+`"`, version.ETag)
+	assert.Equal(t, ManifestURL, version.Source)
+
+	// The catalog has not been modified, so it is not downloaded again
+	assert.NoError(t, DownloadCatalog(context.Background(), nil, dst))
+	assert.Equal(t, 1, downloads)
+	assert.Equal(t, "7ebe98db8b5edd1ab901b7d6b43647fd35790b2a332c43739efdf9383d590651", hashFile(dst))
+
+	// A failed download does not touch the existing catalog
+	defer func(delay time.Duration) { retryDelay = delay }(retryDelay)
+	retryDelay = time.Millisecond
+	current = "5f2e4c1a-0000-4000-8000-000000000000"
+	corrupt = true
+	assert.Error(t, DownloadCatalog(context.Background(), nil, dst))
+	assert.Equal(t, 2+defaultRetries, downloads)
+	assert.Equal(t, "7ebe98db8b5edd1ab901b7d6b43647fd35790b2a332c43739efdf9383d590651", hashFile(dst))
+	assert.Equal(t, "164a1c4b-4dbd-4909-8f88-8e7a18c562f2", CatalogVersion(dst))
+	entries, err := os.ReadDir(tmp)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// A new version is downloaded
+	corrupt = false
+	assert.NoError(t, DownloadCatalog(context.Background(), nil, dst))
+	assert.Equal(t, 3+defaultRetries, downloads)
+	assert.Equal(t, current, CatalogVersion(dst))
+}
+
 func Test_fetchManifest(t *testing.T) {
 	var tests = []struct {
 		input       string
@@ -141,7 +275,7 @@ func Test_fetchManifest(t *testing.T) {
 		defer server.Close()
 
 		ManifestURL = server.URL
-		res, err := fetchManifest(context.Background())
+		res, err := fetchManifest(context.Background(), http.DefaultClient, ManifestURL)
 		if test.expectError {
 			assert.Error(t, err)
 		} else {
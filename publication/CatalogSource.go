@@ -0,0 +1,278 @@
+package publication
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCatalogNotModified is returned by CatalogSource.Open if the catalog
+// hasn't been modified since the given CatalogRevision.
+var ErrCatalogNotModified = errors.New("Catalog has not been modified")
+
+// CatalogSource is a place the catalog.db.gz can be fetched from, like
+// the JW CDN or a local mirror of it.
+type CatalogSource interface {
+	// Current returns the version of the newest catalog.
+	Current(ctx context.Context) (string, error)
+	// Open opens the catalog.db.gz of the given version. If the catalog
+	// hasn't been modified since revision, it returns ErrCatalogNotModified.
+	Open(ctx context.Context, current string, revision CatalogRevision) (*CatalogFile, error)
+}
+
+// CatalogRevision identifies the content of a fetched catalog.db.gz, so
+// it only has to be fetched again once it has been modified.
+type CatalogRevision struct {
+	ETag         string
+	LastModified string
+}
+
+// CatalogFile is an opened catalog.db.gz of a CatalogSource. Its Body
+// has to be closed by the caller.
+type CatalogFile struct {
+	Body io.ReadCloser
+	// Size of the catalog.db.gz or -1 if it is unknown
+	Size     int64
+	Revision CatalogRevision
+}
+
+// DefaultCatalogSource returns the CatalogSource given by
+// ManifestURL and CatalogURL.
+func DefaultCatalogSource() CatalogSource {
+	return &HTTPCatalogSource{
+		ManifestURL: ManifestURL,
+		CatalogURL:  CatalogURL,
+	}
+}
+
+// ParseCatalogSource returns the CatalogSource for source, which can be
+// the base URL of a catalog server (like https://app.jw-cdn.org/catalogs/publications/v4),
+// a local directory mirroring it, or a single catalog.db.gz.
+func ParseCatalogSource(source string) (CatalogSource, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return NewHTTPCatalogSource(source), nil
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s is neither a URL nor an existing file or directory", source)
+	}
+	if info.IsDir() {
+		return &DirCatalogSource{Dir: source}, nil
+	}
+	return &FileCatalogSource{Path: source}, nil
+}
+
+// HTTPCatalogSource fetches the catalog from a server with the
+// same layout as the JW CDN.
+type HTTPCatalogSource struct {
+	// ManifestURL is the URL of the manifest.json
+	ManifestURL string
+	// CatalogURL is the URL of the catalog.db.gz, where %s
+	// is replaced by the current version
+	CatalogURL string
+	// Client is used for all requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// NewHTTPCatalogSource returns a HTTPCatalogSource for the server at baseURL,
+// which provides the manifest at <baseURL>/manifest.json and the catalog at
+// <baseURL>/<current>/catalog.db.gz.
+func NewHTTPCatalogSource(baseURL string) *HTTPCatalogSource {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	return &HTTPCatalogSource{
+		ManifestURL: baseURL + "/manifest.json",
+		CatalogURL:  baseURL + "/%s/catalog.db.gz",
+	}
+}
+
+// Current returns the version of the newest catalog given by the manifest.
+func (s *HTTPCatalogSource) Current(ctx context.Context) (string, error) {
+	mfst, err := fetchManifest(ctx, s.client(), s.ManifestURL)
+	if err != nil {
+		return "", err
+	}
+	return mfst.Current, nil
+}
+
+// Open requests the catalog.db.gz of the given version. The
+// revision is sent along, so the server can answer with 304 if it
+// hasn't been modified.
+func (s *HTTPCatalogSource) Open(ctx context.Context, current string, revision CatalogRevision) (*CatalogFile, error) {
+	url := fmt.Sprintf(s.CatalogURL, current)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while creating request for %s", url)
+	}
+	if revision.ETag != "" {
+		req.Header.Set("If-None-Match", revision.ETag)
+	}
+	if revision.LastModified != "" {
+		req.Header.Set("If-Modified-Since", revision.LastModified)
+	}
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while downloading catalog from %s", url)
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, ErrCatalogNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.Wrapf(statusError(resp.StatusCode), "Error while downloading catalog from %s", url)
+	}
+
+	return &CatalogFile{
+		Body: resp.Body,
+		Size: resp.ContentLength,
+		Revision: CatalogRevision{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		},
+	}, nil
+}
+
+func (s *HTTPCatalogSource) client() *http.Client {
+	if s.Client == nil {
+		return http.DefaultClient
+	}
+	return s.Client
+}
+
+// DirCatalogSource reads the catalog from a local directory that mirrors
+// the layout of the JW CDN: a manifest.json and <current>/catalog.db.gz.
+type DirCatalogSource struct {
+	Dir string
+}
+
+// Current returns the version of the newest catalog given by the manifest.json.
+func (s *DirCatalogSource) Current(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.Dir, "manifest.json"))
+	if err != nil {
+		return "", errors.Wrap(err, "Could not read catalog manifest")
+	}
+	mfst := catalogManifest{}
+	if err := json.Unmarshal(data, &mfst); err != nil {
+		return "", errors.Wrap(err, "Could not unmarshall catalog manifest file")
+	}
+
+	return mfst.Current, nil
+}
+
+// Open opens the catalog.db.gz of the given version. Its modification
+// time is used as revision.
+func (s *DirCatalogSource) Open(ctx context.Context, current string, revision CatalogRevision) (*CatalogFile, error) {
+	return openCatalogFile(ctx, filepath.Join(s.Dir, current, "catalog.db.gz"), revision)
+}
+
+// FileCatalogSource reads the catalog from a single catalog.db.gz. As there
+// is no manifest, its SHA-256 checksum is used as version.
+type FileCatalogSource struct {
+	Path string
+}
+
+// Current returns the SHA-256 checksum of the catalog.db.gz.
+func (s *FileCatalogSource) Current(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return "", errors.Wrap(err, "Could not open catalog")
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", errors.Wrap(err, "Error while reading catalog")
+	}
+
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
+
+// Open opens the catalog.db.gz. Its modification time is used as revision.
+func (s *FileCatalogSource) Open(ctx context.Context, current string, revision CatalogRevision) (*CatalogFile, error) {
+	return openCatalogFile(ctx, s.Path, revision)
+}
+
+// openCatalogFile opens the local catalog.db.gz at path, unless
+// its modification time equals the one of revision.
+func openCatalogFile(ctx context.Context, path string, revision CatalogRevision) (*CatalogFile, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not open catalog")
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrap(err, "Could not open catalog")
+	}
+
+	modified := info.ModTime().UTC().Format(http.TimeFormat)
+	if revision.LastModified == modified {
+		f.Close()
+		return nil, ErrCatalogNotModified
+	}
+
+	return &CatalogFile{
+		Body:     f,
+		Size:     info.Size(),
+		Revision: CatalogRevision{LastModified: modified},
+	}, nil
+}
+
+// fetchManifest fetches the latest manifest from manifestURL
+func fetchManifest(ctx context.Context, client *http.Client, manifestURL string) (catalogManifest, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return catalogManifest{}, errors.Wrapf(err, "Error while creating new request for %s", manifestURL)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return catalogManifest{}, errors.Wrapf(err, "Could not download catalog manifest from %s", manifestURL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return catalogManifest{}, errors.Wrapf(statusError(resp.StatusCode), "Could not download catalog manifest from %s", manifestURL)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return catalogManifest{}, errors.Wrap(err, "Error while reading response body for catalog manifest")
+	}
+
+	mfst := catalogManifest{}
+	err = json.Unmarshal([]byte(body), &mfst)
+	if err != nil {
+		return catalogManifest{}, errors.Wrap(err, "Could not unmarshall catalog manifest file")
+	}
+
+	return mfst, nil
+}
+
+// statusError is an unexpected HTTP status code of a catalog server
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("server responded with %d %s", int(e), http.StatusText(int(e)))
+}
@@ -0,0 +1,150 @@
+package publication
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const catalogHash = "7ebe98db8b5edd1ab901b7d6b43647fd35790b2a332c43739efdf9383d590651"
+
+func TestParseCatalogSource(t *testing.T) {
+	src, err := ParseCatalogSource("https://mirror.example.com/catalogs/v4/")
+	require.NoError(t, err)
+	assert.Equal(t, &HTTPCatalogSource{
+		ManifestURL: "https://mirror.example.com/catalogs/v4/manifest.json",
+		CatalogURL:  "https://mirror.example.com/catalogs/v4/%s/catalog.db.gz",
+	}, src)
+
+	src, err = ParseCatalogSource("testdata")
+	require.NoError(t, err)
+	assert.Equal(t, &DirCatalogSource{Dir: "testdata"}, src)
+
+	src, err = ParseCatalogSource(filepath.Join("testdata", "catalog.db.gz"))
+	require.NoError(t, err)
+	assert.Equal(t, &FileCatalogSource{Path: filepath.Join("testdata", "catalog.db.gz")}, src)
+
+	_, err = ParseCatalogSource("nonexistent")
+	assert.Error(t, err)
+}
+
+func TestDirCatalogSource(t *testing.T) {
+	mirror := t.TempDir()
+	current := "164a1c4b-4dbd-4909-8f88-8e7a18c562f2"
+	require.NoError(t, os.WriteFile(filepath.Join(mirror, "manifest.json"),
+		[]byte(`{"version": 1, "current": "`+current+`"}`), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(mirror, current), 0755))
+	data, err := os.ReadFile(filepath.Join("testdata", "catalog.db.gz"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(mirror, current, "catalog.db.gz"), data, 0644))
+
+	src := &DirCatalogSource{Dir: mirror}
+	res, err := src.Current(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, current, res)
+
+	dst := filepath.Join(t.TempDir(), "catalog.db")
+	prgrs := make(chan Progress)
+	done := make(chan error)
+	go func() {
+		done <- DownloadCatalogFrom(context.Background(), prgrs, dst, DownloadOptions{Source: src})
+	}()
+	for range prgrs {
+	}
+	assert.NoError(t, <-done)
+	assert.Equal(t, catalogHash, hashFile(dst))
+	assert.Equal(t, current, CatalogVersion(dst))
+	needsUpdate, err := CheckCatalogUpdate(context.Background(), dst, nil)
+	assert.NoError(t, err)
+	assert.False(t, needsUpdate)
+
+	// An unmodified catalog is not opened again
+	version, err := readCatalogVersion(dst)
+	require.NoError(t, err)
+	assert.Equal(t, sourceName(src), version.Source)
+	_, err = src.Open(context.Background(), current, CatalogRevision{LastModified: version.LastModified})
+	assert.Equal(t, ErrCatalogNotModified, err)
+	assert.NoError(t, DownloadCatalogFrom(context.Background(), nil, dst, DownloadOptions{Source: src}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = src.Current(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = (&DirCatalogSource{Dir: t.TempDir()}).Current(context.Background())
+	assert.Error(t, err)
+}
+
+func TestFileCatalogSource(t *testing.T) {
+	path := filepath.Join("testdata", "catalog.db.gz")
+	src := &FileCatalogSource{Path: path}
+
+	current, err := src.Current(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, hashFile(path), current)
+
+	dst := filepath.Join(t.TempDir(), "catalog.db")
+	err = DownloadCatalogFrom(context.Background(), nil, dst, DownloadOptions{Source: src, Checksum: current})
+	assert.NoError(t, err)
+	assert.Equal(t, catalogHash, hashFile(dst))
+	assert.Equal(t, current, CatalogVersion(dst))
+
+	_, err = (&FileCatalogSource{Path: "nonexistent"}).Current(context.Background())
+	assert.Error(t, err)
+}
+
+func TestDownloadCatalogFrom_checksum(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "catalog.db")
+	src := &FileCatalogSource{Path: filepath.Join("testdata", "catalog.db.gz")}
+
+	err := DownloadCatalogFrom(context.Background(), nil, dst, DownloadOptions{Source: src, Checksum: "abc"})
+	assert.ErrorContains(t, err, "instead of abc")
+	assert.False(t, CatalogExists(dst))
+	assert.Equal(t, "", CatalogVersion(dst))
+}
+
+func TestDownloadCatalogFrom_retries(t *testing.T) {
+	defer func(delay time.Duration) { retryDelay = delay }(retryDelay)
+	retryDelay = time.Millisecond
+
+	requests := 0
+	status := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/manifest.json" {
+			rw.Write([]byte(`{"version": 1, "current": "164a1c4b"}`))
+			return
+		}
+		requests++
+		if requests == 1 {
+			rw.WriteHeader(status)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", "catalog.db.gz"))
+		assert.NoError(t, err)
+		rw.Write(data)
+	}))
+	defer server.Close()
+	src := NewHTTPCatalogSource(server.URL)
+
+	// Server errors are retried
+	dst := filepath.Join(t.TempDir(), "catalog.db")
+	assert.NoError(t, DownloadCatalogFrom(context.Background(), nil, dst, DownloadOptions{Source: src, Retries: 1}))
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, catalogHash, hashFile(dst))
+
+	// Client errors are not
+	requests = 0
+	status = http.StatusNotFound
+	dst = filepath.Join(t.TempDir(), "catalog.db")
+	err := DownloadCatalogFrom(context.Background(), nil, dst, DownloadOptions{Source: src, Retries: 3})
+	assert.ErrorContains(t, err, "404 Not Found")
+	assert.Equal(t, 1, requests)
+	assert.False(t, CatalogExists(dst))
+}
@@ -0,0 +1,168 @@
+package publication
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenCatalog(t *testing.T) {
+	catalog, err := OpenCatalog(filepath.Join("testdata", "catalog.db"))
+	require.NoError(t, err)
+	assert.NoError(t, catalog.Close())
+
+	_, err = OpenCatalog("nonexistent.db")
+	assert.Error(t, err)
+}
+
+func TestCatalog_LookupPublication(t *testing.T) {
+	catalog, err := OpenCatalog(filepath.Join("testdata", "catalog.db"))
+	require.NoError(t, err)
+	defer catalog.Close()
+
+	tests := []struct {
+		name        string
+		query       Lookup
+		want        int
+		expectError bool
+	}{
+		{name: "Document", query: Lookup{DocumentID: 1102002025, MepsLanguage: 0}, want: 67},
+		{name: "KeySymbol", query: Lookup{KeySymbol: "cl", MepsLanguage: 1}, want: 129},
+		{name: "Issue", query: Lookup{KeySymbol: "w", IssueTagNumber: 20210200, MepsLanguage: 0}, want: 305097},
+		{name: "Nonexistent", query: Lookup{KeySymbol: "nonexistent"}, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Run twice to make sure prepared statements can be reused
+			for i := 0; i < 2; i++ {
+				publ, err := catalog.LookupPublication(tt.query)
+				if tt.expectError {
+					assert.ErrorIs(t, err, sql.ErrNoRows)
+					continue
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, publ.ID)
+			}
+		})
+	}
+
+	publ, err := catalog.LookupLocation(&model.Location{
+		KeySymbol:    sql.NullString{String: "cl", Valid: true},
+		MepsLanguage: sql.NullInt32{Int32: 1, Valid: true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 129, publ.ID)
+}
+
+func TestCatalog_LookupPublications(t *testing.T) {
+	catalog, err := OpenCatalog(filepath.Join("testdata", "catalog.db"))
+	require.NoError(t, err)
+	defer catalog.Close()
+
+	queries := []Lookup{
+		{DocumentID: 1102002020, MepsLanguage: 0},
+		{KeySymbol: "cl", MepsLanguage: 1},
+		{KeySymbol: "cl", MepsLanguage: 1},
+		{KeySymbol: "nonexistent", MepsLanguage: 0},
+	}
+	res, err := catalog.LookupPublications(queries)
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+	assert.Equal(t, 67, res[queries[0]].ID)
+	assert.Equal(t, 129, res[queries[1]].ID)
+	assert.NotContains(t, res, queries[3])
+}
+
+func TestCatalog_Documents(t *testing.T) {
+	catalog, err := OpenCatalog(filepath.Join("testdata", "catalog.db"))
+	require.NoError(t, err)
+	defer catalog.Close()
+
+	docs, err := catalog.Documents(Lookup{KeySymbol: "cl", MepsLanguage: 0})
+	assert.NoError(t, err)
+	require.Len(t, docs, 40)
+	for i, doc := range docs {
+		assert.Equal(t, 1102002020+i, doc.DocumentID)
+		assert.Equal(t, i+1, doc.Section)
+		assert.Equal(t, 67, doc.Publication.ID)
+	}
+	assert.Equal(t, "Draw Close to Jehovah (2014), document 1", docs[0].Describe())
+
+	_, err = catalog.Documents(Lookup{KeySymbol: "nonexistent", MepsLanguage: 0})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCatalog_LookupDocument(t *testing.T) {
+	catalog, err := OpenCatalog(filepath.Join("testdata", "catalog.db"))
+	require.NoError(t, err)
+	defer catalog.Close()
+
+	tests := []struct {
+		name         string
+		documentID   int
+		mepsLanguage int
+		wantSection  int
+		expectError  bool
+	}{
+		{name: "First", documentID: 1102002020, mepsLanguage: 0, wantSection: 1},
+		{name: "Last", documentID: 1102002059, mepsLanguage: 0, wantSection: 40},
+		{name: "Wrong language", documentID: 1102002020, mepsLanguage: 1, expectError: true},
+		{name: "Nonexistent", documentID: 1, mepsLanguage: 0, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := catalog.LookupDocument(tt.documentID, tt.mepsLanguage)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.documentID, doc.DocumentID)
+			assert.Equal(t, tt.wantSection, doc.Section)
+			assert.Equal(t, 67, doc.Publication.ID)
+		})
+	}
+}
+
+func TestCatalog_Languages(t *testing.T) {
+	catalog, err := OpenCatalog(filepath.Join("testdata", "catalog.db"))
+	require.NoError(t, err)
+	defer catalog.Close()
+
+	tests := []struct {
+		name           string
+		keySymbol      string
+		issueTagNumber int
+		want           []int
+	}{
+		{name: "All", want: []int{0, 1}},
+		{name: "Publication", keySymbol: "cl", want: []int{0, 1}},
+		{name: "Issue", keySymbol: "w", issueTagNumber: 20210200, want: []int{0}},
+		{name: "Nonexistent", keySymbol: "nonexistent", want: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := catalog.Languages(tt.keySymbol, tt.issueTagNumber)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+}
+
+func TestCatalog_SearchPublications(t *testing.T) {
+	catalog, err := OpenCatalog(filepath.Join("testdata", "catalog.db"))
+	require.NoError(t, err)
+	defer catalog.Close()
+
+	res, err := catalog.SearchPublications("jehov", 0, 0)
+	assert.NoError(t, err)
+	ids := []int{}
+	for _, publ := range res {
+		ids = append(ids, publ.ID)
+	}
+	assert.Equal(t, []int{305097, 67}, ids)
+}
@@ -0,0 +1,90 @@
+package publication
+
+import (
+	"database/sql"
+
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/pkg/errors"
+)
+
+// EnrichLocations fills missing metadata of the Locations in db. Bible
+// chapters get their reference (like "John 3") as Title. All other Locations
+// are looked up in catalog: Locations of a whole publication get its title if
+// they don't have one, and a missing KeySymbol and IssueTagNumber are taken
+// from the publication as long as no other Location with the same UniqueKey
+// exists. The Title of Locations of a document is left untouched, as it names
+// the document (like an article), which the catalog does not contain. If
+// catalog is nil, only Bible chapters are enriched. It returns the number of
+// Locations that have been changed.
+func EnrichLocations(db *model.Database, catalog *Catalog) (int, error) {
+	if db == nil {
+		return 0, nil
+	}
+
+	keys := make(map[string]bool, len(db.Location))
+	for _, location := range db.Location {
+		if location != nil {
+			keys[location.UniqueKey()] = true
+		}
+	}
+
+	enriched := 0
+	for _, location := range db.Location {
+		if location == nil {
+			continue
+		}
+
+		changed := false
+		if location.Title.String == "" {
+			if reference := location.Reference(); reference != "" {
+				location.Title = sql.NullString{String: reference, Valid: true}
+				enriched++
+				continue
+			}
+		}
+		if catalog == nil || location.BookNumber.Valid || !location.MepsLanguage.Valid {
+			continue
+		}
+		fillTitle := location.Title.String == "" && !location.DocumentID.Valid
+		if !fillTitle && location.KeySymbol.String != "" {
+			continue
+		}
+
+		publ, err := catalog.LookupLocation(location)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return enriched, errors.Wrapf(err, "Error while looking up publication of location %d", location.LocationID)
+		}
+
+		if fillTitle {
+			title := publ.Title
+			if publ.IssueTitle.String != "" {
+				title = publ.IssueTitle.String
+			}
+			if title != "" {
+				location.Title = sql.NullString{String: title, Valid: true}
+				changed = true
+			}
+		}
+		if location.KeySymbol.String == "" && publ.KeySymbol.String != "" {
+			updated := *location
+			updated.KeySymbol = publ.KeySymbol
+			updated.IssueTagNumber = publ.IssueTagNumber
+			if !keys[updated.UniqueKey()] {
+				delete(keys, location.UniqueKey())
+				keys[updated.UniqueKey()] = true
+				location.KeySymbol = updated.KeySymbol
+				location.IssueTagNumber = updated.IssueTagNumber
+				changed = true
+			}
+		}
+
+		if changed {
+			enriched++
+		}
+	}
+
+	return enriched, nil
+}
@@ -0,0 +1,184 @@
+package publication
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrichLocations(t *testing.T) {
+	catalog, err := OpenCatalog(filepath.Join("testdata", "catalog.db"))
+	require.NoError(t, err)
+	defer catalog.Close()
+
+	tests := []struct {
+		name         string
+		catalog      *Catalog
+		locations    []*model.Location
+		want         []*model.Location
+		wantEnriched int
+	}{
+		{
+			name:    "With catalog",
+			catalog: catalog,
+			locations: []*model.Location{
+				nil,
+				{
+					LocationID:    1,
+					BookNumber:    sql.NullInt32{Int32: 66, Valid: true},
+					ChapterNumber: sql.NullInt32{Int32: 21, Valid: true},
+					KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+					MepsLanguage:  sql.NullInt32{Int32: 2, Valid: true},
+				},
+				{
+					LocationID:   2,
+					DocumentID:   sql.NullInt32{Int32: 1102002020, Valid: true},
+					MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+				},
+				{
+					LocationID:   3,
+					DocumentID:   sql.NullInt32{Int32: 1102002021, Valid: true},
+					KeySymbol:    sql.NullString{String: "cl", Valid: true},
+					MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+					Title:        sql.NullString{String: "Chapter 2", Valid: true},
+				},
+				{
+					LocationID:   4,
+					DocumentID:   sql.NullInt32{Int32: 1102002021, Valid: true},
+					MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+				},
+				{
+					LocationID:     5,
+					IssueTagNumber: 20210200,
+					KeySymbol:      sql.NullString{String: "w", Valid: true},
+					MepsLanguage:   sql.NullInt32{Int32: 0, Valid: true},
+					LocationType:   1,
+				},
+				{
+					LocationID:   6,
+					KeySymbol:    sql.NullString{String: "nonexistent", Valid: true},
+					MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+					LocationType: 1,
+				},
+				{
+					LocationID:   7,
+					DocumentID:   sql.NullInt32{Int32: 1102002022, Valid: true},
+					MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+					Title:        sql.NullString{String: "Chapter 3", Valid: true},
+				},
+			},
+			want: []*model.Location{
+				nil,
+				{
+					LocationID:    1,
+					BookNumber:    sql.NullInt32{Int32: 66, Valid: true},
+					ChapterNumber: sql.NullInt32{Int32: 21, Valid: true},
+					KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+					MepsLanguage:  sql.NullInt32{Int32: 2, Valid: true},
+					Title:         sql.NullString{String: "Offenbarung 21", Valid: true},
+				},
+				{
+					LocationID:   2,
+					DocumentID:   sql.NullInt32{Int32: 1102002020, Valid: true},
+					KeySymbol:    sql.NullString{String: "cl", Valid: true},
+					MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+				},
+				{
+					LocationID:   3,
+					DocumentID:   sql.NullInt32{Int32: 1102002021, Valid: true},
+					KeySymbol:    sql.NullString{String: "cl", Valid: true},
+					MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+					Title:        sql.NullString{String: "Chapter 2", Valid: true},
+				},
+				{
+					// Setting the KeySymbol would create a duplicate of Location 3
+					LocationID:   4,
+					DocumentID:   sql.NullInt32{Int32: 1102002021, Valid: true},
+					MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+				},
+				{
+					LocationID:     5,
+					IssueTagNumber: 20210200,
+					KeySymbol:      sql.NullString{String: "w", Valid: true},
+					MepsLanguage:   sql.NullInt32{Int32: 0, Valid: true},
+					LocationType:   1,
+					Title:          sql.NullString{String: "The Watchtower, February 2021", Valid: true},
+				},
+				{
+					LocationID:   6,
+					KeySymbol:    sql.NullString{String: "nonexistent", Valid: true},
+					MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+					LocationType: 1,
+				},
+				{
+					// The title of a document is kept
+					LocationID:   7,
+					DocumentID:   sql.NullInt32{Int32: 1102002022, Valid: true},
+					KeySymbol:    sql.NullString{String: "cl", Valid: true},
+					MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+					Title:        sql.NullString{String: "Chapter 3", Valid: true},
+				},
+			},
+			wantEnriched: 4,
+		},
+		{
+			name: "Without catalog",
+			locations: []*model.Location{
+				nil,
+				{
+					LocationID:    1,
+					BookNumber:    sql.NullInt32{Int32: 66, Valid: true},
+					ChapterNumber: sql.NullInt32{Int32: 21, Valid: true},
+					KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+					MepsLanguage:  sql.NullInt32{Int32: 2, Valid: true},
+				},
+				{
+					LocationID:   2,
+					DocumentID:   sql.NullInt32{Int32: 1102002020, Valid: true},
+					MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+				},
+			},
+			want: []*model.Location{
+				nil,
+				{
+					LocationID:    1,
+					BookNumber:    sql.NullInt32{Int32: 66, Valid: true},
+					ChapterNumber: sql.NullInt32{Int32: 21, Valid: true},
+					KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+					MepsLanguage:  sql.NullInt32{Int32: 2, Valid: true},
+					Title:         sql.NullString{String: "Offenbarung 21", Valid: true},
+				},
+				{
+					LocationID:   2,
+					DocumentID:   sql.NullInt32{Int32: 1102002020, Valid: true},
+					MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+				},
+			},
+			wantEnriched: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &model.Database{Location: tt.locations}
+
+			enriched, err := EnrichLocations(db, tt.catalog)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantEnriched, enriched)
+			assert.Equal(t, tt.want, db.Location)
+
+			// Running it again does not change anything
+			enriched, err = EnrichLocations(db, tt.catalog)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, enriched)
+			assert.Equal(t, tt.want, db.Location)
+		})
+	}
+
+	enriched, err := EnrichLocations(nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, enriched)
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
+	"github.com/AndreasSko/go-jwlm/model"
 	"github.com/pkg/errors"
 
 	// Register SQLite driver
@@ -57,24 +60,104 @@ func LookupPublication(dbPath string, query Lookup) (Publication, error) {
 	return lookupPublication(db, query)
 }
 
+// LookupLocation looks up the publication the given Location belongs
+// to from catalogDB located at dbPath.
+func LookupLocation(dbPath string, location *model.Location) (Publication, error) {
+	return LookupPublication(dbPath, LocationLookup(location))
+}
+
+// LocationLookup returns the Lookup for the publication of a Location. If
+// the Location belongs to a document, the document is used for the lookup.
+func LocationLookup(location *model.Location) Lookup {
+	return Lookup{
+		DocumentID:     int(location.DocumentID.Int32),
+		KeySymbol:      location.KeySymbol.String,
+		IssueTagNumber: location.IssueTagNumber,
+		MepsLanguage:   int(location.MepsLanguage.Int32),
+	}
+}
+
 func lookupPublication(db *sql.DB, query Lookup) (Publication, error) {
 	var row *sql.Row
 	if query.DocumentID != 0 {
-		stmt, err := db.Prepare("SELECT P.* " +
-			"FROM Publication AS P, PublicationDocument AS PD " +
-			"WHERE P.Id = PD.PublicationId AND PD.DocumentId = ? AND P.MepsLanguageId = ?")
+		stmt, err := db.Prepare(publicationByDocumentQuery)
 		if err != nil {
 			return Publication{}, errors.Wrap(err, "Error while preparing query")
 		}
 		row = stmt.QueryRow(query.DocumentID, query.MepsLanguage)
 	} else {
-		stmt, err := db.Prepare("SELECT * FROM Publication WHERE KeySymbol = ? AND MepsLanguageId = ? AND IssueTagNumber = ?")
+		stmt, err := db.Prepare(publicationQuery)
 		if err != nil {
 			return Publication{}, errors.Wrap(err, "Error while preparing query")
 		}
 		row = stmt.QueryRow(query.KeySymbol, query.MepsLanguage, query.IssueTagNumber)
 	}
 
+	publ, err := scanPublication(row)
+	if err != nil {
+		return Publication{}, err
+	}
+
+	return publ, nil
+}
+
+// SearchPublications returns the publications from catalogDB located at dbPath
+// whose title, issue title, short title, or symbol contains query (ignoring the
+// case). If mepsLanguage is not negative, only publications in this language are
+// returned. The newest publications come first and at most limit are returned,
+// where a limit of 0 returns all of them.
+func SearchPublications(dbPath string, query string, mepsLanguage int, limit int) ([]Publication, error) {
+	// Check if file exists
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("CatalogDB does not exist at %s", dbPath)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath+"?immutable=1")
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while opening SQLite database")
+	}
+	defer db.Close()
+
+	return searchPublications(db, query, mepsLanguage, limit)
+}
+
+func searchPublications(db *sql.DB, query string, mepsLanguage int, limit int) ([]Publication, error) {
+	stmt, err := db.Prepare(searchPublicationsQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while preparing query")
+	}
+	defer stmt.Close()
+
+	return querySearchPublications(stmt, query, mepsLanguage, limit)
+}
+
+func querySearchPublications(stmt *sql.Stmt, query string, mepsLanguage int, limit int) ([]Publication, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := stmt.Query("%"+query+"%", mepsLanguage, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while querying publications")
+	}
+	defer rows.Close()
+
+	result := []Publication{}
+	for rows.Next() {
+		publ, err := scanPublication(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, publ)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "Error while scanning publications")
+	}
+
+	return result, nil
+}
+
+// scanPublication scans a row of the Publication table
+func scanPublication(row interface{ Scan(dest ...any) error }) (Publication, error) {
 	publ := Publication{}
 	err := row.Scan(&publ.PublicationRootKeyID,
 		&publ.MepsLanguageID,
@@ -98,6 +181,69 @@ func lookupPublication(db *sql.DB, query Lookup) (Publication, error) {
 	return publ, nil
 }
 
+// LookupDocumentIDs looks up the publication described by query (using its KeySymbol,
+// IssueTagNumber, and MepsLanguage) from catalogDB located at dbPath and returns
+// the IDs of all documents it contains.
+func LookupDocumentIDs(dbPath string, query Lookup) ([]int, error) {
+	// Check if file exists
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("CatalogDB does not exist at %s", dbPath)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath+"?immutable=1")
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while opening SQLite database")
+	}
+	defer db.Close()
+
+	return lookupDocumentIDs(db, query)
+}
+
+func lookupDocumentIDs(db *sql.DB, query Lookup) ([]int, error) {
+	stmt, err := db.Prepare(documentIDsQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while preparing query")
+	}
+	defer stmt.Close()
+
+	return queryDocumentIDs(stmt, query)
+}
+
+func queryDocumentIDs(stmt *sql.Stmt, query Lookup) ([]int, error) {
+	rows, err := stmt.Query(query.KeySymbol, query.MepsLanguage, query.IssueTagNumber)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error while querying documents")
+	}
+	defer rows.Close()
+
+	result := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.Wrap(err, "Error while scanning row for document")
+		}
+		result = append(result, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "Error while scanning documents")
+	}
+
+	return result, nil
+}
+
+// Describe returns the title of the publication including its issue
+// and year, like "The Watchtower, February 2021".
+func (m Publication) Describe() string {
+	title := m.Title
+	if m.IssueTitle.String != "" {
+		title = m.IssueTitle.String
+	}
+	if year := strconv.Itoa(m.Year); m.Year != 0 && !strings.Contains(title, year) {
+		title += " (" + year + ")"
+	}
+	return title
+}
+
 // MarshalJSON returns the JSON encoding of the entry
 func (m Publication) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/AndreasSko/go-jwlm/model"
 	"github.com/DATA-DOG/go-sqlmock"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
@@ -207,6 +208,23 @@ func Test_lookupPublication(t *testing.T) {
 	assert.Equal(t, publication, res)
 }
 
+func TestLookupDocumentIDs(t *testing.T) {
+	catalogDB := filepath.Join("testdata", "catalog.db")
+
+	res, err := LookupDocumentIDs(catalogDB, Lookup{KeySymbol: "cl", MepsLanguage: 0})
+	assert.NoError(t, err)
+	assert.Len(t, res, 40)
+	assert.Equal(t, 1102002020, res[0])
+	assert.Equal(t, 1102002059, res[39])
+
+	res, err = LookupDocumentIDs(catalogDB, Lookup{KeySymbol: "nonexistent", MepsLanguage: 0})
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+
+	_, err = LookupDocumentIDs("nonexistent.db", Lookup{KeySymbol: "cl"})
+	assert.Error(t, err)
+}
+
 func TestPublication_MarshalJSON(t *testing.T) {
 	publ := Publication{
 		ID:                    1,
@@ -232,3 +250,76 @@ func TestPublication_MarshalJSON(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expected, string(jsn))
 }
+
+func TestLookupLocation(t *testing.T) {
+	catalogDB := filepath.Join("testdata", "catalog.db")
+
+	publ, err := LookupLocation(catalogDB, &model.Location{
+		DocumentID:   sql.NullInt32{Int32: 1102002020, Valid: true},
+		MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Draw Close to Jehovah", publ.Title)
+
+	publ, err = LookupLocation(catalogDB, &model.Location{
+		IssueTagNumber: 20210200,
+		KeySymbol:      sql.NullString{String: "w", Valid: true},
+		MepsLanguage:   sql.NullInt32{Int32: 0, Valid: true},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 305097, publ.ID)
+
+	_, err = LookupLocation(catalogDB, &model.Location{
+		KeySymbol:    sql.NullString{String: "nonexistent", Valid: true},
+		MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+	})
+	assert.Error(t, err)
+}
+
+func TestPublication_Describe(t *testing.T) {
+	assert.Equal(t, "The Watchtower, February 2021", Publication{
+		Title:      "The Watchtower Announcing Jehovah’s Kingdom (Study)—2021",
+		IssueTitle: sql.NullString{String: "The Watchtower, February 2021", Valid: true},
+		Year:       2021,
+	}.Describe())
+	assert.Equal(t, "Draw Close to Jehovah (2014)", Publication{
+		Title: "Draw Close to Jehovah",
+		Year:  2014,
+	}.Describe())
+	assert.Equal(t, "Draw Close to Jehovah", Publication{
+		Title: "Draw Close to Jehovah",
+	}.Describe())
+}
+
+func TestSearchPublications(t *testing.T) {
+	catalogDB := filepath.Join("testdata", "catalog.db")
+
+	tests := []struct {
+		name         string
+		query        string
+		mepsLanguage int
+		limit        int
+		want         []int
+	}{
+		{name: "Title", query: "jehov", mepsLanguage: -1, want: []int{305097, 67, 129}},
+		{name: "Language", query: "jehov", mepsLanguage: 1, want: []int{129}},
+		{name: "Symbol", query: "CL", mepsLanguage: 0, want: []int{67}},
+		{name: "Issue title", query: "February 2021", mepsLanguage: -1, want: []int{305097}},
+		{name: "Limit", query: "", mepsLanguage: -1, limit: 1, want: []int{305097}},
+		{name: "No result", query: "nonexistent", mepsLanguage: -1, want: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := SearchPublications(catalogDB, tt.query, tt.mepsLanguage, tt.limit)
+			assert.NoError(t, err)
+			ids := []int{}
+			for _, publ := range res {
+				ids = append(ids, publ.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+
+	_, err := SearchPublications("nonexistent.db", "", -1, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,537 @@
+// Package server provides a REST API for merging JW Library backups,
+// so merges can be run from other tools or a browser. A browser UI for
+// resolving conflicts is served at /.
+//
+// A merge is done within a session:
+//
+//	POST   /sessions                  upload two or more backups (multipart field "backup")
+//	GET    /sessions/{id}             get the status of the merge
+//	GET    /sessions/{id}/conflicts   list the unsolved conflicts
+//	POST   /sessions/{id}/resolutions resolve conflicts by choosing a side
+//	GET    /sessions/{id}/download    download the merged backup
+//	DELETE /sessions/{id}             remove the session
+//
+// Requests sent by browsers from pages of other origins are rejected, so
+// other websites can't access the uploaded backups. To prevent DNS rebinding,
+// only requests for a loopback host or the host of Server.Addr are accepted.
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AndreasSko/go-jwlm/merger"
+	"github.com/AndreasSko/go-jwlm/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// errInvalidSession is returned if a session can't be created because of invalid parameters.
+var errInvalidSession = errors.New("invalid session")
+
+// errSessionFailed is returned if the merge of a session failed and can't be continued.
+var errSessionFailed = errors.New("merge failed")
+
+// defaultMaxUploadSize is the maximum size of all backups uploaded for a session.
+const defaultMaxUploadSize = 2 << 30
+
+// defaultSessionTimeout is the time after which unused sessions are removed.
+const defaultSessionTimeout = 24 * time.Hour
+
+// cleanupInterval is the interval in which expired sessions are removed.
+var cleanupInterval = time.Minute
+
+// Server handles the merge sessions and serves the REST API.
+type Server struct {
+	// MaxUploadSize limits the size of the backups uploaded for a session.
+	MaxUploadSize int64
+	// SessionTimeout is the time after which unused sessions are removed.
+	SessionTimeout time.Duration
+	// SkipPlaylists allows to skip prevention of merging if playlists exist in the backups.
+	// It is meant as a temporary workaround until merging of playlists is implemented.
+	SkipPlaylists bool
+	// TempDir is used for temporary files, if any are needed. If not set, os.TempDir() will be used.
+	TempDir string
+	// Addr is the address the server listens on (like localhost:8080). Besides
+	// loopback hosts, requests are only accepted for its host.
+	Addr string
+	// OnDone is called once a session has merged all of its backups.
+	OnDone func(id string, merged *model.Database)
+
+	mux      *http.ServeMux
+	mu       sync.Mutex
+	sessions map[string]*session
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// New creates a new Server with the default limits. It removes expired
+// sessions in the background until it is closed.
+func New() *Server {
+	srv := &Server{
+		MaxUploadSize:  defaultMaxUploadSize,
+		SessionTimeout: defaultSessionTimeout,
+		mux:            http.NewServeMux(),
+		sessions:       map[string]*session{},
+		stop:           make(chan struct{}),
+	}
+
+	srv.mux.HandleFunc("POST /sessions", srv.createSession)
+	srv.mux.HandleFunc("GET /sessions/{id}", srv.withSession(srv.getStatus))
+	srv.mux.HandleFunc("DELETE /sessions/{id}", srv.deleteSession)
+	srv.mux.HandleFunc("GET /sessions/{id}/conflicts", srv.withSession(srv.getConflicts))
+	srv.mux.HandleFunc("POST /sessions/{id}/resolutions", srv.withSession(srv.postResolutions))
+	srv.mux.HandleFunc("GET /sessions/{id}/download", srv.withSession(srv.download))
+	srv.mux.Handle("GET /", uiHandler())
+
+	go srv.cleanup(cleanupInterval)
+
+	return srv
+}
+
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !srv.allowedHost(r.Host) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("requests for host %s are not allowed", r.Host))
+		return
+	}
+	if !sameOrigin(r) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("requests from %s are not allowed", r.Header.Get("Origin")))
+		return
+	}
+	srv.mux.ServeHTTP(w, r)
+}
+
+// Close stops removing expired sessions in the background.
+func (srv *Server) Close() {
+	srv.stopOnce.Do(func() { close(srv.stop) })
+}
+
+// cleanup removes expired sessions in the given interval until the Server is closed.
+func (srv *Server) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			srv.mu.Lock()
+			srv.removeExpiredSessions()
+			srv.mu.Unlock()
+		case <-srv.stop:
+			return
+		}
+	}
+}
+
+// allowedHost checks if host (the Host header of a request) is a loopback host
+// or the host the server listens on. Otherwise, a page of another website could
+// access the server by resolving its own name to the server's address (DNS rebinding).
+func (srv *Server) allowedHost(host string) bool {
+	hostname := (&url.URL{Host: host}).Hostname()
+	if hostname == "" {
+		return false
+	}
+	if strings.EqualFold(hostname, "localhost") {
+		return true
+	}
+	if ip := net.ParseIP(hostname); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	return strings.EqualFold(hostname, (&url.URL{Host: srv.Addr}).Hostname())
+}
+
+// sameOrigin checks if a request sent by a browser comes from a page of the
+// server itself. Requests without an Origin header (like the ones of curl)
+// are not sent by browsers on behalf of other websites and therefore allowed.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return u.Host == r.Host
+}
+
+// status represents the state of a session.
+type status struct {
+	ID string `json:"id"`
+	// Backups is the number of backups of the session.
+	Backups int `json:"backups"`
+	// Merged is the number of backups that have been merged so far.
+	Merged int `json:"merged"`
+	// Step is the merge step that is currently waiting for conflicts to be resolved.
+	Step              string `json:"step,omitempty"`
+	UnsolvedConflicts int    `json:"unsolvedConflicts"`
+	Done              bool   `json:"done"`
+	// Error is set if the merge failed. The session can't be continued then.
+	Error string `json:"error,omitempty"`
+}
+
+// modelRelatedTuple contains a model and its related entries
+type modelRelatedTuple struct {
+	Model   model.Model   `json:"model"`
+	Related model.Related `json:"related"`
+}
+
+// conflict represents two Models that collide
+type conflict struct {
+	Key   string            `json:"key"`
+	Left  modelRelatedTuple `json:"left"`
+	Right modelRelatedTuple `json:"right"`
+}
+
+// resolution chooses the side of the conflict with the given key
+type resolution struct {
+	Key  string           `json:"key"`
+	Side merger.MergeSide `json:"side"`
+}
+
+// createSession imports the uploaded backups and starts merging them.
+// Conflicts can be resolved automatically by passing the name of a
+// resolver (like chooseLeft) as a form value named after the merge step.
+func (srv *Server) createSession(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, srv.MaxUploadSize)
+	uploads, resolvers, err := srv.readUploads(r)
+	defer func() {
+		for _, upload := range uploads {
+			upload.file.Close()
+			os.Remove(upload.file.Name())
+		}
+	}()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("could not parse uploaded backups: %w", err))
+		return
+	}
+	if len(uploads) < 2 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("at least two backups are needed for merging, got %d", len(uploads)))
+		return
+	}
+
+	backups := make([]*model.Database, len(uploads))
+	for i, upload := range uploads {
+		db := &model.Database{
+			SkipPlaylists: srv.SkipPlaylists,
+			TempDir:       srv.TempDir,
+		}
+		if err := db.ImportJWLBackupFrom(upload.file, upload.size); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Errorf("could not import backup %s: %w", upload.filename, err))
+			return
+		}
+		backups[i] = db
+	}
+
+	id, err := srv.CreateSession(backups, resolvers)
+	if err != nil {
+		code := http.StatusUnprocessableEntity
+		if errors.Is(err, errInvalidSession) {
+			code = http.StatusBadRequest
+		}
+		writeError(w, code, err)
+		return
+	}
+
+	srv.mu.Lock()
+	s := srv.sessions[id]
+	srv.mu.Unlock()
+	s.Lock()
+	defer s.Unlock()
+	writeJSON(w, http.StatusCreated, s.status())
+}
+
+// maxFormValueSize is the maximum size of a form value (like the name of a resolver).
+const maxFormValueSize = 1 << 10
+
+// upload is a backup uploaded for a session. It is stored in a temporary file.
+type upload struct {
+	filename string
+	file     *os.File
+	size     int64
+}
+
+// readUploads reads the multipart form of r. The backups (field "backup") are
+// stored in temporary files within srv.TempDir, which have to be closed and removed
+// by the caller, even if an error is returned. The first non-empty value of all other
+// fields is returned by its name.
+func (srv *Server) readUploads(r *http.Request) ([]upload, map[string]string, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var uploads []upload
+	values := map[string]string{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return uploads, values, nil
+		}
+		if err != nil {
+			return uploads, nil, err
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFormValueSize+1))
+			if err != nil {
+				return uploads, nil, err
+			}
+			if len(value) > maxFormValueSize {
+				return uploads, nil, fmt.Errorf("value of %s is too large", part.FormName())
+			}
+			if _, ok := values[part.FormName()]; !ok && len(value) > 0 {
+				values[part.FormName()] = string(value)
+			}
+			continue
+		}
+		if part.FormName() != "backup" {
+			continue
+		}
+
+		file, err := os.CreateTemp(srv.TempDir, "go-jwlm-upload-")
+		if err != nil {
+			return uploads, nil, err
+		}
+		uploads = append(uploads, upload{filename: part.FileName(), file: file})
+		size, err := io.Copy(file, part)
+		if err != nil {
+			return uploads, nil, err
+		}
+		uploads[len(uploads)-1].size = size
+	}
+}
+
+// CreateSession creates a new session for merging the given backups in their
+// order and returns its ID. Conflicts of a merge step (like notes) are resolved
+// automatically if a resolver (like chooseLeft) is given for it. The merge is
+// run until the first conflicts need to be resolved.
+func (srv *Server) CreateSession(backups []*model.Database, resolvers map[string]string) (string, error) {
+	id, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+	s, err := newSession(id, backups, resolvers)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errInvalidSession, err)
+	}
+	if err := s.advance(); err != nil {
+		return "", err
+	}
+
+	srv.notifyDone(s)
+
+	srv.mu.Lock()
+	srv.removeExpiredSessions()
+	srv.sessions[id] = s
+	srv.mu.Unlock()
+
+	return id, nil
+}
+
+// withSession looks up the session of the request and locks it while
+// calling handler.
+func (srv *Server) withSession(handler func(http.ResponseWriter, *http.Request, *session)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		srv.mu.Lock()
+		s, ok := srv.sessions[r.PathValue("id")]
+		srv.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("session %s does not exist", r.PathValue("id")))
+			return
+		}
+
+		s.Lock()
+		defer s.Unlock()
+		s.lastUsed = time.Now()
+		handler(w, r, s)
+	}
+}
+
+func (srv *Server) getStatus(w http.ResponseWriter, r *http.Request, s *session) {
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+func (srv *Server) deleteSession(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	id := r.PathValue("id")
+	if _, ok := srv.sessions[id]; !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("session %s does not exist", id))
+		return
+	}
+	delete(srv.sessions, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getConflicts returns the unsolved conflicts of the session sorted by their key.
+func (srv *Server) getConflicts(w http.ResponseWriter, r *http.Request, s *session) {
+	result := []conflict{}
+	for _, key := range s.unsolvedKeys() {
+		c := s.conflicts[key]
+		result = append(result, conflict{
+			Key: key,
+			Left: modelRelatedTuple{
+				Model:   c.Left,
+				Related: c.Left.RelatedEntries(s.merged),
+			},
+			Right: modelRelatedTuple{
+				Model:   c.Right,
+				Related: c.Right.RelatedEntries(s.merged),
+			},
+		})
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// postResolutions resolves the given conflicts and continues merging.
+func (srv *Server) postResolutions(w http.ResponseWriter, r *http.Request, s *session) {
+	var resolutions []resolution
+	if err := json.NewDecoder(r.Body).Decode(&resolutions); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("could not parse resolutions: %w", err))
+		return
+	}
+
+	sides := make(map[string]merger.MergeSide, len(resolutions))
+	for _, res := range resolutions {
+		sides[res.Key] = res.Side
+	}
+	if err := s.resolve(sides); err != nil {
+		code := http.StatusBadRequest
+		if errors.Is(err, errSessionFailed) {
+			code = http.StatusUnprocessableEntity
+		}
+		writeError(w, code, err)
+		return
+	}
+	srv.notifyDone(s)
+
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+// download exports the merged backup. Its name and device name can be
+// set with the query parameters name and device. With metadata=left or
+// metadata=right, the name, device name, and thumbnail are inherited from
+// the first or last backup of the session.
+func (srv *Server) download(w http.ResponseWriter, r *http.Request, s *session) {
+	if !s.done {
+		writeError(w, http.StatusConflict, fmt.Errorf("merge is not finished yet"))
+		return
+	}
+
+	opts := model.ExportOptions{
+		Metadata:   &model.Metadata{},
+		Name:       r.URL.Query().Get("name"),
+		DeviceName: r.URL.Query().Get("device"),
+	}
+	switch metadata := r.URL.Query().Get("metadata"); metadata {
+	case "left":
+		opts.Metadata = s.backups[0].Metadata
+	case "right":
+		opts.Metadata = s.backups[len(s.backups)-1].Metadata
+	case "", "default":
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%s is not a valid option for metadata. Can be 'left', 'right', or 'default'", metadata))
+		return
+	}
+	// Extra files (like media of playlists) are not merged yet
+	if opts.Metadata != nil {
+		md := *opts.Metadata
+		md.ExtraFiles = nil
+		opts.Metadata = &md
+	}
+	var buf bytes.Buffer
+	if err := s.result.ExportJWLBackupToWithOptions(&buf, opts); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="merged.jwlibrary"`)
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Error(err)
+	}
+}
+
+// status returns the current status of the session.
+func (s *session) status() status {
+	result := status{
+		ID:                s.id,
+		Backups:           len(s.backups),
+		Merged:            s.next,
+		Step:              s.stepName(),
+		UnsolvedConflicts: len(s.unsolved),
+		Done:              s.done,
+	}
+	if s.err != nil {
+		result.Error = s.err.Error()
+	}
+	if s.done {
+		result.Merged = len(s.backups)
+	}
+	return result
+}
+
+// notifyDone calls OnDone if the session has just merged all of its backups.
+func (srv *Server) notifyDone(s *session) {
+	if !s.done || s.notified {
+		return
+	}
+	s.notified = true
+	if srv.OnDone != nil {
+		srv.OnDone(s.id, s.result)
+	}
+}
+
+// removeExpiredSessions removes all sessions that haven't been used
+// within the SessionTimeout. srv.mu must be held by the caller.
+func (srv *Server) removeExpiredSessions() {
+	if srv.SessionTimeout <= 0 {
+		return
+	}
+	for id, s := range srv.sessions {
+		if s.TryLock() {
+			expired := time.Since(s.lastUsed) > srv.SessionTimeout
+			s.Unlock()
+			if expired {
+				delete(srv.sessions, id)
+			}
+		}
+	}
+}
+
+// newSessionID creates a random ID for a session.
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("could not create session ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// writeJSON writes value as JSON with the given status code.
+func writeJSON(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Error(err)
+	}
+}
+
+// writeError writes err as JSON object with the given status code.
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": strings.TrimSpace(err.Error())})
+}
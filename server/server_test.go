@@ -0,0 +1,431 @@
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// backupWithNote returns a backup containing a single Note with the given title.
+func backupWithNote(t *testing.T, title string) []byte {
+	db := &model.Database{
+		Note: []*model.Note{
+			nil,
+			{
+				NoteID:       1,
+				GUID:         "F75A18EE-FC17-4E0B-ABB6-CC16DABE9610",
+				Title:        sql.NullString{String: title, Valid: true},
+				Content:      sql.NullString{String: "Content", Valid: true},
+				LastModified: "2023-07-15T10:54:07+00:00",
+				Created:      "2023-07-15T10:54:07+00:00",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, db.ExportJWLBackupTo(&buf))
+	return buf.Bytes()
+}
+
+// createSession uploads the given backups together with the form values
+// and returns the response.
+func createSession(t *testing.T, srv http.Handler, backups [][]byte, values map[string]string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for i, backup := range backups {
+		fw, err := mw.CreateFormFile("backup", strings.Repeat("x", i+1)+".jwlibrary")
+		require.NoError(t, err)
+		_, err = fw.Write(backup)
+		require.NoError(t, err)
+	}
+	for key, value := range values {
+		require.NoError(t, mw.WriteField(key, value))
+	}
+	require.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/sessions", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec
+}
+
+// do sends a request for localhost to srv and returns the response.
+func do(srv http.Handler, method string, target string, body io.Reader) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(method, "http://localhost"+target, body))
+	return rec
+}
+
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	var result T
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&result))
+	return result
+}
+
+func TestServer_merge(t *testing.T) {
+	srv := New()
+	defer srv.Close()
+	left := backupWithNote(t, "Left")
+	right := backupWithNote(t, "Right")
+
+	rec := createSession(t, srv, [][]byte{left, right, left}, nil)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	st := decode[status](t, rec)
+	assert.Equal(t, 3, st.Backups)
+	assert.Equal(t, 1, st.Merged)
+	assert.Equal(t, "notes", st.Step)
+	assert.Equal(t, 1, st.UnsolvedConflicts)
+	assert.False(t, st.Done)
+
+	rec = do(srv, http.MethodGet, "/sessions/"+st.ID+"/download", nil)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = do(srv, http.MethodGet, "/sessions/"+st.ID+"/conflicts", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var conflicts []struct {
+		Key   string `json:"key"`
+		Left  struct{ Model map[string]interface{} }
+		Right struct{ Model map[string]interface{} }
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&conflicts))
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, map[string]interface{}{"String": "Left", "Valid": true}, conflicts[0].Left.Model["title"])
+	assert.Equal(t, map[string]interface{}{"String": "Right", "Valid": true}, conflicts[0].Right.Model["title"])
+
+	rec = do(srv, http.MethodPost, "/sessions/"+st.ID+"/resolutions",
+		strings.NewReader(`[{"key": "`+conflicts[0].Key+`", "side": "middle"}]`))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// Merging the third backup results in the same conflict again
+	rec = do(srv, http.MethodPost, "/sessions/"+st.ID+"/resolutions",
+		strings.NewReader(`[{"key": "`+conflicts[0].Key+`", "side": "rightSide"}]`))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	st = decode[status](t, rec)
+	assert.Equal(t, 2, st.Merged)
+	assert.Equal(t, 1, st.UnsolvedConflicts)
+
+	rec = do(srv, http.MethodPost, "/sessions/"+st.ID+"/resolutions",
+		strings.NewReader(`[{"key": "`+conflicts[0].Key+`", "side": "leftSide"}]`))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	st = decode[status](t, rec)
+	assert.Equal(t, 3, st.Merged)
+	assert.True(t, st.Done)
+
+	rec = do(srv, http.MethodGet, "/sessions/"+st.ID+"/download?name=merged", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	merged := &model.Database{}
+	require.NoError(t, merged.ImportJWLBackupFrom(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len())))
+	assert.Equal(t, "Right", merged.Note[1].Title.String)
+	assert.Equal(t, "merged", merged.Metadata.Name)
+
+	rec = do(srv, http.MethodDelete, "/sessions/"+st.ID, nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = do(srv, http.MethodGet, "/sessions/"+st.ID, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_failedMerge(t *testing.T) {
+	srv := New()
+	defer srv.Close()
+
+	locations := []*model.Location{
+		nil,
+		{
+			LocationID:    1,
+			BookNumber:    sql.NullInt32{Int32: 43, Valid: true},
+			ChapterNumber: sql.NullInt32{Int32: 3, Valid: true},
+			KeySymbol:     sql.NullString{String: "nwtsty", Valid: true},
+			MepsLanguage:  sql.NullInt32{Int32: 0, Valid: true},
+		},
+		{
+			LocationID:   2,
+			KeySymbol:    sql.NullString{String: "nwtsty", Valid: true},
+			MepsLanguage: sql.NullInt32{Int32: 0, Valid: true},
+			LocationType: 1,
+		},
+	}
+	note := func(title string) []*model.Note {
+		return []*model.Note{nil, {
+			NoteID:       1,
+			GUID:         "F75A18EE-FC17-4E0B-ABB6-CC16DABE9610",
+			Title:        sql.NullString{String: title, Valid: true},
+			LastModified: "2023-07-15T10:54:07+00:00",
+			Created:      "2023-07-15T10:54:07+00:00",
+		}}
+	}
+	bookmark := func(title string) []*model.Bookmark {
+		return []*model.Bookmark{nil, {BookmarkID: 1, LocationID: 1, PublicationLocationID: 2, Slot: 1, Title: title}}
+	}
+	backups := []*model.Database{
+		{Location: locations, Bookmark: bookmark("Left"), Note: note("Left")},
+		{Note: note("Right")},
+		{Location: locations, Bookmark: bookmark("Other")},
+	}
+
+	// Bookmarks have no modification date, so choosing the
+	// newest one fails once they conflict in the second merge
+	id, err := srv.CreateSession(backups, map[string]string{"bookmarks": "chooseNewest"})
+	require.NoError(t, err)
+	rec := do(srv, http.MethodGet, "/sessions/"+id+"/conflicts", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	conflicts := decode[[]struct{ Key string }](t, rec)
+	require.Len(t, conflicts, 1)
+
+	resolutions := `[{"key": "` + conflicts[0].Key + `", "side": "leftSide"}]`
+	rec = do(srv, http.MethodPost, "/sessions/"+id+"/resolutions", strings.NewReader(resolutions))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = do(srv, http.MethodGet, "/sessions/"+id, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	st := decode[status](t, rec)
+	assert.Contains(t, st.Error, "LastModified")
+	assert.False(t, st.Done)
+
+	// The merge is not retried with the state of the failed one
+	rec = do(srv, http.MethodPost, "/sessions/"+id+"/resolutions", strings.NewReader(`[]`))
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	rec = do(srv, http.MethodGet, "/sessions/"+id+"/download", nil)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestServer_createSession(t *testing.T) {
+	srv := New()
+	defer srv.Close()
+	srv.TempDir = t.TempDir()
+	left := backupWithNote(t, "Left")
+	right := backupWithNote(t, "Right")
+
+	rec := createSession(t, srv, [][]byte{left, right}, map[string]string{"notes": "chooseRight"})
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	st := decode[status](t, rec)
+	assert.True(t, st.Done)
+	assert.Equal(t, 0, st.UnsolvedConflicts)
+
+	rec = do(srv, http.MethodGet, "/sessions/"+st.ID, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, st, decode[status](t, rec))
+
+	tests := []struct {
+		name    string
+		backups [][]byte
+		values  map[string]string
+		code    int
+	}{
+		{
+			name:    "only one backup",
+			backups: [][]byte{left},
+			code:    http.StatusBadRequest,
+		},
+		{
+			name:    "invalid backup",
+			backups: [][]byte{left, []byte("no zip")},
+			code:    http.StatusUnprocessableEntity,
+		},
+		{
+			name:    "invalid resolver",
+			backups: [][]byte{left, right},
+			values:  map[string]string{"notes": "chooseMiddle"},
+			code:    http.StatusBadRequest,
+		},
+		{
+			name:    "unknown step",
+			backups: [][]byte{left, right},
+			values:  map[string]string{"playlists": "chooseLeft"},
+			code:    http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := createSession(t, srv, tt.backups, tt.values)
+			assert.Equal(t, tt.code, rec.Code)
+			assert.Contains(t, decode[map[string]string](t, rec), "error")
+		})
+	}
+
+	// Uploaded backups are stored in the TempDir and removed afterwards
+	uploads, err := filepath.Glob(filepath.Join(srv.TempDir, "go-jwlm-upload-*"))
+	assert.NoError(t, err)
+	assert.Empty(t, uploads)
+
+	srv.TempDir = filepath.Join(srv.TempDir, "nonexistent")
+	rec = createSession(t, srv, [][]byte{left, right}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	srv.MaxUploadSize = 10
+	rec = createSession(t, srv, [][]byte{left, right}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestServer_ui(t *testing.T) {
+	srv := New()
+	defer srv.Close()
+
+	rec := do(srv, http.MethodGet, "/", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<script src="app.js"></script>`)
+
+	for _, file := range []string{"/app.js", "/style.css"} {
+		rec = do(srv, http.MethodGet, file, nil)
+		assert.Equal(t, http.StatusOK, rec.Code, file)
+	}
+}
+
+func TestServer_OnDone(t *testing.T) {
+	left := &model.Database{}
+	backup := backupWithNote(t, "Left")
+	require.NoError(t, left.ImportJWLBackupFrom(bytes.NewReader(backup), int64(len(backup))))
+	right := &model.Database{}
+	backup = backupWithNote(t, "Right")
+	require.NoError(t, right.ImportJWLBackupFrom(bytes.NewReader(backup), int64(len(backup))))
+
+	srv := New()
+	defer srv.Close()
+	var doneID string
+	var doneDB *model.Database
+	srv.OnDone = func(id string, merged *model.Database) {
+		doneID = id
+		doneDB = merged
+	}
+
+	id, err := srv.CreateSession([]*model.Database{left, right}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, doneID)
+
+	rec := do(srv, http.MethodGet, "/sessions/"+id+"/conflicts", nil)
+	conflicts := decode[[]map[string]interface{}](t, rec)
+	require.Len(t, conflicts, 1)
+	rec = do(srv, http.MethodPost, "/sessions/"+id+"/resolutions",
+		strings.NewReader(`[{"key": "`+conflicts[0]["key"].(string)+`", "side": "leftSide"}]`))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Equal(t, id, doneID)
+	require.NotNil(t, doneDB)
+	assert.Equal(t, "Left", doneDB.Note[1].Title.String)
+
+	_, err = srv.CreateSession([]*model.Database{left}, nil)
+	assert.ErrorIs(t, err, errInvalidSession)
+}
+
+func TestServer_download(t *testing.T) {
+	srv := New()
+	defer srv.Close()
+
+	backups := [][]byte{}
+	for _, name := range []string{"Left", "Right"} {
+		db := &model.Database{}
+		var buf bytes.Buffer
+		require.NoError(t, db.ExportJWLBackupToWithOptions(&buf, model.ExportOptions{
+			Name:       name + " backup",
+			DeviceName: name + " device",
+		}))
+		backups = append(backups, buf.Bytes())
+	}
+	rec := createSession(t, srv, backups, nil)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	st := decode[status](t, rec)
+	require.True(t, st.Done)
+
+	tests := []struct {
+		query      string
+		wantName   string
+		wantDevice string
+	}{
+		{query: "", wantName: "go-jwlm", wantDevice: "go-jwlm"},
+		{query: "?metadata=default", wantName: "go-jwlm", wantDevice: "go-jwlm"},
+		{query: "?metadata=left", wantName: "Left backup", wantDevice: "Left device"},
+		{query: "?metadata=right", wantName: "Right backup", wantDevice: "Right device"},
+		{query: "?metadata=right&name=merged", wantName: "merged", wantDevice: "Right device"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := do(srv, http.MethodGet, "/sessions/"+st.ID+"/download"+tt.query, nil)
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			merged := &model.Database{}
+			require.NoError(t, merged.ImportJWLBackupFrom(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len())))
+			assert.Equal(t, tt.wantName, merged.Metadata.Name)
+			assert.Equal(t, tt.wantDevice, merged.Metadata.DeviceName)
+		})
+	}
+
+	rec = do(srv, http.MethodGet, "/sessions/"+st.ID+"/download?metadata=middle", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestServer_origin(t *testing.T) {
+	srv := New()
+	defer srv.Close()
+	srv.Addr = "jwlm.local:8080"
+	left := backupWithNote(t, "Left")
+	right := backupWithNote(t, "Right")
+	rec := createSession(t, srv, [][]byte{left, right}, map[string]string{"notes": "chooseLeft"})
+	require.Equal(t, http.StatusCreated, rec.Code)
+	id := decode[status](t, rec).ID
+
+	tests := []struct {
+		name   string
+		host   string
+		origin string
+		code   int
+	}{
+		{name: "Without Origin", host: "localhost:8080", code: http.StatusOK},
+		{name: "Same origin", host: "localhost:8080", origin: "http://localhost:8080", code: http.StatusOK},
+		{name: "IPv4 loopback", host: "127.0.0.1:8080", origin: "http://127.0.0.1:8080", code: http.StatusOK},
+		{name: "IPv6 loopback", host: "[::1]:8080", origin: "http://[::1]:8080", code: http.StatusOK},
+		{name: "Listen address", host: "jwlm.local:8080", origin: "http://jwlm.local:8080", code: http.StatusOK},
+		{name: "Other origin", host: "localhost:8080", origin: "http://evil.example", code: http.StatusForbidden},
+		{name: "Origin with suffix", host: "localhost:8080", origin: "http://localhost:8080.evil.example", code: http.StatusForbidden},
+		{name: "Null origin", host: "localhost:8080", origin: "null", code: http.StatusForbidden},
+		{name: "DNS rebinding", host: "evil.example:8080", origin: "http://evil.example:8080", code: http.StatusForbidden},
+		{name: "DNS rebinding without Origin", host: "evil.example:8080", code: http.StatusForbidden},
+		{name: "Without host", code: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/sessions/"+id, nil)
+			req.Host = tt.host
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tt.code, rec.Code)
+		})
+	}
+}
+
+func TestServer_removeExpiredSessions(t *testing.T) {
+	interval := cleanupInterval
+	cleanupInterval = 10 * time.Millisecond
+	t.Cleanup(func() { cleanupInterval = interval })
+
+	srv := New()
+	defer srv.Close()
+	srv.mu.Lock()
+	srv.SessionTimeout = 50 * time.Millisecond
+	srv.mu.Unlock()
+
+	rec := createSession(t, srv, [][]byte{backupWithNote(t, "Left"), backupWithNote(t, "Right")}, nil)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	id := decode[status](t, rec).ID
+
+	// Expired sessions are removed without creating new ones
+	assert.Eventually(t, func() bool {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		_, ok := srv.sessions[id]
+		return !ok
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AndreasSko/go-jwlm/merger"
+	"github.com/AndreasSko/go-jwlm/model"
+	"github.com/pkg/errors"
+)
+
+// session is a merge of two or more backups. The backups are merged one
+// after the other into the result of the former merges. If a step runs into
+// conflicts that can't be solved automatically, the session stops until
+// they have been resolved.
+type session struct {
+	sync.Mutex
+
+	id string
+	// backups that are merged in the given order.
+	backups []*model.Database
+	// resolvers are the names of the conflict resolvers by step.
+	resolvers map[string]string
+	lastUsed  time.Time
+
+	// next is the index of the backup that is merged next.
+	next  int
+	step  int
+	left  *model.Database
+	right *model.Database
+	// merged is the Database that is currently being filled by the steps.
+	merged *model.Database
+	// result contains all backups that have been merged so far.
+	result *model.Database
+	done   bool
+	// err is set if the merge failed with an error other than conflicts.
+	// The session can't be continued then.
+	err error
+	// notified indicates if Server.OnDone has been called for the session.
+	notified bool
+
+	conflicts map[string]merger.MergeConflict
+	unsolved  map[string]bool
+	solutions map[string]merger.MergeSolution
+}
+
+// newSession creates a new session for merging the given backups.
+func newSession(id string, backups []*model.Database, resolvers map[string]string) (*session, error) {
+	if len(backups) < 2 {
+		return nil, errors.New("at least two backups are needed for merging")
+	}
+	for name, resolver := range resolvers {
+		if _, ok := merger.LookupMergeStep(name); !ok {
+			return nil, fmt.Errorf("%s can not be resolved automatically", name)
+		}
+		if _, err := merger.AutoResolveConflicts(nil, resolver); err != nil {
+			return nil, err
+		}
+	}
+
+	return &session{
+		id:        id,
+		backups:   backups,
+		resolvers: resolvers,
+		lastUsed:  time.Now(),
+		result:    backups[0],
+		next:      1,
+	}, nil
+}
+
+// advance merges the backups as far as possible. It stops if
+// all backups are merged or there are unsolved conflicts.
+func (s *session) advance() error {
+	for !s.done {
+		if len(s.unsolved) > 0 {
+			return nil
+		}
+
+		if s.merged == nil {
+			s.startPair()
+		}
+
+		for s.step < len(merger.MergeSteps) {
+			step := merger.MergeSteps[s.step]
+			err := step.Run(context.Background(), s.left, s.right, s.merged, s.solutions, s.resolvers[step.Name])
+			if err == nil {
+				s.step++
+				s.conflicts = nil
+				s.unsolved = nil
+				s.solutions = map[string]merger.MergeSolution{}
+				continue
+			}
+
+			mcErr, ok := err.(merger.MergeConflictError)
+			if !ok {
+				return err
+			}
+			s.addConflicts(mcErr.Conflicts)
+			return nil
+		}
+
+		if err := merger.PrepareDatabasesPostMerge(s.merged); err != nil {
+			return err
+		}
+		s.result = s.merged
+		s.merged = nil
+		s.next++
+		s.done = s.next >= len(s.backups)
+	}
+
+	return nil
+}
+
+// startPair prepares merging the next backup into the current result.
+func (s *session) startPair() {
+	s.left, s.right, s.merged = merger.PrepareMerge(s.result, s.backups[s.next])
+	s.step = 0
+	s.conflicts = nil
+	s.unsolved = nil
+	s.solutions = map[string]merger.MergeSolution{}
+}
+
+// addConflicts adds the given conflicts to the unsolved ones, unless
+// they already exist.
+func (s *session) addConflicts(conflicts map[string]merger.MergeConflict) {
+	if s.conflicts == nil {
+		s.conflicts = make(map[string]merger.MergeConflict, len(conflicts))
+	}
+	if s.unsolved == nil {
+		s.unsolved = make(map[string]bool, len(conflicts))
+	}
+
+	for key, conflict := range conflicts {
+		if _, exists := s.conflicts[key]; !exists {
+			s.conflicts[key] = conflict
+			s.unsolved[key] = true
+		}
+	}
+}
+
+// unsolvedKeys returns the sorted keys of all unsolved conflicts.
+func (s *session) unsolvedKeys() []string {
+	keys := make([]string, 0, len(s.unsolved))
+	for key := range s.unsolved {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// resolve solves the conflicts by choosing the given side for each key. Either all
+// resolutions are applied or - if one of them is invalid - none of them. Once all
+// conflicts are solved, the merge continues. If it fails with an error other than
+// conflicts, the session is marked as failed and can't be continued.
+func (s *session) resolve(resolutions map[string]merger.MergeSide) error {
+	if s.err != nil {
+		return fmt.Errorf("%w: %w", errSessionFailed, s.err)
+	}
+	for key, side := range resolutions {
+		if !s.unsolved[key] {
+			return fmt.Errorf("unsolved conflict with key %s does not exist", key)
+		}
+		if side != merger.LeftSide && side != merger.RightSide {
+			return fmt.Errorf("side %s is not valid. Can be '%s' or '%s'", side, merger.LeftSide, merger.RightSide)
+		}
+	}
+
+	for key, side := range resolutions {
+		conflict := s.conflicts[key]
+		solution := merger.MergeSolution{
+			Side:      side,
+			Solution:  conflict.Left,
+			Discarded: conflict.Right,
+		}
+		if side == merger.RightSide {
+			solution.Solution, solution.Discarded = conflict.Right, conflict.Left
+		}
+		s.solutions[key] = solution
+		delete(s.unsolved, key)
+	}
+
+	// The solutions have already been used for merging, so the
+	// session is left in an inconsistent state if it fails.
+	if err := s.advance(); err != nil {
+		s.err = err
+		return fmt.Errorf("%w: %w", errSessionFailed, err)
+	}
+	return nil
+}
+
+// stepName returns the name of the current step.
+func (s *session) stepName() string {
+	if s.done || s.merged == nil || s.step >= len(merger.MergeSteps) {
+		return ""
+	}
+	return merger.MergeSteps[s.step].Name
+}
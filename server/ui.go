@@ -0,0 +1,22 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// uiFiles contains the browser UI for resolving conflicts. It is a
+// static page that only uses the REST API of the Server.
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// uiHandler serves the files of the browser UI.
+func uiHandler() http.Handler {
+	files, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServerFS(files)
+}
@@ -0,0 +1,219 @@
+"use strict";
+
+// Names of the colors of markings by their ColorIndex
+const colorNames = ["Gray", "Yellow", "Green", "Blue", "Pink", "Orange", "Purple"];
+
+const state = {
+  sessionID: window.location.hash.slice(1),
+  conflicts: [],
+  current: 0,
+};
+
+const $ = (selector) => document.querySelector(selector);
+
+function show(section) {
+  for (const id of ["upload", "conflicts", "done"]) {
+    $("#" + id).hidden = id !== section;
+  }
+}
+
+function showError(err) {
+  $("#error").textContent = err.message || String(err);
+  $("#error").hidden = false;
+}
+
+async function request(method, path, body) {
+  const options = { method };
+  if (body instanceof FormData) {
+    options.body = body;
+  } else if (body !== undefined) {
+    options.body = JSON.stringify(body);
+    options.headers = { "Content-Type": "application/json" };
+  }
+
+  const response = await fetch(path, options);
+  const result = response.status === 204 ? null : await response.json();
+  if (!response.ok) {
+    throw new Error(result && result.error ? result.error : response.statusText);
+  }
+  return result;
+}
+
+// nullable returns the value of a sql.Null* field or undefined if it is not set
+function nullable(field) {
+  return field && field.Valid ? (field.String ?? field.Int32) : undefined;
+}
+
+function element(tag, text, className) {
+  const el = document.createElement(tag);
+  if (text !== undefined) {
+    el.textContent = text;
+  }
+  if (className) {
+    el.className = className;
+  }
+  return el;
+}
+
+function renderLocation(location) {
+  const div = element("div", undefined, "location");
+  if (!location) {
+    return div;
+  }
+
+  const lines = [];
+  const title = nullable(location.title);
+  if (title) {
+    lines.push(title);
+  }
+  const publication = [nullable(location.keySymbol), nullable(location.mepsLanguage) !== undefined ? "language " + nullable(location.mepsLanguage) : undefined]
+    .filter((part) => part !== undefined);
+  if (publication.length > 0) {
+    lines.push("Publication: " + publication.join(", "));
+  }
+  if (nullable(location.bookNumber) !== undefined) {
+    lines.push("Book " + nullable(location.bookNumber) + ", chapter " + (nullable(location.chapterNumber) ?? "-"));
+  }
+  if (nullable(location.documentId) !== undefined) {
+    lines.push("Document " + nullable(location.documentId));
+  }
+  if (location.issueTagNumber) {
+    lines.push("Issue " + location.issueTagNumber);
+  }
+  div.textContent = lines.join("\n");
+  return div;
+}
+
+function renderModel(tuple) {
+  const mdl = tuple.model;
+  const related = tuple.related || {};
+  const fragment = document.createDocumentFragment();
+
+  switch (mdl.type) {
+    case "Note":
+      fragment.append(element("h3", nullable(mdl.title) || "(Note without title)"));
+      fragment.append(element("p", nullable(mdl.content) || ""));
+      fragment.append(element("small", "Last modified: " + mdl.lastModified));
+      break;
+    case "UserMarkBlockRange": {
+      const color = mdl.userMark.colorIndex;
+      const heading = element("h3", " " + (colorNames[color] || "Color " + color) + " marking");
+      heading.prepend(element("span", undefined, "color color-" + color));
+      fragment.append(heading);
+      for (const br of mdl.blockRanges || []) {
+        const tokens = [nullable(br.startToken), nullable(br.endToken)].map((t) => t ?? "-").join("–");
+        fragment.append(element("p", "Paragraph/verse " + br.identifier + ", words " + tokens));
+      }
+      break;
+    }
+    case "Bookmark":
+      fragment.append(element("h3", "Bookmark " + (mdl.slot + 1) + ": " + mdl.title));
+      fragment.append(element("p", nullable(mdl.snippet) || ""));
+      break;
+    case "InputField":
+      fragment.append(element("h3", "Input field " + mdl.textTag));
+      fragment.append(element("p", mdl.value));
+      break;
+    default:
+      fragment.append(element("h3", mdl.type));
+      fragment.append(element("pre", JSON.stringify(mdl, null, 2)));
+  }
+
+  if (related.reference) {
+    fragment.append(element("p", related.reference, "reference"));
+  }
+  fragment.append(renderLocation(related.location));
+  if (related.publicationLocation) {
+    fragment.append(renderLocation(related.publicationLocation));
+  }
+  return fragment;
+}
+
+function renderConflict() {
+  const conflict = state.conflicts[state.current];
+  $("#progress").textContent = "Conflict " + (state.current + 1) + " of " + state.conflicts.length;
+  $("#left").replaceChildren(element("h4", "Left"), renderModel(conflict.left));
+  $("#right").replaceChildren(element("h4", "Right"), renderModel(conflict.right));
+  $("#previous").disabled = state.current === 0;
+  $("#next").disabled = state.current >= state.conflicts.length - 1;
+}
+
+function renderStatus(status) {
+  let text = status.merged + " of " + status.backups + " backups merged";
+  if (status.step) {
+    text += " – currently merging " + status.step;
+  }
+  $("#status").textContent = text;
+}
+
+async function refresh() {
+  const status = await request("GET", "sessions/" + state.sessionID);
+  renderStatus(status);
+  if (status.done) {
+    show("done");
+    return;
+  }
+
+  state.conflicts = await request("GET", "sessions/" + state.sessionID + "/conflicts");
+  state.current = Math.max(0, Math.min(state.current, state.conflicts.length - 1));
+  show("conflicts");
+  renderConflict();
+}
+
+async function resolve(resolutions) {
+  $("#error").hidden = true;
+  await request("POST", "sessions/" + state.sessionID + "/resolutions", resolutions);
+  await refresh();
+}
+
+$("#upload-form").addEventListener("submit", async (event) => {
+  event.preventDefault();
+  $("#error").hidden = true;
+  try {
+    const status = await request("POST", "sessions", new FormData(event.target));
+    state.sessionID = status.id;
+    window.location.hash = status.id;
+    await refresh();
+  } catch (err) {
+    showError(err);
+  }
+});
+
+$(".choose").addEventListener("click", (event) => {
+  const side = event.target.dataset.side;
+  if (side) {
+    resolve([{ key: state.conflicts[state.current].key, side }]).catch(showError);
+  }
+});
+
+$(".bulk").addEventListener("click", (event) => {
+  const side = event.target.dataset.bulk;
+  if (side) {
+    resolve(state.conflicts.map((conflict) => ({ key: conflict.key, side }))).catch(showError);
+  }
+});
+
+$("#previous").addEventListener("click", () => {
+  state.current--;
+  renderConflict();
+});
+
+$("#next").addEventListener("click", () => {
+  state.current++;
+  renderConflict();
+});
+
+$("#download-form").addEventListener("submit", (event) => {
+  event.preventDefault();
+  const name = new FormData(event.target).get("name");
+  window.location.href = "sessions/" + state.sessionID + "/download?name=" + encodeURIComponent(name);
+});
+
+if (state.sessionID) {
+  refresh().catch((err) => {
+    showError(err);
+    show("upload");
+  });
+} else {
+  show("upload");
+}